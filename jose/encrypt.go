@@ -48,11 +48,16 @@ func Encrypt(data []byte, opts ...Option) (*JSONWebEncryption, error) {
 		return nil, err
 	}
 
+	iterations := PBKDF2Iterations
+	if ctx.pbes2Iterations > 0 {
+		iterations = ctx.pbes2Iterations
+	}
+
 	// Encrypt private key using PBES2
 	recipient := Recipient{
 		Algorithm:  PBES2_HS256_A128KW,
 		Key:        passphrase,
-		PBES2Count: PBKDF2Iterations,
+		PBES2Count: iterations,
 		PBES2Salt:  salt,
 	}
 
@@ -75,14 +80,45 @@ func Encrypt(data []byte, opts ...Option) (*JSONWebEncryption, error) {
 }
 
 // EncryptJWK returns the given JWK encrypted with the default encryption
-// algorithm (PBES2-HS256+A128KW).
-func EncryptJWK(jwk *JSONWebKey, passphrase []byte) (*JSONWebEncryption, error) {
+// algorithm (PBES2-HS256+A128KW). The compact serialization of the returned
+// JWE can be decrypted using DecryptJWK. The number of PBKDF2 iterations can
+// be configured using WithPBES2Iterations.
+func EncryptJWK(jwk *JSONWebKey, passphrase []byte, opts ...Option) (*JSONWebEncryption, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("failed to encrypt JWK: password cannot be empty")
+	}
+
 	b, err := json.Marshal(jwk)
 	if err != nil {
 		return nil, errors.Wrap(err, "error marshaling JWK")
 	}
 
-	return Encrypt(b, WithPassword(passphrase), WithContentType("jwk+json"))
+	opts = append(opts[:len(opts):len(opts)], WithPassword(passphrase), WithContentType("jwk+json"))
+	return Encrypt(b, opts...)
+}
+
+// DecryptJWK returns the JWK in the given JWE encrypted with a password, e.g.
+// using EncryptJWK. Unlike Decrypt, it fails if the data is not a JWE.
+func DecryptJWK(data, passphrase []byte) (*JSONWebKey, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("failed to decrypt JWK: password cannot be empty")
+	}
+
+	enc, err := ParseEncrypted(string(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing JWE")
+	}
+
+	b, err := enc.Decrypt(passphrase)
+	if err != nil {
+		return nil, errors.New("failed to decrypt JWE: invalid password")
+	}
+
+	jwk := new(JSONWebKey)
+	if err := json.Unmarshal(b, jwk); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling JWK")
+	}
+	return jwk, nil
 }
 
 // Decrypt returns the decrypted version of the given data if it's encrypted,
@@ -16,6 +16,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/smallstep/assert"
 	"go.step.sm/crypto/randutil"
+	"go.step.sm/crypto/x25519"
 )
 
 var testPassword = []byte("Supercalifragilisticexpialidocious")
@@ -344,6 +345,76 @@ func TestEncryptDecryptJWK(t *testing.T) {
 	}
 }
 
+func TestDecryptJWK(t *testing.T) {
+	ecKey := fixJWK(mustGenerateJWK(t, "EC", "P-256", "ES256", "sig", "", 0))
+	jwe, err := EncryptJWK(ecKey, testPassword, WithPBES2Iterations(1000))
+	assert.FatalError(t, err)
+	encrypted, err := jwe.CompactSerialize()
+	assert.FatalError(t, err)
+
+	// The compact JWE header contains the algorithm and the iterations.
+	enc, err := ParseEncrypted(encrypted)
+	assert.FatalError(t, err)
+	assert.Equals(t, string(PBES2_HS256_A128KW), enc.Header.Algorithm)
+	assert.Equals(t, float64(1000), enc.Header.ExtraHeaders["p2c"])
+	assert.Equals(t, "jwk+json", enc.Header.ExtraHeaders["cty"])
+
+	notJWK, err := Encrypt([]byte("not a jwk"), WithPassword(testPassword), WithPBES2Iterations(1000))
+	assert.FatalError(t, err)
+	notJWKData, err := notJWK.CompactSerialize()
+	assert.FatalError(t, err)
+
+	type args struct {
+		data       []byte
+		passphrase []byte
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *JSONWebKey
+		wantErr bool
+	}{
+		{"ok", args{[]byte(encrypted), testPassword}, ecKey, false},
+		{"fail wrong password", args{[]byte(encrypted), []byte("wrong password")}, nil, true},
+		{"fail empty password", args{[]byte(encrypted), nil}, nil, true},
+		{"fail not encrypted", args{[]byte(`{"kty":"oct","k":"c2VjcmV0"}`), testPassword}, nil, true},
+		{"fail not jwk", args{[]byte(notJWKData), testPassword}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecryptJWK(tt.args.data, tt.args.passphrase)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DecryptJWK() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecryptJWK() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptJWK_options(t *testing.T) {
+	ecKey := fixJWK(mustGenerateJWK(t, "EC", "P-256", "ES256", "sig", "", 0))
+
+	_, err := EncryptJWK(ecKey, nil)
+	assert.Error(t, err)
+	_, err = EncryptJWK(ecKey, []byte{})
+	assert.Error(t, err)
+	_, err = EncryptJWK(ecKey, testPassword, WithPBES2Iterations(999))
+	assert.Error(t, err)
+	_, err = EncryptJWK(ecKey, testPassword, WithPBES2Iterations(1000001))
+	assert.Error(t, err)
+
+	jwe, err := EncryptJWK(ecKey, testPassword, WithPBES2Iterations(2000))
+	assert.FatalError(t, err)
+	encrypted, err := jwe.CompactSerialize()
+	assert.FatalError(t, err)
+	enc, err := ParseEncrypted(encrypted)
+	assert.FatalError(t, err)
+	assert.Equals(t, float64(2000), enc.Header.ExtraHeaders["p2c"])
+}
+
 func TestDecrypt(t *testing.T) {
 	data := []byte("the-plain-data")
 	jwe := mustEncryptData(t, data, testPassword)
@@ -445,3 +516,49 @@ func TestDecrypt_highP2C(t *testing.T) {
 		<-timer.C
 	}
 }
+
+func TestEncryptDecrypt_ECDHES(t *testing.T) {
+	algs := []KeyAlgorithm{ECDH_ES, ECDH_ES_A128KW, ECDH_ES_A192KW, ECDH_ES_A256KW}
+	for _, crv := range []string{P256, P384, P521} {
+		jwk := mustGenerateJWK(t, "EC", crv, "", "enc", "", 0)
+		for _, alg := range algs {
+			t.Run(crv+"/"+string(alg), func(t *testing.T) {
+				encrypter, err := NewEncrypter(A256GCM, Recipient{
+					Algorithm: alg,
+					Key:       jwk.Public().Key,
+				}, nil)
+				assert.FatalError(t, err)
+				jwe, err := encrypter.Encrypt([]byte("the-plaintext"))
+				assert.FatalError(t, err)
+				s, err := jwe.CompactSerialize()
+				assert.FatalError(t, err)
+				jwe, err = ParseEncrypted(s)
+				assert.FatalError(t, err)
+				assert.Equals(t, string(alg), jwe.Header.Algorithm)
+				got, err := jwe.Decrypt(jwk.Key)
+				assert.FatalError(t, err)
+				assert.Equals(t, []byte("the-plaintext"), got)
+			})
+		}
+	}
+}
+
+func TestDecrypt_ECDHES_curveMismatch(t *testing.T) {
+	p256 := mustGenerateJWK(t, "EC", P256, "", "enc", "", 0)
+	p521 := mustGenerateJWK(t, "EC", P521, "", "enc", "", 0)
+
+	encrypter, err := NewEncrypter(A256GCM, Recipient{
+		Algorithm: ECDH_ES_A256KW,
+		Key:       p256.Public().Key,
+	}, nil)
+	assert.FatalError(t, err)
+	jwe, err := encrypter.Encrypt([]byte("the-plaintext"))
+	assert.FatalError(t, err)
+
+	_, err = jwe.Decrypt(p521.Key)
+	assert.Error(t, err)
+	_, x25519Priv, err := x25519.GenerateKey(rand.Reader)
+	assert.FatalError(t, err)
+	_, err = jwe.Decrypt(X25519Decrypter(x25519Priv))
+	assert.Error(t, err)
+}
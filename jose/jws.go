@@ -0,0 +1,84 @@
+package jose
+
+import (
+	"github.com/go-jose/go-jose/v3"
+	"github.com/pkg/errors"
+	"go.step.sm/crypto/x25519"
+)
+
+// headerB64 is the header parameter defined in RFC 7797 used to sign
+// unencoded payloads.
+const headerB64 = "b64"
+
+// WithUnencodedPayload returns SignerOptions that sign the payload without
+// base64url-encoding it, as defined in RFC 7797. The "b64" header is set to
+// false and added to the "crit" header. If opts is not nil, it will be
+// modified and returned.
+//
+// Unencoded payloads are only supported with detached signatures, see
+// SignDetached and VerifyDetached.
+func WithUnencodedPayload(opts *SignerOptions) *SignerOptions {
+	if opts == nil {
+		opts = new(SignerOptions)
+	}
+	return opts.WithBase64(false)
+}
+
+// SignDetached signs the given payload and returns the JWS in compact
+// serialization format with the payload detached, "<header>..<signature>".
+// The payload must be sent separately and provided to VerifyDetached.
+func SignDetached(signer Signer, payload []byte) (string, error) {
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		return "", errors.Wrap(TrimPrefix(err), "error signing payload")
+	}
+	s, err := jws.DetachedCompactSerialize()
+	if err != nil {
+		return "", errors.Wrap(TrimPrefix(err), "error serializing jws")
+	}
+	return s, nil
+}
+
+// ParseDetachedJWS parses a JWS in compact serialization format with a
+// detached payload.
+func ParseDetachedJWS(s string, payload []byte) (*JSONWebSignature, error) {
+	return jose.ParseDetached(s, payload)
+}
+
+// VerifyDetached validates the signature of a JWS on the given payload, that
+// was transmitted separately. The payload is the original payload, even if
+// the JWS was signed with an unencoded payload (RFC 7797). If the JWS uses the
+// "b64" header, it must be protected and included in the "crit" header.
+func VerifyDetached(jws *JSONWebSignature, payload []byte, publicKey interface{}) error {
+	if jws == nil || len(jws.Signatures) != 1 {
+		return errors.New("error verifying jws: expecting exactly one signature")
+	}
+	if err := validateB64Header(jws.Signatures[0]); err != nil {
+		return errors.Wrap(err, "error verifying jws")
+	}
+	if k, ok := publicKey.(x25519.PublicKey); ok {
+		publicKey = X25519Verifier(k)
+	}
+	return jws.DetachedVerify(payload, publicKey)
+}
+
+// validateB64Header validates the "b64" header following RFC 7797, section 6.
+func validateB64Header(sig Signature) error {
+	if _, ok := sig.Unprotected.ExtraHeaders[headerB64]; ok {
+		return errors.New("b64 header must be protected")
+	}
+	v, ok := sig.Protected.ExtraHeaders[headerB64]
+	if !ok {
+		return nil
+	}
+	if _, ok := v.(bool); !ok {
+		return errors.New("invalid b64 header")
+	}
+	crit, _ := sig.Protected.ExtraHeaders["crit"].([]interface{})
+	for _, name := range crit {
+		if name == headerB64 {
+			return nil
+		}
+	}
+	return errors.New("b64 header must be included in the crit header")
+}
@@ -0,0 +1,126 @@
+package jose
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/smallstep/assert"
+	"go.step.sm/crypto/x25519"
+)
+
+func TestSignDetached(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.FatalError(t, err)
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.FatalError(t, err)
+	xPub, xKey, err := x25519.GenerateKey(rand.Reader)
+	assert.FatalError(t, err)
+
+	payload := []byte(`{"event":"certificate.issued","id":"$.123"}`)
+
+	type args struct {
+		key  interface{}
+		pub  interface{}
+		opts *SignerOptions
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantEncoded bool
+	}{
+		{"ok ES256", args{ecKey, ecKey.Public(), nil}, true},
+		{"ok EdDSA", args{edKey, edPub, nil}, true},
+		{"ok XEdDSA", args{xKey, xPub, nil}, true},
+		{"ok ES256 unencoded", args{ecKey, ecKey.Public(), WithUnencodedPayload(nil)}, false},
+		{"ok EdDSA unencoded", args{edKey, edPub, WithUnencodedPayload(nil)}, false},
+		{"ok XEdDSA unencoded", args{xKey, xPub, WithUnencodedPayload(new(SignerOptions).WithType("JOSE"))}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewSigner(SigningKey{Key: tt.args.key}, tt.args.opts)
+			assert.FatalError(t, err)
+
+			s, err := SignDetached(signer, payload)
+			assert.FatalError(t, err)
+			parts := strings.Split(s, ".")
+			assert.Len(t, 3, parts)
+			assert.Equals(t, "", parts[1])
+
+			jws, err := ParseDetachedJWS(s, payload)
+			assert.FatalError(t, err)
+			assert.NoError(t, VerifyDetached(jws, payload, tt.args.pub))
+			assert.Error(t, VerifyDetached(jws, []byte("other payload"), tt.args.pub))
+
+			// The signing input uses the raw payload if it is unencoded.
+			b64, ok := jws.Signatures[0].Protected.ExtraHeaders[headerB64]
+			if tt.wantEncoded {
+				assert.False(t, ok)
+			} else {
+				assert.Equals(t, false, b64)
+				assert.Equals(t, []interface{}{"b64"}, jws.Signatures[0].Protected.ExtraHeaders["crit"])
+				if pub, ok := tt.args.pub.(ed25519.PublicKey); ok {
+					sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+					assert.FatalError(t, err)
+					assert.True(t, ed25519.Verify(pub, append([]byte(parts[0]+"."), payload...), sig))
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyDetached_fail(t *testing.T) {
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.FatalError(t, err)
+	payload := []byte("payload")
+
+	sign := func(opts *SignerOptions) *JSONWebSignature {
+		signer, err := NewSigner(SigningKey{Key: edKey}, opts)
+		assert.FatalError(t, err)
+		s, err := SignDetached(signer, payload)
+		assert.FatalError(t, err)
+		jws, err := ParseDetachedJWS(s, payload)
+		assert.FatalError(t, err)
+		return jws
+	}
+
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.FatalError(t, err)
+
+	tests := []struct {
+		name   string
+		jws    *JSONWebSignature
+		pub    interface{}
+		errMsg string
+	}{
+		{"fail nil", nil, edPub, "error verifying jws: expecting exactly one signature"},
+		{"fail b64 without crit", sign(new(SignerOptions).WithHeader("b64", false)), edPub, "error verifying jws: b64 header must be included in the crit header"},
+		{"fail wrong key", sign(WithUnencodedPayload(nil)), otherKey.Public(), "go-jose/go-jose: error in cryptographic primitive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyDetached(tt.jws, payload, tt.pub)
+			if assert.Error(t, err) {
+				assert.Equals(t, tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseDetachedJWS(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.FatalError(t, err)
+	signer, err := NewSigner(SigningKey{Key: edKey}, nil)
+	assert.FatalError(t, err)
+	s, err := SignDetached(signer, []byte("payload"))
+	assert.FatalError(t, err)
+
+	_, err = ParseDetachedJWS(s, nil)
+	assert.Error(t, err)
+	_, err = ParseDetachedJWS("not.a.jws", []byte("payload"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,242 @@
+package jose
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultRemoteKeySetCacheDuration is the time a remote key set is cached
+	// if the response does not define a Cache-Control max-age directive.
+	DefaultRemoteKeySetCacheDuration = time.Hour
+	// DefaultRemoteKeySetMinRefreshInterval is the minimum time between two
+	// refreshes triggered by an unknown kid.
+	DefaultRemoteKeySetMinRefreshInterval = time.Minute
+	// DefaultRemoteKeySetMaxStaleAge is the maximum time a key set can be used
+	// after its expiration if it cannot be refreshed.
+	DefaultRemoteKeySetMaxStaleAge = 24 * time.Hour
+	// DefaultRemoteKeySetTimeout is the maximum time a request to retrieve
+	// the key set can take.
+	DefaultRemoteKeySetTimeout = 30 * time.Second
+)
+
+// RemoteKeySetOptions are the options used to configure a RemoteKeySet. Zero
+// values will use the defaults.
+type RemoteKeySetOptions struct {
+	// Client is the HTTP client used to retrieve the key set. Defaults to
+	// http.DefaultClient.
+	Client *http.Client
+	// CacheDuration is the time a key set is cached if the response does not
+	// contain a Cache-Control max-age directive.
+	CacheDuration time.Duration
+	// MinRefreshInterval is the minimum time between two refreshes triggered
+	// by an unknown kid.
+	MinRefreshInterval time.Duration
+	// MaxStaleAge is the maximum time after the expiration a key set is used
+	// if it cannot be refreshed.
+	MaxStaleAge time.Duration
+	// Timeout is the maximum time a request to retrieve the key set can take.
+	Timeout time.Duration
+}
+
+// RemoteKeySet is a JWK Set retrieved from a URL. The key set is cached using
+// the Cache-Control header of the response, and it is refreshed when it
+// expires or when a key with an unknown kid is requested. Concurrent refreshes
+// share a single request, and the cached key set is served while it's in
+// flight.
+type RemoteKeySet struct {
+	url                string
+	client             *http.Client
+	cacheDuration      time.Duration
+	minRefreshInterval time.Duration
+	maxStaleAge        time.Duration
+	timeout            time.Duration
+	now                func() time.Time
+
+	mu          sync.Mutex
+	keys        *JSONWebKeySet
+	lastRefresh time.Time
+	expiresAt   time.Time
+	inflight    *refreshCall
+}
+
+// refreshCall is a refresh of the key set in progress.
+type refreshCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewRemoteKeySet creates a new RemoteKeySet that retrieves the JWK Set from
+// the given URL. The given context is only used for the initial retrieval of
+// the key set, which fails if it cannot be retrieved; later refreshes are not
+// bound to it.
+func NewRemoteKeySet(ctx gocontext.Context, jwksURL string, opts *RemoteKeySetOptions) (*RemoteKeySet, error) {
+	if opts == nil {
+		opts = new(RemoteKeySetOptions)
+	}
+	ks := &RemoteKeySet{
+		url:                jwksURL,
+		client:             opts.Client,
+		cacheDuration:      opts.CacheDuration,
+		minRefreshInterval: opts.MinRefreshInterval,
+		maxStaleAge:        opts.MaxStaleAge,
+		timeout:            opts.Timeout,
+		now:                time.Now,
+	}
+	if ks.client == nil {
+		ks.client = http.DefaultClient
+	}
+	if ks.cacheDuration <= 0 {
+		ks.cacheDuration = DefaultRemoteKeySetCacheDuration
+	}
+	if ks.minRefreshInterval <= 0 {
+		ks.minRefreshInterval = DefaultRemoteKeySetMinRefreshInterval
+	}
+	if ks.maxStaleAge <= 0 {
+		ks.maxStaleAge = DefaultRemoteKeySetMaxStaleAge
+	}
+	if ks.timeout <= 0 {
+		ks.timeout = DefaultRemoteKeySetTimeout
+	}
+
+	if err := ks.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// VerificationKey returns the key with the given kid. If the cached key set
+// has expired, or the kid is not found, the key set will be retrieved again.
+// If the key set cannot be refreshed, the stale key set will be used until
+// the max stale age is reached.
+func (ks *RemoteKeySet) VerificationKey(kid string) (*JSONWebKey, error) {
+	now := ks.now()
+	ks.mu.Lock()
+	expiresAt := ks.expiresAt
+	ks.mu.Unlock()
+
+	if !now.Before(expiresAt) {
+		if err := ks.refresh(gocontext.Background()); err != nil && now.Sub(expiresAt) >= ks.maxStaleAge {
+			return nil, err
+		}
+	}
+
+	jwk, lastRefresh, ok := ks.lookup(kid)
+	if ok {
+		return jwk, nil
+	}
+
+	// Refresh on unknown kids, it might be a rotated key. The cached key set
+	// is still valid, so if the refresh fails the key is just not found.
+	if now.Sub(lastRefresh) >= ks.minRefreshInterval {
+		if err := ks.refresh(gocontext.Background()); err == nil {
+			if jwk, _, ok := ks.lookup(kid); ok {
+				return jwk, nil
+			}
+		}
+	}
+
+	return nil, errors.Errorf("cannot find key with kid %s on %s", kid, ks.url)
+}
+
+// lookup returns the key with the given kid in the cached key set, and the
+// time of the last refresh.
+func (ks *RemoteKeySet) lookup(kid string) (*JSONWebKey, time.Time, bool) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	jwks := ks.keys.Key(kid)
+	if len(jwks) == 0 {
+		return nil, ks.lastRefresh, false
+	}
+	return &jwks[0], ks.lastRefresh, true
+}
+
+// refresh retrieves the key set from the URL and replaces the cached one. If
+// a refresh is already in progress, it waits for it and returns its result.
+// On error the current key set is kept.
+func (ks *RemoteKeySet) refresh(ctx gocontext.Context) error {
+	ks.mu.Lock()
+	if call := ks.inflight; call != nil {
+		ks.mu.Unlock()
+		<-call.done
+		return call.err
+	}
+	call := &refreshCall{done: make(chan struct{})}
+	ks.inflight = call
+	ks.lastRefresh = ks.now()
+	start := ks.lastRefresh
+	ks.mu.Unlock()
+
+	keys, maxAge, err := ks.fetch(ctx)
+
+	ks.mu.Lock()
+	if err == nil {
+		ks.keys = keys
+		ks.expiresAt = start.Add(maxAge)
+	}
+	ks.inflight = nil
+	call.err = err
+	ks.mu.Unlock()
+	close(call.done)
+	return err
+}
+
+// fetch retrieves the key set from the URL, and returns it with the time it
+// can be cached.
+func (ks *RemoteKeySet) fetch(ctx gocontext.Context) (*JSONWebKeySet, time.Duration, error) {
+	ctx, cancel := gocontext.WithTimeout(ctx, ks.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.url, http.NoBody)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "error creating request for %s", ks.url)
+	}
+	req.Header.Set("Accept", "application/jwk-set+json, application/json")
+
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "error retrieving %s", ks.url)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, 0, errors.Errorf("error retrieving %s: status code %d", ks.url, resp.StatusCode)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "error retrieving %s", ks.url)
+	}
+
+	keys := new(JSONWebKeySet)
+	if err := json.Unmarshal(b, keys); err != nil {
+		return nil, 0, errors.Errorf("error reading %s: unsupported format", ks.url)
+	}
+
+	return keys, cacheDuration(resp.Header, ks.cacheDuration), nil
+}
+
+// cacheDuration returns the time a response can be cached using the
+// Cache-Control header. It returns the default duration if the header does not
+// contain a max-age or a no-cache directive.
+func cacheDuration(h http.Header, def time.Duration) time.Duration {
+	for _, directive := range strings.Split(h.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-cache" || directive == "no-store":
+			return 0
+		case strings.HasPrefix(directive, "max-age="):
+			if n, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil && n >= 0 {
+				return time.Duration(n) * time.Second
+			}
+		}
+	}
+	return def
+}
@@ -0,0 +1,259 @@
+package jose
+
+import (
+	gocontext "context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smallstep/assert"
+)
+
+func TestNewRemoteKeySet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case "/ok":
+			w.Header().Set("Content-Type", "application/jwk-set+json")
+			http.ServeFile(w, r, "testdata/jwks.json")
+		case "/bad":
+			fmt.Fprintln(w, "not a jwks")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		opts    *RemoteKeySetOptions
+		wantErr bool
+	}{
+		{"ok", srv.URL + "/ok", nil, false},
+		{"ok with options", srv.URL + "/ok", &RemoteKeySetOptions{Client: srv.Client(), CacheDuration: time.Minute}, false},
+		{"fail not found", srv.URL + "/missing", nil, true},
+		{"fail bad format", srv.URL + "/bad", nil, true},
+		{"fail url", "https://%", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRemoteKeySet(gocontext.Background(), tt.url, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRemoteKeySet() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				assert.Nil(t, got)
+			} else {
+				assert.NotNil(t, got)
+			}
+		})
+	}
+}
+
+func TestRemoteKeySet_VerificationKey(t *testing.T) {
+	readKeySet := func(filenames ...string) string {
+		var keys []string
+		for _, fn := range filenames {
+			b, err := os.ReadFile(fn)
+			assert.FatalError(t, err)
+			keys = append(keys, string(b))
+		}
+		s := `{"keys":[`
+		for i, k := range keys {
+			if i > 0 {
+				s += ","
+			}
+			s += k
+		}
+		return s + "]}"
+	}
+
+	const (
+		p256Kid = "V93A-Yh7Bhw1W2E0igFciviJzX4PXPswoVgriehm9Co"
+		rsaKid  = "CIsktcixZ5GyfkoWFyEV0tp5foASmBV4D-W7clYrCu8"
+	)
+
+	var (
+		mu           sync.Mutex
+		requests     int
+		statusCode   = http.StatusOK
+		cacheControl = "max-age=60"
+		body         = readKeySet("testdata/p256.pub.json")
+	)
+	setResponse := func(code int, cc, b string) {
+		mu.Lock()
+		defer mu.Unlock()
+		statusCode, cacheControl, body = code, cc, b
+	}
+	getRequests := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		w.Header().Set("Cache-Control", cacheControl)
+		w.WriteHeader(statusCode)
+		fmt.Fprint(w, body)
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	ks, err := NewRemoteKeySet(gocontext.Background(), srv.URL, &RemoteKeySetOptions{
+		MinRefreshInterval: 10 * time.Second,
+		MaxStaleAge:        time.Hour,
+	})
+	assert.FatalError(t, err)
+	ks.now = func() time.Time { return now }
+	assert.Equals(t, 1, getRequests())
+
+	// Cached key
+	jwk, err := ks.VerificationKey(p256Kid)
+	assert.FatalError(t, err)
+	assert.Equals(t, p256Kid, jwk.KeyID)
+	assert.Equals(t, 1, getRequests())
+
+	// Unknown kid within the min refresh interval
+	_, err = ks.VerificationKey(rsaKid)
+	assert.Error(t, err)
+	assert.Equals(t, 1, getRequests())
+
+	// Rotated key set, the unknown kid triggers a refresh
+	setResponse(http.StatusOK, "max-age=60", readKeySet("testdata/rsa.pub.json"))
+	now = now.Add(20 * time.Second)
+	jwk, err = ks.VerificationKey(rsaKid)
+	assert.FatalError(t, err)
+	assert.Equals(t, rsaKid, jwk.KeyID)
+	assert.Equals(t, 2, getRequests())
+
+	// The old key is not available anymore
+	now = now.Add(20 * time.Second)
+	_, err = ks.VerificationKey(p256Kid)
+	assert.Error(t, err)
+	assert.Equals(t, 3, getRequests())
+
+	// Expired key set is refreshed
+	setResponse(http.StatusOK, "no-cache", readKeySet("testdata/p256.pub.json", "testdata/rsa.pub.json"))
+	now = now.Add(2 * time.Minute)
+	jwk, err = ks.VerificationKey(p256Kid)
+	assert.FatalError(t, err)
+	assert.Equals(t, p256Kid, jwk.KeyID)
+	assert.Equals(t, 4, getRequests())
+
+	// Network errors serve the stale key set
+	setResponse(http.StatusInternalServerError, "", "")
+	now = now.Add(30 * time.Minute)
+	jwk, err = ks.VerificationKey(rsaKid)
+	assert.FatalError(t, err)
+	assert.Equals(t, rsaKid, jwk.KeyID)
+	assert.Equals(t, 5, getRequests())
+
+	// Stale key set cannot be used after the max stale age
+	now = now.Add(time.Hour)
+	_, err = ks.VerificationKey(rsaKid)
+	assert.Error(t, err)
+	assert.Equals(t, 6, getRequests())
+
+	// Recover after the server is back
+	setResponse(http.StatusOK, "", readKeySet("testdata/rsa.pub.json"))
+	jwk, err = ks.VerificationKey(rsaKid)
+	assert.FatalError(t, err)
+	assert.Equals(t, rsaKid, jwk.KeyID)
+	assert.Equals(t, 7, getRequests())
+
+	// Unknown kids return the lookup miss if the refresh fails
+	setResponse(http.StatusInternalServerError, "", "")
+	now = now.Add(20 * time.Second)
+	_, err = ks.VerificationKey(p256Kid)
+	assert.HasPrefix(t, err.Error(), "cannot find key with kid "+p256Kid)
+	assert.Equals(t, 8, getRequests())
+}
+
+func TestRemoteKeySet_VerificationKey_concurrent(t *testing.T) {
+	const p256Kid = "V93A-Yh7Bhw1W2E0igFciviJzX4PXPswoVgriehm9Co"
+	b, err := os.ReadFile("testdata/p256.pub.json")
+	assert.FatalError(t, err)
+
+	var (
+		mu       sync.Mutex
+		requests int
+	)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+		// Block the refreshes until all the lookups are waiting.
+		if n > 1 {
+			<-release
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		fmt.Fprintf(w, `{"keys":[%s]}`, b)
+	}))
+	defer srv.Close()
+
+	// The context of the constructor is not used for the refreshes.
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	ks, err := NewRemoteKeySet(ctx, srv.URL, nil)
+	assert.FatalError(t, err)
+	cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ks.VerificationKey(p256Kid)
+			errs <- err
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equals(t, 2, requests)
+}
+
+func Test_cacheDuration(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		want         time.Duration
+	}{
+		{"empty", "", time.Hour},
+		{"max-age", "max-age=300", 5 * time.Minute},
+		{"max-age with others", "public, Max-Age=60, must-revalidate", time.Minute},
+		{"max-age zero", "max-age=0", 0},
+		{"no-cache", "no-cache", 0},
+		{"no-store", "private, no-store", 0},
+		{"bad max-age", "max-age=foo", time.Hour},
+		{"negative max-age", "max-age=-1", time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			if tt.cacheControl != "" {
+				h.Set("Cache-Control", tt.cacheControl)
+			}
+			if got := cacheDuration(h, time.Hour); got != tt.want {
+				t.Errorf("cacheDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package jose
 
 import (
+	"github.com/pkg/errors"
 	"go.step.sm/crypto/internal/utils"
 )
 
@@ -13,6 +14,7 @@ type context struct {
 	passwordPrompt   string
 	passwordPrompter PasswordPrompter
 	contentType      string
+	pbes2Iterations  int
 }
 
 // apply the options to the context and returns an error if one of the options
@@ -123,3 +125,17 @@ func WithContentType(cty string) Option {
 		return nil
 	}
 }
+
+// WithPBES2Iterations sets the number of PBKDF2 iterations used when
+// encrypting data with a password. By default PBKDF2Iterations are used. The
+// number of iterations must be between 1000, the minimum recommended by RFC
+// 7518, and 1000000, the maximum accepted when decrypting.
+func WithPBES2Iterations(n int) Option {
+	return func(ctx *context) error {
+		if n < minPBES2Iterations || n > maxPBES2Iterations {
+			return errors.Errorf("invalid number of PBES2 iterations %d: it must be between %d and %d", n, minPBES2Iterations, maxPBES2Iterations)
+		}
+		ctx.pbes2Iterations = n
+		return nil
+	}
+}
@@ -350,7 +350,11 @@ func guessJWKAlgorithm(ctx *context, jwk *JSONWebKey) {
 		case ed25519.PrivateKey, ed25519.PublicKey:
 			jwk.Algorithm = EdDSA
 		case x25519.PrivateKey, x25519.PublicKey:
-			jwk.Algorithm = XEdDSA
+			if jwk.Use == "enc" {
+				jwk.Algorithm = string(DefaultECKeyAlgorithm)
+			} else {
+				jwk.Algorithm = XEdDSA
+			}
 		}
 	}
 }
@@ -30,6 +30,12 @@ const PBKDF2SaltSize = 16
 // number of iterations from 100k to 650k.
 const PBKDF2Iterations = 600000
 
+// Limits of the number of PBKDF2 iterations set using WithPBES2Iterations.
+const (
+	minPBES2Iterations = 1000
+	maxPBES2Iterations = 1000000
+)
+
 // JSONWebSignature represents a signed JWS object after parsing.
 type JSONWebSignature = jose.JSONWebSignature
 
@@ -226,8 +232,12 @@ func ParseEncrypted(input string) (*JSONWebEncryption, error) {
 	return jose.ParseEncrypted(input)
 }
 
-// NewEncrypter creates an appropriate encrypter based on the key type.
+// NewEncrypter creates an appropriate encrypter based on the key type. X25519
+// keys are supported using the ECDH-ES key management algorithms.
 func NewEncrypter(enc ContentEncryption, rcpt Recipient, opts *EncrypterOptions) (Encrypter, error) {
+	if key, ok := x25519RecipientKey(rcpt.Key); ok {
+		return newX25519Encrypter(enc, rcpt, key, opts)
+	}
 	return jose.NewEncrypter(enc, rcpt, opts)
 }
 
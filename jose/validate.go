@@ -13,6 +13,7 @@ import (
 
 	"github.com/pkg/errors"
 	"go.step.sm/crypto/keyutil"
+	"go.step.sm/crypto/x25519"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -197,6 +198,12 @@ func validateEncJWK(jwk *JSONWebKey) error {
 			return nil
 		}
 		kty = "EC"
+	case x25519.PrivateKey, x25519.PublicKey:
+		switch alg {
+		case ECDH_ES, ECDH_ES_A128KW, ECDH_ES_A192KW, ECDH_ES_A256KW:
+			return nil
+		}
+		kty = OKP
 	case ed25519.PrivateKey, ed25519.PublicKey:
 		return errors.New("key Ed25519 cannot be used for encryption")
 	}
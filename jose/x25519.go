@@ -2,10 +2,15 @@ package jose
 
 import (
 	"crypto"
+	"crypto/aes"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/binary"
 	"fmt"
+	"strings"
 
+	"github.com/go-jose/go-jose/v3"
+	josecipher "github.com/go-jose/go-jose/v3/cipher"
 	"github.com/pkg/errors"
 	"go.step.sm/crypto/x25519"
 )
@@ -64,3 +69,236 @@ func (v X25519Verifier) VerifyPayload(payload, signature []byte, alg SignatureAl
 	}
 	return nil
 }
+
+// x25519Encrypter implements the jose.Encrypter interface using ECDH-ES with
+// an X25519 recipient key. The key agreement and the key derivation are done
+// on each call, and the resulting key is used for direct encryption or to wrap
+// the content encryption key, according to the recipient algorithm.
+type x25519Encrypter struct {
+	enc  ContentEncryption
+	rcpt Recipient
+	key  x25519.PublicKey
+	opts EncrypterOptions
+}
+
+func newX25519Encrypter(enc ContentEncryption, rcpt Recipient, key x25519.PublicKey, opts *EncrypterOptions) (*x25519Encrypter, error) {
+	switch rcpt.Algorithm {
+	case ECDH_ES:
+		if _, ok := contentKeySizes[enc]; !ok {
+			return nil, errors.Errorf("unsupported content encryption algorithm %s", enc)
+		}
+	case ECDH_ES_A128KW, ECDH_ES_A192KW, ECDH_ES_A256KW:
+	default:
+		return nil, errors.Errorf("x25519 key does not support the key algorithm %s", rcpt.Algorithm)
+	}
+	if len(key) != 32 {
+		return nil, errors.New("invalid x25519 key")
+	}
+	e := &x25519Encrypter{
+		enc:  enc,
+		rcpt: rcpt,
+		key:  key,
+	}
+	if opts != nil {
+		e.opts = *opts
+	}
+	return e, nil
+}
+
+// Encrypt encrypts the given plaintext.
+func (e *x25519Encrypter) Encrypt(plaintext []byte) (*JSONWebEncryption, error) {
+	return e.EncryptWithAuthData(plaintext, nil)
+}
+
+// EncryptWithAuthData encrypts the given plaintext and authenticates the given
+// additional data.
+func (e *x25519Encrypter) EncryptWithAuthData(plaintext, aad []byte) (*JSONWebEncryption, error) {
+	epk, priv, err := x25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "error generating ephemeral key")
+	}
+	z, err := priv.SharedKey(e.key)
+	if err != nil {
+		return nil, errors.Wrap(err, "error deriving shared key")
+	}
+
+	// Direct key agreement derives the content encryption key, in other case
+	// the derived key wraps it.
+	var alg KeyAlgorithm
+	var key []byte
+	if e.rcpt.Algorithm == ECDH_ES {
+		alg = DIRECT
+		key = deriveECDHES(string(e.enc), nil, nil, z, contentKeySizes[e.enc])
+	} else {
+		alg = KeyAlgorithm(strings.TrimPrefix(string(e.rcpt.Algorithm), "ECDH-ES+"))
+		key = deriveECDHES(string(e.rcpt.Algorithm), nil, nil, z, keyWrapSizes[e.rcpt.Algorithm])
+	}
+
+	// The protected header must contain the ECDH-ES algorithm and the
+	// ephemeral public key, these headers are set after the recipient ones.
+	opts := EncrypterOptions{
+		Compression:  e.opts.Compression,
+		ExtraHeaders: make(map[HeaderKey]interface{}, len(e.opts.ExtraHeaders)+2),
+	}
+	for k, v := range e.opts.ExtraHeaders {
+		opts.ExtraHeaders[k] = v
+	}
+	opts.ExtraHeaders["alg"] = e.rcpt.Algorithm
+	opts.ExtraHeaders["epk"] = map[string]string{
+		"kty": OKP,
+		"crv": "X25519",
+		"x":   base64.RawURLEncoding.EncodeToString(epk),
+	}
+
+	encrypter, err := jose.NewEncrypter(e.enc, jose.Recipient{
+		Algorithm: alg,
+		Key:       key,
+		KeyID:     e.rcpt.KeyID,
+	}, &opts)
+	if err != nil {
+		return nil, err
+	}
+	return encrypter.EncryptWithAuthData(plaintext, aad)
+}
+
+// Options returns the options used to create the encrypter.
+func (e *x25519Encrypter) Options() EncrypterOptions {
+	return e.opts
+}
+
+// x25519RecipientKey returns the X25519 public key of a recipient key if the
+// key is an X25519 key or a JWK with an X25519 key.
+func x25519RecipientKey(key interface{}) (x25519.PublicKey, bool) {
+	switch k := key.(type) {
+	case x25519.PublicKey:
+		return k, true
+	case x25519.PrivateKey:
+		pub, ok := k.Public().(x25519.PublicKey)
+		return pub, ok
+	case JSONWebKey:
+		return x25519RecipientKey(k.Key)
+	case *JSONWebKey:
+		return x25519RecipientKey(k.Key)
+	default:
+		return nil, false
+	}
+}
+
+// X25519Decrypter implements the jose.OpaqueKeyDecrypter interface using an
+// X25519 key and ECDH-ES, with or without key wrapping, as the key management
+// algorithm. It can be used to decrypt a JWE:
+//
+//	jwe.Decrypt(jose.X25519Decrypter(key))
+type X25519Decrypter x25519.PrivateKey
+
+// DecryptKey derives the content encryption key using the ephemeral public key
+// in the header, and unwraps the encrypted key if necessary. It will fail if
+// the ephemeral public key is not an X25519 key.
+func (d X25519Decrypter) DecryptKey(encryptedKey []byte, header Header) ([]byte, error) {
+	epk, ok := header.ExtraHeaders["epk"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("failed to decrypt key: missing or invalid epk header")
+	}
+	if epk["kty"] != OKP || epk["crv"] != "X25519" {
+		return nil, errors.Errorf("failed to decrypt key: epk with kty '%v' and crv '%v' does not match the recipient key", epk["kty"], epk["crv"])
+	}
+	x, err := decodeHeader(epk, "x")
+	if err != nil {
+		return nil, err
+	}
+	apu, err := decodeHeader(header.ExtraHeaders, "apu")
+	if err != nil {
+		return nil, err
+	}
+	apv, err := decodeHeader(header.ExtraHeaders, "apv")
+	if err != nil {
+		return nil, err
+	}
+
+	z, err := x25519.PrivateKey(d).SharedKey(x)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt key")
+	}
+
+	alg := KeyAlgorithm(header.Algorithm)
+	switch alg {
+	case ECDH_ES:
+		enc, _ := header.ExtraHeaders["enc"].(string)
+		size, ok := contentKeySizes[ContentEncryption(enc)]
+		if !ok {
+			return nil, errors.Errorf("failed to decrypt key: unsupported content encryption algorithm '%s'", enc)
+		}
+		if len(encryptedKey) != 0 {
+			return nil, errors.New("failed to decrypt key: unexpected encrypted key")
+		}
+		return deriveECDHES(enc, apu, apv, z, size), nil
+	case ECDH_ES_A128KW, ECDH_ES_A192KW, ECDH_ES_A256KW:
+		kek := deriveECDHES(string(alg), apu, apv, z, keyWrapSizes[alg])
+		block, err := aes.NewCipher(kek)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decrypt key")
+		}
+		cek, err := josecipher.KeyUnwrap(block, encryptedKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decrypt key")
+		}
+		return cek, nil
+	default:
+		return nil, errors.Errorf("x25519 key does not support the key algorithm %s", alg)
+	}
+}
+
+// decodeHeader decodes the base64url value of the given key in the header, it
+// returns nil if the key is not present.
+func decodeHeader[K ~string](m map[K]interface{}, key K) ([]byte, error) {
+	v, ok := m[key]
+	if !ok {
+		return nil, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, errors.Errorf("failed to decrypt key: invalid %s header", key)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decrypt key: invalid %s header", key)
+	}
+	return b, nil
+}
+
+// contentKeySizes are the sizes of the keys used by each content encryption
+// algorithm.
+var contentKeySizes = map[ContentEncryption]int{
+	A128CBC_HS256: 32,
+	A192CBC_HS384: 48,
+	A256CBC_HS512: 64,
+	A128GCM:       16,
+	A192GCM:       24,
+	A256GCM:       32,
+}
+
+// keyWrapSizes are the sizes of the keys used by each ECDH-ES key wrapping
+// algorithm.
+var keyWrapSizes = map[KeyAlgorithm]int{
+	ECDH_ES_A128KW: 16,
+	ECDH_ES_A192KW: 24,
+	ECDH_ES_A256KW: 32,
+}
+
+// deriveECDHES derives a key of the given size from the shared secret z using
+// the Concat KDF defined in RFC 7518, section 4.6.2.
+func deriveECDHES(algID string, apu, apv, z []byte, size int) []byte {
+	lengthPrefixed := func(b []byte) []byte {
+		out := make([]byte, len(b)+4)
+		binary.BigEndian.PutUint32(out, uint32(len(b)))
+		copy(out[4:], b)
+		return out
+	}
+	supPubInfo := make([]byte, 4)
+	binary.BigEndian.PutUint32(supPubInfo, uint32(size)*8)
+
+	key := make([]byte, size)
+	// Read on the KDF never fails
+	_, _ = josecipher.NewConcatKDF(crypto.SHA256, z, lengthPrefixed([]byte(algID)), lengthPrefixed(apu), lengthPrefixed(apv), supPubInfo, nil).Read(key)
+	return key
+}
@@ -358,3 +358,75 @@ func TestX25519Verifier_VerifyPayload(t *testing.T) {
 		})
 	}
 }
+
+func TestX25519Encrypter_Decrypter(t *testing.T) {
+	pub, priv, err := x25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPriv, err := x25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		enc  ContentEncryption
+		alg  KeyAlgorithm
+		key  interface{}
+	}{
+		{"ECDH-ES", A256GCM, ECDH_ES, pub},
+		{"ECDH-ES A128CBC-HS256", A128CBC_HS256, ECDH_ES, pub},
+		{"ECDH-ES+A128KW", A256GCM, ECDH_ES_A128KW, pub},
+		{"ECDH-ES+A192KW", A128GCM, ECDH_ES_A192KW, priv},
+		{"ECDH-ES+A256KW", A256CBC_HS512, ECDH_ES_A256KW, &JSONWebKey{Key: pub, KeyID: "the-kid"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypter, err := NewEncrypter(tt.enc, Recipient{Algorithm: tt.alg, Key: tt.key}, nil)
+			if err != nil {
+				t.Fatalf("NewEncrypter() error = %v", err)
+			}
+			jwe, err := encrypter.Encrypt([]byte("the-plaintext"))
+			if err != nil {
+				t.Fatalf("Encrypter.Encrypt() error = %v", err)
+			}
+			s, err := jwe.CompactSerialize()
+			if err != nil {
+				t.Fatalf("JSONWebEncryption.CompactSerialize() error = %v", err)
+			}
+			if jwe, err = ParseEncrypted(s); err != nil {
+				t.Fatalf("ParseEncrypted() error = %v", err)
+			}
+			if jwe.Header.Algorithm != string(tt.alg) {
+				t.Errorf("JSONWebEncryption.Header.Algorithm = %s, want %s", jwe.Header.Algorithm, tt.alg)
+			}
+			got, err := jwe.Decrypt(X25519Decrypter(priv))
+			if err != nil {
+				t.Fatalf("JSONWebEncryption.Decrypt() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, []byte("the-plaintext")) {
+				t.Errorf("JSONWebEncryption.Decrypt() = %s, want the-plaintext", got)
+			}
+			if _, err := jwe.Decrypt(X25519Decrypter(otherPriv)); err == nil {
+				t.Error("JSONWebEncryption.Decrypt() with other key error = nil")
+			}
+		})
+	}
+}
+
+func TestNewEncrypter_x25519Fail(t *testing.T) {
+	pub, _, err := x25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewEncrypter(A256GCM, Recipient{Algorithm: RSA_OAEP, Key: pub}, nil); err == nil {
+		t.Error("NewEncrypter() error = nil")
+	}
+	if _, err := NewEncrypter("foo", Recipient{Algorithm: ECDH_ES, Key: pub}, nil); err == nil {
+		t.Error("NewEncrypter() error = nil")
+	}
+	if _, err := NewEncrypter(A256GCM, Recipient{Algorithm: ECDH_ES, Key: x25519.PublicKey{1, 2, 3}}, nil); err == nil {
+		t.Error("NewEncrypter() error = nil")
+	}
+}
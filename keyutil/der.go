@@ -0,0 +1,110 @@
+package keyutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+)
+
+// Format is the DER encoding of a key.
+type Format int
+
+// Supported DER encodings.
+const (
+	// PKCS1Format is the RSA-only encoding defined in RFC 8017. It is used
+	// for both private and public keys.
+	PKCS1Format Format = iota + 1
+	// PKCS8Format is the private key encoding defined in RFC 5208.
+	PKCS8Format
+	// SEC1Format is the EC private key encoding defined in RFC 5915.
+	SEC1Format
+	// PKIXFormat is the SubjectPublicKeyInfo encoding of public keys defined
+	// in RFC 5280.
+	PKIXFormat
+)
+
+// String returns the name of the format.
+func (f Format) String() string {
+	switch f {
+	case PKCS1Format:
+		return "PKCS#1"
+	case PKCS8Format:
+		return "PKCS#8"
+	case SEC1Format:
+		return "SEC1"
+	case PKIXFormat:
+		return "PKIX"
+	default:
+		return "unknown"
+	}
+}
+
+// ConvertDER re-encodes the given DER key in the given format. The input can
+// be a PKCS#1, PKCS#8 or SEC1 private key, or a PKIX or PKCS#1 public key.
+// Private keys can only be converted to PKCS#1 (RSA), PKCS#8 or SEC1 (ECDSA),
+// and public keys to PKIX or PKCS#1 (RSA); any other conversion returns an
+// error.
+func ConvertDER(der []byte, to Format) ([]byte, error) {
+	key, private, err := parseDER(der)
+	if err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	switch to {
+	case PKCS1Format:
+		switch k := key.(type) {
+		case *rsa.PrivateKey:
+			b = x509.MarshalPKCS1PrivateKey(k)
+		case *rsa.PublicKey:
+			b = x509.MarshalPKCS1PublicKey(k)
+		default:
+			return nil, errors.Errorf("error converting key: %T cannot be encoded as %s", key, to)
+		}
+	case PKCS8Format:
+		if !private {
+			return nil, errors.Errorf("error converting key: a public key cannot be encoded as %s", to)
+		}
+		b, err = x509.MarshalPKCS8PrivateKey(key)
+	case SEC1Format:
+		k, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, errors.Errorf("error converting key: %T cannot be encoded as %s", key, to)
+		}
+		b, err = x509.MarshalECPrivateKey(k)
+	case PKIXFormat:
+		if private {
+			return nil, errors.Errorf("error converting key: a private key cannot be encoded as %s", to)
+		}
+		b, err = x509.MarshalPKIXPublicKey(key)
+	default:
+		return nil, errors.Errorf("error converting key: unsupported format %d", int(to))
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error converting key")
+	}
+	return b, nil
+}
+
+// parseDER parses a DER key in any of the supported formats, and returns the
+// key and whether it is a private key.
+func parseDER(der []byte) (key interface{}, private bool, err error) {
+	if key, err = x509.ParsePKCS8PrivateKey(der); err == nil {
+		return key, true, nil
+	}
+	if key, err = x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, true, nil
+	}
+	if key, err = x509.ParseECPrivateKey(der); err == nil {
+		return key, true, nil
+	}
+	if key, err = x509.ParsePKIXPublicKey(der); err == nil {
+		return key, false, nil
+	}
+	if key, err = x509.ParsePKCS1PublicKey(der); err == nil {
+		return key, false, nil
+	}
+	return nil, false, errors.New("error converting key: unsupported DER key format")
+}
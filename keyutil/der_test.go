@@ -0,0 +1,97 @@
+package keyutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"testing"
+
+	"github.com/smallstep/assert"
+)
+
+func TestConvertDER(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.FatalError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.FatalError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.FatalError(t, err)
+
+	mustPKCS8 := func(key interface{}) []byte {
+		b, err := x509.MarshalPKCS8PrivateKey(key)
+		assert.FatalError(t, err)
+		return b
+	}
+	mustPKIX := func(key interface{}) []byte {
+		b, err := x509.MarshalPKIXPublicKey(key)
+		assert.FatalError(t, err)
+		return b
+	}
+	mustSEC1 := func(key *ecdsa.PrivateKey) []byte {
+		b, err := x509.MarshalECPrivateKey(key)
+		assert.FatalError(t, err)
+		return b
+	}
+
+	rsaPKCS1 := x509.MarshalPKCS1PrivateKey(rsaKey)
+	rsaPKCS8 := mustPKCS8(rsaKey)
+	rsaPubPKCS1 := x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)
+	rsaPubPKIX := mustPKIX(&rsaKey.PublicKey)
+	ecSEC1 := mustSEC1(ecKey)
+	ecPKCS8 := mustPKCS8(ecKey)
+	ecPubPKIX := mustPKIX(&ecKey.PublicKey)
+	edPKCS8 := mustPKCS8(edKey)
+	edPubPKIX := mustPKIX(edKey.Public())
+
+	tests := []struct {
+		name    string
+		der     []byte
+		to      Format
+		want    []byte
+		wantErr bool
+	}{
+		{"rsa pkcs1 to pkcs8", rsaPKCS1, PKCS8Format, rsaPKCS8, false},
+		{"rsa pkcs8 to pkcs1", rsaPKCS8, PKCS1Format, rsaPKCS1, false},
+		{"rsa pkcs1 to pkcs1", rsaPKCS1, PKCS1Format, rsaPKCS1, false},
+		{"rsa public pkix to pkcs1", rsaPubPKIX, PKCS1Format, rsaPubPKCS1, false},
+		{"rsa public pkcs1 to pkix", rsaPubPKCS1, PKIXFormat, rsaPubPKIX, false},
+		{"ec sec1 to pkcs8", ecSEC1, PKCS8Format, ecPKCS8, false},
+		{"ec pkcs8 to sec1", ecPKCS8, SEC1Format, ecSEC1, false},
+		{"ec public pkix to pkix", ecPubPKIX, PKIXFormat, ecPubPKIX, false},
+		{"ed25519 pkcs8 to pkcs8", edPKCS8, PKCS8Format, edPKCS8, false},
+		{"ed25519 public pkix to pkix", edPubPKIX, PKIXFormat, edPubPKIX, false},
+		{"fail rsa to sec1", rsaPKCS1, SEC1Format, nil, true},
+		{"fail ec to pkcs1", ecSEC1, PKCS1Format, nil, true},
+		{"fail ed25519 to pkcs1", edPKCS8, PKCS1Format, nil, true},
+		{"fail ed25519 to sec1", edPKCS8, SEC1Format, nil, true},
+		{"fail private to pkix", rsaPKCS8, PKIXFormat, nil, true},
+		{"fail public to pkcs8", rsaPubPKIX, PKCS8Format, nil, true},
+		{"fail public to sec1", ecPubPKIX, SEC1Format, nil, true},
+		{"fail ec public to pkcs1", ecPubPKIX, PKCS1Format, nil, true},
+		{"fail unknown format", rsaPKCS1, Format(0), nil, true},
+		{"fail bad der", []byte("not a key"), PKCS8Format, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertDER(tt.der, tt.to)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equals(t, tt.want, got)
+		})
+	}
+}
+
+func TestFormat_String(t *testing.T) {
+	assert.Equals(t, "PKCS#1", PKCS1Format.String())
+	assert.Equals(t, "PKCS#8", PKCS8Format.String())
+	assert.Equals(t, "SEC1", SEC1Format.String())
+	assert.Equals(t, "PKIX", PKIXFormat.String())
+	assert.Equals(t, "unknown", Format(0).String())
+}
@@ -0,0 +1,126 @@
+package keyutil
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+
+	"github.com/pkg/errors"
+	"go.step.sm/crypto/x25519"
+)
+
+// GenerateECDHKey generates a key that can be used for key agreement using the
+// given curve. Supported curves are "P-256", "P-384", "P-521", and "X25519".
+//
+// Unlike the keys returned by GenerateSigner, the returned key cannot be used
+// to sign.
+func GenerateECDHKey(crv string) (*ecdh.PrivateKey, error) {
+	c, err := ecdhCurve(crv)
+	if err != nil {
+		return nil, err
+	}
+	key, err := c.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "error generating ECDH key")
+	}
+	return key, nil
+}
+
+// ECDHPrivateKey converts the given ECDSA or X25519 private key to an ECDH
+// private key. An ECDH private key is returned as is.
+func ECDHPrivateKey(priv crypto.PrivateKey) (*ecdh.PrivateKey, error) {
+	switch k := priv.(type) {
+	case *ecdh.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		key, err := k.ECDH()
+		if err != nil {
+			return nil, errors.Wrap(err, "error converting ECDSA key")
+		}
+		return key, nil
+	case x25519.PrivateKey:
+		key, err := ecdh.X25519().NewPrivateKey(k)
+		if err != nil {
+			return nil, errors.Wrap(err, "error converting X25519 key")
+		}
+		return key, nil
+	default:
+		return nil, errors.Errorf("unsupported private key type %T", priv)
+	}
+}
+
+// ECDHPublicKey converts the given ECDSA or X25519 public key to an ECDH public
+// key. An ECDH public key is returned as is.
+func ECDHPublicKey(pub crypto.PublicKey) (*ecdh.PublicKey, error) {
+	switch k := pub.(type) {
+	case *ecdh.PublicKey:
+		return k, nil
+	case *ecdsa.PublicKey:
+		key, err := k.ECDH()
+		if err != nil {
+			return nil, errors.Wrap(err, "error converting ECDSA key")
+		}
+		return key, nil
+	case x25519.PublicKey:
+		key, err := ecdh.X25519().NewPublicKey(k)
+		if err != nil {
+			return nil, errors.Wrap(err, "error converting X25519 key")
+		}
+		return key, nil
+	default:
+		return nil, errors.Errorf("unsupported public key type %T", pub)
+	}
+}
+
+// ECDSAPrivateKey converts the given ECDH private key on a NIST curve to an
+// ECDSA private key. X25519 keys are not supported.
+func ECDSAPrivateKey(priv *ecdh.PrivateKey) (*ecdsa.PrivateKey, error) {
+	pub, err := ECDSAPublicKey(priv.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	return &ecdsa.PrivateKey{
+		PublicKey: *pub,
+		D:         new(big.Int).SetBytes(priv.Bytes()),
+	}, nil
+}
+
+// ECDSAPublicKey converts the given ECDH public key on a NIST curve to an
+// ECDSA public key. X25519 keys are not supported.
+func ECDSAPublicKey(pub *ecdh.PublicKey) (*ecdsa.PublicKey, error) {
+	var c elliptic.Curve
+	switch pub.Curve() {
+	case ecdh.P256():
+		c = elliptic.P256()
+	case ecdh.P384():
+		c = elliptic.P384()
+	case ecdh.P521():
+		c = elliptic.P521()
+	default:
+		return nil, errors.New("unsupported ECDH curve: only P-256, P-384 and P-521 can be converted to ECDSA")
+	}
+	//nolint:staticcheck // the point is validated by crypto/ecdh
+	x, y := elliptic.Unmarshal(c, pub.Bytes())
+	if x == nil {
+		return nil, errors.New("error converting ECDH key: invalid point")
+	}
+	return &ecdsa.PublicKey{Curve: c, X: x, Y: y}, nil
+}
+
+func ecdhCurve(crv string) (ecdh.Curve, error) {
+	switch crv {
+	case "P-256":
+		return ecdh.P256(), nil
+	case "P-384":
+		return ecdh.P384(), nil
+	case "P-521":
+		return ecdh.P521(), nil
+	case "X25519":
+		return ecdh.X25519(), nil
+	default:
+		return nil, errors.Errorf("invalid value for argument crv (crv: '%s')", crv)
+	}
+}
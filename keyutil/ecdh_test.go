@@ -0,0 +1,143 @@
+package keyutil
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/smallstep/assert"
+	"go.step.sm/crypto/x25519"
+)
+
+func TestGenerateECDHKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		crv     string
+		want    ecdh.Curve
+		wantErr bool
+	}{
+		{"P-256", "P-256", ecdh.P256(), false},
+		{"P-384", "P-384", ecdh.P384(), false},
+		{"P-521", "P-521", ecdh.P521(), false},
+		{"X25519", "X25519", ecdh.X25519(), false},
+		{"fail Ed25519", "Ed25519", nil, true},
+		{"fail empty", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			priv, err := GenerateECDHKey(tt.crv)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateECDHKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				assert.Nil(t, priv)
+				return
+			}
+			assert.Equals(t, tt.want, priv.Curve())
+
+			// Key agreement with a second key
+			peer, err := GenerateECDHKey(tt.crv)
+			assert.FatalError(t, err)
+			s1, err := priv.ECDH(peer.PublicKey())
+			assert.FatalError(t, err)
+			s2, err := peer.ECDH(priv.PublicKey())
+			assert.FatalError(t, err)
+			assert.True(t, bytes.Equal(s1, s2))
+
+			// Using GenerateKey
+			key, err := GenerateKey("ECDH", tt.crv, 0)
+			assert.FatalError(t, err)
+			k, ok := key.(*ecdh.PrivateKey)
+			assert.Fatal(t, ok)
+			assert.Equals(t, tt.want, k.Curve())
+
+			pub, err := PublicKey(k)
+			assert.FatalError(t, err)
+			assert.True(t, Equal(k.PublicKey(), pub))
+			assert.True(t, Equal(k, k))
+			assert.False(t, Equal(k, priv))
+		})
+	}
+}
+
+func TestECDHPrivateKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.FatalError(t, err)
+	_, xKey, err := x25519.GenerateKey(rand.Reader)
+	assert.FatalError(t, err)
+	ecdhKey, err := ecdh.P384().GenerateKey(rand.Reader)
+	assert.FatalError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.FatalError(t, err)
+
+	tests := []struct {
+		name    string
+		priv    interface{}
+		pub     interface{}
+		wantErr bool
+	}{
+		{"ecdsa", ecKey, ecKey.Public(), false},
+		{"x25519", xKey, xKey.Public(), false},
+		{"ecdh", ecdhKey, ecdhKey.PublicKey(), false},
+		{"fail ed25519", edKey, edKey.Public(), true},
+		{"fail bad x25519", x25519.PrivateKey{1, 2, 3}, x25519.PublicKey{1, 2, 3}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			priv, err := ECDHPrivateKey(tt.priv)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ECDHPrivateKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			pub, err := ECDHPublicKey(tt.pub)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ECDHPublicKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				assert.Nil(t, priv)
+				assert.Nil(t, pub)
+				return
+			}
+			assert.True(t, priv.PublicKey().Equal(pub))
+
+			// Key agreement with the converted keys
+			peer, err := priv.Curve().GenerateKey(rand.Reader)
+			assert.FatalError(t, err)
+			s1, err := priv.ECDH(peer.PublicKey())
+			assert.FatalError(t, err)
+			s2, err := peer.ECDH(pub)
+			assert.FatalError(t, err)
+			assert.True(t, bytes.Equal(s1, s2))
+		})
+	}
+}
+
+func TestECDSAPrivateKey(t *testing.T) {
+	for _, crv := range []string{"P-256", "P-384", "P-521"} {
+		t.Run(crv, func(t *testing.T) {
+			priv, err := GenerateECDHKey(crv)
+			assert.FatalError(t, err)
+
+			ecKey, err := ECDSAPrivateKey(priv)
+			assert.FatalError(t, err)
+			ecPub, err := ECDSAPublicKey(priv.PublicKey())
+			assert.FatalError(t, err)
+			assert.True(t, ecKey.PublicKey.Equal(ecPub))
+
+			// Round trip
+			got, err := ECDHPrivateKey(ecKey)
+			assert.FatalError(t, err)
+			assert.True(t, priv.Equal(got))
+		})
+	}
+
+	x, err := GenerateECDHKey("X25519")
+	assert.FatalError(t, err)
+	_, err = ECDSAPrivateKey(x)
+	assert.Error(t, err)
+	_, err = ECDSAPublicKey(x.PublicKey())
+	assert.Error(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
+	"encoding/base64"
 	"fmt"
 
 	"go.step.sm/crypto/fingerprint"
@@ -72,3 +73,21 @@ func EncodedFingerprint(pub crypto.PublicKey, encoding FingerprintEncoding) (str
 	}
 	return "SHA256:" + fp, nil
 }
+
+// KeyID returns a deterministic identifier of a public key that can be used to
+// tag signing keys, for example, in logs or in a KMS. The identifier is the
+// base64url encoding, without padding, of the SHA-256 hash of the PKIX, ASN.1
+// DER form of the public key.
+//
+// Unlike the SubjectKeyID in a certificate, defined in RFC 5280 section
+// 4.2.1.2, and Fingerprint, the hash includes the algorithm identifier and
+// not only the subjectPublicKey bit string. An ECDSA key and the equivalent
+// ECDH key have the same KeyID, as their PKIX encodings are the same.
+func KeyID(pub crypto.PublicKey) (string, error) {
+	b, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("error marshaling public key: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
@@ -92,3 +92,99 @@ func TestEncodedFingerprint(t *testing.T) {
 		})
 	}
 }
+
+func TestKeyID(t *testing.T) {
+	ecdsaKey := readPublicKey(t, "testdata/p256.pub")
+	rsaKey := readPublicKey(t, "testdata/rsa.pub")
+	ed25519Key := readPublicKey(t, "testdata/ed25519.pub")
+
+	type args struct {
+		pub crypto.PublicKey
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{"ecdsa", args{ecdsaKey}, "RcPmjZlxMYvzxnLGFzei610pKgXMUJ2jl4Js-jxXmhU", false},
+		{"rsa", args{rsaKey}, "7bZ8w710u70ja1AtMmWwLveoDocYWPSW5NpzPGEoXCg", false},
+		{"ed25519", args{ed25519Key}, "uRFG9UT_JaIS2KV4w2kNi_uQMSTqfTKctmYFBQqeCIs", false},
+		{"fail", args{[]byte("not a key")}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := KeyID(tt.args.pub)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("KeyID() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("KeyID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyID_stable(t *testing.T) {
+	tests := []struct {
+		kty, crv string
+		size     int
+	}{
+		{"EC", "P-256", 0},
+		{"RSA", "", 2048},
+		{"OKP", "Ed25519", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kty, func(t *testing.T) {
+			signer, err := GenerateSigner(tt.kty, tt.crv, tt.size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := KeyID(signer.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The same key after a marshal and parse round trip.
+			b, err := x509.MarshalPKIXPublicKey(signer.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := x509.ParsePKIXPublicKey(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := KeyID(parsed); err != nil || got != want {
+				t.Errorf("KeyID() = %v, %v, want %v", got, err, want)
+			}
+
+			// A different key has a different identifier.
+			other, err := GenerateSigner(tt.kty, tt.crv, tt.size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := KeyID(other.Public()); err != nil || got == want {
+				t.Errorf("KeyID() = %v, %v, want a different identifier", got, err)
+			}
+
+			// It's not the fingerprint.
+			if fp, _ := EncodedFingerprint(signer.Public(), Base64RawURLFingerprint); fp == "SHA256:"+want {
+				t.Errorf("KeyID() = %v, want it to be different than the fingerprint", want)
+			}
+		})
+	}
+
+	// ECDSA and ECDH keys for the same point are equal.
+	pub, err := ECDHPublicKey(readPublicKey(t, "testdata/p256.pub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := KeyID(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "RcPmjZlxMYvzxnLGFzei610pKgXMUJ2jl4Js-jxXmhU"; got != want {
+		t.Errorf("KeyID() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,33 @@
+package keyutil
+
+import (
+	"crypto/sha256"
+	"io"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/hkdf"
+)
+
+// maxDeriveKeyLength is the maximum length of a key derived with HKDF-SHA256,
+// 255 times the size of the hash, as defined in RFC 5869.
+const maxDeriveKeyLength = 255 * sha256.Size
+
+// DeriveKey derives a key of the given length in bytes from the given secret
+// using HKDF-SHA256, as defined in RFC 5869. The secret is usually a shared
+// secret, e.g. the result of an ECDH key agreement, and it should not be used
+// directly as an encryption key. The salt is optional but recommended, and the
+// info binds the derived key to a context, e.g. an application and purpose.
+func DeriveKey(secret, salt, info []byte, length int) ([]byte, error) {
+	switch {
+	case len(secret) == 0:
+		return nil, errors.New("error deriving key: secret cannot be empty")
+	case length <= 0 || length > maxDeriveKeyLength:
+		return nil, errors.Errorf("error deriving key: invalid length %d, it must be between 1 and %d", length, maxDeriveKeyLength)
+	}
+
+	key := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), key); err != nil {
+		return nil, errors.Wrap(err, "error deriving key")
+	}
+	return key, nil
+}
@@ -0,0 +1,85 @@
+package keyutil
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/smallstep/assert"
+)
+
+func TestDeriveKey(t *testing.T) {
+	seq := func(from, to byte) []byte {
+		var b []byte
+		for i := int(from); i <= int(to); i++ {
+			b = append(b, byte(i))
+		}
+		return b
+	}
+
+	type args struct {
+		secret []byte
+		salt   []byte
+		info   []byte
+		length int
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []byte
+		wantErr bool
+	}{
+		// Test vectors from RFC 5869, appendix A.
+		{"ok rfc5869 A.1", args{bytes.Repeat([]byte{0x0b}, 22), seq(0x00, 0x0c), seq(0xf0, 0xf9), 42},
+			mustDecodeHex(t, "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"), false},
+		{"ok rfc5869 A.2", args{seq(0x00, 0x4f), seq(0x60, 0xaf), seq(0xb0, 0xff), 82},
+			mustDecodeHex(t, "b11e398dc80327a1c8e7f78c596a49344f012eda2d4efad8a050cc4c19afa97c59045a99cac7827271cb41c65e590e09da3275600c2f09b8367793a9aca3db71cc30c58179ec3e87c14c01d5c1f3434f1d87"), false},
+		{"ok rfc5869 A.3", args{bytes.Repeat([]byte{0x0b}, 22), nil, nil, 42},
+			mustDecodeHex(t, "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8"), false},
+		{"ok max length", args{[]byte("secret"), nil, nil, 255 * 32}, nil, false},
+		{"fail empty secret", args{nil, []byte("salt"), []byte("info"), 32}, nil, true},
+		{"fail zero length", args{[]byte("secret"), nil, nil, 0}, nil, true},
+		{"fail negative length", args{[]byte("secret"), nil, nil, -1}, nil, true},
+		{"fail long length", args{[]byte("secret"), nil, nil, 255*32 + 1}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DeriveKey(tt.args.secret, tt.args.salt, tt.args.info, tt.args.length)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DeriveKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Len(t, tt.args.length, got)
+			if tt.want != nil {
+				assert.Equals(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDeriveKey_ecdh(t *testing.T) {
+	alice, err := GenerateECDHKey("X25519")
+	assert.FatalError(t, err)
+	bob, err := GenerateECDHKey("X25519")
+	assert.FatalError(t, err)
+
+	aliceSecret, err := alice.ECDH(bob.PublicKey())
+	assert.FatalError(t, err)
+	bobSecret, err := bob.ECDH(alice.PublicKey())
+	assert.FatalError(t, err)
+
+	salt := []byte("salt")
+	aliceKey, err := DeriveKey(aliceSecret, salt, []byte("encryption"), 32)
+	assert.FatalError(t, err)
+	bobKey, err := DeriveKey(bobSecret, salt, []byte("encryption"), 32)
+	assert.FatalError(t, err)
+	assert.Equals(t, aliceKey, bobKey)
+
+	// A different context results in a different key.
+	otherKey, err := DeriveKey(aliceSecret, salt, []byte("authentication"), 32)
+	assert.FatalError(t, err)
+	assert.NotEquals(t, aliceKey, otherKey)
+}
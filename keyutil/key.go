@@ -3,14 +3,19 @@ package keyutil
 
 import (
 	"bytes"
+	"context"
 	"crypto"
+	"crypto/ecdh"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"flag"
 	"math/big"
+	"runtime"
+	"sync"
 	"sync/atomic"
 
 	"github.com/pkg/errors"
@@ -33,21 +38,41 @@ var (
 	MinRSAKeyBytes = 256
 )
 
-type atomicBool int32
+// insecureCounter counts the number of active calls to Insecure.
+type insecureCounter int32
 
-func (b *atomicBool) isSet() bool { return atomic.LoadInt32((*int32)(b)) != 0 }
-func (b *atomicBool) setTrue()    { atomic.StoreInt32((*int32)(b), 1) }
-func (b *atomicBool) setFalse()   { atomic.StoreInt32((*int32)(b), 0) }
+func (c *insecureCounter) isSet() bool { return atomic.LoadInt32((*int32)(c)) > 0 }
+func (c *insecureCounter) inc()        { atomic.AddInt32((*int32)(c), 1) }
+func (c *insecureCounter) dec()        { atomic.AddInt32((*int32)(c), -1) }
 
-var insecureMode atomicBool
+var insecureMode insecureCounter
+
+// isTestBinary reports whether the running binary was built by "go test". The
+// flags of the testing package are only registered in test binaries.
+var isTestBinary = func() bool {
+	return flag.Lookup("test.v") != nil
+}
 
 // Insecure enables the insecure mode in this package and returns a function to
 // revert the configuration. The insecure mode removes the minimum limits when
-// generating RSA keys.
+// generating RSA keys, so small keys, like RSA 512, can be generated quickly in
+// tests.
+//
+// WARNING: keys generated in insecure mode can be trivially broken. Insecure
+// can only be used in tests, it panics if it's called outside a test binary so
+// that the insecure mode can't be enabled by accident in production code.
+//
+// The insecure mode is enabled until all the functions returned by Insecure
+// have been called, so it can be safely used in parallel tests. Calling the
+// same revert function more than once has no effect.
 func Insecure() (revert func()) {
-	insecureMode.setTrue()
+	if !isTestBinary() {
+		panic("keyutil: Insecure can only be used in tests")
+	}
+	insecureMode.inc()
+	var once sync.Once
 	return func() {
-		insecureMode.setFalse()
+		once.Do(insecureMode.dec)
 	}
 }
 
@@ -62,7 +87,9 @@ func PublicKey(priv interface{}) (crypto.PublicKey, error) {
 		return k.Public(), nil
 	case x25519.PrivateKey:
 		return k.Public(), nil
-	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey, x25519.PublicKey:
+	case *ecdh.PrivateKey:
+		return k.PublicKey(), nil
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey, x25519.PublicKey, *ecdh.PublicKey:
 		return k, nil
 	case crypto.Signer:
 		return k.Public(), nil
@@ -83,11 +110,14 @@ func GenerateDefaultKeyPair() (crypto.PublicKey, crypto.PrivateKey, error) {
 	return GenerateKeyPair(DefaultKeyType, DefaultKeyCurve, DefaultKeySize)
 }
 
-// GenerateKey generates a key of the given type (kty).
+// GenerateKey generates a key of the given type (kty). The type "ECDH" will
+// generate an *ecdh.PrivateKey for key agreement, see GenerateECDHKey.
 func GenerateKey(kty, crv string, size int) (crypto.PrivateKey, error) {
 	switch kty {
 	case "EC", "RSA", "OKP":
 		return GenerateSigner(kty, crv, size)
+	case "ECDH":
+		return GenerateECDHKey(crv)
 	case "oct":
 		return generateOctKey(size)
 	default:
@@ -95,6 +125,83 @@ func GenerateKey(kty, crv string, size int) (crypto.PrivateKey, error) {
 	}
 }
 
+// GenerateKeys generates n keys of the given type (kty) concurrently, using
+// the given number of workers, see GenerateKey. If workers is less than 1,
+// GOMAXPROCS workers are used. It returns the first error found, or the
+// context error if ctx is done before all the keys are generated. On error,
+// the keys already generated are zeroed, see ZeroPrivateKey.
+func GenerateKeys(ctx context.Context, n int, kty, crv string, size, workers int) ([]crypto.PrivateKey, error) {
+	if n < 0 {
+		return nil, errors.Errorf("invalid number of keys: %d", n)
+	}
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	keys := make([]crypto.PrivateKey, n)
+	jobs := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				key, err := GenerateKey(kty, crv, size)
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+				keys[i] = key
+			}
+		}()
+	}
+
+	var sent int
+loop:
+	for sent < n && ctx.Err() == nil {
+		select {
+		case jobs <- sent:
+			sent++
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	switch {
+	case firstErr != nil:
+		zeroPrivateKeys(keys)
+		return nil, firstErr
+	case sent < n:
+		zeroPrivateKeys(keys)
+		return nil, ctx.Err()
+	default:
+		return keys, nil
+	}
+}
+
+// zeroPrivateKeys zeros the keys generated by GenerateKeys when it fails, so
+// the key material does not linger in memory.
+func zeroPrivateKeys(keys []crypto.PrivateKey) {
+	for _, k := range keys {
+		ZeroPrivateKey(k)
+	}
+}
+
 // GenerateKeyPair creates an asymmetric crypto keypair using input
 // configuration.
 func GenerateKeyPair(kty, crv string, size int) (crypto.PublicKey, crypto.PrivateKey, error) {
@@ -133,7 +240,8 @@ func ExtractKey(in interface{}) (interface{}, error) {
 	case *rsa.PublicKey, *rsa.PrivateKey,
 		*ecdsa.PublicKey, *ecdsa.PrivateKey,
 		ed25519.PublicKey, ed25519.PrivateKey,
-		x25519.PublicKey, x25519.PrivateKey:
+		x25519.PublicKey, x25519.PrivateKey,
+		*ecdh.PublicKey, *ecdh.PrivateKey:
 		return in, nil
 	case []byte:
 		return in, nil
@@ -189,6 +297,12 @@ func Equal(x, y any) bool {
 	case x25519.PrivateKey:
 		yy, ok := y.(x25519.PrivateKey)
 		return ok && xx.Equal(yy)
+	case *ecdh.PublicKey:
+		yy, ok := y.(*ecdh.PublicKey)
+		return ok && xx.Equal(yy)
+	case *ecdh.PrivateKey:
+		yy, ok := y.(*ecdh.PrivateKey)
+		return ok && xx.Equal(yy)
 	case []byte: // special case for symmetric keys
 		yy, ok := y.([]byte)
 		return ok && bytes.Equal(xx, yy)
@@ -257,6 +371,7 @@ func generateOctKey(size int) (interface{}, error) {
 	for i := range result {
 		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
 		if err != nil {
+			zeroBytes(result)
 			return nil, err
 		}
 		result[i] = chars[num.Int64()]
@@ -1,6 +1,7 @@
 package keyutil
 
 import (
+	"context"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/ed25519"
@@ -325,6 +326,64 @@ func TestGenerateKey(t *testing.T) {
 	}
 }
 
+func TestGenerateKeys(t *testing.T) {
+	keys, err := GenerateKeys(context.Background(), 50, "EC", "P-256", 0, 4)
+	assert.FatalError(t, err)
+	assert.Len(t, 50, keys)
+
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		key, ok := k.(*ecdsa.PrivateKey)
+		assert.Fatal(t, ok, "unexpected key type %T", k)
+		b, err := x509.MarshalPKIXPublicKey(key.Public())
+		assert.FatalError(t, err)
+		assert.False(t, seen[string(b)], "duplicated key")
+		seen[string(b)] = true
+	}
+
+	// Default number of workers
+	keys, err = GenerateKeys(context.Background(), 3, "OKP", "Ed25519", 0, 0)
+	assert.FatalError(t, err)
+	assert.Len(t, 3, keys)
+
+	keys, err = GenerateKeys(context.Background(), 0, "EC", "P-256", 0, 4)
+	assert.NoError(t, err)
+	assert.Len(t, 0, keys)
+}
+
+func TestGenerateKeys_fail(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	type args struct {
+		ctx     context.Context
+		n       int
+		kty     string
+		crv     string
+		size    int
+		workers int
+	}
+	tests := []struct {
+		name   string
+		args   args
+		errMsg string
+	}{
+		{"fail n", args{context.Background(), -1, "EC", "P-256", 0, 4}, "invalid number of keys: -1"},
+		{"fail kty", args{context.Background(), 10, "foo", "", 0, 4}, "unrecognized key type: foo"},
+		{"fail crv", args{context.Background(), 10, "EC", "P-128", 0, 4}, "invalid value for argument crv (crv: 'P-128')"},
+		{"fail canceled", args{canceled, 10, "EC", "P-256", 0, 1}, "context canceled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateKeys(tt.args.ctx, tt.args.n, tt.args.kty, tt.args.crv, tt.args.size, tt.args.workers)
+			if assert.Error(t, err) {
+				assert.Equals(t, tt.errMsg, err.Error())
+			}
+			assert.Nil(t, got)
+		})
+	}
+}
+
 func TestGenerateKey_rsa(t *testing.T) {
 	type args struct {
 		kty  string
@@ -686,6 +745,30 @@ func TestInsecure(t *testing.T) {
 			_, err = GenerateKey("RSA", "", 1024)
 			return
 		}, false},
+		{"fail RSA 512", func(t *testing.T) (err error) {
+			_, err = GenerateSigner("RSA", "", 512)
+			return
+		}, true},
+		{"ok RSA 512 insecure", func(t *testing.T) (err error) {
+			revert := Insecure()
+			t.Cleanup(revert)
+			_, err = GenerateSigner("RSA", "", 512)
+			return
+		}, false},
+		{"fail RSA 512 reverted", func(t *testing.T) (err error) {
+			Insecure()()
+			_, _, err = GenerateKeyPair("RSA", "", 512)
+			return
+		}, true},
+		{"ok RSA 512 nested", func(t *testing.T) (err error) {
+			revert := Insecure()
+			t.Cleanup(revert)
+			inner := Insecure()
+			inner()
+			inner() // no effect
+			_, _, err = GenerateKeyPair("RSA", "", 512)
+			return
+		}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -698,6 +781,19 @@ func TestInsecure(t *testing.T) {
 	}
 }
 
+func TestInsecure_notTest(t *testing.T) {
+	tmp := isTestBinary
+	t.Cleanup(func() { isTestBinary = tmp })
+	isTestBinary = func() bool { return false }
+
+	defer func() {
+		assert.Equals(t, "keyutil: Insecure can only be used in tests", recover())
+		assert.False(t, insecureMode.isSet())
+	}()
+	Insecure()
+	t.Error("Insecure() did not panic")
+}
+
 func TestEqual(t *testing.T) {
 	mustSigner := func(kty, crv string, size int) crypto.Signer {
 		s, err := GenerateSigner(kty, crv, size)
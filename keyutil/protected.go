@@ -0,0 +1,139 @@
+package keyutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.step.sm/crypto/x25519"
+)
+
+// ProtectedSigner is a crypto.Signer that wraps another signer and allows to
+// explicitly destroy its private key material, for example, when keys are
+// rotated in long-running services.
+//
+// Destroy can only zero the key material that is reachable from Go, it cannot
+// remove copies made by the runtime, and values cached in unexported fields of
+// the standard library keys. For signers backed by an HSM, a KMS, or any other
+// type that does not expose the private key, Destroy is a no-op for the key
+// material, but the ProtectedSigner will still refuse to sign.
+type ProtectedSigner struct {
+	mu        sync.RWMutex
+	signer    crypto.Signer
+	public    crypto.PublicKey
+	destroyed bool
+}
+
+// NewProtectedSigner returns a new ProtectedSigner wrapping the given signer.
+// The ProtectedSigner takes ownership of the signer, and the key must not be
+// used directly after calling Destroy.
+func NewProtectedSigner(signer crypto.Signer) (*ProtectedSigner, error) {
+	if signer == nil {
+		return nil, errors.New("signer cannot be nil")
+	}
+	return &ProtectedSigner{
+		signer: signer,
+		public: signer.Public(),
+	}, nil
+}
+
+// Public returns the public key of the signer. The public key is still
+// available after calling Destroy.
+func (s *ProtectedSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+// Sign signs the digest with the wrapped signer. It returns an error if the
+// signer has been destroyed.
+func (s *ProtectedSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.destroyed {
+		return nil, errors.New("error signing: signer has been destroyed")
+	}
+	return s.signer.Sign(rand, digest, opts)
+}
+
+// Destroy zeros the private key material of the wrapped signer, if it is an
+// RSA, ECDSA, Ed25519 or X25519 key, and makes further calls to Sign fail.
+// Destroy waits for in-flight calls to Sign and it is safe to call it multiple
+// times.
+func (s *ProtectedSigner) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.destroyed {
+		return
+	}
+	s.destroyed = true
+
+	ZeroPrivateKey(s.signer)
+	s.signer = nil
+}
+
+// ZeroPrivateKey overwrites with zeros the private key material of the given
+// key, if it is an RSA, ECDSA, Ed25519 or X25519 key, or a symmetric key
+// ([]byte). Other types of keys are not modified. The key cannot be used after
+// calling ZeroPrivateKey.
+//
+// Like Destroy, ZeroPrivateKey can only zero the key material that is
+// reachable from Go, it cannot remove copies made by the runtime, and values
+// cached in unexported fields of the standard library keys.
+func ZeroPrivateKey(k crypto.PrivateKey) {
+	switch k := k.(type) {
+	case *rsa.PrivateKey:
+		if k == nil {
+			return
+		}
+		zeroBigInt(k.D)
+		for _, p := range k.Primes {
+			zeroBigInt(p)
+		}
+		zeroBigInt(k.Precomputed.Dp)
+		zeroBigInt(k.Precomputed.Dq)
+		zeroBigInt(k.Precomputed.Qinv)
+		for _, v := range k.Precomputed.CRTValues {
+			zeroBigInt(v.Exp)
+			zeroBigInt(v.Coeff)
+			zeroBigInt(v.R)
+		}
+	case *ecdsa.PrivateKey:
+		if k == nil {
+			return
+		}
+		zeroBigInt(k.D)
+	case ed25519.PrivateKey:
+		zeroBytes(k)
+	case *ed25519.PrivateKey:
+		if k != nil {
+			zeroBytes(*k)
+		}
+	case x25519.PrivateKey:
+		zeroBytes(k)
+	case []byte:
+		zeroBytes(k)
+	}
+}
+
+// zeroBigInt overwrites the words of the given big.Int and sets it to 0.
+func zeroBigInt(x *big.Int) {
+	if x == nil {
+		return
+	}
+	b := x.Bits()
+	for i := range b {
+		b[i] = 0
+	}
+	x.SetInt64(0)
+}
+
+// zeroBytes overwrites the given slice with zeros.
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
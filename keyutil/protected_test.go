@@ -0,0 +1,172 @@
+package keyutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/smallstep/assert"
+	"go.step.sm/crypto/x25519"
+)
+
+type opaqueSigner struct {
+	crypto.Signer
+}
+
+func TestNewProtectedSigner(t *testing.T) {
+	signer, err := GenerateDefaultSigner()
+	assert.FatalError(t, err)
+
+	s, err := NewProtectedSigner(signer)
+	assert.FatalError(t, err)
+	assert.Equals(t, signer.Public(), s.Public())
+
+	_, err = NewProtectedSigner(nil)
+	assert.Error(t, err)
+}
+
+func TestProtectedSigner_Destroy(t *testing.T) {
+	mustSigner := func(kty, crv string, size int) crypto.Signer {
+		t.Helper()
+		signer, err := GenerateSigner(kty, crv, size)
+		assert.FatalError(t, err)
+		return signer
+	}
+	_, x25519Key, err := x25519.GenerateKey(rand.Reader)
+	assert.FatalError(t, err)
+
+	digest := sha256.Sum256([]byte("the-message"))
+	tests := []struct {
+		name    string
+		signer  crypto.Signer
+		message []byte
+		opts    crypto.SignerOpts
+		check   func(t *testing.T, signer crypto.Signer)
+	}{
+		{"ecdsa", mustSigner("EC", "P-256", 0), digest[:], crypto.SHA256, func(t *testing.T, signer crypto.Signer) {
+			assert.Equals(t, 0, signer.(*ecdsa.PrivateKey).D.Sign())
+		}},
+		{"rsa", mustSigner("RSA", "", 2048), digest[:], crypto.SHA256, func(t *testing.T, signer crypto.Signer) {
+			k := signer.(*rsa.PrivateKey)
+			assert.Equals(t, 0, k.D.Sign())
+			for _, p := range k.Primes {
+				assert.Equals(t, 0, p.Sign())
+			}
+			assert.Equals(t, 0, k.Precomputed.Dp.Sign())
+			assert.Equals(t, 0, k.Precomputed.Dq.Sign())
+			assert.Equals(t, 0, k.Precomputed.Qinv.Sign())
+		}},
+		{"ed25519", mustSigner("OKP", "Ed25519", 0), []byte("the-message"), crypto.Hash(0), func(t *testing.T, signer crypto.Signer) {
+			assert.Equals(t, make(ed25519.PrivateKey, ed25519.PrivateKeySize), signer.(ed25519.PrivateKey))
+		}},
+		{"x25519", x25519Key, []byte("the-message"), crypto.Hash(0), func(t *testing.T, signer crypto.Signer) {
+			assert.Equals(t, make(x25519.PrivateKey, x25519.PrivateKeySize), signer.(x25519.PrivateKey))
+		}},
+		{"opaque", opaqueSigner{mustSigner("EC", "P-256", 0)}, digest[:], crypto.SHA256, func(t *testing.T, signer crypto.Signer) {
+			// Destroy is a no-op for the key material.
+			_, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+			assert.NoError(t, err)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := tt.signer.Public()
+			s, err := NewProtectedSigner(tt.signer)
+			assert.FatalError(t, err)
+
+			_, err = s.Sign(rand.Reader, tt.message, tt.opts)
+			assert.FatalError(t, err)
+
+			s.Destroy()
+			tt.check(t, tt.signer)
+			assert.Equals(t, pub, s.Public())
+
+			sig, err := s.Sign(rand.Reader, tt.message, tt.opts)
+			assert.Error(t, err)
+			assert.Nil(t, sig)
+
+			// Destroy can be called multiple times.
+			s.Destroy()
+			_, err = s.Sign(rand.Reader, tt.message, tt.opts)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestZeroPrivateKey(t *testing.T) {
+	mustKey := func(kty, crv string, size int) crypto.PrivateKey {
+		t.Helper()
+		key, err := GenerateKey(kty, crv, size)
+		assert.FatalError(t, err)
+		return key
+	}
+	_, x25519Key, err := x25519.GenerateKey(rand.Reader)
+	assert.FatalError(t, err)
+
+	tests := []struct {
+		name  string
+		key   crypto.PrivateKey
+		check func(t *testing.T, key crypto.PrivateKey)
+	}{
+		{"ecdsa", mustKey("EC", "P-256", 0), func(t *testing.T, key crypto.PrivateKey) {
+			assert.Equals(t, 0, key.(*ecdsa.PrivateKey).D.Sign())
+		}},
+		{"rsa", mustKey("RSA", "", 2048), func(t *testing.T, key crypto.PrivateKey) {
+			k := key.(*rsa.PrivateKey)
+			assert.Equals(t, 0, k.D.Sign())
+			for _, p := range k.Primes {
+				assert.Equals(t, 0, p.Sign())
+			}
+			assert.Equals(t, 0, k.Precomputed.Dp.Sign())
+			assert.Equals(t, 0, k.Precomputed.Dq.Sign())
+			assert.Equals(t, 0, k.Precomputed.Qinv.Sign())
+		}},
+		{"ed25519", mustKey("OKP", "Ed25519", 0), func(t *testing.T, key crypto.PrivateKey) {
+			assert.Equals(t, make(ed25519.PrivateKey, ed25519.PrivateKeySize), key.(ed25519.PrivateKey))
+		}},
+		{"x25519", x25519Key, func(t *testing.T, key crypto.PrivateKey) {
+			assert.Equals(t, make(x25519.PrivateKey, x25519.PrivateKeySize), key.(x25519.PrivateKey))
+		}},
+		{"oct", mustKey("oct", "", 32), func(t *testing.T, key crypto.PrivateKey) {
+			assert.Equals(t, make([]byte, 32), key.([]byte))
+		}},
+		{"nil", nil, func(t *testing.T, key crypto.PrivateKey) {
+			assert.Nil(t, key)
+		}},
+		{"nil rsa", (*rsa.PrivateKey)(nil), func(t *testing.T, key crypto.PrivateKey) {
+			assert.Nil(t, key.(*rsa.PrivateKey))
+		}},
+		{"nil ecdsa", (*ecdsa.PrivateKey)(nil), func(t *testing.T, key crypto.PrivateKey) {
+			assert.Nil(t, key.(*ecdsa.PrivateKey))
+		}},
+		{"unsupported", "not-a-key", func(t *testing.T, key crypto.PrivateKey) {
+			assert.Equals(t, "not-a-key", key)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ZeroPrivateKey(tt.key)
+			tt.check(t, tt.key)
+		})
+	}
+}
+
+func TestProtectedSigner_Sign(t *testing.T) {
+	signer, err := GenerateDefaultSigner()
+	assert.FatalError(t, err)
+	s, err := NewProtectedSigner(signer)
+	assert.FatalError(t, err)
+
+	digest := sha256.Sum256([]byte("the-message"))
+	sig, err := s.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.FatalError(t, err)
+	assert.True(t, ecdsa.VerifyASN1(signer.Public().(*ecdsa.PublicKey), digest[:], sig))
+
+	// Errors from the wrapped signer are returned.
+	_, err = s.Sign(rand.Reader, []byte("bad-digest"), crypto.SHA256)
+	assert.Error(t, err)
+}
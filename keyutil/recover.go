@@ -0,0 +1,134 @@
+package keyutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"math/big"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// RecoverPublicKey recovers the ECDSA public key used to create the ASN.1
+// signature sig of the given hash, as defined in SEC 1, section 4.1.6. The
+// hash is truncated like in ecdsa.VerifyASN1.
+//
+// The recovery ID must be between 0 and 3. The lowest bit is the parity of
+// the y-coordinate of the ephemeral point R, and the second bit indicates
+// that its x-coordinate is r + N, instead of r, where N is the order of the
+// curve. Up to four keys can be recovered from the same signature, and the
+// recovery ID is used to choose one of them.
+//
+// Public key recovery is only supported on the curves P-256, P-384, and P-521.
+func RecoverPublicKey(curve elliptic.Curve, hash, sig []byte, recoveryID int) (*ecdsa.PublicKey, error) {
+	switch curve {
+	case elliptic.P256(), elliptic.P384(), elliptic.P521():
+	default:
+		return nil, errors.New("error recovering public key: unsupported curve")
+	}
+	if recoveryID < 0 || recoveryID > 3 {
+		return nil, errors.Errorf("error recovering public key: invalid recovery id %d", recoveryID)
+	}
+
+	r, s, err := parseECDSASignature(sig)
+	if err != nil {
+		return nil, err
+	}
+
+	params := curve.Params()
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(params.N) >= 0 || s.Cmp(params.N) >= 0 {
+		return nil, errors.New("error recovering public key: invalid signature")
+	}
+
+	// Calculate the point R = (x, y) where x = r + j*N.
+	x := new(big.Int).Set(r)
+	if recoveryID&2 != 0 {
+		x.Add(x, params.N)
+	}
+	if x.Cmp(params.P) >= 0 {
+		return nil, errors.New("error recovering public key: invalid recovery id for signature")
+	}
+	y := decompressY(params, x, recoveryID&1 == 1)
+	if y == nil {
+		return nil, errors.New("error recovering public key: invalid signature")
+	}
+
+	// Q = r^-1 * (s*R - e*G) = (-e * r^-1)*G + (s * r^-1)*R
+	e := hashToInt(hash, params.N)
+	rInv := new(big.Int).ModInverse(r, params.N)
+	u1 := new(big.Int).Mul(e, rInv)
+	u1.Neg(u1).Mod(u1, params.N)
+	u2 := new(big.Int).Mul(s, rInv)
+	u2.Mod(u2, params.N)
+
+	size := (params.BitSize + 7) / 8
+	//nolint:staticcheck // there's no other way to do arbitrary point operations
+	x1, y1 := curve.ScalarBaseMult(u1.FillBytes(make([]byte, size)))
+	//nolint:staticcheck // there's no other way to do arbitrary point operations
+	x2, y2 := curve.ScalarMult(x, y, u2.FillBytes(make([]byte, size)))
+	//nolint:staticcheck // there's no other way to do arbitrary point operations
+	qx, qy := curve.Add(x1, y1, x2, y2)
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		return nil, errors.New("error recovering public key: invalid signature")
+	}
+
+	pub := &ecdsa.PublicKey{Curve: curve, X: qx, Y: qy}
+	if !ecdsa.VerifyASN1(pub, hash, sig) {
+		return nil, errors.New("error recovering public key: invalid signature")
+	}
+	return pub, nil
+}
+
+// parseECDSASignature parses an ASN.1 ECDSA signature.
+func parseECDSASignature(sig []byte) (r, s *big.Int, err error) {
+	var inner cryptobyte.String
+	r, s = new(big.Int), new(big.Int)
+	input := cryptobyte.String(sig)
+	if !input.ReadASN1(&inner, cryptobyte_asn1.SEQUENCE) ||
+		!input.Empty() ||
+		!inner.ReadASN1Integer(r) ||
+		!inner.ReadASN1Integer(s) ||
+		!inner.Empty() {
+		return nil, nil, errors.New("error recovering public key: invalid signature format")
+	}
+	return r, s, nil
+}
+
+// decompressY returns the y-coordinate of the point with the given
+// x-coordinate and parity, or nil if there is no such point. It only supports
+// curves with a = -3.
+func decompressY(params *elliptic.CurveParams, x *big.Int, odd bool) *big.Int {
+	// y² = x³ - 3x + b
+	y2 := new(big.Int).Mul(x, x)
+	y2.Mul(y2, x)
+	threeX := new(big.Int).Lsh(x, 1)
+	threeX.Add(threeX, x)
+	y2.Sub(y2, threeX)
+	y2.Add(y2, params.B)
+	y2.Mod(y2, params.P)
+
+	y := new(big.Int).ModSqrt(y2, params.P)
+	if y == nil {
+		return nil
+	}
+	if (y.Bit(0) == 1) != odd {
+		y.Sub(params.P, y)
+	}
+	return y
+}
+
+// hashToInt converts a hash value to an integer, truncating it to the bit
+// length of the order of the curve, as defined in SEC 1, section 4.1.3.
+func hashToInt(hash []byte, n *big.Int) *big.Int {
+	orderBits := n.BitLen()
+	orderBytes := (orderBits + 7) / 8
+	if len(hash) > orderBytes {
+		hash = hash[:orderBytes]
+	}
+	e := new(big.Int).SetBytes(hash)
+	if excess := len(hash)*8 - orderBits; excess > 0 {
+		e.Rsh(e, uint(excess))
+	}
+	return e
+}
@@ -0,0 +1,146 @@
+package keyutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"math/big"
+	"testing"
+
+	"github.com/smallstep/assert"
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+func mustBigInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+	i, ok := new(big.Int).SetString(s, 16)
+	assert.True(t, ok)
+	return i
+}
+
+func mustMarshalSignature(t *testing.T, r, s *big.Int) []byte {
+	t.Helper()
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1BigInt(r)
+		b.AddASN1BigInt(s)
+	})
+	sig, err := b.Bytes()
+	assert.FatalError(t, err)
+	return sig
+}
+
+func TestRecoverPublicKey(t *testing.T) {
+	// Test vector from RFC 6979, section A.2.5, using P-256, SHA-256 and the
+	// message "sample".
+	want := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     mustBigInt(t, "60FED4BA255A9D31C961EB74C6356D68C049B8923B61FA6CE669622E60F29FB6"),
+		Y:     mustBigInt(t, "7903FE1008B8BC99A41AE9E95628BC64F2F1B20C2D7E9F5177A3C294D4462299"),
+	}
+	hash := sha256.Sum256([]byte("sample"))
+	sig := mustMarshalSignature(t,
+		mustBigInt(t, "EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716"),
+		mustBigInt(t, "F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8"),
+	)
+	assert.True(t, ecdsa.VerifyASN1(want, hash[:], sig))
+
+	var found int
+	for id := 0; id < 4; id++ {
+		pub, err := RecoverPublicKey(elliptic.P256(), hash[:], sig, id)
+		if err != nil {
+			continue
+		}
+		assert.True(t, ecdsa.VerifyASN1(pub, hash[:], sig))
+		if pub.Equal(want) {
+			found++
+		}
+	}
+	assert.Equals(t, 1, found)
+}
+
+func TestRecoverPublicKey_curves(t *testing.T) {
+	tests := []struct {
+		name  string
+		curve elliptic.Curve
+		hash  []byte
+	}{
+		{"P-256", elliptic.P256(), sha256Sum([]byte("message"))},
+		{"P-384", elliptic.P384(), sha384Sum([]byte("message"))},
+		{"P-521", elliptic.P521(), sha512Sum([]byte("message"))},
+		{"P-256 truncated hash", elliptic.P256(), sha512Sum([]byte("message"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ecdsa.GenerateKey(tt.curve, rand.Reader)
+			assert.FatalError(t, err)
+			sig, err := ecdsa.SignASN1(rand.Reader, key, tt.hash)
+			assert.FatalError(t, err)
+
+			var found bool
+			for id := 0; id < 4; id++ {
+				pub, err := RecoverPublicKey(tt.curve, tt.hash, sig, id)
+				if err == nil && pub.Equal(&key.PublicKey) {
+					found = true
+				}
+			}
+			assert.True(t, found)
+		})
+	}
+}
+
+func TestRecoverPublicKey_fail(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.FatalError(t, err)
+	hash := sha256Sum([]byte("message"))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash)
+	assert.FatalError(t, err)
+
+	n := elliptic.P256().Params().N
+	type args struct {
+		curve      elliptic.Curve
+		hash       []byte
+		sig        []byte
+		recoveryID int
+	}
+	tests := []struct {
+		name   string
+		args   args
+		errMsg string
+	}{
+		{"fail curve", args{elliptic.P224(), hash, sig, 0}, "error recovering public key: unsupported curve"},
+		{"fail negative recovery id", args{elliptic.P256(), hash, sig, -1}, "error recovering public key: invalid recovery id -1"},
+		{"fail recovery id", args{elliptic.P256(), hash, sig, 4}, "error recovering public key: invalid recovery id 4"},
+		{"fail signature format", args{elliptic.P256(), hash, []byte("foo"), 0}, "error recovering public key: invalid signature format"},
+		{"fail r zero", args{elliptic.P256(), hash, mustMarshalSignature(t, big.NewInt(0), big.NewInt(1)), 0}, "error recovering public key: invalid signature"},
+		{"fail s order", args{elliptic.P256(), hash, mustMarshalSignature(t, big.NewInt(1), n), 0}, "error recovering public key: invalid signature"},
+		{"fail r + N", args{elliptic.P256(), hash, mustMarshalSignature(t, new(big.Int).Sub(n, big.NewInt(1)), big.NewInt(1)), 2}, "error recovering public key: invalid recovery id for signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RecoverPublicKey(tt.args.curve, tt.args.hash, tt.args.sig, tt.args.recoveryID)
+			if assert.Error(t, err) {
+				assert.Equals(t, tt.errMsg, err.Error())
+			}
+			assert.Nil(t, got)
+		})
+	}
+}
+
+func sha256Sum(b []byte) []byte {
+	sum := sha256.Sum256(b)
+	return sum[:]
+}
+
+func sha384Sum(b []byte) []byte {
+	sum := sha512.Sum384(b)
+	return sum[:]
+}
+
+func sha512Sum(b []byte) []byte {
+	sum := sha512.Sum512(b)
+	return sum[:]
+}
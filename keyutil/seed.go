@@ -0,0 +1,134 @@
+package keyutil
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+
+	"github.com/pkg/errors"
+)
+
+// MinSeedSize is the minimum size in bytes of the seed used to generate EC
+// keys with GenerateKeyFromSeed.
+const MinSeedSize = 32
+
+// GenerateKeyFromSeed deterministically generates a private key of the given
+// type (kty) and curve (crv) from the given seed. The same seed always
+// produces the same key. Supported types are "EC", with the curves "P-256",
+// "P-384" and "P-521", and "OKP" with the curve "Ed25519".
+//
+// Ed25519 keys are created using ed25519.NewKeyFromSeed, and the seed must be
+// ed25519.SeedSize bytes long.
+//
+// EC keys use the seed, which must be at least MinSeedSize bytes long, to
+// instantiate an HMAC-DRBG with SHA-256 (NIST SP 800-90A), using the curve
+// name as the personalization string, and the scalar is taken from its output
+// by rejection sampling. This is not a standard key derivation function, and
+// keys derived by other implementations from the same seed will be different.
+// It is meant for reproducible test keys, and the seed must be kept as secret
+// as the private key.
+func GenerateKeyFromSeed(kty, crv string, seed []byte) (crypto.PrivateKey, error) {
+	switch kty {
+	case "EC":
+		return generateECKeyFromSeed(crv, seed)
+	case "OKP":
+		if crv != "Ed25519" {
+			return nil, errors.Errorf("missing or invalid value for argument 'crv'. "+
+				"expected 'Ed25519', but got '%s'", crv)
+		}
+		if len(seed) != ed25519.SeedSize {
+			return nil, errors.Errorf("invalid seed size: Ed25519 keys require a seed of %d bytes", ed25519.SeedSize)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	default:
+		return nil, errors.Errorf("unrecognized key type: %s", kty)
+	}
+}
+
+func generateECKeyFromSeed(crv string, seed []byte) (crypto.PrivateKey, error) {
+	var size int
+	var mask byte = 0xff
+	switch crv {
+	case "P-256":
+		size = 32
+	case "P-384":
+		size = 48
+	case "P-521":
+		// The order of P-521 has 521 bits, only the lowest bit of the
+		// first byte can be set.
+		size, mask = 66, 0x01
+	default:
+		return nil, errors.Errorf("invalid value for argument crv (crv: '%s')", crv)
+	}
+	if len(seed) < MinSeedSize {
+		return nil, errors.Errorf("invalid seed size: EC keys require a seed of at least %d bytes", MinSeedSize)
+	}
+
+	c, err := ecdhCurve(crv)
+	if err != nil {
+		return nil, err
+	}
+
+	// Candidates outside the range [1, N-1] are rejected by NewPrivateKey.
+	// The probability of rejecting a candidate is negligible for all the
+	// supported curves, but the number of tries is bounded regardless.
+	drbg := newHMACDRBG(seed, []byte(crv))
+	for i := 0; i < 100; i++ {
+		b := drbg.generate(size)
+		b[0] &= mask
+		var key *ecdh.PrivateKey
+		key, err = c.NewPrivateKey(b)
+		zeroBytes(b)
+		if err == nil {
+			return ECDSAPrivateKey(key)
+		}
+	}
+	return nil, errors.Wrap(err, "error generating EC key")
+}
+
+// hmacDRBG is a minimal HMAC-DRBG with SHA-256 as defined in NIST SP 800-90A,
+// without reseeding or additional input.
+type hmacDRBG struct {
+	k, v []byte
+}
+
+func newHMACDRBG(seed, personalization []byte) *hmacDRBG {
+	d := &hmacDRBG{
+		k: make([]byte, sha256.Size),
+		v: make([]byte, sha256.Size),
+	}
+	for i := range d.v {
+		d.v[i] = 0x01
+	}
+	d.update(append(append([]byte{}, seed...), personalization...))
+	return d
+}
+
+func (d *hmacDRBG) hmac(data ...[]byte) []byte {
+	h := hmac.New(sha256.New, d.k)
+	for _, b := range data {
+		h.Write(b)
+	}
+	return h.Sum(nil)
+}
+
+func (d *hmacDRBG) update(data []byte) {
+	d.k = d.hmac(d.v, []byte{0x00}, data)
+	d.v = d.hmac(d.v)
+	if len(data) > 0 {
+		d.k = d.hmac(d.v, []byte{0x01}, data)
+		d.v = d.hmac(d.v)
+	}
+}
+
+func (d *hmacDRBG) generate(n int) []byte {
+	out := make([]byte, 0, n)
+	for len(out) < n {
+		d.v = d.hmac(d.v)
+		out = append(out, d.v...)
+	}
+	d.update(nil)
+	return out[:n]
+}
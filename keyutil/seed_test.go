@@ -0,0 +1,99 @@
+package keyutil
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+
+	"github.com/smallstep/assert"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	assert.FatalError(t, err)
+	return b
+}
+
+func TestGenerateKeyFromSeed_Ed25519(t *testing.T) {
+	// Test vectors from RFC 8032, section 7.1.
+	tests := []struct {
+		name string
+		seed string
+		pub  string
+	}{
+		{"test 1", "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60", "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"},
+		{"test 2", "4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb", "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c"},
+		{"test 3", "c5aa8df43f9f837bedb7442f31dcb7b166d38535076f094b85ce3a2e0b4458f7", "fc51cd8e6218a1a38da47ed00230f0580816ed13ba3303ac5deb911548908025"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := GenerateKeyFromSeed("OKP", "Ed25519", mustDecodeHex(t, tt.seed))
+			assert.FatalError(t, err)
+			priv, ok := key.(ed25519.PrivateKey)
+			assert.Fatal(t, ok)
+			assert.Equals(t, mustDecodeHex(t, tt.seed), priv.Seed())
+			assert.Equals(t, ed25519.PublicKey(mustDecodeHex(t, tt.pub)), priv.Public())
+		})
+	}
+}
+
+func TestGenerateKeyFromSeed_EC(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x42}, 32)
+	tests := []struct {
+		crv string
+		d   string
+	}{
+		{"P-256", "a7c55409d9965b1b7ae9e961b5b67aac12bbe9095149752ebef441f2a31a21f0"},
+		{"P-384", "55bf576d849e62393ae5248c4e7ea1fff0eb7ba9d232191c97dc4dcbfad5cda2c048419ef668ddce0892c229359a43e8"},
+		{"P-521", "01674c3a4dfdad27f8890320eb5f2ad699b5dbcc055046cfc08b9bac1342ae7f9b57e254477486e00755b779d693a06718d5a0baf0499903c916ec87db33e6713cf7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.crv, func(t *testing.T) {
+			key, err := GenerateKeyFromSeed("EC", tt.crv, seed)
+			assert.FatalError(t, err)
+			priv, ok := key.(*ecdsa.PrivateKey)
+			assert.Fatal(t, ok)
+			assert.Equals(t, tt.crv, priv.Curve.Params().Name)
+			assert.Equals(t, mustDecodeHex(t, tt.d), priv.D.FillBytes(make([]byte, len(tt.d)/2)))
+			assert.True(t, priv.Curve.IsOnCurve(priv.X, priv.Y))
+			assert.FatalError(t, VerifyPair(priv.Public(), priv))
+
+			// The same seed must always generate the same key.
+			again, err := GenerateKeyFromSeed("EC", tt.crv, seed)
+			assert.FatalError(t, err)
+			assert.True(t, priv.Equal(again))
+
+			// A different seed must generate a different key.
+			other, err := GenerateKeyFromSeed("EC", tt.crv, bytes.Repeat([]byte{0x43}, 32))
+			assert.FatalError(t, err)
+			assert.False(t, priv.Equal(other))
+		})
+	}
+}
+
+func TestGenerateKeyFromSeed_fail(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x42}, 32)
+	tests := []struct {
+		name string
+		kty  string
+		crv  string
+		seed []byte
+	}{
+		{"fail kty", "RSA", "", seed},
+		{"fail EC crv", "EC", "P-224", seed},
+		{"fail EC X25519", "EC", "X25519", seed},
+		{"fail EC seed", "EC", "P-256", seed[:16]},
+		{"fail OKP crv", "OKP", "X25519", seed},
+		{"fail OKP seed", "OKP", "Ed25519", seed[:31]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := GenerateKeyFromSeed(tt.kty, tt.crv, tt.seed)
+			assert.Error(t, err)
+			assert.Nil(t, key)
+		})
+	}
+}
@@ -10,7 +10,8 @@ import (
 	"go.step.sm/crypto/kms/uri"
 )
 
-// KeyManager is the interface implemented by all the KMS.
+// KeyManager is the interface implemented by all the KMS. Close releases the
+// resources used by the KMS, and it must be safe to call it multiple times.
 type KeyManager interface {
 	GetPublicKey(req *GetPublicKeyRequest) (crypto.PublicKey, error)
 	CreateKey(req *CreateKeyRequest) (*CreateKeyResponse, error)
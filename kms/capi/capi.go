@@ -18,6 +18,7 @@ import (
 	"math/big"
 	"reflect"
 	"strings"
+	"sync"
 	"unsafe"
 
 	"github.com/pkg/errors"
@@ -85,6 +86,7 @@ type CAPIKMS struct {
 	providerName   string
 	providerHandle uintptr
 	pin            string
+	closed         sync.Once
 }
 
 func certContextToX509(certHandle *windows.CertContext) (*x509.Certificate, error) {
@@ -279,12 +281,15 @@ func init() {
 	})
 }
 
-func (k *CAPIKMS) Close() error {
-	if k.providerHandle != 0 {
-		return nCryptFreeObject(k.providerHandle)
-	}
-
-	return nil
+// Close releases the handle to the key storage provider. It is safe to call
+// Close multiple times, only the first call releases the handle.
+func (k *CAPIKMS) Close() (err error) {
+	k.closed.Do(func() {
+		if k.providerHandle != 0 {
+			err = nCryptFreeObject(k.providerHandle)
+		}
+	})
+	return
 }
 
 // CreateSigner returns a crypto.Signer that will sign using the key passed in via the URI.
@@ -9,6 +9,7 @@ import (
 	"crypto/x509"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"google.golang.org/grpc/codes"
@@ -101,6 +102,7 @@ var newKeyManagementClient = func(ctx context.Context, opts ...option.ClientOpti
 // CloudKMS implements a KMS using Google's Cloud apiv1.
 type CloudKMS struct {
 	client KeyManagementClient
+	closed sync.Once
 }
 
 // New creates a new CloudKMS configured with a new client.
@@ -145,12 +147,13 @@ func NewCloudKMS(client KeyManagementClient) *CloudKMS {
 	}
 }
 
-// Close closes the connection of the Cloud KMS client.
-func (k *CloudKMS) Close() error {
-	if err := k.client.Close(); err != nil {
-		return errors.Wrap(err, "cloudKMS Close failed")
-	}
-	return nil
+// Close closes the connection of the Cloud KMS client. It is safe to call Close
+// multiple times, only the first call closes the client.
+func (k *CloudKMS) Close() (err error) {
+	k.closed.Do(func() {
+		err = errors.Wrap(k.client.Close(), "cloudKMS Close failed")
+	})
+	return
 }
 
 // CreateSigner returns a new cloudkms signer configured with the given signing
@@ -134,7 +134,7 @@ func TestNewCloudKMS(t *testing.T) {
 		args args
 		want *CloudKMS
 	}{
-		{"ok", args{&MockClient{}}, &CloudKMS{&MockClient{}}},
+		{"ok", args{&MockClient{}}, &CloudKMS{client: &MockClient{}}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -165,6 +165,10 @@ func TestCloudKMS_Close(t *testing.T) {
 			if err := k.Close(); (err != nil) != tt.wantErr {
 				t.Errorf("CloudKMS.Close() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			// Close is idempotent
+			if err := k.Close(); err != nil {
+				t.Errorf("CloudKMS.Close() second call error = %v", err)
+			}
 		})
 	}
 }
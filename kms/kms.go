@@ -35,10 +35,24 @@ type Type = apiv1.Type
 // TypeOf returns the KMS type of the given uri.
 var TypeOf = apiv1.TypeOf
 
+// KeyManagerNewFunc is the type of the functions used to initialize a
+// KeyManager.
+type KeyManagerNewFunc = apiv1.KeyManagerNewFunc
+
+// Register adds a function to initialize the KeyManager of the given type.
+// The type is the scheme used in the KMS URIs, and New will use the
+// registered function for options with that type or URI scheme. It can be
+// used to plug in KMS implementations outside this module, and it replaces
+// any function previously registered with the same type.
+//
+// KeyManager implementations must allow Close to be called multiple times.
+var Register = apiv1.Register
+
 // Default is the implementation of the default KMS.
 var Default = &softkms.SoftKMS{}
 
-// New initializes a new KMS from the given type.
+// New initializes a new KMS from the given type, or the scheme of the given
+// URI if the type is not set, using the function registered for that type.
 func New(ctx context.Context, opts apiv1.Options) (KeyManager, error) {
 	if err := opts.Validate(); err != nil {
 		return nil, err
@@ -2,6 +2,7 @@ package kms
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -59,3 +60,44 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+type fakeKMS struct {
+	softkms.SoftKMS
+	opts apiv1.Options
+}
+
+func TestRegister(t *testing.T) {
+	Register("fakekms", func(ctx context.Context, opts apiv1.Options) (KeyManager, error) {
+		if opts.URI == "fakekms:fail=true" {
+			return nil, errors.New("fake error")
+		}
+		return &fakeKMS{opts: opts}, nil
+	})
+
+	km, err := New(context.Background(), apiv1.Options{URI: "fakekms:foo=bar"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	fake, ok := km.(*fakeKMS)
+	if !ok {
+		t.Fatalf("New() = %T, want *fakeKMS", km)
+	}
+	if fake.opts.URI != "fakekms:foo=bar" {
+		t.Errorf("New() options URI = %s, want fakekms:foo=bar", fake.opts.URI)
+	}
+
+	// Types and schemes are case insensitive.
+	if _, err := New(context.Background(), apiv1.Options{Type: "FakeKMS"}); err != nil {
+		t.Errorf("New() error = %v", err)
+	}
+	if typ, err := TypeOf("FAKEKMS:foo=bar"); err != nil || typ != "fakekms" {
+		t.Errorf("TypeOf() = %s, %v, want fakekms", typ, err)
+	}
+
+	if _, err := New(context.Background(), apiv1.Options{URI: "fakekms:fail=true"}); err == nil {
+		t.Error("New() error = nil, want error")
+	}
+	if _, err := New(context.Background(), apiv1.Options{URI: "notregistered:foo=bar"}); err == nil {
+		t.Error("New() error = nil, want error")
+	}
+}
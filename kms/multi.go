@@ -0,0 +1,153 @@
+package kms
+
+import (
+	"crypto"
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+	"go.step.sm/crypto/kms/apiv1"
+)
+
+// MultiKMS is a KeyManager that combines multiple KMS. Read operations, like
+// GetPublicKey or CreateSigner, are tried in order on every KMS, and the
+// result of the first one that succeeds is returned. Write operations, like
+// CreateKey or StoreCertificate, always use the primary KMS.
+//
+// It can be used to migrate keys from one KMS to another: new keys are created
+// in the primary KMS, while existing keys can still be used from the other
+// ones.
+type MultiKMS struct {
+	primary KeyManager
+	others  []KeyManager
+}
+
+// NewMulti returns a new MultiKMS that creates keys in the primary KMS and
+// reads them from the primary or any of the other KMS, in order.
+func NewMulti(primary KeyManager, others ...KeyManager) (*MultiKMS, error) {
+	if primary == nil {
+		return nil, errors.New("multi kms: primary kms cannot be nil")
+	}
+	for _, km := range others {
+		if km == nil {
+			return nil, errors.New("multi kms: kms cannot be nil")
+		}
+	}
+	return &MultiKMS{
+		primary: primary,
+		others:  others,
+	}, nil
+}
+
+// all returns all the KMS, starting with the primary.
+func (k *MultiKMS) all() []KeyManager {
+	return append([]KeyManager{k.primary}, k.others...)
+}
+
+// GetPublicKey returns the public key from the first KMS that has it. If no
+// KMS has it, it returns the error of the primary KMS.
+func (k *MultiKMS) GetPublicKey(req *apiv1.GetPublicKeyRequest) (crypto.PublicKey, error) {
+	var firstErr error
+	for _, km := range k.all() {
+		pub, err := km.GetPublicKey(req)
+		if err == nil {
+			return pub, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// CreateKey creates a new key in the primary KMS.
+func (k *MultiKMS) CreateKey(req *apiv1.CreateKeyRequest) (*apiv1.CreateKeyResponse, error) {
+	return k.primary.CreateKey(req)
+}
+
+// CreateSigner returns a signer from the first KMS that has the key. If no KMS
+// has it, it returns the error of the primary KMS.
+func (k *MultiKMS) CreateSigner(req *apiv1.CreateSignerRequest) (crypto.Signer, error) {
+	var firstErr error
+	for _, km := range k.all() {
+		signer, err := km.CreateSigner(req)
+		if err == nil {
+			return signer, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// CreateDecrypter returns a decrypter from the first KMS implementing
+// apiv1.Decrypter that has the key. If no KMS has it, it returns the error of
+// the first KMS implementing apiv1.Decrypter.
+func (k *MultiKMS) CreateDecrypter(req *apiv1.CreateDecrypterRequest) (crypto.Decrypter, error) {
+	var firstErr error
+	for _, km := range k.all() {
+		d, ok := km.(apiv1.Decrypter)
+		if !ok {
+			continue
+		}
+		decrypter, err := d.CreateDecrypter(req)
+		if err == nil {
+			return decrypter, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		return nil, apiv1.NotImplementedError{Message: "multi kms: no kms implements CreateDecrypter"}
+	}
+	return nil, firstErr
+}
+
+// LoadCertificate returns the certificate from the first KMS implementing
+// apiv1.CertificateManager that has it. If no KMS has it, it returns the error
+// of the first KMS implementing apiv1.CertificateManager.
+func (k *MultiKMS) LoadCertificate(req *apiv1.LoadCertificateRequest) (*x509.Certificate, error) {
+	var firstErr error
+	for _, km := range k.all() {
+		cm, ok := km.(apiv1.CertificateManager)
+		if !ok {
+			continue
+		}
+		cert, err := cm.LoadCertificate(req)
+		if err == nil {
+			return cert, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		return nil, apiv1.NotImplementedError{Message: "multi kms: no kms implements LoadCertificate"}
+	}
+	return nil, firstErr
+}
+
+// StoreCertificate stores the certificate in the primary KMS.
+func (k *MultiKMS) StoreCertificate(req *apiv1.StoreCertificateRequest) error {
+	cm, ok := k.primary.(apiv1.CertificateManager)
+	if !ok {
+		return apiv1.NotImplementedError{Message: "multi kms: primary kms does not implement StoreCertificate"}
+	}
+	return cm.StoreCertificate(req)
+}
+
+// Close closes all the KMS, and returns the first error found.
+func (k *MultiKMS) Close() error {
+	var firstErr error
+	for _, km := range k.all() {
+		if err := km.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+var _ apiv1.KeyManager = (*MultiKMS)(nil)
+var _ apiv1.Decrypter = (*MultiKMS)(nil)
+var _ apiv1.CertificateManager = (*MultiKMS)(nil)
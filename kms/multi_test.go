@@ -0,0 +1,283 @@
+package kms
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.step.sm/crypto/kms/apiv1"
+)
+
+// fakeBackend is a KeyManager that keeps the keys in memory.
+type fakeBackend struct {
+	keys     map[string]crypto.Signer
+	closed   bool
+	closeErr error
+}
+
+func newFakeBackend(t *testing.T, names ...string) *fakeBackend {
+	t.Helper()
+	f := &fakeBackend{keys: make(map[string]crypto.Signer)}
+	for _, name := range names {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		f.keys[name] = key
+	}
+	return f
+}
+
+func (f *fakeBackend) GetPublicKey(req *apiv1.GetPublicKeyRequest) (crypto.PublicKey, error) {
+	if key, ok := f.keys[req.Name]; ok {
+		return key.Public(), nil
+	}
+	return nil, errors.New("key not found")
+}
+
+func (f *fakeBackend) CreateKey(req *apiv1.CreateKeyRequest) (*apiv1.CreateKeyResponse, error) {
+	if _, ok := f.keys[req.Name]; ok {
+		return nil, apiv1.AlreadyExistsError{}
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	f.keys[req.Name] = key
+	return &apiv1.CreateKeyResponse{
+		Name:      req.Name,
+		PublicKey: key.Public(),
+		CreateSignerRequest: apiv1.CreateSignerRequest{
+			SigningKey: req.Name,
+		},
+	}, nil
+}
+
+func (f *fakeBackend) CreateSigner(req *apiv1.CreateSignerRequest) (crypto.Signer, error) {
+	if key, ok := f.keys[req.SigningKey]; ok {
+		return key, nil
+	}
+	return nil, errors.New("key not found")
+}
+
+func (f *fakeBackend) Close() error {
+	f.closed = true
+	return f.closeErr
+}
+
+// fakeCertBackend is a fakeBackend that can decrypt and store certificates.
+type fakeCertBackend struct {
+	*fakeBackend
+	decrypters map[string]crypto.Decrypter
+	certs      map[string]*x509.Certificate
+}
+
+func (f *fakeCertBackend) CreateDecrypter(req *apiv1.CreateDecrypterRequest) (crypto.Decrypter, error) {
+	if d, ok := f.decrypters[req.DecryptionKey]; ok {
+		return d, nil
+	}
+	return nil, errors.New("key not found")
+}
+
+func (f *fakeCertBackend) LoadCertificate(req *apiv1.LoadCertificateRequest) (*x509.Certificate, error) {
+	if cert, ok := f.certs[req.Name]; ok {
+		return cert, nil
+	}
+	return nil, errors.New("certificate not found")
+}
+
+func (f *fakeCertBackend) StoreCertificate(req *apiv1.StoreCertificateRequest) error {
+	f.certs[req.Name] = req.Certificate
+	return nil
+}
+
+func TestNewMulti(t *testing.T) {
+	primary := newFakeBackend(t)
+	secondary := newFakeBackend(t)
+
+	km, err := NewMulti(primary, secondary)
+	require.NoError(t, err)
+	assert.Equal(t, []KeyManager{primary, secondary}, km.all())
+
+	km, err = NewMulti(primary)
+	require.NoError(t, err)
+	assert.Equal(t, []KeyManager{primary}, km.all())
+
+	_, err = NewMulti(nil, secondary)
+	assert.Error(t, err)
+
+	_, err = NewMulti(primary, secondary, nil)
+	assert.Error(t, err)
+}
+
+func TestMultiKMS_GetPublicKey(t *testing.T) {
+	primary := newFakeBackend(t, "both", "primary")
+	secondary := newFakeBackend(t, "both", "secondary")
+	km, err := NewMulti(primary, secondary)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		want    crypto.PublicKey
+		wantErr bool
+	}{
+		{"primary", primary.keys["primary"].Public(), false},
+		{"secondary", secondary.keys["secondary"].Public(), false},
+		{"both", primary.keys["both"].Public(), false},
+		{"missing", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := km.GetPublicKey(&apiv1.GetPublicKeyRequest{Name: tt.name})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMultiKMS_CreateSigner(t *testing.T) {
+	primary := newFakeBackend(t, "both", "primary")
+	secondary := newFakeBackend(t, "both", "secondary")
+	km, err := NewMulti(primary, secondary)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		want    crypto.Signer
+		wantErr bool
+	}{
+		{"primary", primary.keys["primary"], false},
+		{"secondary", secondary.keys["secondary"], false},
+		{"both", primary.keys["both"], false},
+		{"missing", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := km.CreateSigner(&apiv1.CreateSignerRequest{SigningKey: tt.name})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMultiKMS_CreateKey(t *testing.T) {
+	primary := newFakeBackend(t)
+	secondary := newFakeBackend(t, "secondary")
+	km, err := NewMulti(primary, secondary)
+	require.NoError(t, err)
+
+	resp, err := km.CreateKey(&apiv1.CreateKeyRequest{Name: "new"})
+	require.NoError(t, err)
+	assert.Contains(t, primary.keys, "new")
+	assert.NotContains(t, secondary.keys, "new")
+
+	pub, err := km.GetPublicKey(&apiv1.GetPublicKeyRequest{Name: "new"})
+	require.NoError(t, err)
+	assert.Equal(t, resp.PublicKey, pub)
+
+	// Keys are always created in the primary, even if they exist in another
+	// kms.
+	_, err = km.CreateKey(&apiv1.CreateKeyRequest{Name: "secondary"})
+	require.NoError(t, err)
+	assert.NotEqual(t, secondary.keys["secondary"], primary.keys["secondary"])
+
+	_, err = km.CreateKey(&apiv1.CreateKeyRequest{Name: "new"})
+	assert.ErrorAs(t, err, &apiv1.AlreadyExistsError{})
+}
+
+func TestMultiKMS_CreateDecrypter(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	primary := newFakeBackend(t)
+	secondary := &fakeCertBackend{
+		fakeBackend: newFakeBackend(t),
+		decrypters:  map[string]crypto.Decrypter{"secondary": key},
+	}
+
+	km, err := NewMulti(primary, secondary)
+	require.NoError(t, err)
+	got, err := km.CreateDecrypter(&apiv1.CreateDecrypterRequest{DecryptionKey: "secondary"})
+	require.NoError(t, err)
+	assert.Equal(t, key, got)
+
+	_, err = km.CreateDecrypter(&apiv1.CreateDecrypterRequest{DecryptionKey: "missing"})
+	assert.EqualError(t, err, "key not found")
+
+	km, err = NewMulti(primary)
+	require.NoError(t, err)
+	_, err = km.CreateDecrypter(&apiv1.CreateDecrypterRequest{DecryptionKey: "secondary"})
+	assert.ErrorAs(t, err, &apiv1.NotImplementedError{})
+}
+
+func TestMultiKMS_certificates(t *testing.T) {
+	primary := &fakeCertBackend{
+		fakeBackend: newFakeBackend(t),
+		certs:       map[string]*x509.Certificate{},
+	}
+	secondary := &fakeCertBackend{
+		fakeBackend: newFakeBackend(t),
+		certs: map[string]*x509.Certificate{
+			"secondary": {Subject: pkix.Name{CommonName: "secondary"}},
+		},
+	}
+
+	km, err := NewMulti(primary, secondary)
+	require.NoError(t, err)
+
+	cert, err := km.LoadCertificate(&apiv1.LoadCertificateRequest{Name: "secondary"})
+	require.NoError(t, err)
+	assert.Equal(t, "secondary", cert.Subject.CommonName)
+
+	_, err = km.LoadCertificate(&apiv1.LoadCertificateRequest{Name: "missing"})
+	assert.EqualError(t, err, "certificate not found")
+
+	newCert := &x509.Certificate{Subject: pkix.Name{CommonName: "new"}}
+	require.NoError(t, km.StoreCertificate(&apiv1.StoreCertificateRequest{Name: "new", Certificate: newCert}))
+	assert.Equal(t, newCert, primary.certs["new"])
+	assert.NotContains(t, secondary.certs, "new")
+
+	// The primary kms does not implement a CertificateManager.
+	km, err = NewMulti(newFakeBackend(t), secondary)
+	require.NoError(t, err)
+	cert, err = km.LoadCertificate(&apiv1.LoadCertificateRequest{Name: "secondary"})
+	require.NoError(t, err)
+	assert.Equal(t, "secondary", cert.Subject.CommonName)
+	err = km.StoreCertificate(&apiv1.StoreCertificateRequest{Name: "new", Certificate: newCert})
+	assert.ErrorAs(t, err, &apiv1.NotImplementedError{})
+
+	km, err = NewMulti(newFakeBackend(t))
+	require.NoError(t, err)
+	_, err = km.LoadCertificate(&apiv1.LoadCertificateRequest{Name: "secondary"})
+	assert.ErrorAs(t, err, &apiv1.NotImplementedError{})
+}
+
+func TestMultiKMS_Close(t *testing.T) {
+	primary := newFakeBackend(t)
+	secondary := newFakeBackend(t)
+	secondary.closeErr = errors.New("close error")
+	other := newFakeBackend(t)
+	other.closeErr = errors.New("other close error")
+
+	km, err := NewMulti(primary, secondary, other)
+	require.NoError(t, err)
+	assert.EqualError(t, km.Close(), "close error")
+	assert.True(t, primary.closed)
+	assert.True(t, secondary.closed)
+	assert.True(t, other.closed)
+}
@@ -7,6 +7,9 @@ import (
 	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
 
 	"github.com/pkg/errors"
 	"go.step.sm/crypto/keyutil"
@@ -50,11 +53,23 @@ var generateKey = func(kty, crv string, size int) (interface{}, interface{}, err
 }
 
 // SoftKMS is a key manager that uses keys stored in disk.
-type SoftKMS struct{}
+type SoftKMS struct {
+	dir string
+}
 
-// New returns a new SoftKMS.
-func New(_ context.Context, _ apiv1.Options) (*SoftKMS, error) {
-	return &SoftKMS{}, nil
+// New returns a new SoftKMS. If the options URI defines a directory, e.g.
+// "softkms:dir=/path/to/keys", the keys created will be stored as PEM files in
+// that directory, and relative key names will be resolved from it.
+func New(_ context.Context, opts apiv1.Options) (*SoftKMS, error) {
+	k := &SoftKMS{}
+	if opts.URI != "" {
+		u, err := uri.ParseWithScheme(Scheme, opts.URI)
+		if err != nil {
+			return nil, err
+		}
+		k.dir = u.Get("dir")
+	}
+	return k, nil
 }
 
 func init() {
@@ -91,7 +106,7 @@ func (k *SoftKMS) CreateSigner(req *apiv1.CreateSignerRequest) (crypto.Signer, e
 		}
 		return sig, nil
 	case req.SigningKey != "":
-		v, err := pemutil.Read(filename(req.SigningKey), opts...)
+		v, err := pemutil.Read(k.path(req.SigningKey), opts...)
 		if err != nil {
 			return nil, err
 		}
@@ -106,13 +121,28 @@ func (k *SoftKMS) CreateSigner(req *apiv1.CreateSignerRequest) (crypto.Signer, e
 }
 
 // CreateKey generates a new key using Golang crypto and returns both public and
-// private key.
+// private key. If the SoftKMS was configured with a directory, the private key
+// will be also stored as a PEM file in it; the name must be a relative path
+// inside the directory, and existing files are never overwritten.
 func (k *SoftKMS) CreateKey(req *apiv1.CreateKeyRequest) (*apiv1.CreateKeyResponse, error) {
 	v, ok := signatureAlgorithmMapping[req.SignatureAlgorithm]
 	if !ok {
 		return nil, errors.Errorf("softKMS does not support signature algorithm '%s'", req.SignatureAlgorithm)
 	}
 
+	name := filename(req.Name)
+	if k.dir != "" {
+		if !filepath.IsLocal(name) {
+			return nil, errors.Errorf("softKMS key name '%s' is not a relative path inside %s", req.Name, k.dir)
+		}
+		name = filepath.Join(k.dir, name)
+		if _, err := os.Stat(name); err == nil {
+			return nil, apiv1.AlreadyExistsError{
+				Message: "key " + name + " already exists",
+			}
+		}
+	}
+
 	pub, priv, err := generateKey(v.Type, v.Curve, req.Bits)
 	if err != nil {
 		return nil, err
@@ -122,19 +152,55 @@ func (k *SoftKMS) CreateKey(req *apiv1.CreateKeyRequest) (*apiv1.CreateKeyRespon
 		return nil, errors.Errorf("softKMS createKey result is not a crypto.Signer: type %T", priv)
 	}
 
-	return &apiv1.CreateKeyResponse{
-		Name:       filename(req.Name),
+	resp := &apiv1.CreateKeyResponse{
+		Name:       name,
 		PublicKey:  pub,
 		PrivateKey: priv,
 		CreateSignerRequest: apiv1.CreateSignerRequest{
 			Signer: signer,
 		},
-	}, nil
+	}
+
+	if k.dir != "" {
+		if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+			return nil, errors.Wrapf(err, "error creating %s", filepath.Dir(name))
+		}
+		block, err := pemutil.Serialize(priv)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeKey(name, pem.EncodeToMemory(block)); err != nil {
+			return nil, err
+		}
+		resp.CreateSignerRequest.SigningKey = name
+	}
+
+	return resp, nil
+}
+
+// writeKey writes the PEM encoded key to a new file with the given name. It
+// returns an apiv1.AlreadyExistsError if the file already exists.
+func writeKey(name string, b []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			return apiv1.AlreadyExistsError{
+				Message: "key " + name + " already exists",
+			}
+		}
+		return errors.Wrapf(err, "error creating %s", name)
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(name)
+		return errors.Wrapf(err, "error writing %s", name)
+	}
+	return errors.Wrapf(f.Close(), "error closing %s", name)
 }
 
 // GetPublicKey returns the public key from the file passed in the request name.
 func (k *SoftKMS) GetPublicKey(req *apiv1.GetPublicKeyRequest) (crypto.PublicKey, error) {
-	v, err := pemutil.Read(filename(req.Name))
+	v, err := pemutil.Read(k.path(req.Name))
 	if err != nil {
 		return nil, err
 	}
@@ -174,7 +240,7 @@ func (k *SoftKMS) CreateDecrypter(req *apiv1.CreateDecrypterRequest) (crypto.Dec
 		}
 		return decrypter, nil
 	case req.DecryptionKey != "":
-		v, err := pemutil.Read(filename(req.DecryptionKey), opts...)
+		v, err := pemutil.Read(k.path(req.DecryptionKey), opts...)
 		if err != nil {
 			return nil, err
 		}
@@ -188,6 +254,16 @@ func (k *SoftKMS) CreateDecrypter(req *apiv1.CreateDecrypterRequest) (crypto.Dec
 	}
 }
 
+// path returns the file name in the given name or uri. If the SoftKMS was
+// configured with a directory, relative names will be resolved from it.
+func (k *SoftKMS) path(s string) string {
+	name := filename(s)
+	if k.dir != "" && !filepath.IsAbs(name) {
+		return filepath.Join(k.dir, name)
+	}
+	return name
+}
+
 func filename(s string) string {
 	if u, err := uri.ParseWithScheme(Scheme, s); err == nil {
 		if f := u.Get("path"); f != "" {
@@ -10,8 +10,10 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -32,6 +34,9 @@ func TestNew(t *testing.T) {
 		wantErr bool
 	}{
 		{"ok", args{context.Background(), apiv1.Options{}}, &SoftKMS{}, false},
+		{"ok with uri", args{context.Background(), apiv1.Options{URI: "softkms:"}}, &SoftKMS{}, false},
+		{"ok with dir", args{context.Background(), apiv1.Options{URI: "softkms:dir=/path/to/keys"}}, &SoftKMS{dir: "/path/to/keys"}, false},
+		{"fail uri", args{context.Background(), apiv1.Options{URI: "pkcs11:dir=/path/to/keys"}}, nil, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -242,6 +247,137 @@ func TestSoftKMS_CreateKey(t *testing.T) {
 	}
 }
 
+func TestSoftKMS_CreateKey_dir(t *testing.T) {
+	dir := t.TempDir()
+	k, err := New(context.Background(), apiv1.Options{
+		URI: "softkms:dir=" + dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		keyName  string
+		alg      apiv1.SignatureAlgorithm
+		bits     int
+		wantName string
+		opts     crypto.SignerOpts
+	}{
+		{"p256", "p256.pem", apiv1.ECDSAWithSHA256, 0, filepath.Join(dir, "p256.pem"), crypto.SHA256},
+		{"rsa", "softkms:path=rsa.pem", apiv1.SHA256WithRSA, 2048, filepath.Join(dir, "rsa.pem"), crypto.SHA256},
+		{"ed25519", "softkms:keys/ed25519.pem", apiv1.PureEd25519, 0, filepath.Join(dir, "keys", "ed25519.pem"), crypto.Hash(0)},
+		{"p384", "softkms:path=keys/../p384.pem", apiv1.ECDSAWithSHA384, 0, filepath.Join(dir, "p384.pem"), crypto.SHA384},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := k.CreateKey(&apiv1.CreateKeyRequest{
+				Name:               tt.keyName,
+				SignatureAlgorithm: tt.alg,
+				Bits:               tt.bits,
+			})
+			if err != nil {
+				t.Fatalf("SoftKMS.CreateKey() error = %v", err)
+			}
+			if resp.Name != tt.wantName {
+				t.Errorf("SoftKMS.CreateKey() name = %v, want %v", resp.Name, tt.wantName)
+			}
+			if resp.CreateSignerRequest.SigningKey != tt.wantName {
+				t.Errorf("SoftKMS.CreateKey() signingKey = %v, want %v", resp.CreateSignerRequest.SigningKey, tt.wantName)
+			}
+			if fi, err := os.Stat(tt.wantName); err != nil {
+				t.Errorf("os.Stat() error = %v", err)
+			} else if fi.Mode().Perm() != 0600 {
+				t.Errorf("os.Stat() mode = %v, want 0600", fi.Mode().Perm())
+			}
+
+			// Public key round-trip
+			pub, err := k.GetPublicKey(&apiv1.GetPublicKeyRequest{Name: tt.keyName})
+			if err != nil {
+				t.Fatalf("SoftKMS.GetPublicKey() error = %v", err)
+			}
+			if !reflect.DeepEqual(pub, resp.PublicKey) {
+				t.Errorf("SoftKMS.GetPublicKey() = %v, want %v", pub, resp.PublicKey)
+			}
+
+			// Signer round-trip
+			signer, err := k.CreateSigner(&apiv1.CreateSignerRequest{SigningKey: tt.keyName})
+			if err != nil {
+				t.Fatalf("SoftKMS.CreateSigner() error = %v", err)
+			}
+			if !reflect.DeepEqual(signer.Public(), resp.PublicKey) {
+				t.Errorf("SoftKMS.CreateSigner() public = %v, want %v", signer.Public(), resp.PublicKey)
+			}
+			digest := []byte("a message to sign")
+			if tt.opts.HashFunc() != 0 {
+				h := tt.opts.HashFunc().New()
+				h.Write(digest)
+				digest = h.Sum(nil)
+			}
+			sig, err := signer.Sign(rand.Reader, digest, tt.opts)
+			if err != nil {
+				t.Fatalf("Signer.Sign() error = %v", err)
+			}
+			switch pub := pub.(type) {
+			case *ecdsa.PublicKey:
+				if !ecdsa.VerifyASN1(pub, digest, sig) {
+					t.Error("ecdsa.VerifyASN1() failed")
+				}
+			case *rsa.PublicKey:
+				if err := rsa.VerifyPKCS1v15(pub, tt.opts.HashFunc(), digest, sig); err != nil {
+					t.Errorf("rsa.VerifyPKCS1v15() error = %v", err)
+				}
+			case ed25519.PublicKey:
+				if !ed25519.Verify(pub, digest, sig) {
+					t.Error("ed25519.Verify() failed")
+				}
+			default:
+				t.Errorf("unexpected public key type %T", pub)
+			}
+
+			// Keys are not overwritten
+			_, err = k.CreateKey(&apiv1.CreateKeyRequest{
+				Name:               tt.keyName,
+				SignatureAlgorithm: tt.alg,
+				Bits:               tt.bits,
+			})
+			var aee apiv1.AlreadyExistsError
+			if !errors.As(err, &aee) {
+				t.Errorf("SoftKMS.CreateKey() error = %v, want apiv1.AlreadyExistsError", err)
+			}
+		})
+	}
+}
+
+func TestSoftKMS_CreateKey_dirInvalidName(t *testing.T) {
+	dir := t.TempDir()
+	k, err := New(context.Background(), apiv1.Options{
+		URI: "softkms:dir=" + filepath.Join(dir, "keys"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"", "softkms:", "../outside.pem", "softkms:path=../outside.pem", "softkms:path=" + filepath.Join(dir, "absolute.pem")} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := k.CreateKey(&apiv1.CreateKeyRequest{
+				Name:               name,
+				SignatureAlgorithm: apiv1.ECDSAWithSHA256,
+			}); err == nil {
+				t.Error("SoftKMS.CreateKey() error = nil, want error")
+			}
+		})
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("os.ReadDir() = %v, want no entries", entries)
+	}
+}
+
 func TestSoftKMS_GetPublicKey(t *testing.T) {
 	b, err := os.ReadFile("testdata/pub.pem")
 	if err != nil {
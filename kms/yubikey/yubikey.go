@@ -34,6 +34,7 @@ type YubiKey struct {
 	pin           string
 	card          string
 	managementKey [24]byte
+	closed        sync.Once
 }
 
 type pivKey interface {
@@ -357,13 +358,17 @@ func (k *YubiKey) CreateAttestation(req *apiv1.CreateAttestationRequest) (*apiv1
 	}, nil
 }
 
-// Close releases the connection to the YubiKey.
-func (k *YubiKey) Close() error {
-	if err := k.yk.Close(); err != nil {
-		return errors.Wrap(err, "error closing yubikey")
-	}
-	pivMap.Delete(k.card)
-	return nil
+// Close releases the connection to the YubiKey. It is safe to call Close
+// multiple times, only the first call releases the connection.
+func (k *YubiKey) Close() (err error) {
+	k.closed.Do(func() {
+		if err = k.yk.Close(); err != nil {
+			err = errors.Wrap(err, "error closing yubikey")
+			return
+		}
+		pivMap.Delete(k.card)
+	})
+	return
 }
 
 // getPublicKey returns the public key on a slot. First it attempts to do
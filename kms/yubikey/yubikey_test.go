@@ -1057,6 +1057,10 @@ func TestYubiKey_Close(t *testing.T) {
 			if err := k.Close(); (err != nil) != tt.wantErr {
 				t.Errorf("YubiKey.Close() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			// Close is idempotent
+			if err := k.Close(); err != nil {
+				t.Errorf("YubiKey.Close() second call error = %v", err)
+			}
 		})
 	}
 }
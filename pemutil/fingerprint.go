@@ -0,0 +1,63 @@
+package pemutil
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// FingerprintEncoding defines the supported encodings in certificate
+// fingerprints.
+type FingerprintEncoding int
+
+// Supported fingerprint encodings.
+const (
+	// HexFingerprint represents the lower case hex encoding of the
+	// fingerprint, the format used by `step certificate fingerprint`.
+	HexFingerprint FingerprintEncoding = iota + 1
+	// ColonHexFingerprint represents the upper case hex encoding of the
+	// fingerprint with the bytes separated by colons, the format used by
+	// `openssl x509 -fingerprint`.
+	ColonHexFingerprint
+	// Base64Fingerprint represents the base64 encoding of the fingerprint.
+	Base64Fingerprint
+	// SSHFingerprint represents the base64 encoding, without padding, of the
+	// fingerprint prefixed by the name of the hash, e.g. "SHA256:", the format
+	// used by `ssh-keygen -l`.
+	SSHFingerprint
+)
+
+// Fingerprint returns the fingerprint of the certificate, the hash of its DER
+// encoding, using the given hash function and encoding. If the hash function
+// is not available or an invalid encoding is passed, the return value will be
+// an empty string.
+func Fingerprint(cert *x509.Certificate, hash crypto.Hash, enc FingerprintEncoding) string {
+	if cert == nil || !hash.Available() {
+		return ""
+	}
+
+	h := hash.New()
+	h.Write(cert.Raw)
+	sum := h.Sum(nil)
+
+	switch enc {
+	case HexFingerprint:
+		return hex.EncodeToString(sum)
+	case ColonHexFingerprint:
+		parts := make([]string, len(sum))
+		for i, b := range sum {
+			parts[i] = strings.ToUpper(hex.EncodeToString([]byte{b}))
+		}
+		return strings.Join(parts, ":")
+	case Base64Fingerprint:
+		return base64.StdEncoding.EncodeToString(sum)
+	case SSHFingerprint:
+		// SHA-256 is displayed as SHA256 by OpenSSH.
+		name := strings.Replace(hash.String(), "SHA-", "SHA", 1)
+		return name + ":" + base64.RawStdEncoding.EncodeToString(sum)
+	default:
+		return ""
+	}
+}
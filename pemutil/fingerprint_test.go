@@ -0,0 +1,42 @@
+package pemutil
+
+import (
+	"crypto"
+	"crypto/x509"
+	"testing"
+
+	"github.com/smallstep/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	cert, err := ReadCertificate("testdata/ca.crt")
+	assert.FatalError(t, err)
+
+	type args struct {
+		cert *x509.Certificate
+		hash crypto.Hash
+		enc  FingerprintEncoding
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{"sha1 hex", args{cert, crypto.SHA1, HexFingerprint}, "8174f72ecd746ee0831a9c727f2ea630f80fbf11"},
+		{"sha1 colon hex", args{cert, crypto.SHA1, ColonHexFingerprint}, "81:74:F7:2E:CD:74:6E:E0:83:1A:9C:72:7F:2E:A6:30:F8:0F:BF:11"},
+		{"sha1 base64", args{cert, crypto.SHA1, Base64Fingerprint}, "gXT3Ls10buCDGpxyfy6mMPgPvxE="},
+		{"sha1 ssh", args{cert, crypto.SHA1, SSHFingerprint}, "SHA1:gXT3Ls10buCDGpxyfy6mMPgPvxE"},
+		{"sha256 hex", args{cert, crypto.SHA256, HexFingerprint}, "6908751f68290d4573ae0be39a98c8b9b7b7d4e8b2a6694b7509946626adfe98"},
+		{"sha256 colon hex", args{cert, crypto.SHA256, ColonHexFingerprint}, "69:08:75:1F:68:29:0D:45:73:AE:0B:E3:9A:98:C8:B9:B7:B7:D4:E8:B2:A6:69:4B:75:09:94:66:26:AD:FE:98"},
+		{"sha256 base64", args{cert, crypto.SHA256, Base64Fingerprint}, "aQh1H2gpDUVzrgvjmpjIube31OiypmlLdQmUZiat/pg="},
+		{"sha256 ssh", args{cert, crypto.SHA256, SSHFingerprint}, "SHA256:aQh1H2gpDUVzrgvjmpjIube31OiypmlLdQmUZiat/pg"},
+		{"fail encoding", args{cert, crypto.SHA256, FingerprintEncoding(0)}, ""},
+		{"fail hash", args{cert, crypto.Hash(0), HexFingerprint}, ""},
+		{"fail nil", args{nil, crypto.SHA256, HexFingerprint}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equals(t, tt.want, Fingerprint(tt.args.cert, tt.args.hash, tt.args.enc))
+		})
+	}
+}
@@ -4,7 +4,9 @@
 package pemutil
 
 import (
+	"bufio"
 	"bytes"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/elliptic"
@@ -14,6 +16,7 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"io"
 	"math/big"
 	"os"
 
@@ -347,6 +350,66 @@ func ReadCertificateBundle(filename string) ([]*x509.Certificate, error) {
 	return []*x509.Certificate{crt}, nil
 }
 
+// pemBlockFooter is the prefix of the line that ends a PEM block.
+var pemBlockFooter = []byte("-----END ")
+
+// ReadEach reads the PEM-encoded certificates in the given reader, decoding
+// one block at a time, and calls fn with each of them. Unlike
+// ParseCertificateBundle, the data is never fully loaded in memory, so it can
+// be used with large trust bundles. Blocks that are not certificates are
+// skipped. If fn returns an error, ReadEach stops and returns it.
+func ReadEach(r io.Reader, fn func(*x509.Certificate) error) error {
+	var (
+		block   []byte
+		inBlock bool
+		found   bool
+	)
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			trimmed := bytes.TrimSpace(line)
+			switch {
+			case !inBlock && bytes.HasPrefix(trimmed, PEMBlockHeader):
+				inBlock = true
+				block = append(block[:0], line...)
+			case inBlock:
+				block = append(block, line...)
+				if bytes.HasPrefix(trimmed, pemBlockFooter) {
+					inBlock = false
+					p, _ := pem.Decode(block)
+					if p == nil {
+						return errors.New("error decoding pem block")
+					}
+					if p.Type == "CERTIFICATE" && len(p.Headers) == 0 {
+						cert, err := x509.ParseCertificate(p.Bytes)
+						if err != nil {
+							return errors.Wrap(err, "error parsing certificate")
+						}
+						found = true
+						if err := fn(cert); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "error reading pem data")
+		}
+	}
+	if inBlock {
+		return errors.New("error decoding pem block")
+	}
+	if !found {
+		return errors.New("error parsing certificate: no certificate found")
+	}
+	return nil
+}
+
 // ReadCertificateRequest returns a *x509.CertificateRequest from the given
 // filename. It supports certificates formats PEM and DER.
 func ReadCertificateRequest(filename string) (*x509.CertificateRequest, error) {
@@ -457,6 +520,75 @@ func ParseKey(b []byte, opts ...Options) (interface{}, error) {
 	return keyutil.ExtractKey(k)
 }
 
+// ParseKeyPair parses the first certificate in certPEM and the private key in
+// keyPEM, and verifies that the private key matches the public key in the
+// certificate. The given options are used to parse the private key, for
+// example to decrypt it.
+func ParseKeyPair(certPEM, keyPEM []byte, opts ...Options) (*x509.Certificate, crypto.Signer, error) {
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := Parse(keyPEM, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, errors.Errorf("error parsing key pair: key type %T is not a private key", key)
+	}
+	if !keyutil.Equal(cert.PublicKey, signer.Public()) {
+		return nil, nil, errors.New("error parsing key pair: private key does not match the certificate public key")
+	}
+	return cert, signer, nil
+}
+
+// ParseBundle parses a PEM bundle with a private key and a certificate chain,
+// in any order, the same input that tls.X509KeyPair accepts. The certificates
+// are returned in the order they appear in the bundle, and the bundle must
+// contain exactly one private key, matching the first certificate. Other PEM
+// blocks are ignored.
+func ParseBundle(b []byte) ([]*x509.Certificate, crypto.Signer, error) {
+	var block *pem.Block
+	var certs []*x509.Certificate
+	var signer crypto.Signer
+	for len(bytes.TrimSpace(b)) > 0 {
+		block, b = pem.Decode(b)
+		if block == nil {
+			return nil, nil, errors.New("error decoding pem block")
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "error parsing certificate")
+			}
+			certs = append(certs, cert)
+		case "RSA PRIVATE KEY", "EC PRIVATE KEY", "PRIVATE KEY", "ENCRYPTED PRIVATE KEY", "OPENSSH PRIVATE KEY":
+			if signer != nil {
+				return nil, nil, errors.New("error parsing bundle: contains more than one private key")
+			}
+			key, err := Parse(pem.EncodeToMemory(block), WithFilename("bundle"))
+			if err != nil {
+				return nil, nil, err
+			}
+			var ok bool
+			if signer, ok = key.(crypto.Signer); !ok {
+				return nil, nil, errors.Errorf("error parsing bundle: key type %T is not a private key", key)
+			}
+		}
+	}
+	switch {
+	case len(certs) == 0:
+		return nil, nil, errors.New("error parsing bundle: no certificate found")
+	case signer == nil:
+		return nil, nil, errors.New("error parsing bundle: no private key found")
+	case !keyutil.Equal(certs[0].PublicKey, signer.Public()):
+		return nil, nil, errors.New("error parsing bundle: private key does not match the certificate public key")
+	}
+	return certs, signer, nil
+}
+
 // Read returns the key or certificate encoded in the given PEM file.
 // If the file is encrypted it will ask for a password and it will try
 // to decrypt it.
@@ -397,6 +397,62 @@ func TestReadCertificateBundle(t *testing.T) {
 	}
 }
 
+func TestReadEach(t *testing.T) {
+	tests := []struct {
+		fn  string
+		len int
+		err error
+	}{
+		{"testdata/ca.crt", 1, nil},
+		{"testdata/nonPEMHeaderCa.crt", 1, nil},
+		{"testdata/bundle.crt", 2, nil},
+		{"testdata/badca.crt", 0, errors.New("error parsing certificate")},
+		{"testdata/badpem.crt", 0, errors.New("error decoding pem block")},
+		{"testdata/badder.crt", 0, errors.New("error parsing certificate: no certificate found")},
+		{"testdata/openssl.p256.pem", 0, errors.New("error parsing certificate: no certificate found")},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.fn, func(t *testing.T) {
+			f, err := os.Open(tc.fn)
+			assert.FatalError(t, err)
+			defer f.Close()
+
+			var count int
+			err = ReadEach(f, func(crt *x509.Certificate) error {
+				assert.Type(t, &x509.Certificate{}, crt)
+				count++
+				return nil
+			})
+			if tc.err != nil {
+				if assert.Error(t, err) {
+					assert.HasPrefix(t, err.Error(), tc.err.Error())
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equals(t, tc.len, count)
+		})
+	}
+}
+
+func TestReadEach_stop(t *testing.T) {
+	b, err := os.ReadFile("testdata/bundle.crt")
+	assert.FatalError(t, err)
+
+	var count int
+	stop := errors.New("stop")
+	err = ReadEach(bytes.NewReader(bytes.Repeat(append(b, '\n'), 10)), func(*x509.Certificate) error {
+		count++
+		if count == 3 {
+			return stop
+		}
+		return nil
+	})
+	assert.Equals(t, stop, err)
+	assert.Equals(t, 3, count)
+}
+
 func TestParse(t *testing.T) {
 	type ParseTest struct {
 		in      []byte
@@ -937,6 +993,151 @@ func TestParseKey_x509(t *testing.T) {
 	assert.Equals(t, csr.PublicKey, key)
 }
 
+func TestParseKeyPair(t *testing.T) {
+	mustKeyPair := func(signer crypto.Signer, opts ...Options) ([]byte, []byte) {
+		template := &x509.Certificate{
+			Subject:      pkix.Name{CommonName: "test"},
+			SerialNumber: big.NewInt(1),
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
+		assert.FatalError(t, err)
+		block, err := Serialize(signer, opts...)
+		assert.FatalError(t, err)
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(block)
+	}
+
+	ecKey, err := keyutil.GenerateDefaultSigner()
+	assert.FatalError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.FatalError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.FatalError(t, err)
+
+	ecCert, ecPEM := mustKeyPair(ecKey)
+	rsaCert, rsaPEM := mustKeyPair(rsaKey)
+	edCert, edPEM := mustKeyPair(edKey)
+	encCert, encPEM := mustKeyPair(ecKey, WithPassword([]byte("mypassword")))
+	pubPEM, err := os.ReadFile("testdata/openssl.p256.pub.pem")
+	assert.FatalError(t, err)
+
+	type args struct {
+		certPEM []byte
+		keyPEM  []byte
+		opts    []Options
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    crypto.Signer
+		wantErr bool
+	}{
+		{"ok ecdsa", args{ecCert, ecPEM, nil}, ecKey, false},
+		{"ok rsa", args{rsaCert, rsaPEM, nil}, rsaKey, false},
+		{"ok ed25519", args{edCert, edPEM, nil}, edKey, false},
+		{"ok encrypted", args{encCert, encPEM, []Options{WithPassword([]byte("mypassword"))}}, ecKey, false},
+		{"fail mismatch", args{ecCert, rsaPEM, nil}, nil, true},
+		{"fail mismatch same type", args{encCert, edPEM, nil}, nil, true},
+		{"fail certificate", args{ecPEM, ecPEM, nil}, nil, true},
+		{"fail key", args{ecCert, []byte("not a key"), nil}, nil, true},
+		{"fail public key", args{ecCert, pubPEM, nil}, nil, true},
+		{"fail certificate as key", args{ecCert, ecCert, nil}, nil, true},
+		{"fail password", args{encCert, encPEM, []Options{WithPassword([]byte("badpassword"))}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, signer, err := ParseKeyPair(tt.args.certPEM, tt.args.keyPEM, tt.args.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKeyPair() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				assert.Nil(t, cert)
+				assert.Nil(t, signer)
+				return
+			}
+			assert.NotNil(t, cert)
+			assert.True(t, keyutil.Equal(tt.want, signer))
+		})
+	}
+
+	_, _, err = ParseKeyPair(ecCert, rsaPEM)
+	assert.HasPrefix(t, err.Error(), "error parsing key pair: private key does not match")
+}
+
+func TestParseBundle(t *testing.T) {
+	mustCertificate := func(cn string, pub crypto.PublicKey, parent *x509.Certificate, signer crypto.Signer) (*x509.Certificate, []byte) {
+		template := &x509.Certificate{
+			Subject:               pkix.Name{CommonName: cn},
+			SerialNumber:          big.NewInt(1),
+			IsCA:                  parent == nil,
+			BasicConstraintsValid: true,
+		}
+		if parent == nil {
+			parent = template
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+		assert.FatalError(t, err)
+		cert, err := x509.ParseCertificate(der)
+		assert.FatalError(t, err)
+		return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}
+	mustKey := func(signer crypto.Signer) []byte {
+		block, err := Serialize(signer)
+		assert.FatalError(t, err)
+		return pem.EncodeToMemory(block)
+	}
+	join := func(b ...[]byte) []byte {
+		return bytes.Join(b, nil)
+	}
+
+	caKey, err := keyutil.GenerateDefaultSigner()
+	assert.FatalError(t, err)
+	leafKey, err := keyutil.GenerateDefaultSigner()
+	assert.FatalError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.FatalError(t, err)
+
+	ca, caPEM := mustCertificate("ca", caKey.Public(), nil, caKey)
+	leaf, leafPEM := mustCertificate("leaf", leafKey.Public(), ca, caKey)
+	keyPEM := mustKey(leafKey)
+	csrPEM, err := os.ReadFile("testdata/test.csr")
+	assert.FatalError(t, err)
+
+	tests := []struct {
+		name      string
+		bundle    []byte
+		wantCerts []*x509.Certificate
+		wantKey   crypto.Signer
+		wantErr   string
+	}{
+		{"ok key before certs", join(keyPEM, leafPEM, caPEM), []*x509.Certificate{leaf, ca}, leafKey, ""},
+		{"ok certs before key", join(leafPEM, caPEM, keyPEM), []*x509.Certificate{leaf, ca}, leafKey, ""},
+		{"ok key between certs", join(leafPEM, keyPEM, caPEM), []*x509.Certificate{leaf, ca}, leafKey, ""},
+		{"ok with other blocks", join([]byte("# bundle\n"), csrPEM, []byte("\n"), leafPEM, keyPEM), []*x509.Certificate{leaf}, leafKey, ""},
+		{"fail no key", join(leafPEM, caPEM), nil, nil, "error parsing bundle: no private key found"},
+		{"fail no certificate", keyPEM, nil, nil, "error parsing bundle: no certificate found"},
+		{"fail two keys", join(keyPEM, leafPEM, mustKey(edKey)), nil, nil, "error parsing bundle: contains more than one private key"},
+		{"fail mismatch", join(mustKey(edKey), leafPEM, caPEM), nil, nil, "error parsing bundle: private key does not match"},
+		{"fail mismatch order", join(caPEM, leafPEM, keyPEM), nil, nil, "error parsing bundle: private key does not match"},
+		{"fail pem", []byte("not a bundle"), nil, nil, "error decoding pem block"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certs, key, err := ParseBundle(tt.bundle)
+			if tt.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.HasPrefix(t, err.Error(), tt.wantErr)
+				}
+				assert.Nil(t, certs)
+				assert.Nil(t, key)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equals(t, tt.wantCerts, certs)
+			assert.True(t, keyutil.Equal(tt.wantKey, key))
+		})
+	}
+}
+
 func TestParseSSH(t *testing.T) {
 	var key interface{}
 	for fn, td := range files {
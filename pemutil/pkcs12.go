@@ -0,0 +1,445 @@
+package pemutil
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/cipher"
+	"crypto/des" //nolint:gosec // support for legacy PKCS#12 files
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // support for legacy PKCS#12 files
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"hash"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/pkcs12" //nolint:staticcheck // used for RC2 encrypted PKCS#12 files
+)
+
+var (
+	// content types
+	oidDataContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedDataContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+
+	// bag types
+	oidKeyBag              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidPKCS8ShroudedKeyBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidCertTypeX509        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+
+	// password based encryption
+	oidPBEWithSHAAnd3KeyTripleDESCBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+
+	// mac digests
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+)
+
+// errPKCS12Unsupported is returned when a PKCS#12 file uses an encryption
+// algorithm that is not supported natively.
+var errPKCS12Unsupported = errors.New("unsupported PKCS#12 encryption algorithm")
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe pkcs12ContentInfo
+	MacData  pkcs12MacData `asn1:"optional"`
+}
+
+type pkcs12ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type pkcs12MacData struct {
+	Mac        pkcs12DigestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type pkcs12DigestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type pkcs12EncryptedData struct {
+	Version              int
+	EncryptedContentInfo pkcs12EncryptedContentInfo
+}
+
+type pkcs12EncryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type pkcs12EncryptedKey struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Data      []byte
+}
+
+type pkcs12SafeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue   `asn1:"tag:0,explicit"`
+	Attributes []asn1.RawValue `asn1:"set,optional"`
+}
+
+type pkcs12CertBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type pkcs12PBEParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+// ParsePKCS12 parses a PKCS#12 file, also known as PFX, and returns the
+// private key, the certificate for the private key, and the rest of the
+// certificates in the file.
+//
+// It supports files encrypted using PBES2 with AES or 3DES, the default in
+// OpenSSL 3, and the legacy pbeWithSHAAnd3-KeyTripleDES-CBC and
+// pbeWithSHAAnd40BitRC2-CBC algorithms. The integrity of the file is verified
+// using the MAC if present. An empty password is supported.
+func ParsePKCS12(data []byte, password string) (key crypto.Signer, leaf *x509.Certificate, chain []*x509.Certificate, err error) {
+	keys, certs, err := decodePKCS12(data, password)
+	if errors.Is(err, errPKCS12Unsupported) {
+		keys, certs, err = decodeLegacyPKCS12(data, password)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	switch len(keys) {
+	case 0:
+		return nil, nil, nil, errors.New("error parsing PKCS#12: private key not found")
+	case 1:
+		key = keys[0]
+	default:
+		return nil, nil, nil, errors.New("error parsing PKCS#12: found multiple private keys")
+	}
+
+	pub, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return nil, nil, nil, errors.Errorf("error parsing PKCS#12: unsupported key type %T", key)
+	}
+	for _, crt := range certs {
+		if leaf == nil && pub.Equal(crt.PublicKey) {
+			leaf = crt
+		} else {
+			chain = append(chain, crt)
+		}
+	}
+	if leaf == nil {
+		return nil, nil, nil, errors.New("error parsing PKCS#12: certificate for the private key not found")
+	}
+
+	return key, leaf, chain, nil
+}
+
+// decodePKCS12 decodes the keys and certificates in a PKCS#12 file.
+func decodePKCS12(data []byte, password string) ([]crypto.Signer, []*x509.Certificate, error) {
+	var pfx pfxPdu
+	if rest, err := asn1.Unmarshal(data, &pfx); err != nil {
+		return nil, nil, errors.Wrap(err, "error parsing PKCS#12")
+	} else if len(rest) > 0 {
+		return nil, nil, errors.New("error parsing PKCS#12: trailing data found")
+	}
+	if pfx.Version != 3 {
+		return nil, nil, errors.Errorf("error parsing PKCS#12: unsupported version %d", pfx.Version)
+	}
+	if !pfx.AuthSafe.ContentType.Equal(oidDataContentType) {
+		return nil, nil, errors.New("error parsing PKCS#12: only password integrity mode is supported")
+	}
+
+	var authSafe []byte
+	if _, err := asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return nil, nil, errors.Wrap(err, "error parsing PKCS#12")
+	}
+
+	// The password used in the PKCS#12 key derivation is a null terminated
+	// BMPString. An empty password might also be encoded as an empty string.
+	bmpPassword, err := bmpString(password)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(pfx.MacData.Mac.Algorithm.Algorithm) > 0 {
+		err := verifyPKCS12Mac(&pfx.MacData, authSafe, bmpPassword)
+		if errors.Is(err, x509.IncorrectPasswordError) && password == "" {
+			bmpPassword = nil
+			err = verifyPKCS12Mac(&pfx.MacData, authSafe, bmpPassword)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var contents []pkcs12ContentInfo
+	if _, err := asn1.Unmarshal(authSafe, &contents); err != nil {
+		return nil, nil, errors.Wrap(err, "error parsing PKCS#12")
+	}
+
+	d := &pkcs12Decrypter{password: []byte(password), bmpPassword: bmpPassword}
+	var keys []crypto.Signer
+	var certs []*x509.Certificate
+	for _, ci := range contents {
+		var safeContents []byte
+		switch {
+		case ci.ContentType.Equal(oidDataContentType):
+			if _, err := asn1.Unmarshal(ci.Content.Bytes, &safeContents); err != nil {
+				return nil, nil, errors.Wrap(err, "error parsing PKCS#12")
+			}
+		case ci.ContentType.Equal(oidEncryptedDataContentType):
+			var ed pkcs12EncryptedData
+			if _, err := asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil {
+				return nil, nil, errors.Wrap(err, "error parsing PKCS#12")
+			}
+			eci := ed.EncryptedContentInfo
+			if safeContents, err = d.decrypt(eci.ContentEncryptionAlgorithm, eci.EncryptedContent); err != nil {
+				return nil, nil, err
+			}
+		default:
+			return nil, nil, errors.Errorf("error parsing PKCS#12: unsupported content type %s", ci.ContentType)
+		}
+
+		var bags []pkcs12SafeBag
+		if _, err := asn1.Unmarshal(safeContents, &bags); err != nil {
+			return nil, nil, errors.Wrap(err, "error parsing PKCS#12")
+		}
+		for _, bag := range bags {
+			switch {
+			case bag.ID.Equal(oidKeyBag):
+				key, err := parsePKCS12Key(bag.Value.Bytes)
+				if err != nil {
+					return nil, nil, err
+				}
+				keys = append(keys, key)
+			case bag.ID.Equal(oidPKCS8ShroudedKeyBag):
+				var ek pkcs12EncryptedKey
+				if _, err := asn1.Unmarshal(bag.Value.Bytes, &ek); err != nil {
+					return nil, nil, errors.Wrap(err, "error parsing PKCS#12")
+				}
+				der, err := d.decrypt(ek.Algorithm, ek.Data)
+				if err != nil {
+					return nil, nil, err
+				}
+				key, err := parsePKCS12Key(der)
+				if err != nil {
+					return nil, nil, err
+				}
+				keys = append(keys, key)
+			case bag.ID.Equal(oidCertBag):
+				var cb pkcs12CertBag
+				if _, err := asn1.Unmarshal(bag.Value.Bytes, &cb); err != nil {
+					return nil, nil, errors.Wrap(err, "error parsing PKCS#12")
+				}
+				if !cb.ID.Equal(oidCertTypeX509) {
+					continue
+				}
+				crt, err := x509.ParseCertificate(cb.Data)
+				if err != nil {
+					return nil, nil, errors.Wrap(err, "error parsing PKCS#12 certificate")
+				}
+				certs = append(certs, crt)
+			}
+		}
+	}
+
+	return keys, certs, nil
+}
+
+// decodeLegacyPKCS12 decodes the keys and certificates in a PKCS#12 file
+// using golang.org/x/crypto/pkcs12. It supports RC2 encrypted files.
+func decodeLegacyPKCS12(data []byte, password string) ([]crypto.Signer, []*x509.Certificate, error) {
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err != nil {
+		if errors.Is(err, pkcs12.ErrIncorrectPassword) || errors.Is(err, pkcs12.ErrDecryption) {
+			return nil, nil, x509.IncorrectPasswordError
+		}
+		return nil, nil, errors.Wrap(err, "error parsing PKCS#12")
+	}
+
+	var keys []crypto.Signer
+	var certs []*x509.Certificate
+	for _, block := range blocks {
+		switch block.Type {
+		case "CERTIFICATE":
+			crt, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "error parsing PKCS#12 certificate")
+			}
+			certs = append(certs, crt)
+		case "PRIVATE KEY":
+			key, err := ParseDER(block.Bytes)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "error parsing PKCS#12 private key")
+			}
+			signer, ok := key.(crypto.Signer)
+			if !ok {
+				return nil, nil, errors.Errorf("error parsing PKCS#12: unsupported key type %T", key)
+			}
+			keys = append(keys, signer)
+		}
+	}
+	return keys, certs, nil
+}
+
+func parsePKCS12Key(der []byte) (crypto.Signer, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing PKCS#12 private key")
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.Errorf("error parsing PKCS#12: unsupported key type %T", key)
+	}
+	return signer, nil
+}
+
+// pkcs12Decrypter decrypts the encrypted contents and keys of a PKCS#12 file.
+// PBES2 uses the password as is, and the PKCS#12 password based encryption
+// uses the BMPString encoded password.
+type pkcs12Decrypter struct {
+	password    []byte
+	bmpPassword []byte
+}
+
+func (d *pkcs12Decrypter) decrypt(alg pkix.AlgorithmIdentifier, ciphertext []byte) ([]byte, error) {
+	switch {
+	case alg.Algorithm.Equal(oidPBES2):
+		der, err := asn1.Marshal(pkcs12EncryptedKey{Algorithm: alg, Data: ciphertext})
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing PKCS#12")
+		}
+		return DecryptPKCS8PrivateKey(der, d.password)
+	case alg.Algorithm.Equal(oidPBEWithSHAAnd3KeyTripleDESCBC):
+		var params pkcs12PBEParams
+		if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+			return nil, errors.Wrap(err, "error parsing PKCS#12")
+		}
+		key := pkcs12KDF(sha1.New, d.bmpPassword, params.Salt, params.Iterations, 1, 24)
+		iv := pkcs12KDF(sha1.New, d.bmpPassword, params.Salt, params.Iterations, 2, des.BlockSize)
+		block, err := des.NewTripleDESCipher(key) //nolint:gosec // support for legacy PKCS#12 files
+		if err != nil {
+			return nil, err
+		}
+		if len(ciphertext) == 0 || len(ciphertext)%des.BlockSize != 0 {
+			return nil, errors.New("error decrypting PKCS#12: invalid data length")
+		}
+		data := make([]byte, len(ciphertext))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(data, ciphertext)
+		return unpadPKCS12(data, des.BlockSize)
+	default:
+		return nil, errors.Wrapf(errPKCS12Unsupported, "error parsing PKCS#12: algorithm %s", alg.Algorithm)
+	}
+}
+
+// unpadPKCS12 removes the PKCS#7 padding of the given data.
+func unpadPKCS12(data []byte, blockSize int) ([]byte, error) {
+	n := len(data)
+	last := int(data[n-1])
+	if last == 0 || last > blockSize || last > n {
+		return nil, x509.IncorrectPasswordError
+	}
+	for _, v := range data[n-last:] {
+		if int(v) != last {
+			return nil, x509.IncorrectPasswordError
+		}
+	}
+	return data[:n-last], nil
+}
+
+// verifyPKCS12Mac verifies the MAC of the PKCS#12 authenticated safe.
+func verifyPKCS12Mac(md *pkcs12MacData, message, password []byte) error {
+	var h func() hash.Hash
+	switch alg := md.Mac.Algorithm.Algorithm; {
+	case alg.Equal(oidSHA1):
+		h = sha1.New
+	case alg.Equal(oidSHA256):
+		h = sha256.New
+	case alg.Equal(oidSHA384):
+		h = sha512.New384
+	case alg.Equal(oidSHA512):
+		h = sha512.New
+	default:
+		return errors.Errorf("error parsing PKCS#12: unsupported MAC algorithm %s", alg)
+	}
+
+	key := pkcs12KDF(h, password, md.MacSalt, md.Iterations, 3, h().Size())
+	mac := hmac.New(h, key)
+	mac.Write(message)
+	if subtle.ConstantTimeCompare(mac.Sum(nil), md.Mac.Digest) != 1 {
+		return x509.IncorrectPasswordError
+	}
+	return nil
+}
+
+// pkcs12KDF implements the key derivation function defined in RFC 7292,
+// appendix B.2, for the given id: 1 for keys, 2 for IVs, and 3 for MAC keys.
+func pkcs12KDF(h func() hash.Hash, password, salt []byte, iterations int, id byte, size int) []byte {
+	hh := h()
+	u := hh.Size()
+	v := hh.BlockSize()
+
+	// Concatenate copies of the salt and password to a multiple of v.
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		n := v * ((len(b) + v - 1) / v)
+		ret := make([]byte, n)
+		for i := 0; i < n; i += len(b) {
+			copy(ret[i:], b)
+		}
+		return ret
+	}
+	D := bytes.Repeat([]byte{id}, v)
+	I := append(fill(salt), fill(password)...)
+
+	out := make([]byte, 0, size+u)
+	B := make([]byte, v)
+	for len(out) < size {
+		hh.Reset()
+		hh.Write(D)
+		hh.Write(I)
+		A := hh.Sum(nil)
+		for i := 1; i < iterations; i++ {
+			hh.Reset()
+			hh.Write(A)
+			A = hh.Sum(A[:0])
+		}
+		out = append(out, A...)
+
+		// Ij = (Ij + B + 1) mod 2^(v*8) for each v-byte block of I.
+		for i := range B {
+			B[i] = A[i%u]
+		}
+		for j := 0; j < len(I); j += v {
+			carry := uint16(1)
+			for k := v - 1; k >= 0; k-- {
+				carry += uint16(I[j+k]) + uint16(B[k])
+				I[j+k] = byte(carry)
+				carry >>= 8
+			}
+		}
+	}
+	return out[:size]
+}
+
+// bmpString returns the null terminated BMPString encoding of s.
+func bmpString(s string) ([]byte, error) {
+	ret := make([]byte, 0, 2*len(s)+2)
+	for _, r := range s {
+		if t, _ := utf16.EncodeRune(r); t != 0xfffd {
+			return nil, errors.New("error parsing PKCS#12: password contains characters that cannot be encoded in UCS-2")
+		}
+		ret = append(ret, byte(r>>8), byte(r))
+	}
+	return append(ret, 0, 0), nil
+}
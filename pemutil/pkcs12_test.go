@@ -0,0 +1,122 @@
+package pemutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1" //nolint:gosec // PKCS#12 test vectors
+	"crypto/x509"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/smallstep/assert"
+)
+
+func TestParsePKCS12(t *testing.T) {
+	mustCertificate := func(fn string) *x509.Certificate {
+		crt, err := ReadCertificate(fn)
+		assert.FatalError(t, err)
+		return crt
+	}
+	mustKey := func(fn string) crypto.Signer {
+		key, err := Read(fn)
+		assert.FatalError(t, err)
+		return key.(crypto.Signer)
+	}
+
+	leaf := mustCertificate("testdata/pkcs12/leaf.crt")
+	leafKey := mustKey("testdata/pkcs12/leaf.key")
+	ca := mustCertificate("testdata/pkcs12/ca.crt")
+
+	tests := []struct {
+		name      string
+		fn        string
+		password  string
+		wantKey   crypto.Signer
+		wantLeaf  *x509.Certificate
+		wantChain []*x509.Certificate
+	}{
+		{"aes", "testdata/pkcs12/aes.p12", "mypassword", leafKey, leaf, []*x509.Certificate{ca}},
+		{"3des", "testdata/pkcs12/3des.p12", "mypassword", leafKey, leaf, []*x509.Certificate{ca}},
+		{"legacy", "testdata/pkcs12/legacy.p12", "mypassword", leafKey, leaf, []*x509.Certificate{ca}},
+		{"empty password", "testdata/pkcs12/nopass.p12", "", leafKey, leaf, []*x509.Certificate{ca}},
+		{"rsa", "testdata/pkcs12/rsa.p12", "mypassword", mustKey("testdata/pkcs12/rsa.key"), mustCertificate("testdata/pkcs12/rsa.crt"), nil},
+		{"ed25519", "testdata/pkcs12/ed25519.p12", "mypassword", mustKey("testdata/pkcs12/ed25519.key"), mustCertificate("testdata/pkcs12/ed25519.crt"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(tt.fn)
+			assert.FatalError(t, err)
+
+			key, leaf, chain, err := ParsePKCS12(data, tt.password)
+			assert.FatalError(t, err)
+			switch k := key.(type) {
+			case *ecdsa.PrivateKey:
+				assert.True(t, k.Equal(tt.wantKey))
+			case *rsa.PrivateKey:
+				assert.True(t, k.Equal(tt.wantKey))
+			case ed25519.PrivateKey:
+				assert.True(t, k.Equal(tt.wantKey))
+			default:
+				t.Errorf("unexpected key type %T", key)
+			}
+			assert.Equals(t, tt.wantLeaf.Raw, leaf.Raw)
+			assert.Equals(t, len(tt.wantChain), len(chain))
+			for i := range tt.wantChain {
+				assert.Equals(t, tt.wantChain[i].Raw, chain[i].Raw)
+			}
+		})
+	}
+}
+
+func TestParsePKCS12_fail(t *testing.T) {
+	mustRead := func(fn string) []byte {
+		b, err := os.ReadFile(fn)
+		assert.FatalError(t, err)
+		return b
+	}
+
+	for _, fn := range []string{"aes.p12", "3des.p12", "legacy.p12", "nopass.p12"} {
+		t.Run("incorrect password "+fn, func(t *testing.T) {
+			_, _, _, err := ParsePKCS12(mustRead("testdata/pkcs12/"+fn), "foobar")
+			assert.True(t, errors.Is(err, x509.IncorrectPasswordError))
+		})
+	}
+
+	t.Run("no key", func(t *testing.T) {
+		_, _, _, err := ParsePKCS12(mustRead("testdata/pkcs12/nokey.p12"), "mypassword")
+		assert.Equals(t, "error parsing PKCS#12: private key not found", err.Error())
+	})
+
+	t.Run("bad data", func(t *testing.T) {
+		_, _, _, err := ParsePKCS12([]byte("not a pkcs12 file"), "mypassword")
+		assert.Error(t, err)
+		_, _, _, err = ParsePKCS12(mustRead("testdata/pkcs12/leaf.crt"), "mypassword")
+		assert.Error(t, err)
+	})
+
+	t.Run("trailing data", func(t *testing.T) {
+		data := append(mustRead("testdata/pkcs12/aes.p12"), 0)
+		_, _, _, err := ParsePKCS12(data, "mypassword")
+		assert.Equals(t, "error parsing PKCS#12: trailing data found", err.Error())
+	})
+
+	t.Run("bad password encoding", func(t *testing.T) {
+		_, _, _, err := ParsePKCS12(mustRead("testdata/pkcs12/aes.p12"), "\U0001F600")
+		assert.Error(t, err)
+	})
+}
+
+func Test_pkcs12KDF(t *testing.T) {
+	// Test vectors from golang.org/x/crypto/pkcs12.
+	password, err := bmpString("sesame")
+	assert.FatalError(t, err)
+	key := pkcs12KDF(sha1.New, password, []byte("\xff\xff\xff\xff\xff\xff\xff\xff"), 2048, 1, 24)
+	assert.Equals(t, []byte("\x7c\xd9\xfd\x3e\x2b\x3b\xe7\x69\x1a\x44\xe3\xbe\xf0\xf9\xea\x0f\xb9\xb8\x97\xd4\xe3\x25\xd9\xd1"), key)
+
+	// I_j with a leading zero byte.
+	key = pkcs12KDF(sha1.New, []byte("\x00\x00"), []byte("\xf3\x7e\x05\xb5\x18\x32\x4b\x4b"), 2048, 1, 24)
+	assert.Equals(t, []byte("\x00\xf7\x59\xff\x47\xd1\x4d\xd0\x36\x65\xd5\x94\x3c\xb3\xc4\xa3\x9a\x25\x55\xc0\x2a\xed\x66\xe1"), key)
+}
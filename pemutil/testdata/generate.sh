@@ -95,3 +95,28 @@ do
    cp openssh.rsa$size.pem openssh.rsa$size.enc.pem
    $SSH_KEYGEN -p -N mypassword -f openssh.rsa$size.enc.pem
 done
+
+#######################################
+# PKCS#12                             #
+#######################################
+
+mkdir -p pkcs12
+$OPENSSL req -x509 -newkey ec -pkeyopt ec_paramgen_curve:P-256 -nodes -days 3650 -subj "/CN=Test Root CA" -addext "basicConstraints=critical,CA:TRUE" -keyout pkcs12/ca.key -out pkcs12/ca.crt
+$OPENSSL req -new -newkey ec -pkeyopt ec_paramgen_curve:P-256 -nodes -subj "/CN=leaf.example.com" -keyout pkcs12/leaf.key -out pkcs12/leaf.csr
+$OPENSSL x509 -req -in pkcs12/leaf.csr -CA pkcs12/ca.crt -CAkey pkcs12/ca.key -CAcreateserial -days 3650 -out pkcs12/leaf.crt
+$OPENSSL req -x509 -newkey rsa:2048 -nodes -days 3650 -subj "/CN=rsa.example.com" -keyout pkcs12/rsa.key -out pkcs12/rsa.crt
+$OPENSSL req -x509 -newkey ed25519 -nodes -days 3650 -subj "/CN=ed25519.example.com" -keyout pkcs12/ed25519.key -out pkcs12/ed25519.crt
+rm -f pkcs12/leaf.csr pkcs12/ca.srl
+
+# AES-256-CBC with PBKDF2 and a SHA-256 MAC, the OpenSSL 3 default
+$OPENSSL pkcs12 -export -inkey pkcs12/leaf.key -in pkcs12/leaf.crt -certfile pkcs12/ca.crt -passout pass:mypassword -out pkcs12/aes.p12
+$OPENSSL pkcs12 -export -inkey pkcs12/rsa.key -in pkcs12/rsa.crt -passout pass:mypassword -out pkcs12/rsa.p12
+$OPENSSL pkcs12 -export -inkey pkcs12/ed25519.key -in pkcs12/ed25519.crt -passout pass:mypassword -out pkcs12/ed25519.p12
+# Empty password
+$OPENSSL pkcs12 -export -inkey pkcs12/leaf.key -in pkcs12/leaf.crt -certfile pkcs12/ca.crt -passout pass: -out pkcs12/nopass.p12
+# 3DES with a SHA-1 MAC, used in Windows exports
+$OPENSSL pkcs12 -export -inkey pkcs12/leaf.key -in pkcs12/leaf.crt -certfile pkcs12/ca.crt -keypbe PBE-SHA1-3DES -certpbe PBE-SHA1-3DES -macalg sha1 -passout pass:mypassword -out pkcs12/3des.p12
+# RC2-40 and 3DES with a SHA-1 MAC, the OpenSSL 1.1 default
+$OPENSSL pkcs12 -export -legacy -inkey pkcs12/leaf.key -in pkcs12/leaf.crt -certfile pkcs12/ca.crt -passout pass:mypassword -out pkcs12/legacy.p12
+# Certificates only
+$OPENSSL pkcs12 -export -nokeys -in pkcs12/leaf.crt -passout pass:mypassword -out pkcs12/nokey.p12
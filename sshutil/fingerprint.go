@@ -39,6 +39,20 @@ func Fingerprint(pub ssh.PublicKey) string {
 	return EncodedFingerprint(pub, DefaultFingerprint)
 }
 
+// FingerprintSHA256 returns the SHA-256 fingerprint of an ssh public key or
+// certificate in the same format used by "ssh-keygen -lf", the base64 raw
+// encoding of the hash prefixed by "SHA256:".
+func FingerprintSHA256(pub ssh.PublicKey) string {
+	return EncodedFingerprint(pub, Base64RawFingerprint)
+}
+
+// FingerprintMD5 returns the legacy MD5 fingerprint of an ssh public key or
+// certificate in the same format used by "ssh-keygen -E md5 -lf", the
+// colon-separated hex encoding of the hash prefixed by "MD5:".
+func FingerprintMD5(pub ssh.PublicKey) string {
+	return "MD5:" + ssh.FingerprintLegacyMD5(pub)
+}
+
 // EncodedFingerprint returns the SHA-256 hash of an ssh public key or
 // certificate using the specified encoding. If an invalid encoding is passed,
 // the return value will be an empty string.
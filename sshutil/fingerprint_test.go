@@ -71,6 +71,30 @@ func TestFingerprint(t *testing.T) {
 	}
 }
 
+func TestFingerprintSHA256_MD5(t *testing.T) {
+	// Fingerprints generated with "ssh-keygen -lf" and "ssh-keygen -E md5 -lf".
+	tests := []struct {
+		name       string
+		key        string
+		wantSHA256 string
+		wantMD5    string
+	}{
+		{"ed25519", "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAILNTIHyaSfjvcQDZ5kywgnPYI9T7mBvd2MARlg62w6yc test",
+			"SHA256:eXP/zSylznqSFptuKCOvYYknKIa8iRBk30QUj+OKY6g", "MD5:82:c9:39:92:02:dd:c1:1f:90:fe:57:d3:c5:99:e1:a5"},
+		{"rsa", "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQC5s3hjtE3V+hreSpRH/+D96J27OLjqPCtCWdqOlxO1bbviuD48fpVK5DGYIL+gyyHnb91KoWim7OKH1IdxVIfS8WfaUQ43XZox99oyyQkV0KKKuvcjpR9yy2SWvlmWYUAioELfKBRENVF4DkP4bdlGk76iJN2K9DO9aYb2cI3BiOZr1zsLQzEFcXdYAS2+Bx+7ccT4YLvyUaWNT3R5p+QKyefhAmlkGrbUYBBepkfU9FnYwvewOFAUbIqeEkHgJZMsvUrkEtR0Cic7PL5LsTKdvZEN81X+L4nIjGQmd2SsJo7BLGM7iEEuX3/dlcz/h45pUsmYdgiFscMQ9BZTELJz test",
+			"SHA256:YrprGrzxisny7GjEHCk1CoRVDj2e6/AliLl5VjlpUoI", "MD5:db:8a:30:97:46:aa:63:93:69:ad:d8:b2:49:31:b4:66"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(tt.key))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSHA256, FingerprintSHA256(pub))
+			assert.Equal(t, tt.wantMD5, FingerprintMD5(pub))
+			assert.Equal(t, Fingerprint(pub), FingerprintSHA256(pub))
+		})
+	}
+}
+
 func TestEncodedFingerprint(t *testing.T) {
 	_, sshECKey := generateKey(t, "EC", "P-256", 0)
 
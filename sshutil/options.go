@@ -43,6 +43,9 @@ func getFuncMap(err *TemplateError) template.FuncMap {
 // given data.
 func WithTemplate(text string, data TemplateData) Option {
 	return func(cr CertificateRequest, o *Options) error {
+		if data == nil {
+			data = NewTemplateData()
+		}
 		terr := new(TemplateError)
 		funcMap := getFuncMap(terr)
 		// Parse template
@@ -42,9 +42,44 @@ func ValidateTemplateData(data []byte) error {
 }
 
 // TemplateData is an alias for map[string]interface{}. It represents the data
-// passed to the templates.
+// passed to the templates. The following keys are available in templates:
+//
+//   - .Type: the certificate type, "user" or "host".
+//   - .KeyID: the key id of the certificate.
+//   - .Principals: the list of principals.
+//   - .Extensions: the map of extensions.
+//   - .CriticalOptions: the map of critical options.
+//   - .Token: the claims of the token used to authorize the request, for
+//     example {{ .Token.email }} in an OIDC token.
+//   - .Webhooks: the responses of the webhooks by name.
+//   - .Insecure.CR: the certificate request, with the key, type, key id and
+//     principals requested, these values are not validated.
+//   - .Insecure.User: the user provided data, these values are not validated.
 type TemplateData map[string]interface{}
 
+// Identity represents the authenticated identity that requests an SSH
+// certificate, for example an OIDC user.
+type Identity struct {
+	// KeyID is the key id of the certificate.
+	KeyID string
+	// Principals is the list of principals of the certificate.
+	Principals []string
+	// Claims are the claims of the identity, they will be available in the
+	// templates as .Token.
+	Claims map[string]interface{}
+}
+
+// CreateIdentityTemplateData returns a TemplateData with the given certificate
+// type, the key id, principals, and claims of the identity, and the default
+// extensions.
+func CreateIdentityTemplateData(ct CertType, id Identity) TemplateData {
+	data := CreateTemplateData(ct, id.KeyID, id.Principals)
+	if id.Claims != nil {
+		data.SetToken(id.Claims)
+	}
+	return data
+}
+
 // CreateTemplateData returns a TemplateData with the given certificate type,
 // key id, principals, and the default extensions.
 func CreateTemplateData(ct CertType, keyID string, principals []string) TemplateData {
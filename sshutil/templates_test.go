@@ -73,6 +73,82 @@ func TestCreateTemplateData(t *testing.T) {
 	}
 }
 
+func TestCreateIdentityTemplateData(t *testing.T) {
+	claims := map[string]interface{}{
+		"email":  "jane@doe.com",
+		"groups": []interface{}{"admin", "dev"},
+	}
+	type args struct {
+		ct CertType
+		id Identity
+	}
+	tests := []struct {
+		name string
+		args args
+		want TemplateData
+	}{
+		{"user", args{UserCert, Identity{KeyID: "jane@doe.com", Principals: []string{"jane"}, Claims: claims}}, TemplateData{
+			TypeKey:       "user",
+			KeyIDKey:      "jane@doe.com",
+			PrincipalsKey: []string{"jane"},
+			ExtensionsKey: DefaultExtensions(UserCert),
+			TokenKey:      claims,
+		}},
+		{"host", args{HostCert, Identity{KeyID: "foo", Principals: []string{"foo.internal"}}}, TemplateData{
+			TypeKey:       "host",
+			KeyIDKey:      "foo",
+			PrincipalsKey: []string{"foo.internal"},
+			ExtensionsKey: map[string]interface{}(nil),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CreateIdentityTemplateData(tt.args.ct, tt.args.id); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateIdentityTemplateData() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCertificate_identityTemplate(t *testing.T) {
+	key := mustGeneratePublicKey(t)
+	data := CreateIdentityTemplateData(UserCert, Identity{
+		KeyID: "jane@doe.com",
+		Claims: map[string]interface{}{
+			"email":  "jane@doe.com",
+			"groups": []interface{}{"admin", "dev"},
+		},
+	})
+
+	text := `{
+	"type": {{ toJson .Type }},
+	"keyId": {{ toJson .KeyID }},
+	"principals": [{{ toJson (.Token.email | splitList "@" | first) }}, {{ toJson .Token.email }}{{ range .Token.groups }}, {{ toJson (print "group-" .) }}{{ end }}],
+	"extensions": {{ toJson .Extensions }}
+}`
+	cert, err := NewCertificate(CertificateRequest{Key: key}, WithTemplate(text, data))
+	if err != nil {
+		t.Fatalf("NewCertificate() error = %v", err)
+	}
+	if want := []string{"jane", "jane@doe.com", "group-admin", "group-dev"}; !reflect.DeepEqual(cert.Principals, want) {
+		t.Errorf("NewCertificate() principals = %v, want %v", cert.Principals, want)
+	}
+	if cert.KeyID != "jane@doe.com" {
+		t.Errorf("NewCertificate() keyID = %v, want %v", cert.KeyID, "jane@doe.com")
+	}
+	if cert.Type != UserCert {
+		t.Errorf("NewCertificate() type = %v, want %v", cert.Type, UserCert)
+	}
+	if !reflect.DeepEqual(cert.Key, key) {
+		t.Errorf("NewCertificate() key = %v, want %v", cert.Key, key)
+	}
+
+	// Nil data can also be used.
+	if _, err := NewCertificate(CertificateRequest{Key: key}, WithTemplate(`{"keyId": {{ toJson .Insecure.CR.KeyID }}}`, nil)); err != nil {
+		t.Errorf("NewCertificate() error = %v", err)
+	}
+}
+
 func TestDefaultExtensions(t *testing.T) {
 	type args struct {
 		ct CertType
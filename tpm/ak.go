@@ -14,6 +14,7 @@ import (
 	"github.com/smallstep/go-attestation/attest"
 	x509ext "github.com/smallstep/go-attestation/x509"
 
+	internalkey "go.step.sm/crypto/tpm/internal/key"
 	"go.step.sm/crypto/tpm/storage"
 )
 
@@ -26,6 +27,8 @@ type AK struct {
 	data         []byte
 	chain        []*x509.Certificate
 	createdAt    time.Time
+	generation   int
+	retiredAt    time.Time
 	blobs        *Blobs
 	attestParams *attest.AttestationParameters
 	tpm          *TPM
@@ -51,6 +54,19 @@ func (ak *AK) CreatedAt() time.Time {
 	return ak.createdAt.Truncate(time.Second)
 }
 
+// Generation returns the AK generation. It's 0 for a newly
+// created AK, and it's incremented every time the AK is
+// rotated using RotateAK.
+func (ak *AK) Generation() int {
+	return ak.generation
+}
+
+// RetiredAt returns the time the AK was retired by a rotation.
+// It returns the zero time if the AK has not been retired.
+func (ak *AK) RetiredAt() time.Time {
+	return ak.retiredAt.Truncate(time.Second)
+}
+
 // Certificate returns the AK certificate, if set.
 // Will return nil in case no AK certificate is available.
 func (ak *AK) Certificate() *x509.Certificate {
@@ -130,36 +146,92 @@ func (ak *AK) MarshalJSON() ([]byte, error) {
 	return json.Marshal(o)
 }
 
+// CreateAKOption is used to provide options when creating an AK.
+type CreateAKOption func(o *createAKOptions) error
+
+type createAKOptions struct {
+	authPolicy []byte
+}
+
+// WithAuthPolicy sets the digest of the policy that must be satisfied to
+// use the AK to sign, e.g. the one returned by PCRPolicyDigest. It must be
+// a SHA-256 digest. The policy is not required to activate a credential
+// with the AK, but it is required to attest Keys with it, which is not
+// supported by AttestKey, so AttestKey returns ErrPolicySessionRequired.
+// As signing with the AK requires the policy to be satisfied, its creation
+// can't be certified, so the AttestationParameters of the AK don't include
+// a creation attestation.
+func WithAuthPolicy(policy []byte) CreateAKOption {
+	return func(o *createAKOptions) error {
+		if len(policy) == 0 {
+			return errors.New("auth policy cannot be empty")
+		}
+		o.authPolicy = policy
+		return nil
+	}
+}
+
 // CreateAK creates and stores a new AK identified by `name`.
 // If no name is  provided, a random 10 character name is generated.
+// The name cannot contain "@", which is reserved for retired AKs.
 // If an AK with the same name exists, `ErrExists` is returned.
-func (t *TPM) CreateAK(ctx context.Context, name string) (ak *AK, err error) {
-	if err = t.open(ctx); err != nil {
+func (t *TPM) CreateAK(ctx context.Context, name string, opts ...CreateAKOption) (ak *AK, err error) {
+	o := &createAKOptions{}
+	for _, fn := range opts {
+		if err := fn(o); err != nil {
+			return nil, fmt.Errorf("invalid AK options: %w", err)
+		}
+	}
+
+	// go-attestation can't create AKs with an auth policy, so they are
+	// created using go-tpm.
+	openCtx := ctx
+	if len(o.authPolicy) > 0 {
+		openCtx = goTPMCall(ctx)
+	}
+	if err = t.open(openCtx); err != nil {
 		return nil, fmt.Errorf("failed opening TPM: %w", err)
 	}
 	defer closeTPM(ctx, t, &err)
 
+	if err = t.requireVersion20("CreateAK"); err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 	if name, err = processName(name); err != nil {
 		return nil, err
 	}
+	if err = validateAKName(name); err != nil {
+		return nil, err
+	}
 
 	if _, err := t.store.GetAK(name); err == nil {
 		return nil, fmt.Errorf("failed creating AK %q: %w", name, ErrExists)
 	}
 
-	akConfig := attest.AKConfig{
-		Name: prefixAK(name),
-	}
-	aak, err := t.attestTPM.NewAK(&akConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed creating new AK %q: %w", name, err)
-	}
-	defer aak.Close(t.attestTPM)
+	var data []byte
+	if len(o.authPolicy) > 0 {
+		if data, err = internalkey.CreateAK(t.rwc, prefixAK(name), o.authPolicy); err != nil {
+			return nil, fmt.Errorf("failed creating new AK %q: %w", name, err)
+		}
+	} else {
+		if err = t.requireAttestTPM("CreateAK"); err != nil {
+			return nil, err
+		}
 
-	data, err := aak.Marshal()
-	if err != nil {
-		return nil, fmt.Errorf("failed marshaling AK %q: %w", name, err)
+		akConfig := attest.AKConfig{
+			Name: prefixAK(name),
+		}
+		aak, err := t.attestTPM.NewAK(&akConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed creating new AK %q: %w", name, err)
+		}
+		defer aak.Close(t.attestTPM)
+
+		if data, err = aak.Marshal(); err != nil {
+			return nil, fmt.Errorf("failed marshaling AK %q: %w", name, err)
+		}
 	}
 
 	ak = &AK{
@@ -169,14 +241,12 @@ func (t *TPM) CreateAK(ctx context.Context, name string) (ak *AK, err error) {
 		tpm:       t,
 	}
 
-	if err := t.store.AddAK(ak.toStorage()); err != nil {
+	if err := t.updateStore(func(w storage.Writer) error {
+		return w.AddAK(ak.toStorage())
+	}); err != nil {
 		return nil, fmt.Errorf("failed adding AK %q: %w", name, err)
 	}
 
-	if err := t.store.Persist(); err != nil {
-		return nil, fmt.Errorf("failed persisting AK %q: %w", name, err)
-	}
-
 	return ak, nil
 }
 
@@ -199,6 +269,126 @@ func (t *TPM) GetAK(ctx context.Context, name string) (ak *AK, err error) {
 	return akFromStorage(sak, t), nil
 }
 
+// RotateAK replaces the AK identified by `name` with a newly created
+// AK. The logical name keeps pointing to the current AK, so GetAK
+// returns the new AK after the rotation, and the generation of the new
+// AK is incremented. The replaced AK is marked as retired and is kept
+// in storage, so that it can still be used to verify data it signed
+// in the past; it can be retrieved using GetAKGeneration. Keys that
+// were attested by the replaced AK are updated to reference the
+// retired AK. The new AK has no certificate chain. AKs created with
+// an auth policy can't be rotated. It returns `ErrNotFound` if the AK
+// doesn't exist.
+func (t *TPM) RotateAK(ctx context.Context, name string) (ak *AK, err error) {
+	if err = t.open(ctx); err != nil {
+		return nil, fmt.Errorf("failed opening TPM: %w", err)
+	}
+	defer closeTPM(ctx, t, &err)
+
+	if err = t.requireVersion20("RotateAK"); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sak, err := t.store.GetAK(name)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("failed getting AK %q: %w", name, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed getting AK %q: %w", name, err)
+	}
+	if !sak.RetiredAt.IsZero() {
+		return nil, fmt.Errorf("failed rotating AK %q: AK is retired", name)
+	}
+	if policy, err := internalkey.AuthPolicy(sak.Data); err == nil && len(policy) > 0 {
+		return nil, fmt.Errorf("failed rotating AK %q: AKs with an auth policy can't be rotated", name)
+	}
+
+	retiredName := retiredAKName(name, sak.Generation)
+	if _, err := t.store.GetAK(retiredName); err == nil {
+		return nil, fmt.Errorf("failed rotating AK %q: retired AK %q: %w", name, retiredName, ErrExists)
+	}
+
+	skeys, err := t.store.ListKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed listing keys: %w", err)
+	}
+
+	if err = t.requireAttestTPM("RotateAK"); err != nil {
+		return nil, err
+	}
+
+	generation := sak.Generation + 1
+	akConfig := attest.AKConfig{
+		Name: prefixAK(retiredAKName(name, generation)),
+	}
+	aak, err := t.attestTPM.NewAK(&akConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating new AK %q: %w", name, err)
+	}
+	defer aak.Close(t.attestTPM)
+
+	data, err := aak.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("failed marshaling AK %q: %w", name, err)
+	}
+
+	retired := *sak
+	retired.Name = retiredName
+	retired.RetiredAt = now.UTC()
+
+	ak = &AK{
+		name:       name,
+		data:       data,
+		createdAt:  now,
+		generation: generation,
+		tpm:        t,
+	}
+
+	if err := t.updateStore(func(w storage.Writer) error {
+		if err := w.AddAK(&retired); err != nil {
+			return err
+		}
+		for _, skey := range skeys {
+			if skey.AttestedBy == name {
+				skey.AttestedBy = retiredName
+				if err := w.UpdateKey(skey); err != nil {
+					return err
+				}
+			}
+		}
+		return w.UpdateAK(ak.toStorage())
+	}); err != nil {
+		return nil, fmt.Errorf("failed rotating AK %q: %w", name, err)
+	}
+
+	return ak, nil
+}
+
+// GetAKGeneration returns the generation `generation` of the AK
+// identified by `name`. It returns the current AK if `generation`
+// is its current generation, and the retired AK otherwise. It
+// returns `ErrNotFound` if it doesn't exist.
+func (t *TPM) GetAKGeneration(ctx context.Context, name string, generation int) (ak *AK, err error) {
+	if err = t.open(ctx); err != nil {
+		return nil, fmt.Errorf("failed opening TPM: %w", err)
+	}
+	defer closeTPM(ctx, t, &err)
+
+	sak, err := t.store.GetAK(name)
+	if err == nil && sak.Generation != generation {
+		sak, err = t.store.GetAK(retiredAKName(name, generation))
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("failed getting AK %q generation %d: %w", name, generation, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed getting AK %q generation %d: %w", name, generation, err)
+	}
+
+	return akFromStorage(sak, t), nil
+}
+
 var (
 	oidSubjectAlternativeName = asn1.ObjectIdentifier{2, 5, 29, 17}
 )
@@ -229,8 +419,8 @@ func (t *TPM) GetAKByPermanentIdentifier(ctx context.Context, permanentIdentifie
 	return nil, ErrNotFound
 }
 
-// ListAKs returns a slice of AKs. The result is (currently)
-// not ordered.
+// ListAKs returns a slice of AKs. AKs that were retired by a
+// rotation are not included. The result is (currently) not ordered.
 func (t *TPM) ListAKs(ctx context.Context) (aks []*AK, err error) {
 	if err := t.open(ctx); err != nil {
 		return nil, fmt.Errorf("failed opening TPM: %w", err)
@@ -242,8 +432,11 @@ func (t *TPM) ListAKs(ctx context.Context) (aks []*AK, err error) {
 		return nil, fmt.Errorf("failed listing AKs: %w", err)
 	}
 
-	aks = make([]*AK, 0, len(aks))
+	aks = make([]*AK, 0, len(saks))
 	for _, sak := range saks {
+		if !sak.RetiredAt.IsZero() {
+			continue
+		}
 		aks = append(aks, akFromStorage(sak, t))
 	}
 
@@ -253,8 +446,10 @@ func (t *TPM) ListAKs(ctx context.Context) (aks []*AK, err error) {
 }
 
 // DeleteAK removes the AK identified by `name`. It returns `ErrNotfound`
-// if it doesn't exist. Keys that were attested by the AK have to be removed
-// before removing the AK, otherwise an error will be returned.
+// if it doesn't exist. The AKs retired by rotations of the AK are removed
+// too. Keys that were attested by the AK, or by any of its retired AKs,
+// have to be removed before removing the AK, otherwise an error will be
+// returned.
 func (t *TPM) DeleteAK(ctx context.Context, name string) (err error) {
 	if err := t.open(ctx); err != nil {
 		return fmt.Errorf("failed opening TPM: %w", err)
@@ -269,29 +464,54 @@ func (t *TPM) DeleteAK(ctx context.Context, name string) (err error) {
 		return fmt.Errorf("failed getting AK %q: %w", name, err)
 	}
 
+	saks := []*storage.AK{ak}
+	if ak.RetiredAt.IsZero() {
+		for generation := 0; generation < ak.Generation; generation++ {
+			retired, err := t.store.GetAK(retiredAKName(name, generation))
+			if err != nil {
+				if errors.Is(err, storage.ErrNotFound) {
+					continue
+				}
+				return fmt.Errorf("failed getting AK %q generation %d: %w", name, generation, err)
+			}
+			saks = append(saks, retired)
+		}
+	}
+
 	// prevent deleting the AK if the TPM (storage) contains keys that
 	// were attested by it. While keys would still work if the AK were
 	// deleted, some functionalities would no longer work. The AK can
 	// only be deleted if all keys attested by it are deleted first.
-	keys, err := t.GetKeysAttestedBy(internalCall(ctx), name)
-	if err != nil {
-		return fmt.Errorf("failed getting keys attested by AK %q: %w", name, err)
-	}
+	for _, sak := range saks {
+		keys, err := t.GetKeysAttestedBy(internalCall(ctx), sak.Name)
+		if err != nil {
+			return fmt.Errorf("failed getting keys attested by AK %q: %w", sak.Name, err)
+		}
 
-	if len(keys) > 0 {
-		return fmt.Errorf("failed deleting AK %q because %d key(s) exist that were attested by it", name, len(keys))
+		if len(keys) > 0 {
+			return fmt.Errorf("failed deleting AK %q because %d key(s) exist that were attested by it", sak.Name, len(keys))
+		}
 	}
 
-	if err := t.attestTPM.DeleteKey(ak.Data); err != nil { // TODO: we could add a DeleteAK to go-attestation; under the hood it's loaded the same as a key though.
-		return fmt.Errorf("failed deleting AK %q: %w", name, err)
+	if err := t.requireAttestTPM("DeleteAK"); err != nil {
+		return err
 	}
 
-	if err := t.store.DeleteAK(name); err != nil {
-		return fmt.Errorf("failed deleting AK %q from storage: %w", name, err)
+	for _, sak := range saks {
+		if err := t.attestTPM.DeleteKey(sak.Data); err != nil { // TODO: we could add a DeleteAK to go-attestation; under the hood it's loaded the same as a key though.
+			return fmt.Errorf("failed deleting AK %q: %w", sak.Name, err)
+		}
 	}
 
-	if err := t.store.Persist(); err != nil {
-		return fmt.Errorf("failed persisting storage: %w", err)
+	if err := t.updateStore(func(w storage.Writer) error {
+		for _, sak := range saks {
+			if err := w.DeleteAK(sak.Name); err != nil {
+				return fmt.Errorf("failed deleting AK %q from storage: %w", sak.Name, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
 	}
 
 	return
@@ -309,6 +529,10 @@ func (ak *AK) AttestationParameters(ctx context.Context) (params attest.Attestat
 	}
 	defer closeTPM(ctx, ak.tpm, &err)
 
+	if err = ak.tpm.requireAttestTPM("AttestationParameters"); err != nil {
+		return params, err
+	}
+
 	loadedAK, err := ak.tpm.attestTPM.LoadAK(ak.data)
 	if err != nil {
 		return params, fmt.Errorf("failed loading AK %q: %w", ak.name, err)
@@ -334,6 +558,10 @@ func (ak *AK) ActivateCredential(ctx context.Context, in EncryptedCredential) (s
 	}
 	defer closeTPM(ctx, ak.tpm, &err)
 
+	if err = ak.tpm.requireAttestTPM("ActivateCredential"); err != nil {
+		return secret, err
+	}
+
 	loadedAK, err := ak.tpm.attestTPM.LoadAK(ak.data)
 	if err != nil {
 		return secret, fmt.Errorf("failed loading AK %q: %w", ak.name, err)
@@ -360,6 +588,10 @@ func (ak *AK) Blobs(ctx context.Context) (blobs *Blobs, err error) {
 	}
 	defer closeTPM(ctx, ak.tpm, &err)
 
+	if err = ak.tpm.requireAttestTPM("Blobs"); err != nil {
+		return nil, err
+	}
+
 	aak, err := ak.tpm.attestTPM.LoadAK(ak.data)
 	if err != nil {
 		return nil, fmt.Errorf("failed loading AK: %w", err)
@@ -460,22 +692,32 @@ func (ak *AK) HasValidPermanentIdentifier(permanentIdentifier string) bool {
 // toStorage transforms the AK to the struct used for
 // persisting AKs.
 func (ak *AK) toStorage() *storage.AK {
-	return &storage.AK{
-		Name:      ak.name,
-		Data:      ak.data,
-		Chain:     ak.chain,
-		CreatedAt: ak.createdAt.UTC(),
+	sak := &storage.AK{
+		Name:       ak.name,
+		Data:       ak.data,
+		Chain:      ak.chain,
+		CreatedAt:  ak.createdAt.UTC(),
+		Generation: ak.generation,
+	}
+	if !ak.retiredAt.IsZero() {
+		sak.RetiredAt = ak.retiredAt.UTC()
 	}
+	return sak
 }
 
 // akFromStorage recreates an AK from the struct used for
 // persisting AKs.
 func akFromStorage(sak *storage.AK, t *TPM) *AK {
-	return &AK{
-		name:      sak.Name,
-		data:      sak.Data,
-		chain:     sak.Chain,
-		createdAt: sak.CreatedAt.Local(),
-		tpm:       t,
-	}
+	ak := &AK{
+		name:       sak.Name,
+		data:       sak.Data,
+		chain:      sak.Chain,
+		createdAt:  sak.CreatedAt.Local(),
+		generation: sak.Generation,
+		tpm:        t,
+	}
+	if !sak.RetiredAt.IsZero() {
+		ak.retiredAt = sak.RetiredAt.Local()
+	}
+	return ak
 }
@@ -2,9 +2,17 @@ package tpm
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/google/go-tpm/legacy/tpm2"
 	"github.com/google/go-tpm/tpmutil"
+
+	"go.step.sm/crypto/tpm/storage"
 )
 
 // Blobs is a container for the private and public blobs of data
@@ -41,6 +49,141 @@ func toTPM2Tools(blob []byte) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// fromTPM2B validates the 16-bit size header of a TPM2B blob
+// and returns the blob without it.
+func fromTPM2B(blob []byte) ([]byte, error) {
+	if len(blob) < 2 {
+		return nil, fmt.Errorf("blob is too short: %d bytes", len(blob))
+	}
+	size := int(binary.BigEndian.Uint16(blob[0:2]))
+	if size == 0 {
+		return nil, errors.New("blob is empty")
+	}
+	if size != len(blob)-2 {
+		return nil, fmt.Errorf("blob size %d does not match header size %d", len(blob)-2, size)
+	}
+	return blob[2:], nil
+}
+
+// ExportTPM2B returns the AK public and private blobs in the TPM2B_PUBLIC
+// and TPM2B_PRIVATE wire formats, so that the AK can be loaded with other
+// tools, like tpm2-tools. The AK can be imported again using ImportAK.
+func (ak *AK) ExportTPM2B(ctx context.Context) (public, private []byte, err error) {
+	blobs, err := ak.Blobs(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if public, err = blobs.Public(); err != nil {
+		return nil, nil, err
+	}
+	if private, err = blobs.Private(); err != nil {
+		return nil, nil, err
+	}
+	return public, private, nil
+}
+
+// serializedAttestKey mirrors the (unexported) format used by go-attestation
+// to serialize keys that are stored encrypted outside of the TPM.
+type serializedAttestKey struct {
+	Encoding   uint8 `json:"KeyEncoding"`
+	TPMVersion uint8
+	Public     []byte
+	Blob       []byte `json:"KeyBlob"`
+}
+
+const (
+	// keyEncodingEncrypted is the go-attestation encoding for keys
+	// fully represented in encrypted form.
+	keyEncodingEncrypted = 2
+	// tpmVersion20 is the go-attestation TPM 2.0 version.
+	tpmVersion20 = 2
+)
+
+// ImportAK imports an AK from its public and private blobs in the
+// TPM2B_PUBLIC and TPM2B_PRIVATE wire formats, like the ones returned
+// by ExportTPM2B, and stores it with the provided name. The blobs must
+// have been created with the default SRK of the TPM as parent. The
+// creation data of the AK is not available, so it's not included in
+// the AttestationParameters of the imported AK. The name cannot contain
+// "@", which is reserved for retired AKs. If an AK with the same name
+// exists, `ErrExists` is returned.
+func (t *TPM) ImportAK(ctx context.Context, name string, public, private []byte) (ak *AK, err error) {
+	if err = t.open(ctx); err != nil {
+		return nil, fmt.Errorf("failed opening TPM: %w", err)
+	}
+	defer closeTPM(ctx, t, &err)
+
+	if err = t.requireVersion20("ImportAK"); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if name, err = processName(name); err != nil {
+		return nil, err
+	}
+	if err = validateAKName(name); err != nil {
+		return nil, err
+	}
+
+	if _, err := t.store.GetAK(name); err == nil {
+		return nil, fmt.Errorf("failed importing AK %q: %w", name, ErrExists)
+	}
+
+	pub, err := fromTPM2B(public)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TPM2B_PUBLIC: %w", err)
+	}
+	priv, err := fromTPM2B(private)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TPM2B_PRIVATE: %w", err)
+	}
+
+	tpmPub, err := tpm2.DecodePublic(pub)
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding TPM2B_PUBLIC: %w", err)
+	}
+	if tpmPub.Attributes&(tpm2.FlagRestricted|tpm2.FlagSign) != tpm2.FlagRestricted|tpm2.FlagSign ||
+		tpmPub.Attributes&tpm2.FlagDecrypt != 0 {
+		return nil, errors.New("invalid TPM2B_PUBLIC: key is not a restricted signing key")
+	}
+
+	data, err := json.Marshal(serializedAttestKey{
+		Encoding:   keyEncodingEncrypted,
+		TPMVersion: tpmVersion20,
+		Public:     pub,
+		Blob:       priv,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed marshaling AK %q: %w", name, err)
+	}
+
+	if err = t.requireAttestTPM("ImportAK"); err != nil {
+		return nil, err
+	}
+
+	// load the AK to check that it belongs to this TPM
+	aak, err := t.attestTPM.LoadAK(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading AK %q: %w", name, err)
+	}
+	defer aak.Close(t.attestTPM)
+
+	ak = &AK{
+		name:      name,
+		data:      data,
+		createdAt: now,
+		tpm:       t,
+	}
+
+	if err := t.updateStore(func(w storage.Writer) error {
+		return w.AddAK(ak.toStorage())
+	}); err != nil {
+		return nil, fmt.Errorf("failed adding AK %q: %w", name, err)
+	}
+
+	return ak, nil
+}
+
 func (ak *AK) setBlobs(private, public []byte) {
 	ak.blobs = &Blobs{
 		private: private,
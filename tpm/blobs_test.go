@@ -0,0 +1,41 @@
+package tpm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_fromTPM2B(t *testing.T) {
+	tests := []struct {
+		name    string
+		blob    []byte
+		want    []byte
+		wantErr bool
+	}{
+		{"ok", []byte{0x00, 0x03, 0x01, 0x02, 0x03}, []byte{0x01, 0x02, 0x03}, false},
+		{"ok roundtrip", func() []byte {
+			b, err := toTPM2Tools([]byte{0x01, 0x02})
+			require.NoError(t, err)
+			return b
+		}(), []byte{0x01, 0x02}, false},
+		{"fail nil", nil, nil, true},
+		{"fail short", []byte{0x00}, nil, true},
+		{"fail empty", []byte{0x00, 0x00}, nil, true},
+		{"fail size too big", []byte{0x00, 0x04, 0x01, 0x02, 0x03}, nil, true},
+		{"fail size too small", []byte{0x00, 0x02, 0x01, 0x02, 0x03}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fromTPM2B(tt.blob)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
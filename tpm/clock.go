@@ -0,0 +1,62 @@
+package tpm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// ClockInfo holds the values of the TPM clock and its counters, as returned
+// by TPM2_ReadClock. The same values are included in the attestation data
+// signed by the TPM, e.g. in quotes and certifications, allowing verifiers to
+// detect replayed attestations.
+type ClockInfo struct {
+	// Time is the time in milliseconds since the last TPM Reset or
+	// TPM Restart.
+	Time uint64
+	// Clock is the time in milliseconds that advances while the TPM is
+	// powered. It is not reset on TPM Reset.
+	Clock uint64
+	// ResetCount is the number of TPM Resets since the last TPM2_Clear.
+	ResetCount uint32
+	// RestartCount is the number of TPM Restarts or TPM Resumes since the
+	// last TPM Reset or TPM2_Clear.
+	RestartCount uint32
+	// Safe indicates that no value of Clock greater than the current value
+	// has been reported by the TPM before.
+	Safe bool
+}
+
+// ReadClock returns the current values of the TPM clock and its counters.
+func (t *TPM) ReadClock(ctx context.Context) (info *ClockInfo, err error) {
+	if err = t.open(goTPMCall(ctx)); err != nil {
+		return nil, fmt.Errorf("failed opening TPM: %w", err)
+	}
+	defer closeTPM(ctx, t, &err)
+
+	if err = t.requireVersion20("ReadClock"); err != nil {
+		return nil, err
+	}
+
+	// the legacy tpm2.ReadClock only returns the time and clock values, so
+	// the command is run directly to decode the full TPMS_TIME_INFO.
+	resp, code, err := tpmutil.RunCommand(t.rwc, tpm2.TagNoSessions, tpm2.CmdReadClock)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading clock: %w", err)
+	}
+	if code != tpmutil.RCSuccess {
+		return nil, fmt.Errorf("failed reading clock: response code 0x%x", code)
+	}
+
+	var safe byte
+	info = &ClockInfo{}
+	if err = tpmutil.UnpackBuf(bytes.NewBuffer(resp), &info.Time, &info.Clock, &info.ResetCount, &info.RestartCount, &safe); err != nil {
+		return nil, fmt.Errorf("failed decoding clock: %w", err)
+	}
+	info.Safe = safe != 0
+
+	return
+}
@@ -0,0 +1,172 @@
+package tpm
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/smallstep/go-attestation/attest"
+)
+
+// keyCertification is the ASN.1 representation of the value of the key
+// certification extension created by CreateAttestedCSR. It contains the
+// certification parameters of a TPM key and the public area of the AK that
+// certified it. All the values are encoded as OCTET STRINGs:
+//
+//	KeyCertification ::= SEQUENCE {
+//		public            OCTET STRING, -- TPMT_PUBLIC of the key
+//		createData        OCTET STRING, -- TPMS_CREATION_DATA, might be empty
+//		createAttestation OCTET STRING, -- TPMS_ATTEST of TPM2_Certify
+//		createSignature   OCTET STRING, -- TPMT_SIGNATURE by the AK
+//		akPublic          OCTET STRING  -- TPMT_PUBLIC of the AK
+//	}
+type keyCertification struct {
+	Public            []byte
+	CreateData        []byte
+	CreateAttestation []byte
+	CreateSignature   []byte
+	AKPublic          []byte
+}
+
+// CreateAttestedCSR creates a certificate request signed by the TPM key
+// identified by `keyName` with the given subject. The key must have been
+// attested by the AK identified by `akName`, and the certificate request
+// contains the key certification parameters and the public area of the AK in
+// the extension identified by `oid`. Retired AKs can't be used. The OID must
+// be one that the caller controls, and the same one must be passed to
+// VerifyAttestedCSR, which a CA can use to verify the extension.
+func (t *TPM) CreateAttestedCSR(ctx context.Context, keyName, akName string, subject pkix.Name, oid asn1.ObjectIdentifier) (*x509.CertificateRequest, error) {
+	if len(oid) == 0 {
+		return nil, errors.New("key certification extension OID cannot be empty")
+	}
+
+	key, err := t.GetKey(ctx, keyName)
+	if err != nil {
+		return nil, err
+	}
+	if key.AttestedBy() != akName {
+		return nil, fmt.Errorf("key %q was not attested by AK %q", keyName, akName)
+	}
+
+	ak, err := t.GetAK(ctx, akName)
+	if err != nil {
+		return nil, err
+	}
+	if !ak.RetiredAt().IsZero() {
+		return nil, fmt.Errorf("AK %q is retired", akName)
+	}
+	akParams, err := ak.AttestationParameters(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting AK attestation parameters: %w", err)
+	}
+
+	params, err := key.CertificationParameters(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting key certification parameters: %w", err)
+	}
+
+	value, err := asn1.Marshal(keyCertification{
+		Public:            params.Public,
+		CreateData:        params.CreateData,
+		CreateAttestation: params.CreateAttestation,
+		CreateSignature:   params.CreateSignature,
+		AKPublic:          akParams.Public,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed marshaling key certification extension: %w", err)
+	}
+
+	signer, err := key.Signer(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting signer for key %q: %w", keyName, err)
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: subject,
+		ExtraExtensions: []pkix.Extension{
+			{Id: oid, Value: value},
+		},
+	}, signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating certificate request: %w", err)
+	}
+
+	cr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing certificate request: %w", err)
+	}
+
+	return cr, nil
+}
+
+// VerifyAttestedCSR verifies a certificate request created by
+// CreateAttestedCSR with the same extension `oid`. It checks the signature of
+// the certificate request, that the certification parameters in the key
+// certification extension were signed
+// by the AK in the extension, and that they certify the public key in the
+// certificate request. It returns the public key of the AK.
+//
+// The AK is not verified; the caller must check that the returned AK public key
+// belongs to a trusted AK, e.g. comparing it with the public key in the AK
+// certificate.
+func VerifyAttestedCSR(cr *x509.CertificateRequest, oid asn1.ObjectIdentifier) (crypto.PublicKey, error) {
+	if len(oid) == 0 {
+		return nil, errors.New("key certification extension OID cannot be empty")
+	}
+	if err := cr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("failed verifying certificate request signature: %w", err)
+	}
+
+	var value []byte
+	for _, ext := range cr.Extensions {
+		if ext.Id.Equal(oid) {
+			value = ext.Value
+			break
+		}
+	}
+	if value == nil {
+		return nil, errors.New("certificate request does not contain a key certification extension")
+	}
+
+	var kc keyCertification
+	if rest, err := asn1.Unmarshal(value, &kc); err != nil {
+		return nil, fmt.Errorf("failed parsing key certification extension: %w", err)
+	} else if len(rest) > 0 {
+		return nil, errors.New("failed parsing key certification extension: trailing data")
+	}
+
+	akPublic, err := attest.ParseAKPublic(attest.TPMVersion20, kc.AKPublic)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing AK public data: %w", err)
+	}
+
+	params := attest.CertificationParameters{
+		Public:            kc.Public,
+		CreateData:        kc.CreateData,
+		CreateAttestation: kc.CreateAttestation,
+		CreateSignature:   kc.CreateSignature,
+	}
+	if err := params.Verify(attest.VerifyOpts{Public: akPublic.Public, Hash: akPublic.Hash}); err != nil {
+		return nil, fmt.Errorf("failed verifying key certification: %w", err)
+	}
+
+	pub, err := tpm2.DecodePublic(kc.Public)
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding key public data: %w", err)
+	}
+	certified, err := pub.Key()
+	if err != nil {
+		return nil, fmt.Errorf("failed getting certified public key: %w", err)
+	}
+	if k, ok := certified.(comparablePublicKey); !ok || !k.Equal(cr.PublicKey) {
+		return nil, errors.New("certified public key does not match the certificate request public key")
+	}
+
+	return akPublic.Public, nil
+}
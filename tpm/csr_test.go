@@ -0,0 +1,70 @@
+package tpm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testKeyCertificationOID is the OID used in the tests for the key
+// certification extension.
+var testKeyCertificationOID = asn1.ObjectIdentifier{1, 2, 3, 4}
+
+func TestVerifyAttestedCSR_fail(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	mustCSR := func(t *testing.T, exts ...pkix.Extension) *x509.CertificateRequest {
+		t.Helper()
+		der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject:         pkix.Name{CommonName: "device"},
+			ExtraExtensions: exts,
+		}, priv)
+		require.NoError(t, err)
+		cr, err := x509.ParseCertificateRequest(der)
+		require.NoError(t, err)
+		return cr
+	}
+	mustMarshal := func(t *testing.T, v interface{}) []byte {
+		t.Helper()
+		b, err := asn1.Marshal(v)
+		require.NoError(t, err)
+		return b
+	}
+
+	badSignature := mustCSR(t)
+	badSignature.Signature[len(badSignature.Signature)-1] ^= 0xff
+
+	tests := []struct {
+		name    string
+		cr      *x509.CertificateRequest
+		wantErr string
+	}{
+		{"fail signature", badSignature, "failed verifying certificate request signature"},
+		{"fail no extension", mustCSR(t), "certificate request does not contain a key certification extension"},
+		{"fail asn1", mustCSR(t, pkix.Extension{Id: testKeyCertificationOID, Value: []byte{0x04, 0x00}}), "failed parsing key certification extension"},
+		{"fail trailing data", mustCSR(t, pkix.Extension{Id: testKeyCertificationOID, Value: append(mustMarshal(t, keyCertification{}), 0x00)}), "failed parsing key certification extension: trailing data"},
+		{"fail ak public", mustCSR(t, pkix.Extension{Id: testKeyCertificationOID, Value: mustMarshal(t, keyCertification{
+			AKPublic: []byte("not a public area"),
+		})}), "failed parsing AK public data"},
+	}
+	t.Run("fail empty oid", func(t *testing.T) {
+		got, err := VerifyAttestedCSR(mustCSR(t), nil)
+		assert.EqualError(t, err, "key certification extension OID cannot be empty")
+		assert.Nil(t, got)
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyAttestedCSR(tt.cr, testKeyCertificationOID)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Nil(t, got)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package tpm
+
+// DeviceInfo describes a TPM device found by ListDevices.
+type DeviceInfo struct {
+	// Path is the path to the device. It can be used with WithDeviceName.
+	Path string `json:"path"`
+	// Version is the TPM specification version supported by the device.
+	// It's 0 if the version can't be determined.
+	Version Version `json:"version"`
+	// Manufacturer is the manufacturer of the TPM. It's empty if it can't
+	// be determined, e.g. when the device can't be opened by the current
+	// user.
+	Manufacturer Manufacturer `json:"manufacturer"`
+}
+
+// ListDevices returns the TPM devices available on the system, so that
+// one of them can be selected using WithDeviceName, e.g. on systems that
+// have both a firmware and a discrete TPM. Discovery is read-only; the
+// devices are only opened to read their manufacturer, and they are
+// closed right after.
+//
+// Devices are listed on Linux only, using the information in
+// /sys/class/tpm. If the kernel resource manager is available for a
+// device, the path to the resource manager (/dev/tpmrmN) is returned
+// instead of the path to the raw device (/dev/tpmN). On other platforms
+// an error matching ErrNotSupported is returned.
+func ListDevices() ([]DeviceInfo, error) {
+	return listDevices()
+}
@@ -0,0 +1,156 @@
+package tpm
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/smallstep/go-attestation/attest"
+
+	"go.step.sm/crypto/tpm/internal/open"
+	"go.step.sm/crypto/tpm/manufacturer"
+)
+
+// The directories used to list the TPM devices; they can be changed in
+// tests.
+var (
+	sysClassTPMDir = "/sys/class/tpm"
+	devDir         = "/dev"
+)
+
+// readManufacturerID reads the manufacturer of the TPM 2.0 device at
+// path. It can be replaced in tests.
+var readManufacturerID = func(path string) (manufacturer.ID, error) {
+	rwc, err := open.TPM(path)
+	if err != nil {
+		return 0, err
+	}
+	defer rwc.Close()
+
+	vals, _, err := tpm2.GetCapability(rwc, tpm2.CapabilityTPMProperties, 1, uint32(tpm2.Manufacturer))
+	if err != nil {
+		return 0, err
+	}
+	if len(vals) > 0 {
+		if p, ok := vals[0].(tpm2.TaggedProperty); ok && p.Tag == tpm2.Manufacturer {
+			return manufacturer.ID(p.Value), nil
+		}
+	}
+	return 0, errors.New("manufacturer property not found")
+}
+
+var tpmDeviceName = regexp.MustCompile(`^tpm[0-9]+$`)
+
+func listDevices() ([]DeviceInfo, error) {
+	entries, err := os.ReadDir(sysClassTPMDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []DeviceInfo{}, nil
+		}
+		return nil, fmt.Errorf("failed listing TPM devices: %w", err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		if tpmDeviceName.MatchString(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+	// sort tpm2 before tpm10
+	sort.Slice(names, func(i, j int) bool {
+		a, _ := strconv.Atoi(strings.TrimPrefix(names[i], "tpm"))
+		b, _ := strconv.Atoi(strings.TrimPrefix(names[j], "tpm"))
+		return a < b
+	})
+
+	devices := make([]DeviceInfo, 0, len(names))
+	for _, name := range names {
+		sysDir := filepath.Join(sysClassTPMDir, name)
+		rmPath := filepath.Join(devDir, "tpmrm"+strings.TrimPrefix(name, "tpm"))
+
+		var info DeviceInfo
+		switch {
+		case exists(rmPath):
+			info.Path = rmPath
+		case exists(filepath.Join(devDir, name)):
+			info.Path = filepath.Join(devDir, name)
+		default:
+			continue // no device node
+		}
+
+		switch readVersionMajor(sysDir) {
+		case "2":
+			info.Version = Version(attest.TPMVersion20)
+		case "1":
+			info.Version = Version(attest.TPMVersion12)
+		default:
+			// older kernels don't expose the version, but the
+			// resource manager is only available for TPM 2.0
+			if info.Path == rmPath {
+				info.Version = Version(attest.TPMVersion20)
+			}
+		}
+
+		switch info.Version {
+		case Version(attest.TPMVersion20):
+			if id, err := readManufacturerID(info.Path); err == nil {
+				info.Manufacturer = GetManufacturerByID(id)
+			}
+		case Version(attest.TPMVersion12):
+			if id, ok := readCapsManufacturerID(sysDir); ok {
+				info.Manufacturer = GetManufacturerByID(id)
+			}
+		}
+
+		devices = append(devices, info)
+	}
+
+	return devices, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// readVersionMajor returns the content of the tpm_version_major file,
+// available since Linux 5.6.
+func readVersionMajor(sysDir string) string {
+	b, err := os.ReadFile(filepath.Join(sysDir, "tpm_version_major"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// readCapsManufacturerID reads the manufacturer from the caps file that
+// the kernel exposes for TPM 1.2 devices, with a line like
+// "Manufacturer: 0x49465800".
+func readCapsManufacturerID(sysDir string) (manufacturer.ID, bool) {
+	b, err := os.ReadFile(filepath.Join(sysDir, "device", "caps"))
+	if err != nil {
+		return 0, false
+	}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		k, v, ok := strings.Cut(s.Text(), ":")
+		if !ok || strings.TrimSpace(k) != "Manufacturer" {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSpace(v), 0, 32)
+		if err != nil {
+			return 0, false
+		}
+		return manufacturer.ID(id), true
+	}
+	return 0, false
+}
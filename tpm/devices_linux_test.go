@@ -0,0 +1,83 @@
+package tpm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smallstep/go-attestation/attest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.step.sm/crypto/tpm/manufacturer"
+)
+
+func stubDevices(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	root := t.TempDir()
+	for name, content := range files {
+		fn := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fn), 0o700))
+		require.NoError(t, os.WriteFile(fn, []byte(content), 0o600))
+	}
+
+	sysDir, dir, fn := sysClassTPMDir, devDir, readManufacturerID
+	t.Cleanup(func() {
+		sysClassTPMDir, devDir, readManufacturerID = sysDir, dir, fn
+	})
+	sysClassTPMDir = filepath.Join(root, "sys", "class", "tpm")
+	devDir = filepath.Join(root, "dev")
+	readManufacturerID = func(path string) (manufacturer.ID, error) {
+		if filepath.Base(path) == "tpmrm0" {
+			return 0x494E5443, nil // INTC
+		}
+		return 0, errors.New("permission denied")
+	}
+}
+
+func TestListDevices(t *testing.T) {
+	stubDevices(t, map[string]string{
+		// firmware TPM 2.0 with resource manager
+		"sys/class/tpm/tpm0/tpm_version_major": "2\n",
+		"dev/tpm0":                             "",
+		"dev/tpmrm0":                           "",
+		// TPM 1.2
+		"sys/class/tpm/tpm1/tpm_version_major": "1\n",
+		"sys/class/tpm/tpm1/device/caps":       "Manufacturer: 0x49465800\nTCG version: 1.2\n",
+		"dev/tpm1":                             "",
+		// TPM 2.0 on an older kernel that can't be opened
+		"sys/class/tpm/tpm2/dev": "",
+		"dev/tpmrm2":             "",
+		// TPM 2.0 without device nodes
+		"sys/class/tpm/tpm3/tpm_version_major": "2\n",
+		// ignored
+		"sys/class/tpm/foo/tpm_version_major": "2\n",
+	})
+
+	devices, err := ListDevices()
+	require.NoError(t, err)
+	require.Len(t, devices, 3)
+
+	assert.Equal(t, filepath.Join(devDir, "tpmrm0"), devices[0].Path)
+	assert.Equal(t, Version(attest.TPMVersion20), devices[0].Version)
+	assert.Equal(t, "Intel", devices[0].Manufacturer.Name)
+	assert.Equal(t, manufacturer.ID(0x494E5443), devices[0].Manufacturer.ID)
+
+	assert.Equal(t, filepath.Join(devDir, "tpm1"), devices[1].Path)
+	assert.Equal(t, Version(attest.TPMVersion12), devices[1].Version)
+	assert.Equal(t, "Infineon", devices[1].Manufacturer.Name)
+
+	assert.Equal(t, filepath.Join(devDir, "tpmrm2"), devices[2].Path)
+	assert.Equal(t, Version(attest.TPMVersion20), devices[2].Version)
+	assert.Equal(t, Manufacturer{}, devices[2].Manufacturer)
+}
+
+func TestListDevices_noDevices(t *testing.T) {
+	stubDevices(t, nil)
+
+	devices, err := ListDevices()
+	require.NoError(t, err)
+	assert.Empty(t, devices)
+}
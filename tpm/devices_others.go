@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package tpm
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func listDevices() ([]DeviceInfo, error) {
+	return nil, fmt.Errorf("listing TPM devices on %s: %w", runtime.GOOS, ErrNotSupported)
+}
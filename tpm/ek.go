@@ -158,6 +158,10 @@ func (t *TPM) GetEKs(ctx context.Context) (eks []*EK, err error) {
 	}
 	defer closeTPM(ctx, t, &err)
 
+	if err = t.requireAttestTPM("GetEKs"); err != nil {
+		return nil, err
+	}
+
 	aeks, err := t.attestTPM.EKs()
 	if err != nil {
 		return nil, fmt.Errorf("failed getting EKs: %w", err)
@@ -211,7 +215,7 @@ func (t *TPM) prepareEKCertificateURL(ctx context.Context, ekURL string) (*url.U
 		return nil, fmt.Errorf("failed parsing EK certificate URL %q: %w", ekURL, err)
 	}
 
-	info, err := t.Info(internalCall(ctx))
+	info, err := t.cachedInfo(internalCall(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed getting TPM info: %w", err)
 	}
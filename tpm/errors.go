@@ -1,9 +1,82 @@
 package tpm
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // ErrNotFound is returned when a Key or AK cannot be found
 var ErrNotFound = errors.New("not found")
 
 // ErrExists is returned when a Key or AK already exists
 var ErrExists = errors.New("already exists")
+
+// ErrNotSupported is returned when an operation is not supported
+// by the TPM.
+var ErrNotSupported = errors.New("not supported")
+
+// NotFoundError is returned when the Key identified by Name
+// doesn't exist. It matches ErrNotFound when used with errors.Is.
+type NotFoundError struct {
+	Name string
+}
+
+// Error implements the error interface.
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("key %q not found", e.Name)
+}
+
+// Is reports whether target is ErrNotFound.
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// ErrPolicySessionRequired is returned when a Key or an AK created
+// with an auth policy is used without a policy session.
+var ErrPolicySessionRequired = errors.New("policy session required")
+
+// NotSupportedError is returned when an operation requires a TPM 2.0,
+// but the TPM reports to be of another version, like TPM 1.2. It
+// matches ErrNotSupported when used with errors.Is.
+type NotSupportedError struct {
+	Operation string
+	Version   Version
+}
+
+// Error implements the error interface.
+func (e *NotSupportedError) Error() string {
+	return fmt.Sprintf("%s not supported for operation %s", e.Version, e.Operation)
+}
+
+// Is reports whether target is ErrNotSupported.
+func (e *NotSupportedError) Is(target error) bool {
+	return target == ErrNotSupported
+}
+
+// AttestationUnavailableError is returned by operations that require
+// go-attestation when the TPM was created using WithPreferGoTPM, and
+// go-attestation failed to open it, but go-tpm didn't. In that case
+// the operations related to attestation, like creating and using AKs,
+// attesting Keys, getting the EKs, and getting the TPM Info, are not
+// available. Operations like GetRandom, CreateKey, and signing with a
+// Key are still available. It matches ErrNotSupported when used with
+// errors.Is, and unwraps to the error returned by go-attestation.
+type AttestationUnavailableError struct {
+	Operation string
+	Err       error
+}
+
+// Error implements the error interface.
+func (e *AttestationUnavailableError) Error() string {
+	return fmt.Sprintf("operation %s requires attestation, which is not available: %v", e.Operation, e.Err)
+}
+
+// Is reports whether target is ErrNotSupported.
+func (e *AttestationUnavailableError) Is(target error) bool {
+	return target == ErrNotSupported
+}
+
+// Unwrap returns the error returned by go-attestation.
+func (e *AttestationUnavailableError) Unwrap() error {
+	return e.Err
+}
@@ -4,21 +4,28 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
+	"github.com/google/go-tpm/legacy/tpm2"
 	"github.com/smallstep/go-attestation/attest"
 
 	"go.step.sm/crypto/tpm/manufacturer"
 )
 
 // Info models information about a TPM. It contains the
-// TPM version, interface, manufacturer, vendor info and
-// firmware version.
+// TPM version, interface, manufacturer, vendor info,
+// firmware version and, if available, the dictionary
+// attack lockout status and whether the TPM is operating
+// in FIPS 140-2 mode. FIPSMode is nil if the mode can't
+// be determined.
 type Info struct {
 	Version         Version         `json:"version"`
 	Interface       Interface       `json:"interface"`
 	Manufacturer    Manufacturer    `json:"manufacturer"`
 	VendorInfo      string          `json:"vendorInfo,omitempty"`
 	FirmwareVersion FirmwareVersion `json:"firmwareVersion,omitempty"`
+	LockoutStatus   *LockoutStatus  `json:"lockoutStatus,omitempty"`
+	FIPSMode        *bool           `json:"fipsMode,omitempty"`
 }
 
 // Version models the TPM specification version supported
@@ -93,6 +100,36 @@ func (fv FirmwareVersion) MarshalJSON() ([]byte, error) {
 	return json.Marshal(fv.String())
 }
 
+// LockoutStatus models the state of the dictionary attack
+// protection of a TPM. The failed tries counter is incremented
+// on every authorization failure, and the TPM enters lockout
+// mode when it reaches the maximum number of tries. The counter
+// is decremented by one after every recovery time interval.
+type LockoutStatus struct {
+	InLockout       bool
+	FailedTries     uint32
+	MaxTries        uint32
+	RecoveryTime    time.Duration
+	LockoutRecovery time.Duration
+}
+
+// MarshalJSON marshals the TPM lockout status to JSON.
+func (s LockoutStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		InLockout       bool   `json:"inLockout"`
+		FailedTries     uint32 `json:"failedTries"`
+		MaxTries        uint32 `json:"maxTries"`
+		RecoveryTime    string `json:"recoveryTime"`
+		LockoutRecovery string `json:"lockoutRecovery"`
+	}{
+		InLockout:       s.InLockout,
+		FailedTries:     s.FailedTries,
+		MaxTries:        s.MaxTries,
+		RecoveryTime:    s.RecoveryTime.String(),
+		LockoutRecovery: s.LockoutRecovery.String(),
+	})
+}
+
 // Manufacturer models a TPM Manufacturer.
 type Manufacturer struct {
 	ID    manufacturer.ID `json:"id"`
@@ -110,7 +147,8 @@ func (m Manufacturer) String() string {
 }
 
 // GetManufacturerByID returns a Manufacturer based on its Manufacturer ID
-// code.
+// code. If the manufacturer is not known, the name is "Unknown" followed
+// by the hexadecimal representation of the ID, e.g. "Unknown (0x58595A57)".
 func GetManufacturerByID(id manufacturer.ID) (m Manufacturer) {
 	m.ID = id
 	m.ASCII, m.Hex = manufacturer.GetEncodings(id)
@@ -118,14 +156,35 @@ func GetManufacturerByID(id manufacturer.ID) (m Manufacturer) {
 	if id == 4294963664 {
 		m.ASCII = "FIDO"
 	}
-	m.Name = manufacturer.GetNameByASCII(m.ASCII)
+	if manufacturer.IsKnown(id) {
+		m.Name = manufacturer.GetNameByASCII(m.ASCII)
+	} else {
+		m.Name = fmt.Sprintf("Unknown (0x%s)", m.Hex)
+	}
 
 	return
 }
 
-// Info returns info about the TPM. The info doesn't change, so
-// it's cached after the first lookup.
-func (t *TPM) Info(ctx context.Context) (info *Info, err error) {
+// Info returns info about the TPM. Most of the info doesn't change,
+// so it's cached after the first lookup. The lockout status and the
+// FIPS mode are read from the TPM on every call; they're omitted if the
+// TPM doesn't expose them or they can't be read.
+func (t *TPM) Info(ctx context.Context) (*Info, error) {
+	info, err := t.cachedInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := *info
+	if info.Version == Version(attest.TPMVersion20) {
+		result.LockoutStatus, result.FIPSMode = t.readStatus(ctx)
+	}
+
+	return &result, nil
+}
+
+// cachedInfo returns the info about the TPM that doesn't change.
+func (t *TPM) cachedInfo(ctx context.Context) (info *Info, err error) {
 	if t.info != nil {
 		return t.info, nil
 	}
@@ -135,6 +194,10 @@ func (t *TPM) Info(ctx context.Context) (info *Info, err error) {
 	}
 	defer closeTPM(ctx, t, &err)
 
+	if err = t.requireAttestTPM("Info"); err != nil {
+		return nil, err
+	}
+
 	ainfo, err := t.attestTPM.Info()
 	if err != nil {
 		return nil, fmt.Errorf("failed getting TPM info: %w", err)
@@ -156,3 +219,98 @@ func (t *TPM) Info(ctx context.Context) (info *Info, err error) {
 
 	return
 }
+
+// readStatus reads the dictionary attack lockout status and the FIPS
+// mode of the TPM, opening the TPM once for both. Both are best-effort:
+// they're nil if the TPM can't be opened or they can't be read.
+func (t *TPM) readStatus(ctx context.Context) (status *LockoutStatus, fips *bool) {
+	var err error
+	if err = t.open(goTPMCall(ctx)); err != nil {
+		return nil, nil
+	}
+	defer closeTPM(ctx, t, &err)
+
+	// the TPM is already opened for internal calls, but the
+	// go-tpm command channel might not be available.
+	if t.rwc == nil {
+		return nil, nil
+	}
+
+	status, _ = t.lockoutStatus()
+	fips, _ = t.fipsMode()
+
+	return
+}
+
+// inLockout is the inLockout bit in the TPMA_PERMANENT attributes.
+const inLockout = 1 << 9
+
+// lockoutStatus returns the dictionary attack lockout status of
+// the TPM, read from the TPM_PT_PERMANENT and TPM_PT_LOCKOUT_*
+// properties. It returns nil if the TPM doesn't expose them. The
+// TPM must be opened for go-tpm operations.
+func (t *TPM) lockoutStatus() (*LockoutStatus, error) {
+	count := uint32(tpm2.LockoutRecovery - tpm2.TPMAPermanent + 1)
+	vals, _, err := tpm2.GetCapability(t.rwc, tpm2.CapabilityTPMProperties, count, uint32(tpm2.TPMAPermanent))
+	if err != nil {
+		return nil, fmt.Errorf("failed getting lockout status: %w", err)
+	}
+
+	props := make(map[tpm2.TPMProp]uint32, len(vals))
+	for _, v := range vals {
+		if p, ok := v.(tpm2.TaggedProperty); ok {
+			props[p.Tag] = p.Value
+		}
+	}
+	for _, tag := range []tpm2.TPMProp{tpm2.TPMAPermanent, tpm2.LockoutCounter, tpm2.MaxAuthFail, tpm2.LockoutInterval, tpm2.LockoutRecovery} {
+		if _, ok := props[tag]; !ok {
+			return nil, nil
+		}
+	}
+
+	return &LockoutStatus{
+		InLockout:       props[tpm2.TPMAPermanent]&inLockout != 0,
+		FailedTries:     props[tpm2.LockoutCounter],
+		MaxTries:        props[tpm2.MaxAuthFail],
+		RecoveryTime:    time.Duration(props[tpm2.LockoutInterval]) * time.Second,
+		LockoutRecovery: time.Duration(props[tpm2.LockoutRecovery]) * time.Second,
+	}, nil
+}
+
+// fips1402 is the FIPS_140_2 bit in the TPMA_MODES attributes.
+const fips1402 = 1 << 0
+
+// fipsMode returns whether the TPM is operating in FIPS 140-2 mode,
+// read from the TPM_PT_MODES property. It returns nil if the TPM
+// implements a version of the specification without the property.
+// The TPM must be opened for go-tpm operations.
+func (t *TPM) fipsMode() (*bool, error) {
+	vals, _, err := tpm2.GetCapability(t.rwc, tpm2.CapabilityTPMProperties, 1, uint32(tpm2.TPMModes))
+	if err != nil {
+		return nil, fmt.Errorf("failed getting FIPS mode: %w", err)
+	}
+
+	// if the property is not supported, the TPM returns the next one.
+	for _, v := range vals {
+		if p, ok := v.(tpm2.TaggedProperty); ok && p.Tag == tpm2.TPMModes {
+			fips := p.Value&fips1402 != 0
+			return &fips, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// requireVersion20 returns a *NotSupportedError if the TPM reports to be a
+// TPM 1.2, so that operations that are only available on a TPM 2.0 fail with
+// a clear error instead of failing somewhere down the line. The version is
+// detected when the TPM is opened using go-attestation and is kept after it's
+// closed, so it must be called after opening the TPM. If the version is not
+// known yet, like when only go-tpm was used, no error is returned, and the
+// operation is attempted.
+func (t *TPM) requireVersion20(operation string) error {
+	if t.version == Version(attest.TPMVersion12) {
+		return &NotSupportedError{Operation: operation, Version: t.version}
+	}
+	return nil
+}
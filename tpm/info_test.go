@@ -1,8 +1,10 @@
 package tpm
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/smallstep/go-attestation/attest"
 	"github.com/stretchr/testify/require"
@@ -41,6 +43,22 @@ func TestFirmwareVersion_MarshalJSON(t *testing.T) {
 	require.JSONEq(t, `"13.37"`, string(b))
 }
 
+func TestLockoutStatus_MarshalJSON(t *testing.T) {
+	b, err := json.Marshal(&LockoutStatus{
+		InLockout:       true,
+		FailedTries:     3,
+		MaxTries:        3,
+		RecoveryTime:    10 * time.Minute,
+		LockoutRecovery: 24 * time.Hour,
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"inLockout":true,"failedTries":3,"maxTries":3,"recoveryTime":"10m0s","lockoutRecovery":"24h0m0s"}`, string(b))
+
+	b, err = json.Marshal(Info{Version: Version(attest.TPMVersion20)})
+	require.NoError(t, err)
+	require.NotContains(t, string(b), "lockoutStatus")
+}
+
 func TestVersion_MarshalJSON(t *testing.T) {
 	b, err := json.Marshal(Version(attest.TPMVersion12))
 	require.NoError(t, err)
@@ -70,6 +88,7 @@ func Test_GetManufacturerByID(t *testing.T) {
 		{"infineon", 1229346816, Manufacturer{1229346816, "Infineon", "IFX", "49465800"}},
 		{"intel", 1229870147, Manufacturer{1229870147, "Intel", "INTC", "494E5443"}},
 		{"fido", 4294963664, Manufacturer{4294963664, "FIDO Alliance", "FIDO", "FFFFF1D0"}},
+		{"unknown", 0x58595A57, Manufacturer{0x58595A57, "Unknown (0x58595A57)", "XYZW", "58595A57"}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -104,3 +123,81 @@ func TestID_MarshalJSON(t *testing.T) {
 	require.NoError(t, err)
 	require.JSONEq(t, `{"id":"1229346816", "name":"Infineon", "ascii":"IFX", "hex":"49465800"}`, string(b))
 }
+
+// getCapabilityPropertyResponse is a TPM2_GetCapability response with a
+// single TPM property.
+func getCapabilityPropertyResponse(prop, value uint32) []byte {
+	return []byte{
+		0x80, 0x01,
+		0x00, 0x00, 0x00, 0x1b,
+		0x00, 0x00, 0x00, 0x00,
+		0x00,                   // moreData
+		0x00, 0x00, 0x00, 0x06, // TPM_CAP_TPM_PROPERTIES
+		0x00, 0x00, 0x00, 0x01, // count
+		byte(prop >> 24), byte(prop >> 16), byte(prop >> 8), byte(prop),
+		byte(value >> 24), byte(value >> 16), byte(value >> 8), byte(value),
+	}
+}
+
+func TestTPM_fipsMode(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name     string
+		response []byte
+		want     *bool
+		wantErr  bool
+	}{
+		{"ok fips", getCapabilityPropertyResponse(0x12d, 0x01), &yes, false},
+		{"ok fips with other modes", getCapabilityPropertyResponse(0x12d, 0x03), &yes, false},
+		{"ok not fips", getCapabilityPropertyResponse(0x12d, 0x00), &no, false},
+		{"ok not supported", getCapabilityPropertyResponse(0x12e, 0x01), nil, false},
+		{"fail error", []byte{0x80, 0x01, 0x00, 0x00, 0x00, 0x0a, 0x00, 0x00, 0x01, 0x01}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := &recordingCommandChannel{response: tt.response}
+			tpm, err := New(WithCommandChannel(cc))
+			require.NoError(t, err)
+
+			ctx := goTPMCall(context.Background())
+			require.NoError(t, tpm.open(ctx))
+			defer func() { require.NoError(t, tpm.close(ctx)) }()
+
+			got, err := tpm.fipsMode()
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.want, got)
+			require.Len(t, cc.commands, 1)
+		})
+	}
+}
+
+func TestTPM_Info_statusError(t *testing.T) {
+	cc := &recordingCommandChannel{
+		// TPM_RC_FAILURE
+		response: []byte{0x80, 0x01, 0x00, 0x00, 0x00, 0x0a, 0x00, 0x00, 0x01, 0x01},
+	}
+	tpm, err := New(WithCommandChannel(cc))
+	require.NoError(t, err)
+
+	// the static info is cached, so only the status is read
+	tpm.info = &Info{
+		Version:   Version(attest.TPMVersion20),
+		Interface: Interface(attest.TPMInterfaceCommandChannel),
+	}
+
+	info, err := tpm.Info(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, info.LockoutStatus)
+	require.Nil(t, info.FIPSMode)
+	require.Equal(t, Version(attest.TPMVersion20), info.Version)
+	require.Len(t, cc.commands, 2)
+	require.Equal(t, 1, cc.closed)
+
+	// Available doesn't read the status
+	require.NoError(t, tpm.Available())
+	require.Len(t, cc.commands, 2)
+}
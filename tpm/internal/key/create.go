@@ -18,3 +18,13 @@ import "io"
 func Create(rwc io.ReadWriteCloser, keyName string, config CreateConfig) ([]byte, error) {
 	return create(rwc, keyName, config)
 }
+
+// CreateAK creates a new AK with the given auth policy and returns a
+// serialized representation of it, in the format `go-attestation` uses
+// for AKs. The AK is created from the same template `go-attestation`
+// uses, but `go-attestation` can't create AKs with an auth policy. The
+// creation of the AK is not certified, as signing with it requires the
+// policy to be satisfied.
+func CreateAK(rwc io.ReadWriteCloser, keyName string, policy []byte) ([]byte, error) {
+	return createAK(rwc, keyName, policy)
+}
@@ -106,6 +106,9 @@ type CreateConfig struct {
 	// Size is used to specify the bit size of the key or elliptic curve. For
 	// example, '256' is used to specify curve P-256.
 	Size int
+	// AuthPolicy is the digest of the policy that must be satisfied to use
+	// the key. If set, the key can't be used with a password.
+	AuthPolicy []byte
 }
 
 func (c *CreateConfig) Validate() error {
@@ -239,6 +242,20 @@ var (
 			},
 		},
 	}
+	// Default AK template, the same one go-attestation uses to create
+	// RSA AKs.
+	akTemplateRSA = tpm2.Public{
+		Type:       tpm2.AlgRSA,
+		NameAlg:    tpm2.AlgSHA256,
+		Attributes: tpm2.FlagSignerDefault | tpm2.FlagNoDA,
+		RSAParameters: &tpm2.RSAParams{
+			Sign: &tpm2.SigScheme{
+				Alg:  tpm2.AlgRSASSA,
+				Hash: tpm2.AlgSHA256,
+			},
+			KeyBits: 2048,
+		},
+	}
 	// Basic template for an RSA key signing outside-TPM objects. Other
 	// fields are populated depending on the key creation options.
 	rsaKeyTemplate = tpm2.Public{
@@ -9,6 +9,10 @@ import (
 )
 
 func create(_ io.ReadWriteCloser, keyName string, config CreateConfig) ([]byte, error) {
+	if len(config.AuthPolicy) > 0 {
+		return nil, fmt.Errorf("auth policies are not supported by PCP")
+	}
+
 	pcp, err := openPCP()
 	if err != nil {
 		return nil, fmt.Errorf("failed to open PCP: %w", err)
@@ -29,3 +33,7 @@ func create(_ io.ReadWriteCloser, keyName string, config CreateConfig) ([]byte,
 
 	return out.Serialize()
 }
+
+func createAK(_ io.ReadWriteCloser, _ string, _ []byte) ([]byte, error) {
+	return nil, fmt.Errorf("auth policies are not supported by PCP")
+}
@@ -0,0 +1,90 @@
+package key
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// setAuthPolicy sets the policy digest in the template. The key won't be
+// usable with a password, as the userWithAuth attribute is cleared. The
+// digest must be computed using the name algorithm of the template.
+func setAuthPolicy(tmpl *tpm2.Public, policy []byte) error {
+	if len(policy) == 0 {
+		return nil
+	}
+	h, err := tmpl.NameAlg.Hash()
+	if err != nil {
+		return fmt.Errorf("unsupported name algorithm: %w", err)
+	}
+	if len(policy) != h.Size() {
+		return fmt.Errorf("auth policy must be a %s digest of %d bytes, got %d bytes", h, h.Size(), len(policy))
+	}
+	tmpl.AuthPolicy = policy
+	tmpl.Attributes &^= tpm2.FlagUserWithAuth
+	return nil
+}
+
+func deserializeKey(data []byte) (*serializedKey, error) {
+	var sk serializedKey
+	if err := json.Unmarshal(data, &sk); err != nil {
+		return nil, fmt.Errorf("failed unmarshaling key: %w", err)
+	}
+	return &sk, nil
+}
+
+// AuthPolicy returns the policy digest that must be satisfied to use the
+// serialized key in data. It returns nil if the key can be used with a
+// password.
+func AuthPolicy(data []byte) ([]byte, error) {
+	sk, err := deserializeKey(data)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := tpm2.DecodePublic(sk.Public)
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding public area: %w", err)
+	}
+	if pub.Attributes&tpm2.FlagUserWithAuth != 0 {
+		return nil, nil
+	}
+	return pub.AuthPolicy, nil
+}
+
+// Load loads the serialized key in data under the SRK, and returns its
+// handle. The handle must be flushed after use.
+func Load(rwc io.ReadWriteCloser, data []byte) (tpmutil.Handle, error) {
+	sk, err := deserializeKey(data)
+	if err != nil {
+		return 0, err
+	}
+	if sk.Encoding != keyEncodingEncrypted {
+		return 0, fmt.Errorf("unsupported key encoding %s", sk.Encoding)
+	}
+	srk, _, err := getPrimaryKeyHandle(rwc, commonSrkEquivalentHandle)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get SRK handle: %w", err)
+	}
+	handle, _, err := tpm2.Load(rwc, srk, "", sk.Public, sk.Blob)
+	if err != nil {
+		return 0, fmt.Errorf("Load() failed: %w", err)
+	}
+	return handle, nil
+}
+
+// Public returns the public key of the serialized key in data.
+func Public(data []byte) (crypto.PublicKey, error) {
+	sk, err := deserializeKey(data)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := tpm2.DecodePublic(sk.Public)
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding public area: %w", err)
+	}
+	return pub.Key()
+}
@@ -2,8 +2,16 @@ package open
 
 import (
 	"io"
+
+	"go.step.sm/crypto/tpm/internal/socket"
 )
 
+// TPM opens the TPM identified by `deviceName`. If `deviceName` is a
+// `tcp://` or `unix://` address, commands are sent to the socket
+// instead of to a TPM device.
 func TPM(deviceName string) (io.ReadWriteCloser, error) {
+	if socket.HasScheme(deviceName) {
+		return socket.New(deviceName)
+	}
 	return open(deviceName)
 }
@@ -3,6 +3,7 @@ package socket
 import (
 	"errors"
 	"io"
+	"strings"
 )
 
 var (
@@ -10,8 +11,29 @@ var (
 	ErrNotSupported = errors.New("connecting to a TPM using a UNIX socket is not supported on Windows")
 )
 
+const (
+	tcpScheme  = "tcp://"
+	unixScheme = "unix://"
+)
+
+// New returns an io.ReadWriteCloser for the TPM exposed at `path`. The
+// path can be a UNIX socket path, optionally prefixed with `unix://`, or a
+// TCP address in the form `tcp://host:port`, as exposed by swtpm.
 func New(path string) (io.ReadWriteCloser, error) {
-	return newSocket(path)
+	switch {
+	case strings.HasPrefix(path, tcpScheme):
+		return newTCP(strings.TrimPrefix(path, tcpScheme))
+	case strings.HasPrefix(path, unixScheme):
+		return newSocket(strings.TrimPrefix(path, unixScheme))
+	default:
+		return newSocket(path)
+	}
+}
+
+// HasScheme returns whether `path` explicitly refers to a socket using
+// the `tcp://` or `unix://` scheme.
+func HasScheme(path string) bool {
+	return strings.HasPrefix(path, tcpScheme) || strings.HasPrefix(path, unixScheme)
 }
 
 type CommandChannelWithoutMeasurementLog struct {
@@ -0,0 +1,80 @@
+package socket
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// tpmHeaderSize is the size of the header of a TPM response, consisting
+// of the tag, the size of the response and the response code.
+const tpmHeaderSize = 10
+
+// tcpReadWriteCloser sends TPM commands to a TPM listening on a TCP
+// address. Like tpmutil.EmulatorReadWriteCloser, it connects on every
+// Write and disconnects after the response has been read, so that it
+// can be reused when the TPM is opened multiple times. It is not safe
+// for concurrent use.
+type tcpReadWriteCloser struct {
+	address string
+	conn    net.Conn
+}
+
+func newTCP(address string) (io.ReadWriteCloser, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, fmt.Errorf("invalid TPM address %q: %w", address, err)
+	}
+	return &tcpReadWriteCloser{address: address}, nil
+}
+
+// Write connects to the TPM and writes the command.
+func (t *tcpReadWriteCloser) Write(p []byte) (int, error) {
+	if t.conn != nil {
+		return 0, errors.New("must call Write then Read in an alternating sequence")
+	}
+	conn, err := net.Dial("tcp", t.address)
+	if err != nil {
+		return 0, err
+	}
+	t.conn = conn
+	return t.conn.Write(p)
+}
+
+// Read reads the complete TPM response into `p` and disconnects. The
+// response size is read from the response header, because a response
+// can be split into multiple TCP segments.
+func (t *tcpReadWriteCloser) Read(p []byte) (int, error) {
+	if t.conn == nil {
+		return 0, errors.New("must call Write then Read in an alternating sequence")
+	}
+	defer t.Close()
+
+	if len(p) < tpmHeaderSize {
+		return 0, io.ErrShortBuffer
+	}
+	n, err := io.ReadFull(t.conn, p[:tpmHeaderSize])
+	if err != nil {
+		return n, err
+	}
+	size := int(binary.BigEndian.Uint32(p[2:6]))
+	switch {
+	case size < tpmHeaderSize:
+		return n, fmt.Errorf("invalid TPM response size %d", size)
+	case size > len(p):
+		return n, io.ErrShortBuffer
+	}
+	m, err := io.ReadFull(t.conn, p[tpmHeaderSize:size])
+	return n + m, err
+}
+
+// Close closes the connection, if one is open.
+func (t *tcpReadWriteCloser) Close() error {
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn = nil
+	return err
+}
@@ -118,6 +118,12 @@ type CreateKeyConfig struct {
 	// Size is used to specify the bit size of the key or elliptic curve. For
 	// example, '256' is used to specify curve P-256.
 	Size int
+	// AuthPolicy is the digest of the policy that must be satisfied to
+	// use the Key, computed using the name algorithm of the Key, e.g.
+	// SHA-256 for RSA and P-256 keys. A Key with an auth policy can't be
+	// used with the signer returned by GetSigner. See PCRPolicyDigest and
+	// GetPCRPolicySigner.
+	AuthPolicy []byte
 
 	// TODO(hs): move key name to this struct?
 }
@@ -149,6 +155,10 @@ func (t *TPM) CreateKey(ctx context.Context, name string, config CreateKeyConfig
 	}
 	defer closeTPM(ctx, t, &err)
 
+	if err = t.requireVersion20("CreateKey"); err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 	if name, err = processName(name); err != nil {
 		return nil, err
@@ -159,8 +169,9 @@ func (t *TPM) CreateKey(ctx context.Context, name string, config CreateKeyConfig
 	}
 
 	createConfig := internalkey.CreateConfig{
-		Algorithm: config.Algorithm,
-		Size:      config.Size,
+		Algorithm:  config.Algorithm,
+		Size:       config.Size,
+		AuthPolicy: config.AuthPolicy,
 	}
 	if err := t.validate(&createConfig); err != nil {
 		return nil, fmt.Errorf("invalid key creation parameters: %w", err)
@@ -177,14 +188,12 @@ func (t *TPM) CreateKey(ctx context.Context, name string, config CreateKeyConfig
 		tpm:       t,
 	}
 
-	if err := t.store.AddKey(key.toStorage()); err != nil {
+	if err := t.updateStore(func(w storage.Writer) error {
+		return w.AddKey(key.toStorage())
+	}); err != nil {
 		return nil, fmt.Errorf("failed adding key %q to storage: %w", name, err)
 	}
 
-	if err := t.store.Persist(); err != nil {
-		return nil, fmt.Errorf("failed persisting key %q to storage: %w", name, err)
-	}
-
 	return
 }
 
@@ -207,13 +216,17 @@ func (w attestValidationWrapper) Validate() error {
 // AttestKey creates a new Key identified by `name` and attested by the AK
 // identified by `akName`. If no name is  provided, a random 10 character
 // name is generated. If a Key with the same name exists, `ErrExists` is
-// returned.
+// returned. Retired AKs can't be used to attest new Keys.
 func (t *TPM) AttestKey(ctx context.Context, akName, name string, config AttestKeyConfig) (key *Key, err error) {
 	if err = t.open(ctx); err != nil {
 		return nil, fmt.Errorf("failed opening TPM: %w", err)
 	}
 	defer closeTPM(ctx, t, &err)
 
+	if err = t.requireVersion20("AttestKey"); err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 	if name, err = processName(name); err != nil {
 		return nil, err
@@ -227,6 +240,16 @@ func (t *TPM) AttestKey(ctx context.Context, akName, name string, config AttestK
 	if err != nil {
 		return nil, fmt.Errorf("failed getting AK %q: %w", akName, err)
 	}
+	if !ak.RetiredAt.IsZero() {
+		return nil, fmt.Errorf("failed attesting key with AK %q: AK is retired", akName)
+	}
+	if policy, err := internalkey.AuthPolicy(ak.Data); err == nil && len(policy) > 0 {
+		return nil, fmt.Errorf("failed attesting key with AK %q: %w", akName, ErrPolicySessionRequired)
+	}
+
+	if err = t.requireAttestTPM("AttestKey"); err != nil {
+		return nil, err
+	}
 
 	loadedAK, err := t.attestTPM.LoadAK(ak.Data)
 	if err != nil {
@@ -262,14 +285,12 @@ func (t *TPM) AttestKey(ctx context.Context, akName, name string, config AttestK
 		tpm:        t,
 	}
 
-	if err := t.store.AddKey(key.toStorage()); err != nil {
+	if err := t.updateStore(func(w storage.Writer) error {
+		return w.AddKey(key.toStorage())
+	}); err != nil {
 		return nil, fmt.Errorf("failed adding key %q to storage: %w", name, err)
 	}
 
-	if err := t.store.Persist(); err != nil {
-		return nil, fmt.Errorf("failed persisting key %q: %w", name, err)
-	}
-
 	return
 }
 
@@ -352,6 +373,10 @@ func (t *TPM) DeleteKey(ctx context.Context, name string) (err error) {
 		return fmt.Errorf("failed getting key %q: %w", name, err)
 	}
 
+	if err := t.requireAttestTPM("DeleteKey"); err != nil {
+		return err
+	}
+
 	if err := t.attestTPM.DeleteKey(key.Data); err != nil {
 		return fmt.Errorf("failed deleting key %q: %w", name, err)
 	}
@@ -380,6 +405,10 @@ func (k *Key) CertificationParameters(ctx context.Context) (params attest.Certif
 	}
 	defer closeTPM(ctx, k.tpm, &err)
 
+	if err = k.tpm.requireAttestTPM("CertificationParameters"); err != nil {
+		return params, err
+	}
+
 	loadedKey, err := k.tpm.attestTPM.LoadKey(k.data)
 	if err != nil {
 		return attest.CertificationParameters{}, fmt.Errorf("failed loading key %q: %w", k.name, err)
@@ -406,6 +435,10 @@ func (k *Key) Blobs(ctx context.Context) (blobs *Blobs, err error) {
 	}
 	defer closeTPM(ctx, k.tpm, &err)
 
+	if err = k.tpm.requireAttestTPM("Blobs"); err != nil {
+		return nil, err
+	}
+
 	key, err := k.tpm.attestTPM.LoadKey(k.data)
 	if err != nil {
 		return nil, fmt.Errorf("failed loading key: %w", err)
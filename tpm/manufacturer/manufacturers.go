@@ -44,6 +44,21 @@ func GetNameByASCII(ascii string) string {
 	return "unknown"
 }
 
+// fidoID is the FIDO Alliance fake TPM vendor ID, which doesn't
+// conform to the ASCII naming scheme.
+const fidoID ID = 0xFFFFF1D0
+
+// IsKnown returns whether the manufacturer ID is in the list of
+// known TPM manufacturers.
+func IsKnown(id ID) bool {
+	if id == fidoID {
+		return true
+	}
+	ascii, _ := GetEncodings(id)
+	_, ok := manufacturerByASCII[ascii]
+	return ok
+}
+
 func init() {
 	// manufacturerByASCII contains a mapping of TPM manufacturer
 	// ASCII names to full manufacturer names. It is mainly based on the data
@@ -44,6 +44,25 @@ func Test_GetNameByASCII(t *testing.T) {
 	}
 }
 
+func TestIsKnown(t *testing.T) {
+	tests := []struct {
+		name string
+		id   ID
+		want bool
+	}{
+		{"infineon", 1229346816, true},
+		{"intel", 1229870147, true},
+		{"fido", 4294963664, true},
+		{"unknown", 0x58595A57, false},
+		{"zero", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, IsKnown(tt.id))
+		})
+	}
+}
+
 func TestID_MarshalJSON(t *testing.T) {
 	b, err := json.Marshal(ID(12345678))
 	require.NoError(t, err)
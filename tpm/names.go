@@ -3,6 +3,7 @@ package tpm
 import (
 	"crypto/rand"
 	"fmt"
+	"strings"
 )
 
 // processName creates a random 10 character name if the provided
@@ -36,3 +37,23 @@ func prefixAK(name string) string {
 func prefixKey(name string) string {
 	return fmt.Sprintf("app-%s", name)
 }
+
+// retiredAKSeparator separates the name of an AK from its generation
+// in the name used to store a retired AK.
+const retiredAKSeparator = "@"
+
+// retiredAKName returns the name used to store the AK identified by
+// `name` after it has been retired by a rotation. The generation is
+// appended to the name, so that all retired AKs are kept.
+func retiredAKName(name string, generation int) string {
+	return fmt.Sprintf("%s%s%d", name, retiredAKSeparator, generation)
+}
+
+// validateAKName returns an error if `name` can't be used as the name
+// of an AK, because it would collide with the names of retired AKs.
+func validateAKName(name string) error {
+	if strings.Contains(name, retiredAKSeparator) {
+		return fmt.Errorf("invalid AK name %q: name cannot contain %q", name, retiredAKSeparator)
+	}
+	return nil
+}
@@ -24,3 +24,8 @@ func Test_prefixAK(t *testing.T) {
 func Test_prefixKey(t *testing.T) {
 	require.Equal(t, "app-name", prefixKey("name"))
 }
+
+func Test_validateAKName(t *testing.T) {
+	require.NoError(t, validateAKName("name"))
+	require.EqualError(t, validateAKName("name@0"), `invalid AK name "name@0": name cannot contain "@"`)
+}
@@ -0,0 +1,78 @@
+package tpm
+
+import (
+	"context"
+	"time"
+)
+
+// Operation names passed to Observer.OnError.
+const (
+	OperationOpen  = "open"
+	OperationClose = "close"
+	OperationSign  = "sign"
+)
+
+// Observer receives callbacks around operations performed on the TPM
+// device. It can be used to record metrics or tracing information without
+// this package depending on a specific library. Implementations must be
+// safe for concurrent use and should return quickly, as the callbacks are
+// called synchronously.
+type Observer interface {
+	// OnOpen is called after the TPM has been opened successfully.
+	OnOpen(ctx context.Context, duration time.Duration)
+	// OnClose is called after the TPM has been closed successfully.
+	OnClose(ctx context.Context, duration time.Duration)
+	// OnSign is called after a successful signing operation with the TPM
+	// key identified by name.
+	OnSign(ctx context.Context, name string, duration time.Duration)
+	// OnError is called when the operation, one of OperationOpen,
+	// OperationClose or OperationSign, fails.
+	OnError(ctx context.Context, operation string, err error, duration time.Duration)
+}
+
+// WithObserver is used to set an Observer that is notified about
+// operations performed on the TPM device.
+func WithObserver(observer Observer) NewTPMOption {
+	return func(o *options) error {
+		if observer == nil {
+			observer = nopObserver{}
+		}
+		o.observer = observer
+		return nil
+	}
+}
+
+// nopObserver is the default Observer, it ignores all the callbacks.
+type nopObserver struct{}
+
+func (nopObserver) OnOpen(context.Context, time.Duration)                 {}
+func (nopObserver) OnClose(context.Context, time.Duration)                {}
+func (nopObserver) OnSign(context.Context, string, time.Duration)         {}
+func (nopObserver) OnError(context.Context, string, error, time.Duration) {}
+
+// observeOpen reports the result of opening the TPM to the observer.
+func (t *TPM) observeOpen(ctx context.Context, start time.Time, err error) {
+	if err != nil {
+		t.observer.OnError(ctx, OperationOpen, err, time.Since(start))
+		return
+	}
+	t.observer.OnOpen(ctx, time.Since(start))
+}
+
+// observeClose reports the result of closing the TPM to the observer.
+func (t *TPM) observeClose(ctx context.Context, start time.Time, err error) {
+	if err != nil {
+		t.observer.OnError(ctx, OperationClose, err, time.Since(start))
+		return
+	}
+	t.observer.OnClose(ctx, time.Since(start))
+}
+
+// observeSign reports the result of a signing operation to the observer.
+func (t *TPM) observeSign(ctx context.Context, name string, start time.Time, err error) {
+	if err != nil {
+		t.observer.OnError(ctx, OperationSign, err, time.Since(start))
+		return
+	}
+	t.observer.OnSign(ctx, name, time.Since(start))
+}
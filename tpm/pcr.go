@@ -0,0 +1,115 @@
+package tpm
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+)
+
+// ReadPCRs returns the values of all the PCRs in the given banks, keyed by
+// bank and then by PCR index. Banks that are not active in the TPM are
+// skipped, so the result may contain fewer banks than requested. If no banks
+// are given, all the active banks are read.
+func (t *TPM) ReadPCRs(ctx context.Context, banks []crypto.Hash) (pcrs map[crypto.Hash]map[int][]byte, err error) {
+	algs := make([]tpm2.Algorithm, len(banks))
+	for i, h := range banks {
+		if algs[i], err = tpm2.HashToAlgorithm(h); err != nil {
+			return nil, fmt.Errorf("invalid PCR bank %v: %w", h, err)
+		}
+	}
+
+	if err = t.open(goTPMCall(ctx)); err != nil {
+		return nil, fmt.Errorf("failed opening TPM: %w", err)
+	}
+	defer closeTPM(ctx, t, &err)
+
+	if err = t.requireVersion20("ReadPCRs"); err != nil {
+		return nil, err
+	}
+
+	active, err := activePCRBanks(t.rwc)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(banks) == 0 {
+		for alg := range active {
+			algs = append(algs, alg)
+		}
+	}
+
+	pcrs = make(map[crypto.Hash]map[int][]byte, len(algs))
+	for _, alg := range algs {
+		sel, ok := active[alg]
+		if !ok {
+			continue
+		}
+		h, err := alg.Hash()
+		if err != nil {
+			// the bank is active, but the hash is not supported by Go
+			continue
+		}
+		values, err := readPCRBank(t.rwc, sel)
+		if err != nil {
+			return nil, err
+		}
+		pcrs[h] = values
+	}
+
+	return
+}
+
+// activePCRBanks returns the PCR selections of the banks with allocated
+// PCRs, keyed by their hash algorithm.
+func activePCRBanks(rw io.ReadWriter) (map[tpm2.Algorithm]tpm2.PCRSelection, error) {
+	vals, _, err := tpm2.GetCapability(rw, tpm2.CapabilityPCRs, 1, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting PCR banks: %w", err)
+	}
+
+	banks := make(map[tpm2.Algorithm]tpm2.PCRSelection, len(vals))
+	for _, v := range vals {
+		sel, ok := v.(tpm2.PCRSelection)
+		if !ok || len(sel.PCRs) == 0 {
+			continue
+		}
+		banks[sel.Hash] = sel
+	}
+
+	return banks, nil
+}
+
+// readPCRBank reads all the PCRs in the given selection. TPM2_PCR_Read
+// returns at most 8 digests per call, so the PCRs not returned by the TPM are
+// requested again until all of them have been read.
+func readPCRBank(rw io.ReadWriter, sel tpm2.PCRSelection) (map[int][]byte, error) {
+	values := make(map[int][]byte, len(sel.PCRs))
+	remaining := sel.PCRs
+	for len(remaining) > 0 {
+		vals, err := tpm2.ReadPCRs(rw, tpm2.PCRSelection{Hash: sel.Hash, PCRs: remaining})
+		if err != nil {
+			return nil, fmt.Errorf("failed reading PCRs: %w", err)
+		}
+		if len(vals) == 0 {
+			return nil, fmt.Errorf("failed reading PCRs: no values returned for bank %s", sel.Hash)
+		}
+
+		var next []int
+		for _, pcr := range remaining {
+			if v, ok := vals[pcr]; ok {
+				values[pcr] = v
+			} else {
+				next = append(next, pcr)
+			}
+		}
+		if len(next) == len(remaining) {
+			return nil, fmt.Errorf("failed reading PCRs: unexpected values returned for bank %s", sel.Hash)
+		}
+		remaining = next
+	}
+
+	return values, nil
+}
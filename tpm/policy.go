@@ -0,0 +1,223 @@
+package tpm
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	internalkey "go.step.sm/crypto/tpm/internal/key"
+	"go.step.sm/crypto/tpm/storage"
+)
+
+// PCRPolicyDigest returns the digest of a policy that requires the PCRs
+// identified by `pcrs` in the SHA-256 bank to have their current values.
+// The digest can be used as the AuthPolicy when creating a Key that uses
+// SHA-256 as its name algorithm, like RSA and P-256 keys.
+func (t *TPM) PCRPolicyDigest(ctx context.Context, pcrs []int) (digest []byte, err error) {
+	if len(pcrs) == 0 {
+		return nil, errors.New("at least one PCR is required")
+	}
+
+	if err = t.open(goTPMCall(ctx)); err != nil {
+		return nil, fmt.Errorf("failed opening TPM: %w", err)
+	}
+	defer closeTPM(ctx, t, &err)
+
+	if err = t.requireVersion20("PCRPolicyDigest"); err != nil {
+		return nil, err
+	}
+
+	session, err := startPCRPolicySession(t.rwc, tpm2.SessionTrial, pcrs)
+	if err != nil {
+		return nil, err
+	}
+	defer tpm2.FlushContext(t.rwc, session) //nolint:errcheck // trial session is no longer needed
+
+	if digest, err = tpm2.PolicyGetDigest(t.rwc, session); err != nil {
+		return nil, fmt.Errorf("failed getting policy digest: %w", err)
+	}
+
+	return
+}
+
+// GetPCRPolicySigner returns a crypto.Signer for a TPM Key identified by
+// `name`, created with the AuthPolicy returned by PCRPolicyDigest for the
+// same `pcrs`. Every signature is created in a policy session that
+// requires the PCRs to have the values they had when the digest was
+// computed. If the PCR values differ, signing fails.
+func (t *TPM) GetPCRPolicySigner(ctx context.Context, name string, pcrs []int) (csigner crypto.Signer, err error) {
+	if len(pcrs) == 0 {
+		return nil, errors.New("at least one PCR is required")
+	}
+
+	if err = t.open(goTPMCall(ctx)); err != nil {
+		return nil, fmt.Errorf("failed opening TPM: %w", err)
+	}
+	defer closeTPM(ctx, t, &err)
+
+	if err = t.requireVersion20("GetPCRPolicySigner"); err != nil {
+		return nil, err
+	}
+
+	key, err := t.store.GetKey(name)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("failed getting signer for key %q: %w", name, ErrNotFound)
+		}
+		return nil, err
+	}
+
+	policy, err := internalkey.AuthPolicy(key.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading TPM key %q: %w", name, err)
+	}
+	if len(policy) == 0 {
+		return nil, fmt.Errorf("failed getting signer for key %q: key has no auth policy", name)
+	}
+
+	pub, err := internalkey.Public(key.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting TPM public key %q: %w", name, err)
+	}
+
+	csigner = &pcrPolicySigner{
+		tpm:    t,
+		key:    Key{name: name, data: key.Data, attestedBy: key.AttestedBy, createdAt: key.CreatedAt, tpm: t},
+		public: pub,
+		pcrs:   append([]int{}, pcrs...),
+	}
+
+	return
+}
+
+// pcrPolicySigner implements crypto.Signer backed by a TPM key with a
+// PCR auth policy.
+type pcrPolicySigner struct {
+	tpm    *TPM
+	key    Key
+	public crypto.PublicKey
+	pcrs   []int
+}
+
+// Public returns the signers public key.
+func (s *pcrPolicySigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+// Sign implements crypto.Signer. The TPM key is loaded and a new policy
+// session is started on every call to Sign().
+func (s *pcrPolicySigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) (signature []byte, err error) {
+	ctx := context.Background()
+	if err = s.tpm.open(goTPMCall(ctx)); err != nil {
+		return nil, fmt.Errorf("failed opening TPM: %w", err)
+	}
+	defer closeTPM(ctx, s.tpm, &err)
+
+	keyHandle, err := internalkey.Load(s.tpm.rwc, s.key.data)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading TPM key %q: %w", s.key.name, err)
+	}
+	defer tpm2.FlushContext(s.tpm.rwc, keyHandle) //nolint:errcheck // key is no longer needed
+
+	session, err := startPCRPolicySession(s.tpm.rwc, tpm2.SessionPolicy, s.pcrs)
+	if err != nil {
+		return nil, err
+	}
+	defer tpm2.FlushContext(s.tpm.rwc, session) //nolint:errcheck // the session might have been closed already
+
+	scheme, err := sigScheme(s.public, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := tpm2.SignWithSession(s.tpm.rwc, session, keyHandle, "", digest, nil, scheme)
+	if err != nil {
+		return nil, fmt.Errorf("failed signing with TPM key %q: %w", s.key.name, err)
+	}
+
+	return marshalSignature(sig)
+}
+
+// marshalSignature returns the signature in the format expected from a
+// crypto.Signer: ASN.1 for ECDSA and the raw signature for RSA.
+func marshalSignature(sig *tpm2.Signature) ([]byte, error) {
+	switch {
+	case sig.ECC != nil:
+		return asn1.Marshal(struct {
+			R *big.Int
+			S *big.Int
+		}{sig.ECC.R, sig.ECC.S})
+	case sig.RSA != nil:
+		return sig.RSA.Signature, nil
+	default:
+		return nil, fmt.Errorf("unexpected signature algorithm %v", sig.Alg)
+	}
+}
+
+// startPCRPolicySession starts a policy session of type `sessionType` and
+// executes TPM2_PolicyPCR with the current values of the PCRs identified
+// by `pcrs` in the SHA-256 bank. The session must be flushed after use.
+func startPCRPolicySession(rw io.ReadWriter, sessionType tpm2.SessionType, pcrs []int) (tpmutil.Handle, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return 0, fmt.Errorf("failed generating session nonce: %w", err)
+	}
+
+	session, _, err := tpm2.StartAuthSession(rw, tpm2.HandleNull, tpm2.HandleNull, nonce, nil, sessionType, tpm2.AlgNull, tpm2.AlgSHA256)
+	if err != nil {
+		return 0, fmt.Errorf("failed starting policy session: %w", err)
+	}
+
+	if err := tpm2.PolicyPCR(rw, session, nil, tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: pcrs}); err != nil {
+		_ = tpm2.FlushContext(rw, session)
+		return 0, fmt.Errorf("failed executing PolicyPCR: %w", err)
+	}
+
+	return session, nil
+}
+
+// sigScheme returns the TPM signature scheme to sign `digest` with a key
+// with the given public key.
+func sigScheme(pub crypto.PublicKey, digest []byte, opts crypto.SignerOpts) (*tpm2.SigScheme, error) {
+	switch p := pub.(type) {
+	case *ecdsa.PublicKey:
+		scheme := &tpm2.SigScheme{Alg: tpm2.AlgECDSA}
+		switch p.Curve {
+		case elliptic.P256():
+			scheme.Hash = tpm2.AlgSHA256
+		case elliptic.P384():
+			scheme.Hash = tpm2.AlgSHA384
+		case elliptic.P521():
+			scheme.Hash = tpm2.AlgSHA512
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", p.Curve.Params().Name)
+		}
+		return scheme, nil
+	case *rsa.PublicKey:
+		h, err := tpm2.HashToAlgorithm(opts.HashFunc())
+		if err != nil {
+			return nil, fmt.Errorf("failed getting hash algorithm: %w", err)
+		}
+		scheme := &tpm2.SigScheme{Alg: tpm2.AlgRSASSA, Hash: h}
+		if pss, ok := opts.(*rsa.PSSOptions); ok {
+			if pss.SaltLength != rsa.PSSSaltLengthAuto && pss.SaltLength != rsa.PSSSaltLengthEqualsHash && pss.SaltLength != len(digest) {
+				return nil, fmt.Errorf("invalid PSS salt length %d, expected rsa.PSSSaltLengthAuto, rsa.PSSSaltLengthEqualsHash or %d", pss.SaltLength, len(digest))
+			}
+			scheme.Alg = tpm2.AlgRSAPSS
+		}
+		return scheme, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", pub)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"sync"
 
 	"github.com/google/go-tpm/legacy/tpm2"
 )
@@ -93,3 +94,84 @@ func (g *generator) Read(p []byte) (n int, err error) {
 }
 
 var _ io.Reader = (*generator)(nil)
+
+// randReaderBufferSize is the number of random bytes requested from the TPM
+// every time the buffer of the reader returned by RandReader is empty.
+const randReaderBufferSize = 512
+
+// RandReader returns an io.Reader that reads random bytes generated by the
+// TPM. Unlike the reader returned by RandomReader, which opens the TPM on
+// every Read and fails all the calls after the first error, the random bytes
+// are buffered, so that the TPM is only opened when the buffer has to be
+// refilled, and an error only fails the Read that got it. The context is used
+// for all TPM operations, and the reader is safe for concurrent use.
+//
+// Since Go 1.26, most functions generating keys in the standard library, like
+// ecdsa.GenerateKey or rsa.GenerateKey, ignore the io.Reader passed to them
+// and use the system random source, unless GODEBUG=cryptocustomrand=1 is set.
+// The reader can still be used where random bytes are read directly.
+func (t *TPM) RandReader(ctx context.Context) io.Reader {
+	return &bufferedGenerator{
+		t:   t,
+		ctx: ctx,
+	}
+}
+
+type bufferedGenerator struct {
+	t   *TPM
+	ctx context.Context
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (g *bufferedGenerator) Read(p []byte) (n int, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for n < len(p) {
+		if len(g.buf) == 0 {
+			if err = g.fill(); err != nil {
+				return n, err
+			}
+		}
+		m := copy(p[n:], g.buf)
+		for i := range g.buf[:m] {
+			g.buf[i] = 0 // don't keep consumed random bytes around
+		}
+		g.buf = g.buf[m:]
+		n += m
+	}
+
+	return n, nil
+}
+
+// fill requests randReaderBufferSize random bytes from the TPM. The TPM can
+// return less bytes than requested in a single command, so it loops until the
+// buffer is full. If some random bytes were generated before an error, they
+// are kept in the buffer, and the error is ignored.
+func (g *bufferedGenerator) fill() (err error) {
+	if err = g.t.open(goTPMCall(g.ctx)); err != nil {
+		return fmt.Errorf("failed opening TPM: %w", err)
+	}
+	defer closeTPM(g.ctx, g.t, &err)
+
+	buf := make([]byte, 0, randReaderBufferSize)
+	for len(buf) < randReaderBufferSize {
+		r, rerr := tpm2.GetRandom(g.t.rwc, uint16(randReaderBufferSize-len(buf)))
+		if rerr == nil && len(r) == 0 {
+			rerr = errors.New("no data returned")
+		}
+		if rerr != nil {
+			if len(buf) == 0 {
+				return fmt.Errorf("failed generating random data: %w", rerr)
+			}
+			break
+		}
+		buf = append(buf, r...)
+	}
+
+	g.buf = buf
+	return nil
+}
+
+var _ io.Reader = (*bufferedGenerator)(nil)
@@ -0,0 +1,160 @@
+package tpm
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/smallstep/go-attestation/attest"
+
+	closer "go.step.sm/crypto/tpm/internal/close"
+)
+
+// retryOptions configures retrying transient TPM failures.
+type retryOptions struct {
+	attempts int
+	backoff  time.Duration
+}
+
+// enabled reports whether failures should be retried.
+func (r retryOptions) enabled() bool {
+	return r.attempts > 1
+}
+
+// maxBackoff is the maximum time to wait before a retry.
+const maxBackoff = time.Minute
+
+// delay returns the time to wait before the given retry attempt, starting at
+// 1. The backoff doubles after each attempt, up to maxBackoff.
+func (r retryOptions) delay(attempt int) time.Duration {
+	d := r.backoff
+	for i := 1; i < attempt && d > 0 && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
+
+// wait waits before the given retry attempt, starting at 1. It returns false
+// if the context is done before or while waiting.
+func (r retryOptions) wait(ctx context.Context, attempt int) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	d := r.delay(attempt)
+	if d == 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// TPM response codes that indicate that the command can be sent again.
+// TPM_RC_RETRY is already retried by go-tpm, but with a fixed backoff.
+const (
+	rcYielded = 0x908 // TPM_RC_YIELDED
+	rcTesting = 0x90A // TPM_RC_TESTING
+	rcRetry   = 0x922 // TPM_RC_RETRY
+)
+
+// isRetryableResponse reports whether the TPM response has a response code
+// that indicates that the command can be sent again. Other response codes,
+// including authorization failures, are never retried.
+func isRetryableResponse(resp []byte) bool {
+	if len(resp) < 10 {
+		return false
+	}
+	switch binary.BigEndian.Uint32(resp[6:10]) {
+	case rcYielded, rcTesting, rcRetry:
+		return true
+	default:
+		return false
+	}
+}
+
+// isTransientError reports whether the error returned by the TPM device or
+// command channel is transient, and the operation can be retried.
+func isTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, syscall.EBUSY) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var terr interface{ Temporary() bool }
+	if errors.As(err, &terr) && terr.Temporary() {
+		return true
+	}
+	var toerr interface{ Timeout() bool }
+	return errors.As(err, &toerr) && toerr.Timeout()
+}
+
+// retryChannel wraps a TPM transport and sends a command again when writing
+// it fails with a transient error, or when the TPM responds with a response
+// code that indicates that the command can be retried. Errors reading the
+// response are returned as is, as the TPM might have executed the command.
+// It stops retrying when ctx, the context of the current operation, is done.
+type retryChannel struct {
+	ctx     context.Context
+	rwc     io.ReadWriteCloser
+	opts    retryOptions
+	command []byte
+}
+
+func newRetryChannel(ctx context.Context, rwc io.ReadWriteCloser, opts retryOptions) *retryChannel {
+	return &retryChannel{ctx: ctx, rwc: rwc, opts: opts}
+}
+
+// Write sends the command to the TPM, retrying transient failures.
+func (r *retryChannel) Write(b []byte) (int, error) {
+	r.command = append(r.command[:0], b...)
+	n, err := r.rwc.Write(b)
+	for attempt := 1; err != nil && attempt < r.opts.attempts; attempt++ {
+		if !isTransientError(err) || !r.opts.wait(r.ctx, attempt) {
+			break
+		}
+		n, err = r.rwc.Write(b)
+	}
+	return n, err
+}
+
+// Read reads the response of the last command, sending the command again if
+// the response can be retried.
+func (r *retryChannel) Read(b []byte) (int, error) {
+	n, err := r.rwc.Read(b)
+	for attempt := 1; err == nil && attempt < r.opts.attempts; attempt++ {
+		if !isRetryableResponse(b[:n]) || !r.opts.wait(r.ctx, attempt) {
+			break
+		}
+		if _, werr := r.rwc.Write(r.command); werr != nil {
+			return 0, werr
+		}
+		n, err = r.rwc.Read(b)
+	}
+	return n, err
+}
+
+// Close closes the underlying transport.
+func (r *retryChannel) Close() error {
+	return closer.RWC(r.rwc)
+}
+
+// MeasurementLog implements attest.CommandChannelTPM20.
+func (r *retryChannel) MeasurementLog() ([]byte, error) {
+	if cc, ok := r.rwc.(attest.CommandChannelTPM20); ok {
+		return cc.MeasurementLog()
+	}
+	return nil, errors.New("measurement log not available")
+}
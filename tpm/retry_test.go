@@ -0,0 +1,198 @@
+package tpm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/smallstep/go-attestation/attest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getRandomResponse is a TPM2_GetRandom response with the given response
+// code and the random bytes 1, 2, 3, 4.
+func getRandomResponse(rc uint32) []byte {
+	if rc != 0 {
+		return []byte{
+			0x80, 0x01,
+			0x00, 0x00, 0x00, 0x0a,
+			byte(rc >> 24), byte(rc >> 16), byte(rc >> 8), byte(rc),
+		}
+	}
+	return []byte{
+		0x80, 0x01,
+		0x00, 0x00, 0x00, 0x10,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x04, 0x01, 0x02, 0x03, 0x04,
+	}
+}
+
+// flakyResult is the result of reading the response of a command.
+type flakyResult struct {
+	response []byte
+	err      error
+}
+
+// flakyCommandChannel is a CommandChannel that records the commands written
+// to it, and returns the given results in order. Once all the results have
+// been returned, it keeps returning the last one. Writes fail with the given
+// write errors in order.
+type flakyCommandChannel struct {
+	results   []flakyResult
+	writeErrs []error
+	commands  int
+	reads     int
+}
+
+func (c *flakyCommandChannel) Read(b []byte) (int, error) {
+	r := c.results[len(c.results)-1]
+	if c.reads < len(c.results) {
+		r = c.results[c.reads]
+	}
+	c.reads++
+	if r.err != nil {
+		return 0, r.err
+	}
+	return copy(b, r.response), nil
+}
+
+func (c *flakyCommandChannel) Write(b []byte) (int, error) {
+	c.commands++
+	if c.commands <= len(c.writeErrs) && c.writeErrs[c.commands-1] != nil {
+		return 0, c.writeErrs[c.commands-1]
+	}
+	return len(b), nil
+}
+
+func (c *flakyCommandChannel) Close() error {
+	return nil
+}
+
+func (c *flakyCommandChannel) MeasurementLog() ([]byte, error) {
+	return nil, nil
+}
+
+func TestWithRetry(t *testing.T) {
+	busy := &osError{syscall.EBUSY}
+	yielded := flakyResult{response: getRandomResponse(rcYielded)}
+	ok := flakyResult{response: getRandomResponse(0)}
+
+	tests := []struct {
+		name         string
+		results      []flakyResult
+		writeErrs    []error
+		opts         []NewTPMOption
+		cancel       bool
+		wantCommands int
+		wantErr      bool
+	}{
+		{"ok write busy twice", []flakyResult{ok}, []error{busy, busy}, []NewTPMOption{WithRetry(3, time.Millisecond)}, false, 3, false},
+		{"ok yielded twice", []flakyResult{yielded, yielded, ok}, nil, []NewTPMOption{WithRetry(3, time.Millisecond)}, false, 3, false},
+		{"ok yielded and testing", []flakyResult{yielded, {response: getRandomResponse(rcTesting)}, ok}, nil, []NewTPMOption{WithRetry(3, 0)}, false, 3, false},
+		{"ok no failures", []flakyResult{ok}, nil, []NewTPMOption{WithRetry(3, time.Millisecond)}, false, 1, false},
+		{"fail attempts", []flakyResult{yielded, yielded, ok}, nil, []NewTPMOption{WithRetry(2, time.Millisecond)}, false, 2, true},
+		{"fail write attempts", []flakyResult{ok}, []error{busy, busy}, []NewTPMOption{WithRetry(2, time.Millisecond)}, false, 2, true},
+		{"fail without retry", []flakyResult{yielded, ok}, nil, nil, false, 1, true},
+		{"fail read busy", []flakyResult{{err: busy}, ok}, nil, []NewTPMOption{WithRetry(3, time.Millisecond)}, false, 1, true},
+		{"fail auth", []flakyResult{{response: getRandomResponse(0x98e)}, ok}, nil, []NewTPMOption{WithRetry(3, time.Millisecond)}, false, 1, true},
+		{"fail other error", []flakyResult{{err: io.ErrUnexpectedEOF}, ok}, nil, []NewTPMOption{WithRetry(3, time.Millisecond)}, false, 1, true},
+		{"fail canceled", []flakyResult{yielded, ok}, nil, []NewTPMOption{WithRetry(3, time.Millisecond)}, true, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := &flakyCommandChannel{results: tt.results, writeErrs: tt.writeErrs}
+			tpm, err := New(append([]NewTPMOption{WithCommandChannel(cc)}, tt.opts...)...)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+
+			got, err := tpm.GenerateRandom(ctx, 4)
+			assert.Equal(t, tt.wantCommands, cc.commands)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []byte{1, 2, 3, 4}, got)
+		})
+	}
+}
+
+func TestWithRetry_open(t *testing.T) {
+	var calls int
+	t.Cleanup(func() { openAttestTPM = attest.OpenTPM })
+	openAttestTPM = func(config *attest.OpenConfig) (*attest.TPM, error) {
+		calls++
+		if calls <= 2 {
+			return nil, &osError{syscall.EBUSY}
+		}
+		return attest.OpenTPM(config)
+	}
+
+	tpm, err := New(WithSimulator(&closeSimulator{}), WithRetry(3, time.Millisecond))
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, tpm.open(ctx))
+	assert.Equal(t, 3, calls)
+	assert.NotNil(t, tpm.attestTPM)
+	require.NoError(t, tpm.close(ctx))
+
+	// The cached attest.TPM of the simulator retries commands using the
+	// context of the current operation.
+	ctx2, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, tpm.open(ctx2))
+	assert.Equal(t, 3, calls)
+	require.NotNil(t, tpm.attestRetry)
+	assert.Equal(t, ctx2, tpm.attestRetry.ctx)
+	require.NoError(t, tpm.close(ctx2))
+
+	// Non-transient errors are not retried.
+	calls = 0
+	openAttestTPM = func(config *attest.OpenConfig) (*attest.TPM, error) {
+		calls++
+		return nil, errors.New("permission denied")
+	}
+	tpm, err = New(WithSimulator(&closeSimulator{}), WithRetry(3, time.Millisecond))
+	require.NoError(t, err)
+	assert.Error(t, tpm.open(ctx))
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryOptions_delay(t *testing.T) {
+	r := retryOptions{attempts: 100, backoff: time.Second}
+	assert.Equal(t, time.Second, r.delay(1))
+	assert.Equal(t, 2*time.Second, r.delay(2))
+	assert.Equal(t, 32*time.Second, r.delay(6))
+	assert.Equal(t, maxBackoff, r.delay(7))
+	assert.Equal(t, maxBackoff, r.delay(99))
+
+	r = retryOptions{attempts: 100, backoff: time.Duration(1<<62 + 1)}
+	assert.Equal(t, maxBackoff, r.delay(99))
+
+	r = retryOptions{attempts: 100}
+	assert.Equal(t, time.Duration(0), r.delay(99))
+}
+
+func TestWithRetry_options(t *testing.T) {
+	_, err := New(WithRetry(0, time.Second))
+	assert.EqualError(t, err, "invalid number of attempts 0")
+	_, err = New(WithRetry(3, -time.Second))
+	assert.EqualError(t, err, "invalid backoff -1s")
+}
+
+// osError wraps a syscall error, like the errors returned by the TPM device.
+type osError struct {
+	err error
+}
+
+func (e *osError) Error() string { return "tpm device: " + e.err.Error() }
+func (e *osError) Unwrap() error { return e.err }
@@ -6,7 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"time"
 
+	"github.com/google/go-tpm/legacy/tpm2"
+
+	"go.step.sm/crypto/kms/uri"
+	internalkey "go.step.sm/crypto/tpm/internal/key"
 	"go.step.sm/crypto/tpm/storage"
 	"go.step.sm/crypto/tpm/tss2"
 )
@@ -28,11 +33,24 @@ func (s *signer) Public() crypto.PublicKey {
 // will reload the TPM key to be used.
 func (s *signer) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) (signature []byte, err error) {
 	ctx := context.Background()
+	start := time.Now()
+	defer func() { s.tpm.observeSign(ctx, s.key.name, start, err) }()
+
+	// keys with an auth policy can't be used with a password session
+	if policy, err := internalkey.AuthPolicy(s.key.data); err == nil && len(policy) > 0 {
+		return nil, fmt.Errorf("failed signing with TPM key %q: %w", s.key.name, ErrPolicySessionRequired)
+	}
+
 	if err = s.tpm.open(ctx); err != nil {
 		return nil, fmt.Errorf("failed opening TPM: %w", err)
 	}
 	defer closeTPM(ctx, s.tpm, &err)
 
+	// sign using go-tpm if go-attestation is not available
+	if s.tpm.attestTPM == nil && s.tpm.attestErr != nil {
+		return s.signWithGoTPM(digest, opts)
+	}
+
 	loadedKey, err := s.tpm.attestTPM.LoadKey(s.key.data)
 	if err != nil {
 		return nil, err
@@ -53,6 +71,28 @@ func (s *signer) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) (si
 	return signer.Sign(rand, digest, opts)
 }
 
+// signWithGoTPM signs the digest loading the key using go-tpm. The TPM
+// must be open.
+func (s *signer) signWithGoTPM(digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	keyHandle, err := internalkey.Load(s.tpm.rwc, s.key.data)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading TPM key %q: %w", s.key.name, err)
+	}
+	defer tpm2.FlushContext(s.tpm.rwc, keyHandle) //nolint:errcheck // key is no longer needed
+
+	scheme, err := sigScheme(s.public, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := tpm2.Sign(s.tpm.rwc, keyHandle, "", digest, nil, scheme)
+	if err != nil {
+		return nil, fmt.Errorf("failed signing with TPM key %q: %w", s.key.name, err)
+	}
+
+	return marshalSignature(sig)
+}
+
 // GetSigner returns a crypto.Signer for a TPM Key identified by `name`.
 func (t *TPM) GetSigner(ctx context.Context, name string) (csigner crypto.Signer, err error) {
 	if err = t.open(ctx); err != nil {
@@ -60,6 +100,10 @@ func (t *TPM) GetSigner(ctx context.Context, name string) (csigner crypto.Signer
 	}
 	defer closeTPM(ctx, t, &err)
 
+	if err = t.requireVersion20("GetSigner"); err != nil {
+		return nil, err
+	}
+
 	key, err := t.store.GetKey(name)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
@@ -68,6 +112,21 @@ func (t *TPM) GetSigner(ctx context.Context, name string) (csigner crypto.Signer
 		return nil, err
 	}
 
+	// go-attestation is not available, the key will be loaded using
+	// go-tpm when signing.
+	if t.attestTPM == nil && t.attestErr != nil {
+		var pub crypto.PublicKey
+		if pub, err = internalkey.Public(key.Data); err != nil {
+			return nil, fmt.Errorf("failed getting TPM public key %q: %w", name, err)
+		}
+		csigner = &signer{
+			tpm:    t,
+			key:    Key{name: name, data: key.Data, attestedBy: key.AttestedBy, createdAt: key.CreatedAt, tpm: t},
+			public: pub,
+		}
+		return
+	}
+
 	loadedKey, err := t.attestTPM.LoadKey(key.Data)
 	if err != nil {
 		return nil, err
@@ -92,6 +151,46 @@ func (t *TPM) GetSigner(ctx context.Context, name string) (csigner crypto.Signer
 	return
 }
 
+// signerURIScheme is the scheme of the URIs accepted by CreateSigner; it's the
+// same scheme used by the TPM KMS.
+const signerURIScheme = "tpmkms"
+
+// CreateSigner returns a crypto.Signer for the TPM Key identified by the
+// given URI, using the same format as the TPM KMS, e.g. "tpmkms:name=my-key".
+// The name can also be given as the only value of the URI, as in
+// "tpmkms:my-key". Signing with an AK is not supported. If the key doesn't
+// exist, a *NotFoundError is returned.
+func (t *TPM) CreateSigner(ctx context.Context, rawuri string) (crypto.Signer, error) {
+	u, err := uri.ParseWithScheme(signerURIScheme, rawuri)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing %q: %w", rawuri, err)
+	}
+	if u.GetBool("ak") {
+		return nil, fmt.Errorf("failed creating signer for %q: signing with an AK is %w", rawuri, ErrNotSupported)
+	}
+
+	name := u.Get("name")
+	if name == "" && len(u.Values) == 1 {
+		for k, v := range u.Values {
+			if len(v) == 1 && v[0] == "" {
+				name = k
+			}
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("failed parsing %q: name is required", rawuri)
+	}
+
+	csigner, err := t.GetSigner(ctx, name)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, &NotFoundError{Name: name}
+		}
+		return nil, err
+	}
+	return csigner, nil
+}
+
 // tss2Signer is a wrapper on top of [*tss2.Signer] that opens and closes the
 // tpm on each sign call.
 type tss2Signer struct {
@@ -101,6 +200,9 @@ type tss2Signer struct {
 
 func (s *tss2Signer) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) (signature []byte, err error) {
 	ctx := context.Background()
+	start := time.Now()
+	defer func() { s.tpm.observeSign(ctx, "", start, err) }()
+
 	if err = s.tpm.open(goTPMCall(ctx)); err != nil {
 		return nil, fmt.Errorf("failed opening TPM: %w", err)
 	}
@@ -15,6 +15,10 @@ func New() (Simulator, error) {
 	return &NoSimulator{}, errors.New("no simulator available")
 }
 
+func NewPersistent(string) (Simulator, error) {
+	return &NoSimulator{}, errors.New("no simulator available")
+}
+
 func (s *NoSimulator) Open() error {
 	return errors.New("cannot open: no simulator available")
 }
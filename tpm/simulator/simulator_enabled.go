@@ -5,10 +5,15 @@ package simulator
 
 import (
 	"bytes"
+	"crypto/rand"
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"os"
+	"strings"
 
 	gotpm "github.com/google/go-tpm-tools/simulator"
 )
@@ -49,6 +54,35 @@ func New(opts ...NewSimulatorOption) (Simulator, error) {
 	return ws, nil
 }
 
+// NewPersistent returns a simulator that keeps its primary seeds stable
+// across processes by persisting the seed to the file at path. If the file
+// doesn't exist, a random seed is generated and written to it; otherwise
+// the seed is read from it. Keys derived from the primary seeds, like the
+// EK and the SRK, are the same every time the simulator is opened, so keys
+// and AKs kept in a persistent store can be loaded again. The simulator
+// NVRAM itself is not persisted, so NV indices and persistent handles
+// have to be recreated.
+//
+// The seed is not secret, so the persistent simulator must only be used
+// for testing.
+func NewPersistent(path string, opts ...NewSimulatorOption) (Simulator, error) {
+	seed, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("failed generating TPM simulator seed: %w", err)
+		}
+		seed = []byte(hex.EncodeToString(b))
+		if err := os.WriteFile(path, seed, 0600); err != nil {
+			return nil, fmt.Errorf("failed writing TPM simulator seed: %w", err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("failed reading TPM simulator seed: %w", err)
+	}
+	return New(append([]NewSimulatorOption{WithSeed(strings.TrimSpace(string(seed)))}, opts...)...)
+}
+
 func (s *WrappingSimulator) Open() error {
 	var sim *gotpm.Simulator
 	var err error
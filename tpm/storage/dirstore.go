@@ -3,6 +3,7 @@ package storage
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -17,6 +18,7 @@ import (
 type Dirstore struct {
 	store     *diskv.Diskv
 	directory string
+	migrator  migrator
 }
 
 const tpmExtension = ".tpmobj"
@@ -229,8 +231,75 @@ func (s *Dirstore) Persist() error {
 }
 
 func (s *Dirstore) Load() error {
-	// reads are performed directly
+	// reads are performed directly; objects in an older format are only
+	// rewritten the first time the store is loaded.
+	return s.migrator.migrate(s)
+}
+
+// Begin starts a new Transaction. Objects are stored in separate files,
+// so the operations are applied one by one when the transaction is
+// committed. If one of them fails, the objects modified by the previous
+// operations are restored to their original state.
+func (s *Dirstore) Begin() (Transaction, error) {
+	return newTransaction(s.commit), nil
+}
+
+func (s *Dirstore) commit(ops []txOp) (err error) {
+	type backup struct {
+		key    string
+		data   []byte
+		exists bool
+	}
+
+	var journal []backup
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := len(journal) - 1; i >= 0; i-- {
+			b := journal[i]
+			var rerr error
+			switch {
+			case b.exists:
+				rerr = s.store.WriteStream(b.key, bytes.NewReader(b.data), true)
+			case s.store.Has(b.key):
+				rerr = s.store.Erase(b.key)
+			}
+			if rerr != nil {
+				err = errors.Join(err, fmt.Errorf("failed restoring %q: %w", b.key, rerr))
+			}
+		}
+	}()
+
+	for _, op := range ops {
+		b := backup{key: op.key}
+		if s.store.Has(op.key) {
+			if b.data, err = s.store.Read(op.key); err != nil {
+				return fmt.Errorf("failed reading %q from store: %w", op.key, err)
+			}
+			b.exists = true
+		}
+		journal = append(journal, b)
+		if err = op.apply(s); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-var _ TPMStore = (*Dirstore)(nil)
+func (s *Dirstore) rawObjects() (map[string][]byte, error) {
+	result := make(map[string][]byte)
+	for _, prefix := range []string{akPrefix, keyPrefix} {
+		for k := range s.store.KeysPrefix(prefix, nil) {
+			data, err := s.store.Read(k)
+			if err != nil {
+				return nil, fmt.Errorf("failed reading %q from store: %w", k, err)
+			}
+			result[k] = data
+		}
+	}
+	return result, nil
+}
+
+var _ TransactionalStore = (*Dirstore)(nil)
@@ -9,3 +9,7 @@ var ErrNotFound = errors.New("not found")
 
 // ErrExists is returned when a Key or AK already exists in storage
 var ErrExists = errors.New("already exists")
+
+// ErrTransactionDone is returned when a Transaction is used after it
+// has been committed or rolled back
+var ErrTransactionDone = errors.New("transaction has already been committed or rolled back")
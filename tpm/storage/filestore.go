@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -16,6 +18,7 @@ import (
 type Filestore struct {
 	store    *jsonstore.JSONStore
 	filepath string
+	migrator migrator
 }
 
 // NewFilestore creates a new instance of a Filestore
@@ -199,7 +202,64 @@ func (s *Filestore) Load() error {
 		store = new(jsonstore.JSONStore)
 	}
 	s.store = store
+	return s.migrator.migrate(s)
+}
+
+// Begin starts a new Transaction. The operations in the transaction are
+// applied to a copy of the in-memory storage, and the result is written
+// to the file at once when it's committed.
+func (s *Filestore) Begin() (Transaction, error) {
+	return newTransaction(s.commit), nil
+}
+
+func (s *Filestore) commit(ops []txOp) error {
+	// The store is locked until the staged data replaces it, so that
+	// concurrent commits are not lost and readers don't see a partial
+	// update.
+	s.store.Lock()
+	defer s.store.Unlock()
+
+	data := make(map[string]json.RawMessage, len(s.store.Data))
+	for k, v := range s.store.Data {
+		data[k] = v
+	}
+
+	staged := &Filestore{
+		store:    &jsonstore.JSONStore{Data: data},
+		filepath: s.filepath,
+	}
+	for _, op := range ops {
+		if err := op.apply(staged); err != nil {
+			return err
+		}
+	}
+
+	// Write to a temporary file in the same directory and rename it, so
+	// that the file is either fully updated or not modified at all. The
+	// name keeps the original suffix, as jsonstore uses it to decide if
+	// the file is compressed.
+	tmp := filepath.Join(filepath.Dir(s.filepath), ".tmp-"+filepath.Base(s.filepath))
+	if err := jsonstore.Save(staged.store, tmp); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed writing store: %w", err)
+	}
+	if err := os.Rename(tmp, s.filepath); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed writing store: %w", err)
+	}
+
+	s.store.Data = staged.store.Data
 	return nil
 }
 
-var _ TPMStore = (*Filestore)(nil)
+func (s *Filestore) rawObjects() (map[string][]byte, error) {
+	result := make(map[string][]byte)
+	for _, k := range s.store.Keys() {
+		if strings.HasPrefix(k, akPrefix) || strings.HasPrefix(k, keyPrefix) {
+			result[k] = s.store.Data[k]
+		}
+	}
+	return result, nil
+}
+
+var _ TransactionalStore = (*Filestore)(nil)
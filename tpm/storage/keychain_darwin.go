@@ -0,0 +1,79 @@
+//go:build darwin
+// +build darwin
+
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// errSecItemNotFound is the exit code of the security command when an item
+// does not exist.
+const errSecItemNotFound = 44
+
+// darwinKeyring stores the items as generic passwords in the default Keychain
+// using the security command. The data is base64 encoded.
+type darwinKeyring struct {
+	service string
+}
+
+func newKeyring(service string) (keyring, error) {
+	if _, err := exec.LookPath("security"); err != nil {
+		return nil, fmt.Errorf("%w: security command not found", ErrKeychainNotSupported)
+	}
+	return &darwinKeyring{service: service}, nil
+}
+
+func (k *darwinKeyring) get(account string) ([]byte, error) {
+	out, err := k.run(nil, "find-generic-password", "-s", k.service, "-a", account, "-w")
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+}
+
+func (k *darwinKeyring) set(account string, data []byte) error {
+	// Use the interactive mode, so the password is not in the arguments
+	// of the process.
+	cmd := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		quote(k.service), quote(account), base64.StdEncoding.EncodeToString(data))
+	_, err := k.run(strings.NewReader(cmd), "-i")
+	return err
+}
+
+func (k *darwinKeyring) delete(account string) error {
+	_, err := k.run(nil, "delete-generic-password", "-s", k.service, "-a", account)
+	return err
+}
+
+func (k *darwinKeyring) run(stdin *strings.Reader, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("security", args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == errSecItemNotFound {
+			return nil, errKeyringNotFound
+		}
+		return nil, fmt.Errorf("failed running security %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	// In interactive mode errors are only written to stderr.
+	if stdin != nil && stderr.Len() > 0 {
+		return nil, fmt.Errorf("failed running security: %s", strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// quote quotes a string to be used as an argument in the interactive mode of
+// the security command.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
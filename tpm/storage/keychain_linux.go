@@ -0,0 +1,70 @@
+//go:build linux
+// +build linux
+
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// linuxKeyring stores the items in the Secret Service, e.g. GNOME Keyring or
+// KWallet, using the secret-tool command. The data is base64 encoded.
+type linuxKeyring struct {
+	service string
+}
+
+func newKeyring(service string) (keyring, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil, fmt.Errorf("%w: secret-tool command not found", ErrKeychainNotSupported)
+	}
+	return &linuxKeyring{service: service}, nil
+}
+
+func (k *linuxKeyring) get(account string) ([]byte, error) {
+	out, err := k.run(nil, "lookup", "service", k.service, "account", account)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+}
+
+func (k *linuxKeyring) set(account string, data []byte) error {
+	// The secret is read from the standard input.
+	label := fmt.Sprintf("%s %s", k.service, account)
+	stdin := strings.NewReader(base64.StdEncoding.EncodeToString(data))
+	_, err := k.run(stdin, "store", "--label", label, "service", k.service, "account", account)
+	return err
+}
+
+func (k *linuxKeyring) delete(account string) error {
+	// secret-tool clear does not fail if the item does not exist.
+	if _, err := k.get(account); err != nil {
+		return err
+	}
+	_, err := k.run(nil, "clear", "service", k.service, "account", account)
+	return err
+}
+
+func (k *linuxKeyring) run(stdin *strings.Reader, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		// secret-tool lookup exits with 1 and no output if the item does
+		// not exist.
+		var exitErr *exec.ExitError
+		if args[0] == "lookup" && errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && stdout.Len() == 0 && stderr.Len() == 0 {
+			return nil, errKeyringNotFound
+		}
+		return nil, fmt.Errorf("failed running secret-tool %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
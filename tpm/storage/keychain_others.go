@@ -0,0 +1,8 @@
+//go:build !darwin && !linux && !windows
+// +build !darwin,!linux,!windows
+
+package storage
+
+func newKeyring(string) (keyring, error) {
+	return nil, ErrKeychainNotSupported
+}
@@ -0,0 +1,15 @@
+//go:build !darwin && !linux && !windows
+// +build !darwin,!linux,!windows
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKeychainstore_notSupported(t *testing.T) {
+	_, err := NewKeychainstore("step-tpm-test")
+	assert.ErrorIs(t, err, ErrKeychainNotSupported)
+}
@@ -0,0 +1,49 @@
+//go:build keychain && (darwin || linux || windows)
+// +build keychain
+// +build darwin linux windows
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestKeychainstore_platform uses the credential store of the operating
+// system. It requires an unlocked keychain, and on Linux, secret-tool and a
+// Secret Service provider.
+func TestKeychainstore_platform(t *testing.T) {
+	t0 := time.Time{} // we're hit by https://github.com/stretchr/testify/issues/950
+	s, err := NewKeychainstore("step-tpm-test-" + t.Name())
+	require.NoError(t, err)
+	require.NoError(t, s.Load())
+	t.Cleanup(func() {
+		for _, name := range s.ListKeyNames() {
+			assert.NoError(t, s.DeleteKey(name))
+		}
+		for _, name := range s.ListAKNames() {
+			assert.NoError(t, s.DeleteAK(name))
+		}
+		assert.NoError(t, s.Persist())
+		assert.NoError(t, s.erase(keychainIndex, 0))
+	})
+
+	ak := &AK{Name: "ak", Data: []byte{1, 2, 3, 4}, CreatedAt: t0}
+	key := &Key{Name: "key", Data: make([]byte, 3*keychainChunkSize), AttestedBy: "ak", CreatedAt: t0}
+	require.NoError(t, s.AddAK(ak))
+	require.NoError(t, s.AddKey(key))
+	require.NoError(t, s.Persist())
+
+	s2, err := NewKeychainstore("step-tpm-test-" + t.Name())
+	require.NoError(t, err)
+	require.NoError(t, s2.Load())
+	gotAK, err := s2.GetAK("ak")
+	require.NoError(t, err)
+	assert.Equal(t, ak, gotAK)
+	gotKey, err := s2.GetKey("key")
+	require.NoError(t, err)
+	assert.Equal(t, key, gotKey)
+}
@@ -0,0 +1,116 @@
+//go:build windows
+// +build windows
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+)
+
+var (
+	advapi32        = windows.NewLazySystemDLL("advapi32.dll")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+// credential is the CREDENTIALW structure.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// windowsKeyring stores the items as generic credentials in the Windows
+// Credential Manager. The target name of a credential is the service and the
+// account joined by a colon.
+type windowsKeyring struct {
+	service string
+}
+
+func newKeyring(service string) (keyring, error) {
+	if err := advapi32.Load(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrKeychainNotSupported, err)
+	}
+	return &windowsKeyring{service: service}, nil
+}
+
+func (k *windowsKeyring) target(account string) (*uint16, error) {
+	return windows.UTF16PtrFromString(k.service + ":" + account)
+}
+
+func (k *windowsKeyring) get(account string) ([]byte, error) {
+	target, err := k.target(account)
+	if err != nil {
+		return nil, err
+	}
+	var cred *credential
+	r, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if r == 0 {
+		return nil, credError("CredReadW", err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred))) //nolint:errcheck // CredFree does not return errors
+
+	data := make([]byte, cred.CredentialBlobSize)
+	if cred.CredentialBlobSize > 0 {
+		copy(data, unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize))
+	}
+	return data, nil
+}
+
+func (k *windowsKeyring) set(account string, data []byte) error {
+	target, err := k.target(account)
+	if err != nil {
+		return err
+	}
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(data)),
+		Persist:            credPersistLocalMachine,
+	}
+	if len(data) > 0 {
+		cred.CredentialBlob = &data[0]
+	}
+	if r, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0); r == 0 {
+		return credError("CredWriteW", err)
+	}
+	return nil
+}
+
+func (k *windowsKeyring) delete(account string) error {
+	target, err := k.target(account)
+	if err != nil {
+		return err
+	}
+	if r, _, err := procCredDeleteW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0); r == 0 {
+		return credError("CredDeleteW", err)
+	}
+	return nil
+}
+
+func credError(name string, err error) error {
+	if errors.Is(err, windows.ERROR_NOT_FOUND) {
+		return errKeyringNotFound
+	}
+	return fmt.Errorf("failed calling %s: %w", name, err)
+}
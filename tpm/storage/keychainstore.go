@@ -0,0 +1,363 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrKeychainNotSupported is returned when the credential store of the
+// operating system is not supported or not available.
+var ErrKeychainNotSupported = errors.New("keychain storage is not supported on this platform")
+
+// errKeyringNotFound is returned by a keyring when an item does not exist.
+var errKeyringNotFound = errors.New("keyring item not found")
+
+// keyring is the interface implemented by the platform credential stores. The
+// items are identified by the account, in the service of the keyring.
+type keyring interface {
+	get(account string) ([]byte, error)
+	set(account string, data []byte) error
+	delete(account string) error
+}
+
+const (
+	// keychainIndex is the account of the item with the names of the stored
+	// objects.
+	keychainIndex = "index"
+	// keychainChunkSize is the maximum size of an item, objects are split in
+	// multiple items if necessary. The Windows Credential Manager has the
+	// lowest limit, 2560 bytes.
+	keychainChunkSize = 2048
+)
+
+// Keychainstore is a concrete implementation of the TPMStore interface that
+// stores TPM objects in the credential store of the operating system: the
+// Keychain on macOS, the Credential Manager on Windows, and the Secret Service
+// (e.g. GNOME Keyring) on Linux, using secret-tool. Like the Filestore, it
+// keeps an in-memory map of AKs and TPM Keys that is written to the credential
+// store when Persist is called.
+//
+// Each object is stored in its own items, and an additional item stores the
+// names of all the objects, as credential stores cannot be listed in a
+// portable way.
+type Keychainstore struct {
+	mu       sync.RWMutex
+	keyring  keyring
+	objects  map[string][]byte
+	stored   map[string][]byte
+	migrator migrator
+}
+
+// NewKeychainstore creates a new instance of a Keychainstore using the given
+// service name to identify its items in the credential store. It returns an
+// error wrapping ErrKeychainNotSupported if the platform credential store is
+// not supported or not available.
+func NewKeychainstore(service string) (*Keychainstore, error) {
+	if service == "" {
+		return nil, errors.New("service cannot be empty")
+	}
+	kr, err := newKeyring(service)
+	if err != nil {
+		return nil, err
+	}
+	return newKeychainstore(kr), nil
+}
+
+func newKeychainstore(kr keyring) *Keychainstore {
+	return &Keychainstore{
+		keyring: kr,
+		objects: make(map[string][]byte),
+		stored:  make(map[string][]byte),
+	}
+}
+
+func (s *Keychainstore) ListKeys() ([]*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var result = make([]*Key, 0)
+	for _, k := range s.names(keyPrefix) {
+		key := &Key{}
+		if err := json.Unmarshal(s.objects[k], key); err != nil {
+			return nil, fmt.Errorf("failed unmarshaling key: %w", err)
+		}
+		result = append(result, key)
+	}
+	return result, nil
+}
+
+func (s *Keychainstore) ListKeyNames() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var result = make([]string, 0)
+	for _, k := range s.names(keyPrefix) {
+		result = append(result, strings.TrimPrefix(k, keyPrefix))
+	}
+	return result
+}
+
+func (s *Keychainstore) GetKey(name string) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.objects[keyForKey(name)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	key := &Key{}
+	if err := json.Unmarshal(data, key); err != nil {
+		return nil, fmt.Errorf("failed unmarshaling key: %w", err)
+	}
+	return key, nil
+}
+
+func (s *Keychainstore) AddKey(key *Key) error {
+	return s.put(keyForKey(key.Name), key, false)
+}
+
+func (s *Keychainstore) UpdateKey(key *Key) error {
+	return s.put(keyForKey(key.Name), key, true)
+}
+
+func (s *Keychainstore) DeleteKey(name string) error {
+	return s.remove(keyForKey(name))
+}
+
+func (s *Keychainstore) ListAKs() ([]*AK, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var result = make([]*AK, 0)
+	for _, k := range s.names(akPrefix) {
+		ak := &AK{}
+		if err := json.Unmarshal(s.objects[k], ak); err != nil {
+			return nil, fmt.Errorf("failed unmarshaling AK: %w", err)
+		}
+		result = append(result, ak)
+	}
+	return result, nil
+}
+
+func (s *Keychainstore) ListAKNames() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var result = make([]string, 0)
+	for _, k := range s.names(akPrefix) {
+		result = append(result, strings.TrimPrefix(k, akPrefix))
+	}
+	return result
+}
+
+func (s *Keychainstore) GetAK(name string) (*AK, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.objects[keyForAK(name)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	ak := &AK{}
+	if err := json.Unmarshal(data, ak); err != nil {
+		return nil, fmt.Errorf("failed unmarshaling AK: %w", err)
+	}
+	return ak, nil
+}
+
+func (s *Keychainstore) AddAK(ak *AK) error {
+	return s.put(keyForAK(ak.Name), ak, false)
+}
+
+func (s *Keychainstore) UpdateAK(ak *AK) error {
+	return s.put(keyForAK(ak.Name), ak, true)
+}
+
+func (s *Keychainstore) DeleteAK(name string) error {
+	return s.remove(keyForAK(name))
+}
+
+// Persist writes the objects that have changed since the last Load or Persist
+// to the credential store, and deletes the ones that have been removed.
+func (s *Keychainstore) Persist() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, data := range s.objects {
+		if old, ok := s.stored[k]; ok && bytes.Equal(old, data) {
+			continue
+		}
+		if err := s.write(k, data); err != nil {
+			return fmt.Errorf("failed writing %q to keychain: %w", k, err)
+		}
+	}
+	for k := range s.stored {
+		if _, ok := s.objects[k]; ok {
+			continue
+		}
+		if err := s.erase(k, 0); err != nil {
+			return fmt.Errorf("failed deleting %q from keychain: %w", k, err)
+		}
+	}
+
+	index, err := json.Marshal(s.names(""))
+	if err != nil {
+		return fmt.Errorf("failed serializing keychain index: %w", err)
+	}
+	if err := s.write(keychainIndex, index); err != nil {
+		return fmt.Errorf("failed writing keychain index: %w", err)
+	}
+
+	s.stored = make(map[string][]byte, len(s.objects))
+	for k, v := range s.objects {
+		s.stored[k] = v
+	}
+	return nil
+}
+
+// Load reads all the objects from the credential store. Objects stored in an
+// older format are migrated the first time the store is loaded.
+func (s *Keychainstore) Load() error {
+	if err := s.load(); err != nil {
+		return err
+	}
+	return s.migrator.migrate(s)
+}
+
+func (s *Keychainstore) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	objects := make(map[string][]byte)
+	data, err := s.read(keychainIndex)
+	switch {
+	case errors.Is(err, errKeyringNotFound):
+		// nothing stored yet
+	case err != nil:
+		return fmt.Errorf("failed reading keychain index: %w", err)
+	default:
+		var names []string
+		if err := json.Unmarshal(data, &names); err != nil {
+			return fmt.Errorf("failed unmarshaling keychain index: %w", err)
+		}
+		for _, k := range names {
+			if objects[k], err = s.read(k); err != nil {
+				return fmt.Errorf("failed reading %q from keychain: %w", k, err)
+			}
+		}
+	}
+
+	s.objects = objects
+	s.stored = make(map[string][]byte, len(objects))
+	for k, v := range objects {
+		s.stored[k] = v
+	}
+	return nil
+}
+
+func (s *Keychainstore) put(k string, v any, update bool) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed serializing object: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.objects[k]; ok != update {
+		if update {
+			return ErrNotFound
+		}
+		return ErrExists
+	}
+	s.objects[k] = data
+	return nil
+}
+
+func (s *Keychainstore) remove(k string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.objects[k]; !ok {
+		return ErrNotFound
+	}
+	delete(s.objects, k)
+	return nil
+}
+
+// names returns the sorted storage keys with the given prefix.
+func (s *Keychainstore) names(prefix string) []string {
+	result := make([]string, 0, len(s.objects))
+	for k := range s.objects {
+		if strings.HasPrefix(k, prefix) {
+			result = append(result, k)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// chunkAccount returns the account of the i-th item of an object.
+func chunkAccount(k string, i int) string {
+	return fmt.Sprintf("%s/%d", k, i)
+}
+
+// read reads all the items of an object and joins them.
+func (s *Keychainstore) read(k string) ([]byte, error) {
+	var data []byte
+	for i := 0; ; i++ {
+		chunk, err := s.keyring.get(chunkAccount(k, i))
+		if err != nil {
+			if i > 0 && errors.Is(err, errKeyringNotFound) {
+				return data, nil
+			}
+			return nil, err
+		}
+		data = append(data, chunk...)
+		if len(chunk) < keychainChunkSize {
+			return data, nil
+		}
+	}
+}
+
+// write splits an object into items of at most keychainChunkSize bytes, and
+// deletes the items of a previous version of the object that are not used.
+func (s *Keychainstore) write(k string, data []byte) error {
+	var i int
+	for ; ; i++ {
+		n := len(data)
+		if n > keychainChunkSize {
+			n = keychainChunkSize
+		}
+		if err := s.keyring.set(chunkAccount(k, i), data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+		// An object with a size multiple of the chunk size ends with an
+		// empty item.
+		if n < keychainChunkSize {
+			break
+		}
+	}
+	return s.erase(k, i+1)
+}
+
+// erase deletes the items of an object starting at the given item.
+func (s *Keychainstore) erase(k string, from int) error {
+	for i := from; ; i++ {
+		if err := s.keyring.delete(chunkAccount(k, i)); err != nil {
+			if errors.Is(err, errKeyringNotFound) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func (s *Keychainstore) rawObjects() (map[string][]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make(map[string][]byte, len(s.objects))
+	for k, v := range s.objects {
+		result[k] = v
+	}
+	return result, nil
+}
+
+var _ TPMStore = (*Keychainstore)(nil)
@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryKeyring struct {
+	items map[string][]byte
+	err   error
+}
+
+func newMemoryKeyring() *memoryKeyring {
+	return &memoryKeyring{items: make(map[string][]byte)}
+}
+
+func (k *memoryKeyring) get(account string) ([]byte, error) {
+	if k.err != nil {
+		return nil, k.err
+	}
+	data, ok := k.items[account]
+	if !ok {
+		return nil, errKeyringNotFound
+	}
+	return data, nil
+}
+
+func (k *memoryKeyring) set(account string, data []byte) error {
+	if k.err != nil {
+		return k.err
+	}
+	k.items[account] = bytes.Clone(data)
+	return nil
+}
+
+func (k *memoryKeyring) delete(account string) error {
+	if k.err != nil {
+		return k.err
+	}
+	if _, ok := k.items[account]; !ok {
+		return errKeyringNotFound
+	}
+	delete(k.items, account)
+	return nil
+}
+
+func (k *memoryKeyring) accounts() []string {
+	result := make([]string, 0, len(k.items))
+	for a := range k.items {
+		result = append(result, a)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestNewKeychainstore(t *testing.T) {
+	_, err := NewKeychainstore("")
+	assert.EqualError(t, err, "service cannot be empty")
+}
+
+func TestKeychainstore_KeyOperations(t *testing.T) {
+	t0 := time.Time{} // we're hit by https://github.com/stretchr/testify/issues/950
+	kr := newMemoryKeyring()
+	s := newKeychainstore(kr)
+	require.NoError(t, s.Load())
+
+	key := &Key{Name: "1st-key", Data: []byte{1, 2, 3, 4}, AttestedBy: "1st-ak", CreatedAt: t0}
+	require.NoError(t, s.AddKey(key))
+	assert.ErrorIs(t, s.AddKey(key), ErrExists)
+	require.NoError(t, s.AddKey(&Key{Name: "2nd-key", CreatedAt: t0}))
+
+	got, err := s.GetKey("1st-key")
+	require.NoError(t, err)
+	assert.Equal(t, key, got)
+	_, err = s.GetKey("non-existing-key")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	key.Data = []byte{5, 6, 7, 8}
+	require.NoError(t, s.UpdateKey(key))
+	assert.ErrorIs(t, s.UpdateKey(&Key{Name: "non-existing-key"}), ErrNotFound)
+
+	keys, err := s.ListKeys()
+	require.NoError(t, err)
+	assert.Equal(t, []*Key{key, {Name: "2nd-key", CreatedAt: t0}}, keys)
+	assert.Equal(t, []string{"1st-key", "2nd-key"}, s.ListKeyNames())
+
+	// Nothing is written until Persist is called.
+	assert.Empty(t, kr.items)
+	require.NoError(t, s.Persist())
+	assert.Equal(t, []string{"index/0", "key-1st-key/0", "key-2nd-key/0"}, kr.accounts())
+
+	require.NoError(t, s.DeleteKey("2nd-key"))
+	assert.ErrorIs(t, s.DeleteKey("2nd-key"), ErrNotFound)
+	require.NoError(t, s.Persist())
+	assert.Equal(t, []string{"index/0", "key-1st-key/0"}, kr.accounts())
+
+	// A new store reads the persisted objects.
+	s2 := newKeychainstore(kr)
+	require.NoError(t, s2.Load())
+	got, err = s2.GetKey("1st-key")
+	require.NoError(t, err)
+	assert.Equal(t, key, got)
+	assert.Equal(t, []string{"1st-key"}, s2.ListKeyNames())
+}
+
+func TestKeychainstore_AKOperations(t *testing.T) {
+	t0 := time.Time{} // we're hit by https://github.com/stretchr/testify/issues/950
+	kr := newMemoryKeyring()
+	s := newKeychainstore(kr)
+	require.NoError(t, s.Load())
+
+	ak := &AK{Name: "1st-ak", Data: []byte{1, 2, 3, 4}, CreatedAt: t0}
+	require.NoError(t, s.AddAK(ak))
+	assert.ErrorIs(t, s.AddAK(ak), ErrExists)
+
+	got, err := s.GetAK("1st-ak")
+	require.NoError(t, err)
+	assert.Equal(t, ak, got)
+	_, err = s.GetAK("non-existing-ak")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	ak.Data = []byte{5, 6, 7, 8}
+	require.NoError(t, s.UpdateAK(ak))
+	assert.ErrorIs(t, s.UpdateAK(&AK{Name: "non-existing-ak"}), ErrNotFound)
+
+	aks, err := s.ListAKs()
+	require.NoError(t, err)
+	assert.Equal(t, []*AK{ak}, aks)
+	assert.Equal(t, []string{"1st-ak"}, s.ListAKNames())
+	assert.Empty(t, s.ListKeyNames())
+
+	require.NoError(t, s.Persist())
+	s2 := newKeychainstore(kr)
+	require.NoError(t, s2.Load())
+	got, err = s2.GetAK("1st-ak")
+	require.NoError(t, err)
+	assert.Equal(t, ak, got)
+
+	require.NoError(t, s2.DeleteAK("1st-ak"))
+	assert.ErrorIs(t, s2.DeleteAK("1st-ak"), ErrNotFound)
+	require.NoError(t, s2.Persist())
+	assert.Equal(t, []string{"index/0"}, kr.accounts())
+}
+
+func TestKeychainstore_chunks(t *testing.T) {
+	t0 := time.Time{} // we're hit by https://github.com/stretchr/testify/issues/950
+	kr := newMemoryKeyring()
+	s := newKeychainstore(kr)
+	require.NoError(t, s.Load())
+
+	key := &Key{Name: "big-key", Data: bytes.Repeat([]byte{0xAA}, 3*keychainChunkSize), CreatedAt: t0}
+	require.NoError(t, s.AddKey(key))
+	require.NoError(t, s.Persist())
+	assert.Equal(t, []string{"index/0", "key-big-key/0", "key-big-key/1", "key-big-key/2", "key-big-key/3", "key-big-key/4"}, kr.accounts())
+	for _, a := range kr.accounts() {
+		assert.LessOrEqual(t, len(kr.items[a]), keychainChunkSize)
+	}
+
+	s2 := newKeychainstore(kr)
+	require.NoError(t, s2.Load())
+	got, err := s2.GetKey("big-key")
+	require.NoError(t, err)
+	assert.Equal(t, key, got)
+
+	// Unused chunks are deleted when an object gets smaller.
+	key.Data = []byte{1, 2, 3, 4}
+	require.NoError(t, s2.UpdateKey(key))
+	require.NoError(t, s2.Persist())
+	assert.Equal(t, []string{"index/0", "key-big-key/0"}, kr.accounts())
+
+	// An object with a size multiple of the chunk size.
+	data := bytes.Repeat([]byte{0xBB}, 2*keychainChunkSize)
+	require.NoError(t, s2.write("raw", data))
+	assert.Len(t, kr.items["raw/2"], 0)
+	got2, err := s2.read("raw")
+	require.NoError(t, err)
+	assert.Equal(t, data, got2)
+}
+
+func TestKeychainstore_Load(t *testing.T) {
+	t.Run("ok empty", func(t *testing.T) {
+		s := newKeychainstore(newMemoryKeyring())
+		require.NoError(t, s.Load())
+		assert.Empty(t, s.ListKeyNames())
+		assert.Empty(t, s.ListAKNames())
+	})
+
+	t.Run("ok migrate", func(t *testing.T) {
+		kr := newMemoryKeyring()
+		index, err := json.Marshal([]string{"key-old-key"})
+		require.NoError(t, err)
+		kr.items["index/0"] = index
+		kr.items["key-old-key/0"] = []byte(`{"name":"old-key","type":"KEY","data":"AQIDBA=="}`)
+
+		s := newKeychainstore(kr)
+		require.NoError(t, s.Load())
+		key, err := s.GetKey("old-key")
+		require.NoError(t, err)
+		assert.Equal(t, []byte{1, 2, 3, 4}, key.Data)
+
+		var sk serializedKey
+		require.NoError(t, json.Unmarshal(kr.items["key-old-key/0"], &sk))
+		assert.Equal(t, currentVersion, sk.Version)
+	})
+
+	t.Run("fail index", func(t *testing.T) {
+		kr := newMemoryKeyring()
+		kr.items["index/0"] = []byte("not-json")
+		assert.Error(t, newKeychainstore(kr).Load())
+	})
+
+	t.Run("fail missing object", func(t *testing.T) {
+		kr := newMemoryKeyring()
+		kr.items["index/0"] = []byte(`["key-missing"]`)
+		assert.Error(t, newKeychainstore(kr).Load())
+	})
+
+	t.Run("fail keyring", func(t *testing.T) {
+		kr := newMemoryKeyring()
+		kr.err = errors.New("keyring is locked")
+		assert.EqualError(t, newKeychainstore(kr).Load(), "failed reading keychain index: keyring is locked")
+	})
+}
+
+func TestKeychainstore_Persist(t *testing.T) {
+	kr := newMemoryKeyring()
+	s := newKeychainstore(kr)
+	require.NoError(t, s.Load())
+	require.NoError(t, s.AddKey(&Key{Name: "key"}))
+
+	kr.err = errors.New("keyring is locked")
+	assert.Error(t, s.Persist())
+
+	kr.err = nil
+	require.NoError(t, s.Persist())
+	assert.Equal(t, []string{"index/0", "key-key/0"}, kr.accounts())
+}
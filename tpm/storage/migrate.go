@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// rawStore is the interface implemented by the stores that can return the
+// serialized objects, allowing to detect objects in an older format.
+type rawStore interface {
+	// rawObjects returns the serialized AKs and Keys indexed by their
+	// storage key.
+	rawObjects() (map[string][]byte, error)
+}
+
+// Migrate upgrades the AKs and Keys in the store that were serialized in an
+// older format, rewriting them in the current format. The store is only
+// persisted if at least one object was migrated.
+//
+// Objects that cannot be parsed are skipped and left as they are, so that a
+// single corrupt object does not prevent the rest of the store from being
+// used. Stores that cannot return the serialized objects will have all their
+// objects rewritten in the current format.
+func Migrate(store TPMStore) error {
+	rs, ok := store.(rawStore)
+	if !ok {
+		return migrateAll(store)
+	}
+
+	objects, err := rs.rawObjects()
+	if err != nil {
+		return fmt.Errorf("failed reading objects: %w", err)
+	}
+
+	var migrated bool
+	for k, data := range objects {
+		version, err := serializedVersion(data)
+		if err != nil {
+			continue
+		}
+		if version >= currentVersion {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(k, akPrefix):
+			ak := &AK{}
+			if err := json.Unmarshal(data, ak); err != nil {
+				continue
+			}
+			if err := store.UpdateAK(ak); err != nil {
+				return fmt.Errorf("failed migrating AK %q: %w", ak.Name, err)
+			}
+		case strings.HasPrefix(k, keyPrefix):
+			key := &Key{}
+			if err := json.Unmarshal(data, key); err != nil {
+				continue
+			}
+			if err := store.UpdateKey(key); err != nil {
+				return fmt.Errorf("failed migrating key %q: %w", key.Name, err)
+			}
+		default:
+			continue
+		}
+		migrated = true
+	}
+
+	if migrated {
+		if err := store.Persist(); err != nil {
+			return fmt.Errorf("failed persisting migrated objects: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// migrateAll rewrites all the AKs and Keys in the store in the current format.
+func migrateAll(store TPMStore) error {
+	aks, err := store.ListAKs()
+	if err != nil {
+		return fmt.Errorf("failed listing AKs: %w", err)
+	}
+	for _, ak := range aks {
+		if err := store.UpdateAK(ak); err != nil {
+			return fmt.Errorf("failed migrating AK %q: %w", ak.Name, err)
+		}
+	}
+
+	keys, err := store.ListKeys()
+	if err != nil {
+		return fmt.Errorf("failed listing keys: %w", err)
+	}
+	for _, key := range keys {
+		if err := store.UpdateKey(key); err != nil {
+			return fmt.Errorf("failed migrating key %q: %w", key.Name, err)
+		}
+	}
+
+	if err := store.Persist(); err != nil {
+		return fmt.Errorf("failed persisting migrated objects: %w", err)
+	}
+
+	return nil
+}
+
+// migrator runs Migrate on a store only once. If the migration fails, it will
+// be attempted again on the next call.
+type migrator struct {
+	mu   sync.Mutex
+	done bool
+}
+
+func (m *migrator) migrate(store TPMStore) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.done {
+		return nil
+	}
+	if err := Migrate(store); err != nil {
+		return fmt.Errorf("failed migrating store: %w", err)
+	}
+	m.done = true
+	return nil
+}
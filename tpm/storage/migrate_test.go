@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+	data, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dst, data, 0600))
+}
+
+func requireVersion(t *testing.T, data []byte, want int) {
+	t.Helper()
+	version, err := serializedVersion(data)
+	require.NoError(t, err)
+	require.Equal(t, want, version)
+}
+
+func wantMigrated() (*AK, *Key) {
+	createdAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &AK{
+		Name:      "ak1",
+		Data:      []byte{1, 2, 3, 4},
+		CreatedAt: createdAt,
+	}, &Key{
+		Name:       "key1",
+		Data:       []byte{5, 6, 7, 8},
+		AttestedBy: "ak1",
+		CreatedAt:  createdAt,
+	}
+}
+
+func TestMigrate_Filestore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "store.json")
+	copyFile(t, "testdata/v0/filestore.json", filename)
+
+	store := NewFilestore(filename)
+	require.NoError(t, store.Load())
+
+	// objects are upgraded in memory and in disk
+	requireVersion(t, store.store.Data["ak-ak1"], currentVersion)
+	requireVersion(t, store.store.Data["key-key1"], currentVersion)
+
+	loaded := NewFilestore(filename)
+	require.NoError(t, loaded.Load())
+	requireVersion(t, loaded.store.Data["ak-ak1"], currentVersion)
+	requireVersion(t, loaded.store.Data["key-key1"], currentVersion)
+
+	wantAK, wantKey := wantMigrated()
+	ak, err := loaded.GetAK("ak1")
+	require.NoError(t, err)
+	require.Equal(t, wantAK, ak)
+	key, err := loaded.GetKey("key1")
+	require.NoError(t, err)
+	require.Equal(t, wantKey, key)
+
+	// migrating again does not modify the file
+	fi, err := os.Stat(filename)
+	require.NoError(t, err)
+	require.NoError(t, os.Chtimes(filename, time.Time{}, time.Unix(0, 0)))
+	require.NoError(t, Migrate(loaded))
+	fi2, err := os.Stat(filename)
+	require.NoError(t, err)
+	require.Equal(t, fi.Size(), fi2.Size())
+	require.Equal(t, time.Unix(0, 0), fi2.ModTime())
+}
+
+func TestMigrate_Dirstore(t *testing.T) {
+	dir := t.TempDir()
+	copyFile(t, "testdata/v0/dirstore/ak-ak1.tpmobj", filepath.Join(dir, "ak-ak1.tpmobj"))
+	copyFile(t, "testdata/v0/dirstore/key-key1.tpmobj", filepath.Join(dir, "key-key1.tpmobj"))
+
+	store := NewDirstore(dir)
+	require.NoError(t, store.Load())
+
+	for _, name := range []string{"ak-ak1.tpmobj", "key-key1.tpmobj"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		requireVersion(t, data, currentVersion)
+	}
+
+	wantAK, wantKey := wantMigrated()
+	ak, err := store.GetAK("ak1")
+	require.NoError(t, err)
+	require.Equal(t, wantAK, ak)
+	key, err := store.GetKey("key1")
+	require.NoError(t, err)
+	require.Equal(t, wantKey, key)
+}
+
+func TestMigrate_Dirstore_once(t *testing.T) {
+	dir := t.TempDir()
+	store := NewDirstore(dir)
+	require.NoError(t, store.Load())
+
+	// Objects written after the first load are not migrated again.
+	copyFile(t, "testdata/v0/dirstore/ak-ak1.tpmobj", filepath.Join(dir, "ak-ak1.tpmobj"))
+	require.NoError(t, store.Load())
+
+	data, err := os.ReadFile(filepath.Join(dir, "ak-ak1.tpmobj"))
+	require.NoError(t, err)
+	requireVersion(t, data, 0)
+}
+
+func TestMigrate_corruptObject(t *testing.T) {
+	dir := t.TempDir()
+	copyFile(t, "testdata/v0/dirstore/ak-ak1.tpmobj", filepath.Join(dir, "ak-ak1.tpmobj"))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key-bad.tpmobj"), []byte(`{not json`), 0600))
+
+	store := NewDirstore(dir)
+	require.NoError(t, store.Load())
+
+	wantAK, _ := wantMigrated()
+	ak, err := store.GetAK("ak1")
+	require.NoError(t, err)
+	require.Equal(t, wantAK, ak)
+
+	data, err := os.ReadFile(filepath.Join(dir, "key-bad.tpmobj"))
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{not json`), data)
+}
+
+func TestMigrate_unsupportedVersion(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ak-ak1.tpmobj"), []byte(`{"version":2,"name":"ak1","type":"AK"}`), 0600))
+
+	store := NewDirstore(dir)
+	require.NoError(t, store.Load())
+
+	_, err := store.GetAK("ak1")
+	require.EqualError(t, err, "failed unmarshaling AK: unsupported serialized data version 2")
+}
+
+func TestMigrate_genericStore(t *testing.T) {
+	wantAK, wantKey := wantMigrated()
+	store := NewFeedthroughStore(nil)
+	require.NoError(t, Migrate(store))
+
+	inner := NewFilestore(filepath.Join(t.TempDir(), "store.json"))
+	require.NoError(t, inner.AddAK(wantAK))
+	require.NoError(t, inner.AddKey(wantKey))
+	store = NewFeedthroughStore(inner)
+	require.NoError(t, Migrate(store))
+
+	ak, err := inner.GetAK("ak1")
+	require.NoError(t, err)
+	require.Equal(t, wantAK, ak)
+}
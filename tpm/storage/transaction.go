@@ -0,0 +1,101 @@
+package storage
+
+// Writer is the set of TPMStore operations that modify the storage.
+type Writer interface {
+	AddKey(key *Key) error
+	UpdateKey(key *Key) error
+	DeleteKey(name string) error
+
+	AddAK(ak *AK) error
+	UpdateAK(ak *AK) error
+	DeleteAK(name string) error
+}
+
+// Transaction groups multiple storage operations. The operations are
+// not applied until Commit is called, which applies and persists all
+// of them, or none of them if one of the operations fails.
+type Transaction interface {
+	Writer
+	Commit() error
+	Rollback() error
+}
+
+// TransactionalStore is a TPMStore that supports transactions. It's
+// an optional interface; implementations that support it should be
+// used through a Transaction when multiple objects are modified at once.
+type TransactionalStore interface {
+	TPMStore
+	Begin() (Transaction, error)
+}
+
+// txOp is an operation in a transaction. The key is the storage key
+// of the object modified by the operation.
+type txOp struct {
+	key   string
+	apply func(w Writer) error
+}
+
+// transaction is a Transaction that records the operations, and
+// calls commit with them when it's committed.
+type transaction struct {
+	ops    []txOp
+	commit func(ops []txOp) error
+	done   bool
+}
+
+func newTransaction(commit func(ops []txOp) error) *transaction {
+	return &transaction{
+		commit: commit,
+	}
+}
+
+func (t *transaction) add(key string, apply func(w Writer) error) error {
+	if t.done {
+		return ErrTransactionDone
+	}
+	t.ops = append(t.ops, txOp{key: key, apply: apply})
+	return nil
+}
+
+func (t *transaction) AddKey(key *Key) error {
+	return t.add(keyForKey(key.Name), func(w Writer) error { return w.AddKey(key) })
+}
+
+func (t *transaction) UpdateKey(key *Key) error {
+	return t.add(keyForKey(key.Name), func(w Writer) error { return w.UpdateKey(key) })
+}
+
+func (t *transaction) DeleteKey(name string) error {
+	return t.add(keyForKey(name), func(w Writer) error { return w.DeleteKey(name) })
+}
+
+func (t *transaction) AddAK(ak *AK) error {
+	return t.add(keyForAK(ak.Name), func(w Writer) error { return w.AddAK(ak) })
+}
+
+func (t *transaction) UpdateAK(ak *AK) error {
+	return t.add(keyForAK(ak.Name), func(w Writer) error { return w.UpdateAK(ak) })
+}
+
+func (t *transaction) DeleteAK(name string) error {
+	return t.add(keyForAK(name), func(w Writer) error { return w.DeleteAK(name) })
+}
+
+func (t *transaction) Commit() error {
+	if t.done {
+		return ErrTransactionDone
+	}
+	t.done = true
+	return t.commit(t.ops)
+}
+
+func (t *transaction) Rollback() error {
+	if t.done {
+		return ErrTransactionDone
+	}
+	t.done = true
+	t.ops = nil
+	return nil
+}
+
+var _ Transaction = (*transaction)(nil)
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTransactionalStores(t *testing.T) map[string]func() TransactionalStore {
+	t.Helper()
+	return map[string]func() TransactionalStore{
+		"filestore": func() TransactionalStore {
+			s := NewFilestore(filepath.Join(t.TempDir(), "store.json"))
+			require.NoError(t, s.Load())
+			return s
+		},
+		"dirstore": func() TransactionalStore {
+			return NewDirstore(t.TempDir())
+		},
+	}
+}
+
+func TestTransaction_Commit(t *testing.T) {
+	t.Parallel()
+	for name, newStore := range newTransactionalStores(t) {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			s := newStore()
+			require.NoError(t, s.AddKey(&Key{Name: "old-key", Data: []byte{1}}))
+			require.NoError(t, s.Persist())
+
+			tx, err := s.Begin()
+			require.NoError(t, err)
+			require.NoError(t, tx.AddAK(&AK{Name: "ak", Data: []byte{2}}))
+			require.NoError(t, tx.AddKey(&Key{Name: "key", Data: []byte{3}, AttestedBy: "ak"}))
+			require.NoError(t, tx.UpdateKey(&Key{Name: "key", Data: []byte{4}, AttestedBy: "ak"}))
+			require.NoError(t, tx.DeleteKey("old-key"))
+
+			// Nothing is applied before the commit.
+			assert.ElementsMatch(t, []string{"old-key"}, s.ListKeyNames())
+			assert.Empty(t, s.ListAKNames())
+
+			require.NoError(t, tx.Commit())
+			assert.ElementsMatch(t, []string{"key"}, s.ListKeyNames())
+			assert.ElementsMatch(t, []string{"ak"}, s.ListAKNames())
+			key, err := s.GetKey("key")
+			require.NoError(t, err)
+			assert.Equal(t, []byte{4}, key.Data)
+
+			// The changes are persisted.
+			require.NoError(t, s.Load())
+			assert.ElementsMatch(t, []string{"key"}, s.ListKeyNames())
+			assert.ElementsMatch(t, []string{"ak"}, s.ListAKNames())
+
+			assert.ErrorIs(t, tx.Commit(), ErrTransactionDone)
+			assert.ErrorIs(t, tx.Rollback(), ErrTransactionDone)
+			assert.ErrorIs(t, tx.AddKey(&Key{Name: "other"}), ErrTransactionDone)
+		})
+	}
+}
+
+func TestTransaction_Commit_concurrent(t *testing.T) {
+	t.Parallel()
+	for name, newStore := range newTransactionalStores(t) {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			s := newStore()
+
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					tx, err := s.Begin()
+					assert.NoError(t, err)
+					assert.NoError(t, tx.AddKey(&Key{Name: fmt.Sprintf("key-%d", i), Data: []byte{byte(i)}}))
+					assert.NoError(t, tx.Commit())
+					_, err = s.GetKey(fmt.Sprintf("key-%d", i))
+					assert.NoError(t, err)
+				}(i)
+			}
+			wg.Wait()
+
+			assert.Len(t, s.ListKeyNames(), 10)
+		})
+	}
+}
+
+func TestTransaction_Commit_rollback(t *testing.T) {
+	t.Parallel()
+	for name, newStore := range newTransactionalStores(t) {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			s := newStore()
+			require.NoError(t, s.AddKey(&Key{Name: "existing", Data: []byte{1}}))
+			require.NoError(t, s.AddKey(&Key{Name: "updated", Data: []byte{2}}))
+			require.NoError(t, s.Persist())
+
+			tx, err := s.Begin()
+			require.NoError(t, err)
+			require.NoError(t, tx.AddAK(&AK{Name: "ak", Data: []byte{3}}))
+			require.NoError(t, tx.UpdateKey(&Key{Name: "updated", Data: []byte{4}}))
+			require.NoError(t, tx.DeleteKey("existing"))
+			require.NoError(t, tx.AddKey(&Key{Name: "key", Data: []byte{5}}))
+			require.NoError(t, tx.AddKey(&Key{Name: "updated", Data: []byte{6}}))
+			require.NoError(t, tx.AddKey(&Key{Name: "never-added", Data: []byte{7}}))
+
+			err = tx.Commit()
+			assert.True(t, errors.Is(err, ErrExists), "expected ErrExists, got %v", err)
+
+			check := func(t *testing.T) {
+				t.Helper()
+				assert.ElementsMatch(t, []string{"existing", "updated"}, s.ListKeyNames())
+				assert.Empty(t, s.ListAKNames())
+				key, err := s.GetKey("existing")
+				require.NoError(t, err)
+				assert.Equal(t, []byte{1}, key.Data)
+				key, err = s.GetKey("updated")
+				require.NoError(t, err)
+				assert.Equal(t, []byte{2}, key.Data)
+			}
+
+			// No partial records remain in memory or on disk.
+			check(t)
+			require.NoError(t, s.Load())
+			check(t)
+		})
+	}
+}
+
+func TestTransaction_Rollback(t *testing.T) {
+	t.Parallel()
+	for name, newStore := range newTransactionalStores(t) {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			s := newStore()
+			tx, err := s.Begin()
+			require.NoError(t, err)
+			require.NoError(t, tx.AddAK(&AK{Name: "ak"}))
+			require.NoError(t, tx.Rollback())
+
+			assert.ErrorIs(t, tx.Commit(), ErrTransactionDone)
+			assert.ErrorIs(t, tx.DeleteAK("ak"), ErrTransactionDone)
+			assert.Empty(t, s.ListAKNames())
+		})
+	}
+}
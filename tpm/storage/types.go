@@ -7,12 +7,16 @@ import (
 	"time"
 )
 
-// AK is the type used to store AKs.
+// AK is the type used to store AKs. Generation is incremented every
+// time the AK is rotated, and RetiredAt is set in the records of the
+// AKs that were replaced by a rotation.
 type AK struct {
-	Name      string
-	Data      []byte
-	Chain     []*x509.Certificate
-	CreatedAt time.Time
+	Name       string
+	Data       []byte
+	Chain      []*x509.Certificate
+	CreatedAt  time.Time
+	Generation int
+	RetiredAt  time.Time
 }
 
 // MarshalJSON marshals the AK into JSON.
@@ -23,15 +27,20 @@ func (ak *AK) MarshalJSON() ([]byte, error) {
 	}
 
 	sak := serializedAK{
-		Name:      ak.Name,
-		Type:      typeAK,
-		Data:      ak.Data,
-		CreatedAt: ak.CreatedAt,
+		Version:    currentVersion,
+		Name:       ak.Name,
+		Type:       typeAK,
+		Data:       ak.Data,
+		CreatedAt:  ak.CreatedAt,
+		Generation: ak.Generation,
 	}
 
 	if len(chain) > 0 {
 		sak.Chain = chain
 	}
+	if !ak.RetiredAt.IsZero() {
+		sak.RetiredAt = &ak.RetiredAt
+	}
 
 	return json.Marshal(sak)
 }
@@ -46,10 +55,17 @@ func (ak *AK) UnmarshalJSON(data []byte) error {
 	if sak.Type != typeAK {
 		return fmt.Errorf("unexpected serialized data type %q", sak.Type)
 	}
+	if sak.Version > currentVersion {
+		return fmt.Errorf("unsupported serialized data version %d", sak.Version)
+	}
 
 	ak.Name = sak.Name
 	ak.Data = sak.Data
 	ak.CreatedAt = sak.CreatedAt
+	ak.Generation = sak.Generation
+	if sak.RetiredAt != nil {
+		ak.RetiredAt = *sak.RetiredAt
+	}
 
 	if len(sak.Chain) > 0 {
 		chain := make([]*x509.Certificate, len(sak.Chain))
@@ -83,6 +99,7 @@ func (key *Key) MarshalJSON() ([]byte, error) {
 	}
 
 	sk := serializedKey{
+		Version:    currentVersion,
 		Name:       key.Name,
 		Type:       typeKey,
 		Data:       key.Data,
@@ -107,6 +124,9 @@ func (key *Key) UnmarshalJSON(data []byte) error {
 	if sk.Type != typeKey {
 		return fmt.Errorf("unexpected serialized data type %q", sk.Type)
 	}
+	if sk.Version > currentVersion {
+		return fmt.Errorf("unsupported serialized data version %d", sk.Version)
+	}
 
 	key.Name = sk.Name
 	key.Data = sk.Data
@@ -133,6 +153,10 @@ const (
 	keyPrefix = "key-"
 )
 
+// currentVersion is the version of the serialized format. Objects serialized
+// before versioning was introduced have version 0.
+const currentVersion = 1
+
 type tpmObjectType string
 
 const (
@@ -143,16 +167,20 @@ const (
 // serializedAK is the struct used when marshaling
 // a storage AK to JSON.
 type serializedAK struct {
-	Name      string        `json:"name"`
-	Type      tpmObjectType `json:"type"`
-	Data      []byte        `json:"data"`
-	Chain     [][]byte      `json:"chain"`
-	CreatedAt time.Time     `json:"createdAt"`
+	Version    int           `json:"version"`
+	Name       string        `json:"name"`
+	Type       tpmObjectType `json:"type"`
+	Data       []byte        `json:"data"`
+	Chain      [][]byte      `json:"chain"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	Generation int           `json:"generation,omitempty"`
+	RetiredAt  *time.Time    `json:"retiredAt,omitempty"`
 }
 
 // serializedKey is the struct used when marshaling
 // a storage Key to JSON.
 type serializedKey struct {
+	Version    int           `json:"version"`
 	Name       string        `json:"name"`
 	Type       tpmObjectType `json:"type"`
 	Data       []byte        `json:"data"`
@@ -170,3 +198,14 @@ func keyForAK(name string) string {
 func keyForKey(name string) string {
 	return fmt.Sprintf("%s%s", keyPrefix, name)
 }
+
+// serializedVersion returns the version of a serialized AK or Key.
+func serializedVersion(data []byte) (int, error) {
+	var v struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return 0, fmt.Errorf("failed unmarshaling serialized data: %w", err)
+	}
+	return v.Version, nil
+}
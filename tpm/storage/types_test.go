@@ -80,3 +80,41 @@ func TestKey_MarshalUnmarshal(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, key, rkey)
 }
+
+func TestAK_MarshalUnmarshal_rotated(t *testing.T) {
+	retiredAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	ak := &AK{
+		Name:       "ak1@0",
+		Data:       []byte{1, 2, 3, 4},
+		CreatedAt:  time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Generation: 0,
+		RetiredAt:  retiredAt,
+	}
+
+	data, err := json.Marshal(ak)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"retiredAt":"2024-01-02T03:04:05Z"`)
+	require.NotContains(t, string(data), `"generation"`)
+
+	var rak = &AK{}
+	err = json.Unmarshal(data, rak)
+	require.NoError(t, err)
+	require.Equal(t, ak, rak)
+
+	ak = &AK{
+		Name:       "ak1",
+		Data:       []byte{5, 6, 7, 8},
+		CreatedAt:  retiredAt,
+		Generation: 1,
+	}
+
+	data, err = json.Marshal(ak)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"generation":1`)
+	require.NotContains(t, string(data), `"retiredAt"`)
+
+	rak = &AK{}
+	err = json.Unmarshal(data, rak)
+	require.NoError(t, err)
+	require.Equal(t, ak, rak)
+}
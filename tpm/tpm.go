@@ -7,6 +7,8 @@ import (
 	"io"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/smallstep/go-attestation/attest"
 
@@ -26,8 +28,12 @@ type TPM struct {
 	deviceName             string
 	attestConfig           *attest.OpenConfig
 	attestTPM              *attest.TPM
+	attestErr              error
+	attestRetry            *retryChannel
+	version                Version
 	rwc                    io.ReadWriteCloser
 	lock                   sync.RWMutex
+	opened                 atomic.Bool
 	store                  storage.TPMStore
 	simulator              simulator.Simulator
 	commandChannel         CommandChannel
@@ -36,6 +42,7 @@ type TPM struct {
 	initCommandChannelOnce sync.Once
 	info                   *Info
 	eks                    []*EK
+	observer               Observer
 }
 
 // NewTPMOption is used to provide options when instantiating a new
@@ -43,7 +50,10 @@ type TPM struct {
 type NewTPMOption func(o *options) error
 
 // WithDeviceName is used to provide the `name` or path to the TPM
-// device.
+// device. A TPM exposed using a socket, like swtpm, can be used by
+// providing the path to a UNIX socket, optionally prefixed with
+// `unix://`, or a TCP address in the form `tcp://host:port`. It can't
+// be combined with WithSimulator or WithCommandChannel.
 func WithDeviceName(name string) NewTPMOption {
 	return func(o *options) error {
 		if name != "" {
@@ -54,7 +64,9 @@ func WithDeviceName(name string) NewTPMOption {
 }
 
 // WithStore is used to set the TPMStore implementation to use for
-// persisting TPM objects, including AKs and Keys.
+// persisting TPM objects, including AKs and Keys. If the store implements
+// storage.TransactionalStore, changes to the store are made using a
+// storage.Transaction.
 func WithStore(store storage.TPMStore) NewTPMOption {
 	return func(o *options) error {
 		if store == nil {
@@ -76,7 +88,10 @@ func WithDisableDownload() NewTPMOption {
 
 // WithSimulator is used to configure a TPM simulator implementation
 // that simulates TPM operations instead of interacting with an actual
-// TPM.
+// TPM. Once set, opening the TPM routes all operations to the simulator.
+// The simulator is not closed when the TPM is closed; that has to be done
+// by the caller. It can't be combined with WithDeviceName or
+// WithCommandChannel.
 func WithSimulator(sim simulator.Simulator) NewTPMOption {
 	return func(o *options) error {
 		o.simulator = sim
@@ -84,8 +99,29 @@ func WithSimulator(sim simulator.Simulator) NewTPMOption {
 	}
 }
 
+// WithPreferGoTPM makes opening the TPM fall back to go-tpm when
+// go-attestation fails to open it, e.g. when the TPM is exposed by the
+// kernel without a resource manager. Operations that don't depend on
+// go-attestation, like GetRandom, CreateKey, and signing with a Key,
+// will keep working, and the ones that do will return an
+// *AttestationUnavailableError.
+func WithPreferGoTPM() NewTPMOption {
+	return func(o *options) error {
+		o.preferGoTPM = true
+		return nil
+	}
+}
+
+// CommandChannel is a channel along which TPM 2.0 commands are sent
+// and responses are received, like a connection to a remote TPM proxy.
 type CommandChannel attest.CommandChannelTPM20
 
+// WithCommandChannel is used to provide a custom CommandChannel to
+// send TPM commands to, instead of opening a local TPM device. Both
+// go-attestation and go-tpm operations are sent to the command channel.
+// The command channel is closed when the TPM is closed after every
+// operation, so it must be possible to use it again after it has been
+// closed. It can't be combined with WithDeviceName or WithSimulator.
 func WithCommandChannel(commandChannel CommandChannel) NewTPMOption {
 	return func(o *options) error {
 		o.commandChannel = commandChannel
@@ -93,6 +129,30 @@ func WithCommandChannel(commandChannel CommandChannel) NewTPMOption {
 	}
 }
 
+// WithRetry makes the TPM retry transient failures up to `attempts`
+// times in total, waiting `backoff` before the first retry, and doubling
+// the wait after every retry, up to a minute. Opening the TPM is retried
+// when the device is busy or another transient I/O error happens. Commands
+// are sent again when writing them fails with a transient I/O error, and
+// when the TPM responds with TPM_RC_RETRY, TPM_RC_YIELDED, or
+// TPM_RC_TESTING. Errors reading a response are not retried, as the TPM
+// might have executed the command. Other errors, like authorization
+// failures, are never retried, and retrying stops when the context of the
+// operation is done. Commands sent by go-attestation to a local device,
+// without a command channel, are not retried.
+func WithRetry(attempts int, backoff time.Duration) NewTPMOption {
+	return func(o *options) error {
+		if attempts < 1 {
+			return fmt.Errorf("invalid number of attempts %d", attempts)
+		}
+		if backoff < 0 {
+			return fmt.Errorf("invalid backoff %s", backoff)
+		}
+		o.retry = retryOptions{attempts: attempts, backoff: backoff}
+		return nil
+	}
+}
+
 type options struct {
 	deviceName     string
 	attestConfig   *attest.OpenConfig
@@ -100,12 +160,21 @@ type options struct {
 	commandChannel CommandChannel
 	store          storage.TPMStore
 	downloader     *downloader
+	observer       Observer
+	preferGoTPM    bool
+	retry          retryOptions
 }
 
 func (o *options) validate() error {
 	if o.simulator != nil && o.commandChannel != nil {
 		return errors.New("WithSimulator and WithCommandChannel options are mutually exclusive")
 	}
+	if o.simulator != nil && o.deviceName != "" {
+		return errors.New("WithSimulator and WithDeviceName options are mutually exclusive")
+	}
+	if o.commandChannel != nil && o.deviceName != "" {
+		return errors.New("WithCommandChannel and WithDeviceName options are mutually exclusive")
+	}
 	return nil
 }
 
@@ -116,6 +185,7 @@ func New(opts ...NewTPMOption) (*TPM, error) {
 		attestConfig: &attest.OpenConfig{TPMVersion: attest.TPMVersion20},                      // default configuration for TPM attestation use cases
 		store:        storage.BlackHole(),                                                      // default storage doesn't persist anything // TODO(hs): make this in-memory storage instead?
 		downloader:   &downloader{enabled: true, maxDownloads: 10, client: http.DefaultClient}, // EK certificate download (if required) is enabled by default
+		observer:     nopObserver{},                                                            // no observability by default
 	}
 	for _, o := range opts {
 		if err := o(&tpmOptions); err != nil {
@@ -133,6 +203,7 @@ func New(opts ...NewTPMOption) (*TPM, error) {
 		downloader:     tpmOptions.downloader,
 		simulator:      tpmOptions.simulator,
 		commandChannel: tpmOptions.commandChannel,
+		observer:       tpmOptions.observer,
 		options:        &tpmOptions,
 	}, nil
 }
@@ -147,12 +218,17 @@ func (t *TPM) open(ctx context.Context) (err error) {
 		return
 	}
 
+	start := time.Now()
+	defer func() { t.observeOpen(ctx, start, err) }()
+
 	// lock the TPM instance; it's in use now
 	t.lock.Lock()
 	defer func() {
 		if err != nil {
 			t.lock.Unlock()
+			return
 		}
+		t.opened.Store(true)
 	}()
 
 	if err := t.store.Load(); err != nil { // TODO(hs): load this once? Or abstract this away.
@@ -167,17 +243,40 @@ func (t *TPM) open(ctx context.Context) (err error) {
 		return fmt.Errorf("failed initializing command channel: %w", err)
 	}
 
+	err = t.openDevice(ctx)
+	for attempt := 1; err != nil && attempt < t.options.retry.attempts; attempt++ {
+		if !isTransientError(err) || !t.options.retry.wait(ctx, attempt) {
+			break
+		}
+		err = t.openDevice(ctx)
+	}
+
+	return err
+}
+
+// openDevice opens the TPM device, the simulator, or the command channel
+// using go-attestation or go-tpm.
+func (t *TPM) openDevice(ctx context.Context) error {
 	// if a simulator was set, use it as the backing TPM device.
 	// The simulator is currently only used for testing.
 	if t.simulator != nil {
 		if t.attestTPM == nil {
-			at, err := attest.OpenTPM(t.attestConfig)
-			if err != nil {
+			at, err := openAttestTPM(t.retryAttestConfig(ctx))
+			switch {
+			case err == nil:
+				t.attestTPM, t.attestErr = at, nil
+				t.version = attestTPMVersion(at)
+			case t.options.preferGoTPM:
+				t.attestErr = err
+			default:
 				return fmt.Errorf("failed opening attest.TPM: %w", err)
 			}
-			t.attestTPM = at
+		} else if t.attestRetry != nil {
+			// the attest.TPM of the simulator is reused, so retries have
+			// to use the context of the current operation.
+			t.attestRetry.ctx = ctx
 		}
-		t.rwc = t.simulator
+		t.rwc = t.retryRWC(ctx, t.simulator)
 	} else {
 		// TODO(hs): when an internal call to open is performed, but when
 		// switching the "TPM implementation" to use between the two types,
@@ -185,32 +284,76 @@ func (t *TPM) open(ctx context.Context) (err error) {
 		// the only "go-tpm" call is for GetRandom(), but this could change
 		// in the future.
 		if isGoTPMCall(ctx) {
-			rwc, err := open.TPM(t.deviceName)
+			rwc, err := t.openRWC()
 			if err != nil {
 				return fmt.Errorf("failed opening TPM: %w", err)
 			}
-			t.rwc = rwc
+			t.rwc = t.retryRWC(ctx, rwc)
 		} else {
 			// TODO(hs): attest.OpenTPM doesn't currently take into account the
 			// device name provided. This doesn't seem to be an available option
 			// to filter on currently?
-			at, err := attest.OpenTPM(t.attestConfig)
+			at, err := openAttestTPM(t.retryAttestConfig(ctx))
 			if err != nil {
-				return fmt.Errorf("failed opening TPM: %w", err)
+				if !t.options.preferGoTPM {
+					return fmt.Errorf("failed opening TPM: %w", err)
+				}
+				// fall back to go-tpm; operations requiring
+				// go-attestation will fail with an error.
+				rwc, rerr := t.openRWC()
+				if rerr != nil {
+					return fmt.Errorf("failed opening TPM: %w", err)
+				}
+				t.rwc, t.attestErr = t.retryRWC(ctx, rwc), err
+				return nil
 			}
 			t.attestTPM = at
+			t.version = attestTPMVersion(at)
 		}
 	}
 
 	return nil
 }
 
+// openRWC opens the TPM for go-tpm operations. If a command channel was
+// provided using WithCommandChannel, commands are sent to it instead of
+// to the TPM device.
+func (t *TPM) openRWC() (io.ReadWriteCloser, error) {
+	if t.options.commandChannel != nil {
+		return t.options.commandChannel, nil
+	}
+	return open.TPM(t.deviceName)
+}
+
+// retryRWC wraps rwc so that commands are retried if WithRetry was used.
+func (t *TPM) retryRWC(ctx context.Context, rwc io.ReadWriteCloser) io.ReadWriteCloser {
+	if !t.options.retry.enabled() {
+		return rwc
+	}
+	return newRetryChannel(ctx, rwc, t.options.retry)
+}
+
+// retryAttestConfig returns the configuration used to open the TPM with
+// go-attestation. If WithRetry was used, the command channel is wrapped so
+// that commands are retried. The original configuration is kept in
+// t.attestConfig, as it's used to determine how the TPM has to be closed.
+func (t *TPM) retryAttestConfig(ctx context.Context) *attest.OpenConfig {
+	if !t.options.retry.enabled() || t.attestConfig.CommandChannel == nil {
+		return t.attestConfig
+	}
+	t.attestRetry = newRetryChannel(ctx, t.attestConfig.CommandChannel, t.options.retry)
+	return &attest.OpenConfig{
+		TPMVersion:     t.attestConfig.TPMVersion,
+		CommandChannel: t.attestRetry,
+	}
+}
+
 // initializeCommandChannel initializes the TPM's command channel based on
 // configuration provided when creating the TPM instance. The method is
 // primarily used to be able to use a TPM simulator in lieu of a real TPM
 // being available or when the real TPM should not be used. There's a special
-// case for a TPM exposed using a UNIX socket, which also is used primarily
-// for interacting with a TPM simulator.
+// case for a TPM exposed using a UNIX socket or a TCP address, which also is
+// used primarily for interacting with a TPM simulator, like swtpm.
 func (t *TPM) initializeCommandChannel() error {
 	// return early with the complete `attestConfig` set if
 	// command channel was provided before.
@@ -230,8 +373,8 @@ func (t *TPM) initializeCommandChannel() error {
 		t.commandChannel = t.options.commandChannel
 	}
 
-	// finally, check if the device name points to a UNIX socket, and use that
-	// as the command channel, if available.
+	// finally, check if the device name points to a UNIX socket or a TCP
+	// address, and use that as the command channel, if available.
 	if t.commandChannel == nil {
 		if socketCommandChannel, err := trySocketCommandChannel(t.deviceName); err != nil {
 			switch {
@@ -257,7 +400,8 @@ func (t *TPM) initializeCommandChannel() error {
 	return nil
 }
 
-// trySocketCommandChannel tries
+// trySocketCommandChannel tries to create a command channel for the UNIX
+// socket or TCP address at `path`.
 func trySocketCommandChannel(path string) (*socket.CommandChannelWithoutMeasurementLog, error) {
 	rwc, err := socket.New(path)
 	if err != nil {
@@ -266,15 +410,41 @@ func trySocketCommandChannel(path string) (*socket.CommandChannelWithoutMeasurem
 	return &socket.CommandChannelWithoutMeasurementLog{ReadWriteCloser: rwc}, nil
 }
 
+// IsOpen reports whether the TPM is currently open, e.g. while an
+// operation is in progress, or after AttestTPM until its cleanup
+// function is called.
+func (t *TPM) IsOpen() bool {
+	return t.opened.Load()
+}
+
+// Close closes the TPM if it's open, releasing the underlying device
+// and making it available to other operations. Operations open and
+// close the TPM on their own, so Close is only required to release a
+// TPM left open, e.g. by AttestTPM. It's safe to call Close multiple
+// times, and before the TPM was ever opened.
+func (t *TPM) Close(ctx context.Context) error {
+	return t.close(ctx)
+}
+
 // Close closes the TPM instance, cleaning up resources and
-// marking it ready to be use again.
-func (t *TPM) close(ctx context.Context) error {
+// marking it ready to be use again. Closing a TPM that's not
+// open is a no-op.
+func (t *TPM) close(ctx context.Context) (err error) {
 	// prevent closing the TPM multiple times if Open is called
 	// within the package multiple times.
 	if isInternalCall(ctx) {
 		return nil
 	}
 
+	// the TPM is not open, so it's not locked either; unlocking
+	// an unlocked mutex results in a panic.
+	if !t.opened.CompareAndSwap(true, false) {
+		return nil
+	}
+
+	start := time.Now()
+	defer func() { t.observeClose(ctx, start, err) }()
+
 	// if simulation is enabled, closing the TPM simulator must not
 	// happen, because re-opening it will result in a different instance,
 	// resulting in issues when running multiple test operations in
@@ -287,6 +457,7 @@ func (t *TPM) close(ctx context.Context) error {
 
 	// mark the TPM as ready to be used again when returning
 	defer t.lock.Unlock()
+	defer func() { t.attestErr = nil }()
 
 	// clean up the attest.TPM
 	if t.attestTPM != nil {
@@ -307,8 +478,54 @@ func (t *TPM) close(ctx context.Context) error {
 	return nil
 }
 
+// AttestTPM opens the TPM and returns the underlying *attest.TPM, so that it
+// can be used to drive go-attestation directly. The TPM is locked until the
+// returned cleanup function is called, which the caller must always do after
+// being done with the *attest.TPM. The *attest.TPM must not be used after
+// cleanup. If ctx is a context used within this package while the TPM is
+// already open, the TPM is not opened again, and cleanup won't close it.
+func (t *TPM) AttestTPM(ctx context.Context) (at *attest.TPM, cleanup func(), err error) {
+	if err = t.open(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed opening TPM: %w", err)
+	}
+
+	var once sync.Once
+	cleanup = func() {
+		once.Do(func() {
+			_ = t.close(ctx)
+		})
+	}
+
+	if t.attestTPM == nil {
+		cleanup()
+		return nil, nil, errors.New("failed opening TPM: attest.TPM not available")
+	}
+
+	return t.attestTPM, cleanup, nil
+}
+
+// openAttestTPM opens the TPM using go-attestation. It's a variable so
+// that failures can be simulated in tests.
+var openAttestTPM = attest.OpenTPM
+
+// attestTPMVersion returns the version of a TPM opened using go-attestation.
+// It's a variable so that a TPM 1.2 can be simulated in tests.
+var attestTPMVersion = func(at *attest.TPM) Version {
+	return Version(at.Version())
+}
+
+// requireAttestTPM returns an *AttestationUnavailableError if the TPM
+// was opened without go-attestation because of WithPreferGoTPM. It must
+// be called after opening the TPM.
+func (t *TPM) requireAttestTPM(operation string) error {
+	if t.attestTPM == nil && t.attestErr != nil {
+		return &AttestationUnavailableError{Operation: operation, Err: t.attestErr}
+	}
+	return nil
+}
+
 func (t *TPM) Available() (err error) {
-	_, err = t.Info(context.Background())
+	_, err = t.cachedInfo(context.Background())
 	return
 }
 
@@ -320,6 +537,37 @@ func (t *TPM) validate(config validatableConfig) error {
 	return config.Validate()
 }
 
+// updateStore calls `fn` to modify the TPM storage and persists the
+// changes. If the store supports transactions, `fn` is called with a
+// storage.Transaction, so that all changes are persisted at once or not
+// at all. Otherwise the changes are made directly on the store, and are
+// persisted afterwards.
+func (t *TPM) updateStore(fn func(w storage.Writer) error) error {
+	ts, ok := t.store.(storage.TransactionalStore)
+	if !ok {
+		if err := fn(t.store); err != nil {
+			return err
+		}
+		if err := t.store.Persist(); err != nil {
+			return fmt.Errorf("failed persisting storage: %w", err)
+		}
+		return nil
+	}
+
+	tx, err := ts.Begin()
+	if err != nil {
+		return fmt.Errorf("failed starting storage transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed committing storage transaction: %w", err)
+	}
+	return nil
+}
+
 // closeTPM closes TPM `t`. It must be called as a deferred function
 // every time TPM `t` is opened. If `ep` is nil and closing the TPM
 // returned an error, `ep` will be pointed to the latter. In practice
@@ -7,25 +7,34 @@ import (
 	"context"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/base64"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"math"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
 	"github.com/smallstep/go-attestation/attest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"go.step.sm/crypto/keyutil"
 	"go.step.sm/crypto/minica"
+	internalkey "go.step.sm/crypto/tpm/internal/key"
 	"go.step.sm/crypto/tpm/simulator"
 	"go.step.sm/crypto/tpm/storage"
 	"go.step.sm/crypto/tpm/tss2"
@@ -63,6 +72,7 @@ func TestTPM_Info(t *testing.T) {
 	require.NoError(t, err)
 
 	// expected TPM info for the Microsoft TPM simulator
+	fipsMode := true
 	expected := &Info{
 		Version:      Version(2),
 		Interface:    Interface(3),
@@ -72,11 +82,60 @@ func TestTPM_Info(t *testing.T) {
 			Major: 8215,
 			Minor: 1561,
 		},
+		LockoutStatus: &LockoutStatus{
+			MaxTries:        3,
+			RecoveryTime:    1000 * time.Second,
+			LockoutRecovery: 1000 * time.Second,
+		},
+		FIPSMode: &fipsMode,
 	}
 
 	require.Equal(t, expected, info)
 }
 
+func TestTPM_Info_lockoutStatus(t *testing.T) {
+	tpm := newSimulatedTPM(t)
+	ctx := context.Background()
+
+	info, err := tpm.Info(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, info.LockoutStatus)
+	require.False(t, info.LockoutStatus.InLockout)
+	require.NotZero(t, info.LockoutStatus.MaxTries)
+	failedTries := info.LockoutStatus.FailedTries
+
+	// create a DA protected key, and fail its authorization
+	template := tpm2.Public{
+		Type:    tpm2.AlgECC,
+		NameAlg: tpm2.AlgSHA256,
+		Attributes: tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+			tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth,
+		ECCParameters: &tpm2.ECCParams{
+			Sign:    &tpm2.SigScheme{Alg: tpm2.AlgECDSA, Hash: tpm2.AlgSHA256},
+			CurveID: tpm2.CurveNISTP256,
+		},
+	}
+	handle, _, err := tpm2.CreatePrimary(tpm.simulator, tpm2.HandleOwner, tpm2.PCRSelection{}, "", "secret", template)
+	require.NoError(t, err)
+	defer tpm2.FlushContext(tpm.simulator, handle)
+
+	// every authorization failure increments the counter by one
+	digest := make([]byte, 32)
+	for i := 0; i < 2; i++ {
+		_, err = tpm2.Sign(tpm.simulator, handle, "wrong-secret", digest, nil, nil)
+		require.Error(t, err)
+
+		info, err = tpm.Info(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, info.LockoutStatus)
+		require.Equal(t, failedTries+1, info.LockoutStatus.FailedTries)
+		failedTries = info.LockoutStatus.FailedTries
+	}
+
+	// the static info is cached without the lockout status
+	require.Nil(t, tpm.info.LockoutStatus)
+}
+
 func TestTPM_GenerateRandom(t *testing.T) {
 	tpm := newSimulatedTPM(t)
 	b, err := tpm.GenerateRandom(context.Background(), 16)
@@ -88,6 +147,152 @@ func TestTPM_GenerateRandom(t *testing.T) {
 	require.Len(t, b, 10)
 }
 
+func TestTPM_ReadClock(t *testing.T) {
+	tpm := newSimulatedTPM(t)
+	ctx := context.Background()
+
+	c1, err := tpm.ReadClock(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, c1)
+
+	time.Sleep(50 * time.Millisecond)
+
+	c2, err := tpm.ReadClock(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, c2)
+
+	assert.Greater(t, c2.Clock, c1.Clock)
+	assert.Greater(t, c2.Time, c1.Time)
+	assert.Equal(t, c1.ResetCount, c2.ResetCount)
+	assert.Equal(t, c1.RestartCount, c2.RestartCount)
+}
+
+func TestTPM_ReadPCRs(t *testing.T) {
+	tpm := newSimulatedTPM(t)
+	ctx := context.Background()
+
+	// extend the debug PCR so that it's different in both banks
+	digest := sha256.Sum256([]byte("data"))
+	err := tpm.WithTransport(ctx, func(rwc io.ReadWriteCloser) error {
+		return tpm2.PCRExtend(rwc, tpmutil.Handle(16), tpm2.AlgSHA256, digest[:], "")
+	})
+	require.NoError(t, err)
+	zero := make([]byte, 32)
+	want := sha256.Sum256(append(zero, digest[:]...))
+
+	pcrs, err := tpm.ReadPCRs(ctx, []crypto.Hash{crypto.SHA1, crypto.SHA256})
+	require.NoError(t, err)
+	require.Len(t, pcrs, 2)
+	for _, h := range []crypto.Hash{crypto.SHA1, crypto.SHA256} {
+		require.Len(t, pcrs[h], 24)
+		for i := 0; i < 24; i++ {
+			assert.Len(t, pcrs[h][i], h.Size())
+		}
+	}
+	assert.Equal(t, want[:], pcrs[crypto.SHA256][16])
+	assert.Equal(t, make([]byte, 20), pcrs[crypto.SHA1][16])
+
+	// SHA3-256 is not active in the simulator
+	pcrs, err = tpm.ReadPCRs(ctx, []crypto.Hash{crypto.SHA256, crypto.SHA3_256})
+	require.NoError(t, err)
+	require.Len(t, pcrs, 1)
+	assert.Len(t, pcrs[crypto.SHA256], 24)
+
+	// all active banks
+	pcrs, err = tpm.ReadPCRs(ctx, nil)
+	require.NoError(t, err)
+	assert.Contains(t, pcrs, crypto.SHA1)
+	assert.Contains(t, pcrs, crypto.SHA256)
+
+	_, err = tpm.ReadPCRs(ctx, []crypto.Hash{crypto.MD5})
+	assert.Error(t, err)
+}
+
+func TestTPM_WithPreferGoTPM(t *testing.T) {
+	attestErr := errors.New("no resource manager")
+	tmp := openAttestTPM
+	t.Cleanup(func() { openAttestTPM = tmp })
+	openAttestTPM = func(*attest.OpenConfig) (*attest.TPM, error) {
+		return nil, attestErr
+	}
+	ctx := context.Background()
+	sim := withSimulator(t)
+
+	// without the option, opening the TPM fails
+	tpm, err := New(sim, WithStore(storage.NewDirstore(t.TempDir())))
+	require.NoError(t, err)
+	_, err = tpm.GenerateRandom(ctx, 16)
+	assert.ErrorIs(t, err, attestErr)
+
+	tpm, err = New(sim, WithStore(storage.NewDirstore(t.TempDir())), WithPreferGoTPM())
+	require.NoError(t, err)
+
+	// operations that don't require go-attestation
+	b, err := tpm.GenerateRandom(ctx, 16)
+	require.NoError(t, err)
+	assert.Len(t, b, 16)
+
+	key, err := tpm.CreateKey(ctx, "key", CreateKeyConfig{Algorithm: "ECDSA", Size: 256})
+	require.NoError(t, err)
+	signer, err := key.Signer(ctx)
+	require.NoError(t, err)
+	pub, ok := signer.Public().(*ecdsa.PublicKey)
+	require.True(t, ok)
+	digest := sha256.Sum256([]byte("data"))
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+	assert.True(t, ecdsa.VerifyASN1(pub, digest[:], signature))
+
+	// operations that require go-attestation
+	assertUnavailable := func(t *testing.T, operation string, err error) {
+		t.Helper()
+		var aerr *AttestationUnavailableError
+		if assert.ErrorAs(t, err, &aerr) {
+			assert.Equal(t, operation, aerr.Operation)
+		}
+		assert.ErrorIs(t, err, ErrNotSupported)
+		assert.ErrorIs(t, err, attestErr)
+	}
+	_, err = tpm.CreateAK(ctx, "ak")
+	assertUnavailable(t, "CreateAK", err)
+	_, err = tpm.GetEKs(ctx)
+	assertUnavailable(t, "GetEKs", err)
+	_, err = tpm.Info(ctx)
+	assertUnavailable(t, "Info", err)
+	_, err = key.CertificationParameters(ctx)
+	assertUnavailable(t, "CertificationParameters", err)
+	err = tpm.DeleteKey(ctx, "key")
+	assertUnavailable(t, "DeleteKey", err)
+}
+
+func TestTPM_WithTransport(t *testing.T) {
+	tpm := newSimulatedTPM(t)
+
+	var b []byte
+	err := tpm.WithTransport(context.Background(), func(rwc io.ReadWriteCloser) (err error) {
+		b, err = tpm2.GetRandom(rwc, 16)
+		return
+	})
+	require.NoError(t, err)
+	require.Len(t, b, 16)
+
+	// closing the transport must not close the TPM
+	err = tpm.WithTransport(context.Background(), func(rwc io.ReadWriteCloser) error {
+		return rwc.Close()
+	})
+	require.NoError(t, err)
+
+	b, err = tpm.GenerateRandom(context.Background(), 10)
+	require.NoError(t, err)
+	require.Len(t, b, 10)
+
+	// errors are returned to the caller
+	err = tpm.WithTransport(context.Background(), func(rwc io.ReadWriteCloser) error {
+		return errors.New("transport error")
+	})
+	require.EqualError(t, err, "transport error")
+}
+
 func newErrorTPM(t *testing.T) *TPM {
 	t.Helper()
 	tmpDir := t.TempDir()
@@ -195,6 +400,44 @@ func Test_generator_Read(t *testing.T) {
 	}
 }
 
+func TestTPM_RandReader(t *testing.T) {
+	tpm := newSimulatedTPM(t)
+	r := tpm.RandReader(context.Background())
+
+	n, err := r.Read(nil)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// Read 1KB in chunks that don't align with the buffer size, and check
+	// that no 16 bytes block is repeated across buffer refills.
+	var data []byte
+	for len(data) < 1024 {
+		b := make([]byte, 100)
+		n, err := r.Read(b)
+		require.NoError(t, err)
+		require.Equal(t, 100, n)
+		data = append(data, b...)
+	}
+	data = data[:1024]
+	seen := map[string]bool{}
+	for i := 0; i < len(data); i += 16 {
+		block := string(data[i : i+16])
+		require.False(t, seen[block], "repeated random block at %d", i)
+		seen[block] = true
+	}
+
+	// Use the reader to generate a key.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), r)
+	require.NoError(t, err)
+	require.NotNil(t, key)
+
+	// Device errors are returned.
+	errorTPM := newErrorTPM(t)
+	n, err = errorTPM.RandReader(context.Background()).Read(make([]byte, 32))
+	require.EqualError(t, err, "failed generating random data: forced write error")
+	require.Zero(t, n)
+}
+
 func TestTPM_GetEKs(t *testing.T) {
 	tpm := newSimulatedTPM(t)
 	eks, err := tpm.GetEKs(context.Background())
@@ -212,6 +455,25 @@ func TestTPM_GetEKs(t *testing.T) {
 	require.Len(t, b, 32)
 }
 
+func TestTPM_AttestTPM(t *testing.T) {
+	tpm := newSimulatedTPM(t)
+	at, cleanup, err := tpm.AttestTPM(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, at)
+
+	eks, err := at.EKs()
+	require.NoError(t, err)
+	require.Len(t, eks, 1)
+	cleanup()
+	cleanup() // calling cleanup twice must not fail
+
+	// the TPM must be usable again after cleanup
+	got, err := tpm.GetEKs(context.Background())
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, eks[0].Public, got[0].Public())
+}
+
 func TestTPM_CreateAK(t *testing.T) {
 	tpm := newSimulatedTPM(t)
 	ak, err := tpm.CreateAK(context.Background(), "first-ak")
@@ -221,6 +483,26 @@ func TestTPM_CreateAK(t *testing.T) {
 	require.Same(t, tpm, ak.tpm)
 }
 
+func TestTPM_CreateAK_transactionalStore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "store.json")
+	tpm, err := New(withSimulator(t), WithStore(storage.NewFilestore(filename)))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = tpm.CreateAK(ctx, "ak")
+	require.NoError(t, err)
+	_, err = tpm.AttestKey(ctx, "ak", "attested-key", AttestKeyConfig{Algorithm: "RSA", Size: 2048})
+	require.NoError(t, err)
+	_, err = tpm.CreateKey(ctx, "key", CreateKeyConfig{Algorithm: "RSA", Size: 2048})
+	require.NoError(t, err)
+
+	// The records are committed to the file.
+	store := storage.NewFilestore(filename)
+	require.NoError(t, store.Load())
+	require.Equal(t, []string{"ak"}, store.ListAKNames())
+	require.ElementsMatch(t, []string{"attested-key", "key"}, store.ListKeyNames())
+}
+
 func TestTPM_GetAK(t *testing.T) {
 	tpm := newSimulatedTPM(t)
 	ak, err := tpm.CreateAK(context.Background(), "")
@@ -295,6 +577,94 @@ func TestTPM_DeleteAK(t *testing.T) {
 	require.EqualError(t, err, `failed getting AK "non-existing-ak": not found`)
 }
 
+func TestTPM_RotateAK(t *testing.T) {
+	ctx := context.Background()
+	tpm := newSimulatedTPM(t)
+
+	ak, err := tpm.CreateAK(ctx, "rotated-ak")
+	require.NoError(t, err)
+	require.Equal(t, 0, ak.Generation())
+	require.True(t, ak.RetiredAt().IsZero())
+	key, err := tpm.AttestKey(ctx, "rotated-ak", "attested-key", AttestKeyConfig{Algorithm: "RSA", Size: 2048})
+	require.NoError(t, err)
+	require.Equal(t, "rotated-ak", key.AttestedBy())
+
+	newAK, err := tpm.RotateAK(ctx, "rotated-ak")
+	require.NoError(t, err)
+	require.Equal(t, "rotated-ak", newAK.Name())
+	require.Equal(t, 1, newAK.Generation())
+	require.True(t, newAK.RetiredAt().IsZero())
+	require.Same(t, tpm, newAK.tpm)
+	require.NotEqual(t, ak.Public(), newAK.Public())
+
+	// the name resolves to the new AK
+	current, err := tpm.GetAK(ctx, "rotated-ak")
+	require.NoError(t, err)
+	require.Equal(t, newAK.Data(), current.Data())
+	require.Equal(t, 1, current.Generation())
+	require.Equal(t, newAK.Public(), current.Public())
+
+	current, err = tpm.GetAKGeneration(ctx, "rotated-ak", 1)
+	require.NoError(t, err)
+	require.Equal(t, newAK.Data(), current.Data())
+
+	// the old AK is still available and usable
+	old, err := tpm.GetAKGeneration(ctx, "rotated-ak", 0)
+	require.NoError(t, err)
+	require.Equal(t, "rotated-ak@0", old.Name())
+	require.Equal(t, ak.Data(), old.Data())
+	require.Equal(t, 0, old.Generation())
+	require.False(t, old.RetiredAt().IsZero())
+	require.Equal(t, ak.CreatedAt(), old.CreatedAt())
+	require.Equal(t, ak.Public(), old.Public())
+
+	// keys attested by the old AK reference the retired AK
+	key, err = tpm.GetKey(ctx, "attested-key")
+	require.NoError(t, err)
+	require.Equal(t, "rotated-ak@0", key.AttestedBy())
+
+	// retired AKs are not listed and can't attest new keys
+	aks, err := tpm.ListAKs(ctx)
+	require.NoError(t, err)
+	require.Len(t, aks, 1)
+	require.Equal(t, "rotated-ak", aks[0].Name())
+	_, err = tpm.AttestKey(ctx, "rotated-ak@0", "other-key", AttestKeyConfig{Algorithm: "RSA", Size: 2048})
+	require.EqualError(t, err, `failed attesting key with AK "rotated-ak@0": AK is retired`)
+
+	// names of retired AKs are reserved
+	_, err = tpm.CreateAK(ctx, "rotated-ak@1")
+	require.EqualError(t, err, `invalid AK name "rotated-ak@1": name cannot contain "@"`)
+
+	// a second rotation keeps all the generations
+	newestAK, err := tpm.RotateAK(ctx, "rotated-ak")
+	require.NoError(t, err)
+	require.Equal(t, 2, newestAK.Generation())
+	for i, want := range []*AK{ak, newAK, newestAK} {
+		got, err := tpm.GetAKGeneration(ctx, "rotated-ak", i)
+		require.NoError(t, err)
+		require.Equal(t, want.Data(), got.Data())
+	}
+
+	_, err = tpm.GetAKGeneration(ctx, "rotated-ak", 3)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = tpm.RotateAK(ctx, "rotated-ak@0")
+	require.Error(t, err)
+
+	_, err = tpm.RotateAK(ctx, "non-existing-ak")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// deleting the AK requires deleting the keys attested by retired AKs
+	err = tpm.DeleteAK(ctx, "rotated-ak")
+	require.EqualError(t, err, `failed deleting AK "rotated-ak@0" because 1 key(s) exist that were attested by it`)
+	require.NoError(t, tpm.DeleteKey(ctx, "attested-key"))
+	require.NoError(t, tpm.DeleteAK(ctx, "rotated-ak"))
+	for i := 0; i < 3; i++ {
+		_, err = tpm.GetAKGeneration(ctx, "rotated-ak", i)
+		require.ErrorIs(t, err, ErrNotFound)
+	}
+}
+
 func TestAK_AttestationParameters(t *testing.T) {
 	tpm := newSimulatedTPM(t)
 	ak, err := tpm.CreateAK(context.Background(), "first-ak")
@@ -367,6 +737,54 @@ func TestAK_Blobs(t *testing.T) {
 	require.Len(t, public, int(size)+2)
 }
 
+func TestAK_ExportTPM2B_ImportAK(t *testing.T) {
+	ctx := context.Background()
+	tpm := newSimulatedTPM(t)
+
+	ak, err := tpm.CreateAK(ctx, "first-ak")
+	require.NoError(t, err)
+
+	public, private, err := ak.ExportTPM2B(ctx)
+	require.NoError(t, err)
+	require.Len(t, public, int(binary.BigEndian.Uint16(public[0:2]))+2)
+	require.Len(t, private, int(binary.BigEndian.Uint16(private[0:2]))+2)
+
+	imported, err := tpm.ImportAK(ctx, "imported-ak", public, private)
+	require.NoError(t, err)
+	require.Equal(t, "imported-ak", imported.Name())
+	require.Same(t, tpm, imported.tpm)
+	require.Equal(t, ak.Public(), imported.Public())
+
+	// the imported AK is persisted and exports the same blobs
+	stored, err := tpm.GetAK(ctx, "imported-ak")
+	require.NoError(t, err)
+	gotPublic, gotPrivate, err := stored.ExportTPM2B(ctx)
+	require.NoError(t, err)
+	require.Equal(t, public, gotPublic)
+	require.Equal(t, private, gotPrivate)
+
+	_, err = tpm.ImportAK(ctx, "imported-ak", public, private)
+	require.ErrorIs(t, err, ErrExists)
+
+	_, err = tpm.ImportAK(ctx, "bad-public", public[:len(public)-1], private)
+	require.Error(t, err)
+
+	_, err = tpm.ImportAK(ctx, "bad-private", public, private[:1])
+	require.Error(t, err)
+
+	// a key that is not a restricted signing key can't be imported as an AK
+	key, err := tpm.CreateKey(ctx, "first-key", CreateKeyConfig{Algorithm: "RSA", Size: 2048})
+	require.NoError(t, err)
+	blobs, err := key.Blobs(ctx)
+	require.NoError(t, err)
+	keyPublic, err := blobs.Public()
+	require.NoError(t, err)
+	keyPrivate, err := blobs.Private()
+	require.NoError(t, err)
+	_, err = tpm.ImportAK(ctx, "not-an-ak", keyPublic, keyPrivate)
+	require.Error(t, err)
+}
+
 func TestAK_ToTSS2(t *testing.T) {
 	ctx := context.Background()
 	tpm := newSimulatedTPM(t)
@@ -500,6 +918,169 @@ func TestTPM_CreateKey(t *testing.T) {
 	assert.Nil(t, key)
 }
 
+func TestTPM_persistentSimulator(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	seedFile := filepath.Join(tmpDir, "simulator.seed")
+	store := storage.NewDirstore(filepath.Join(tmpDir, "store"))
+
+	newPersistentTPM := func(t *testing.T) (*TPM, simulator.Simulator) {
+		t.Helper()
+		sim, err := simulator.NewPersistent(seedFile)
+		require.NoError(t, err)
+		require.NoError(t, sim.Open())
+		tpm, err := New(WithSimulator(sim), WithStore(store))
+		require.NoError(t, err)
+		return tpm, sim
+	}
+
+	tpm, sim := newPersistentTPM(t)
+	key, err := tpm.CreateKey(ctx, "persistent-key", CreateKeyConfig{Algorithm: "ECDSA", Size: 256})
+	require.NoError(t, err)
+	signer, err := key.Signer(ctx)
+	require.NoError(t, err)
+	pub := signer.Public()
+	eks, err := tpm.GetEKs(ctx)
+	require.NoError(t, err)
+	require.NoError(t, sim.Close())
+
+	tpm, sim = newPersistentTPM(t)
+	t.Cleanup(func() {
+		require.NoError(t, sim.Close())
+	})
+	reopenedEKs, err := tpm.GetEKs(ctx)
+	require.NoError(t, err)
+	require.Equal(t, eks[0].Public(), reopenedEKs[0].Public())
+
+	key, err = tpm.GetKey(ctx, "persistent-key")
+	require.NoError(t, err)
+	signer, err = key.Signer(ctx)
+	require.NoError(t, err)
+	require.Equal(t, pub, signer.Public())
+
+	digest := sha256.Sum256([]byte("data"))
+	sig, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+	require.True(t, ecdsa.VerifyASN1(pub.(*ecdsa.PublicKey), digest[:], sig))
+}
+
+func TestNewPersistent(t *testing.T) {
+	seedFile := filepath.Join(t.TempDir(), "simulator.seed")
+	_, err := simulator.NewPersistent(seedFile)
+	require.NoError(t, err)
+	seed, err := os.ReadFile(seedFile)
+	require.NoError(t, err)
+	require.Len(t, seed, 16)
+
+	_, err = simulator.NewPersistent(seedFile)
+	require.NoError(t, err)
+	reread, err := os.ReadFile(seedFile)
+	require.NoError(t, err)
+	require.Equal(t, seed, reread)
+
+	require.NoError(t, os.WriteFile(seedFile, []byte("not-hex"), 0600))
+	_, err = simulator.NewPersistent(seedFile)
+	require.Error(t, err)
+}
+
+func TestTPM_CreateKey_authPolicy(t *testing.T) {
+	tpm := newSimulatedTPM(t)
+	ctx := context.Background()
+	pcrs := []int{16}
+
+	policy, err := tpm.PCRPolicyDigest(ctx, pcrs)
+	require.NoError(t, err)
+	require.Len(t, policy, 32)
+
+	config := CreateKeyConfig{
+		Algorithm:  "ECDSA",
+		Size:       256,
+		AuthPolicy: policy,
+	}
+	key, err := tpm.CreateKey(ctx, "pcr-key", config)
+	require.NoError(t, err)
+
+	digest := make([]byte, 32)
+	_, err = rand.Read(digest)
+	require.NoError(t, err)
+
+	// signing without a policy session fails
+	signer, err := key.Signer(ctx)
+	require.NoError(t, err)
+	_, err = signer.Sign(rand.Reader, digest, crypto.SHA256)
+	assert.ErrorIs(t, err, ErrPolicySessionRequired)
+
+	// signing succeeds while the PCRs match
+	signer, err = tpm.GetPCRPolicySigner(ctx, "pcr-key", pcrs)
+	require.NoError(t, err)
+	pub, ok := signer.Public().(*ecdsa.PublicKey)
+	require.True(t, ok)
+	signature, err := signer.Sign(rand.Reader, digest, crypto.SHA256)
+	require.NoError(t, err)
+	assert.True(t, ecdsa.VerifyASN1(pub, digest, signature))
+
+	// signing fails after extending the PCR
+	err = tpm2.PCRExtend(tpm.simulator, tpmutil.Handle(16), tpm2.AlgSHA256, make([]byte, 32), "")
+	require.NoError(t, err)
+	_, err = signer.Sign(rand.Reader, digest, crypto.SHA256)
+	assert.Error(t, err)
+
+	// keys without an auth policy can't be used with a policy signer
+	_, err = tpm.CreateKey(ctx, "key", CreateKeyConfig{Algorithm: "ECDSA", Size: 256})
+	require.NoError(t, err)
+	_, err = tpm.GetPCRPolicySigner(ctx, "key", pcrs)
+	assert.EqualError(t, err, `failed getting signer for key "key": key has no auth policy`)
+
+	// the policy digest must match the name algorithm
+	config = CreateKeyConfig{
+		Algorithm:  "ECDSA",
+		Size:       384,
+		AuthPolicy: policy,
+	}
+	_, err = tpm.CreateKey(ctx, "p384-key", config)
+	assert.EqualError(t, err, `failed creating key "p384-key": incorrect key options: auth policy must be a SHA-384 digest of 48 bytes, got 32 bytes`)
+}
+
+func TestTPM_CreateAK_authPolicy(t *testing.T) {
+	tpm := newSimulatedTPM(t)
+	ctx := context.Background()
+
+	policy, err := tpm.PCRPolicyDigest(ctx, []int{16})
+	require.NoError(t, err)
+
+	ak, err := tpm.CreateAK(ctx, "pcr-ak", WithAuthPolicy(policy))
+	require.NoError(t, err)
+	require.Equal(t, "pcr-ak", ak.Name())
+	require.Same(t, tpm, ak.tpm)
+	_, ok := ak.Public().(*rsa.PublicKey)
+	require.True(t, ok)
+
+	got, err := internalkey.AuthPolicy(ak.Data())
+	require.NoError(t, err)
+	require.Equal(t, policy, got)
+
+	// the AK is stored, and can be loaded by go-attestation
+	stored, err := tpm.GetAK(ctx, "pcr-ak")
+	require.NoError(t, err)
+	require.Equal(t, ak.Data(), stored.Data())
+	params, err := stored.AttestationParameters(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, params.Public)
+
+	// keys can't be attested without a policy session
+	_, err = tpm.AttestKey(ctx, "pcr-ak", "key", AttestKeyConfig{Algorithm: "RSA", Size: 2048})
+	require.ErrorIs(t, err, ErrPolicySessionRequired)
+
+	_, err = tpm.RotateAK(ctx, "pcr-ak")
+	require.EqualError(t, err, `failed rotating AK "pcr-ak": AKs with an auth policy can't be rotated`)
+
+	_, err = tpm.CreateAK(ctx, "empty-policy-ak", WithAuthPolicy(nil))
+	require.EqualError(t, err, "invalid AK options: auth policy cannot be empty")
+
+	_, err = tpm.CreateAK(ctx, "bad-policy-ak", WithAuthPolicy(make([]byte, 48)))
+	require.EqualError(t, err, `failed creating new AK "bad-policy-ak": incorrect AK options: auth policy must be a SHA-256 digest of 32 bytes, got 48 bytes`)
+}
+
 func TestTPM_AttestKey(t *testing.T) {
 	tpm := newSimulatedTPM(t)
 	ak, err := tpm.CreateAK(context.Background(), "first-ak")
@@ -651,6 +1232,77 @@ func TestKey_CertificationParameters(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestTPM_CreateAttestedCSR(t *testing.T) {
+	tpm := newSimulatedTPM(t)
+	ctx := context.Background()
+
+	ak, err := tpm.CreateAK(ctx, "ak")
+	require.NoError(t, err)
+	_, err = tpm.CreateAK(ctx, "other-ak")
+	require.NoError(t, err)
+	_, err = tpm.AttestKey(ctx, "ak", "key", AttestKeyConfig{Algorithm: "RSA", Size: 2048})
+	require.NoError(t, err)
+	_, err = tpm.CreateKey(ctx, "unattested-key", CreateKeyConfig{Algorithm: "RSA", Size: 2048})
+	require.NoError(t, err)
+
+	subject := pkix.Name{CommonName: "device-1234", Organization: []string{"Smallstep"}}
+	cr, err := tpm.CreateAttestedCSR(ctx, "key", "ak", subject, testKeyCertificationOID)
+	require.NoError(t, err)
+	require.NoError(t, cr.CheckSignature())
+	assert.Equal(t, "device-1234", cr.Subject.CommonName)
+	assert.Equal(t, []string{"Smallstep"}, cr.Subject.Organization)
+
+	signer, err := tpm.GetSigner(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, signer.Public(), cr.PublicKey)
+
+	// parse the extension
+	var found int
+	for _, ext := range cr.Extensions {
+		if ext.Id.Equal(testKeyCertificationOID) {
+			assert.False(t, ext.Critical)
+			var kc keyCertification
+			rest, err := asn1.Unmarshal(ext.Value, &kc)
+			require.NoError(t, err)
+			require.Empty(t, rest)
+			assert.NotEmpty(t, kc.Public)
+			assert.NotEmpty(t, kc.CreateAttestation)
+			assert.NotEmpty(t, kc.CreateSignature)
+			assert.NotEmpty(t, kc.AKPublic)
+			found++
+		}
+	}
+	require.Equal(t, 1, found)
+
+	// verify the certificate request
+	akPub, err := VerifyAttestedCSR(cr, testKeyCertificationOID)
+	require.NoError(t, err)
+	assert.Equal(t, ak.Public(), akPub)
+
+	// the key certification does not certify a different key
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:         subject,
+		ExtraExtensions: cr.Extensions,
+	}, priv)
+	require.NoError(t, err)
+	other, err := x509.ParseCertificateRequest(der)
+	require.NoError(t, err)
+	_, err = VerifyAttestedCSR(other, testKeyCertificationOID)
+	assert.EqualError(t, err, "certified public key does not match the certificate request public key")
+
+	// fail with keys not attested by the AK
+	_, err = tpm.CreateAttestedCSR(ctx, "key", "other-ak", subject, testKeyCertificationOID)
+	assert.EqualError(t, err, `key "key" was not attested by AK "other-ak"`)
+	_, err = tpm.CreateAttestedCSR(ctx, "unattested-key", "ak", subject, testKeyCertificationOID)
+	assert.EqualError(t, err, `key "unattested-key" was not attested by AK "ak"`)
+	_, err = tpm.CreateAttestedCSR(ctx, "non-existing-key", "ak", subject, testKeyCertificationOID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = tpm.CreateAttestedCSR(ctx, "key", "ak", subject, nil)
+	assert.EqualError(t, err, "key certification extension OID cannot be empty")
+}
+
 func TestKey_Blobs(t *testing.T) {
 	tpm := newSimulatedTPM(t)
 	config := CreateKeyConfig{
@@ -835,6 +1487,43 @@ func TestTPM_GetSigner(t *testing.T) {
 	require.Nil(t, signer)
 }
 
+func TestTPM_CreateSigner(t *testing.T) {
+	tpm := newSimulatedTPM(t)
+	ctx := context.Background()
+	key, err := tpm.CreateKey(ctx, "my-key", CreateKeyConfig{
+		Algorithm: "RSA",
+		Size:      2048,
+	})
+	require.NoError(t, err)
+	keySigner, err := key.Signer(ctx)
+	require.NoError(t, err)
+
+	digest := crypto.SHA256.New()
+	digest.Write([]byte("data"))
+	sum := digest.Sum(nil)
+
+	for _, u := range []string{"tpmkms:name=my-key", "tpmkms:my-key"} {
+		t.Run(u, func(t *testing.T) {
+			signer, err := tpm.CreateSigner(ctx, u)
+			require.NoError(t, err)
+			require.Equal(t, keySigner.Public(), signer.Public())
+
+			signature, err := signer.Sign(rand.Reader, sum, crypto.SHA256)
+			require.NoError(t, err)
+			require.NoError(t, rsa.VerifyPKCS1v15(signer.Public().(*rsa.PublicKey), crypto.SHA256, sum, signature))
+		})
+	}
+
+	signer, err := tpm.CreateSigner(ctx, "tpmkms:name=non-existing-key")
+	var notFound *NotFoundError
+	if assert.ErrorAs(t, err, &notFound) {
+		assert.Equal(t, "non-existing-key", notFound.Name)
+	}
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.EqualError(t, err, `key "non-existing-key" not found`)
+	assert.Nil(t, signer)
+}
+
 func TestKey_Signer(t *testing.T) {
 	tpm := newSimulatedTPM(t)
 	config := CreateKeyConfig{
@@ -899,6 +1588,34 @@ func Test_signer_Sign(t *testing.T) {
 	}
 }
 
+func Test_signer_Sign_observer(t *testing.T) {
+	o := &recordingObserver{}
+	tpm, err := New(withSimulator(t), WithStore(storage.NewDirstore(t.TempDir())), WithObserver(o))
+	require.NoError(t, err)
+
+	key, err := tpm.CreateKey(context.Background(), "first-key", CreateKeyConfig{Algorithm: "RSA", Size: 2048})
+	require.NoError(t, err)
+	s, err := key.Signer(context.Background())
+	require.NoError(t, err)
+
+	o.calls = nil
+	digest := make([]byte, 32)
+	_, err = s.Sign(rand.Reader, digest, crypto.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, []observedCall{{callback: "OnOpen"}, {callback: "OnClose"}, {callback: "OnSign", name: "first-key"}}, o.calls)
+
+	// signing with a key that can't be loaded fails
+	o.calls = nil
+	bad := &signer{tpm: tpm, key: Key{name: "bad-key", data: []byte{1, 2, 3, 4}}, public: s.Public()}
+	_, err = bad.Sign(rand.Reader, digest, crypto.SHA256)
+	require.Error(t, err)
+	if assert.Len(t, o.calls, 3) {
+		assert.Equal(t, "OnError", o.calls[2].callback)
+		assert.Equal(t, OperationSign, o.calls[2].operation)
+		assert.Equal(t, err, o.calls[2].err)
+	}
+}
+
 func TestCreateTSS2Signer(t *testing.T) {
 	ctx := context.Background()
 	tpm := newSimulatedTPM(t)
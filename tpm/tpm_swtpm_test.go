@@ -0,0 +1,73 @@
+//go:build swtpm
+// +build swtpm
+
+package tpm
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"os"
+	"testing"
+
+	"github.com/smallstep/go-attestation/attest"
+	"github.com/stretchr/testify/require"
+
+	"go.step.sm/crypto/tpm/storage"
+)
+
+// The tests in this file require a running swtpm exposing its command
+// channel on a UNIX socket or a TCP address, for example:
+//
+//	swtpm socket --tpm2 --tpmstate dir=/tmp/swtpm --flags not-need-init,startup-clear \
+//	  --server type=tcp,port=2321 --ctrl type=tcp,port=2322
+//
+// The tests are run with:
+//
+//	SWTPM_DEVICE=tcp://127.0.0.1:2321 go test -tags swtpm ./tpm/
+func newSwtpmTPM(t *testing.T) *TPM {
+	t.Helper()
+	device := os.Getenv("SWTPM_DEVICE")
+	if device == "" {
+		t.Skip("SWTPM_DEVICE is not set")
+	}
+	tpm, err := New(WithDeviceName(device), WithStore(storage.NewDirstore(t.TempDir())))
+	require.NoError(t, err)
+	return tpm
+}
+
+func TestSwtpm_Info(t *testing.T) {
+	tpm := newSwtpmTPM(t)
+	info, err := tpm.Info(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, Version(attest.TPMVersion20), info.Version)
+}
+
+func TestSwtpm_GenerateRandom(t *testing.T) {
+	tpm := newSwtpmTPM(t)
+	b, err := tpm.GenerateRandom(context.Background(), 16)
+	require.NoError(t, err)
+	require.Len(t, b, 16)
+}
+
+func TestSwtpm_AttestKey(t *testing.T) {
+	tpm := newSwtpmTPM(t)
+	ctx := context.Background()
+
+	ak, err := tpm.CreateAK(ctx, "ak")
+	require.NoError(t, err)
+	_, err = ak.AttestationParameters(ctx)
+	require.NoError(t, err)
+
+	key, err := tpm.AttestKey(ctx, "ak", "key", AttestKeyConfig{Algorithm: "ECDSA", Size: 256})
+	require.NoError(t, err)
+	require.True(t, key.WasAttestedBy(ak))
+
+	signer, err := key.Signer(ctx)
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("hello swtpm"))
+	sig, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+	require.NotEmpty(t, sig)
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"errors"
 	"io"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/smallstep/go-attestation/attest"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -56,6 +60,103 @@ func newCloseErrorTPM(t *testing.T) *TPM {
 	return tpm
 }
 
+func TestNew_withSimulator(t *testing.T) {
+	sim := &closeSimulator{}
+	tpm, err := New(WithSimulator(sim))
+	require.NoError(t, err)
+	assert.Same(t, sim, tpm.simulator)
+
+	ctx := context.Background()
+	require.NoError(t, tpm.open(ctx))
+	assert.Same(t, sim, tpm.rwc)
+	assert.NotNil(t, tpm.attestTPM)
+	require.NoError(t, tpm.close(ctx))
+
+	// the simulator is kept open and used again
+	require.NoError(t, tpm.open(ctx))
+	assert.Same(t, sim, tpm.rwc)
+	require.NoError(t, tpm.close(ctx))
+
+	_, err = New(WithSimulator(sim), WithDeviceName("/dev/tpmrm0"))
+	assert.EqualError(t, err, "invalid TPM options provided: WithSimulator and WithDeviceName options are mutually exclusive")
+
+	_, err = New(WithDeviceName("/dev/tpmrm0"), WithSimulator(sim))
+	assert.EqualError(t, err, "invalid TPM options provided: WithSimulator and WithDeviceName options are mutually exclusive")
+
+	_, err = New(WithSimulator(sim), WithCommandChannel(sim))
+	assert.EqualError(t, err, "invalid TPM options provided: WithSimulator and WithCommandChannel options are mutually exclusive")
+}
+
+// recordingCommandChannel is a CommandChannel that records the TPM
+// commands written to it, and replies with a fixed response.
+type recordingCommandChannel struct {
+	commands [][]byte
+	response []byte
+	pending  []byte
+	closed   int
+}
+
+func (c *recordingCommandChannel) Read(b []byte) (int, error) {
+	if len(c.pending) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *recordingCommandChannel) Write(b []byte) (int, error) {
+	c.commands = append(c.commands, append([]byte(nil), b...))
+	c.pending = c.response
+	return len(b), nil
+}
+
+func (c *recordingCommandChannel) Close() error {
+	c.closed++
+	return nil
+}
+
+func (c *recordingCommandChannel) MeasurementLog() ([]byte, error) {
+	return nil, nil
+}
+
+func TestNew_withCommandChannel(t *testing.T) {
+	random := []byte{1, 2, 3, 4}
+	cc := &recordingCommandChannel{
+		// TPM2_GetRandom response: TPM_ST_NO_SESSIONS, size, TPM_RC_SUCCESS,
+		// and a TPM2B_DIGEST with the random bytes.
+		response: append([]byte{
+			0x80, 0x01,
+			0x00, 0x00, 0x00, 0x10,
+			0x00, 0x00, 0x00, 0x00,
+			0x00, 0x04,
+		}, random...),
+	}
+	tpm, err := New(WithCommandChannel(cc))
+	require.NoError(t, err)
+
+	got, err := tpm.GenerateRandom(context.Background(), 4)
+	require.NoError(t, err)
+	assert.Equal(t, random, got)
+	assert.Equal(t, 1, cc.closed)
+
+	// TPM2_GetRandom command: TPM_ST_NO_SESSIONS, size, TPM_CC_GetRandom,
+	// and the number of bytes requested.
+	require.Len(t, cc.commands, 1)
+	assert.Equal(t, []byte{
+		0x80, 0x01,
+		0x00, 0x00, 0x00, 0x0c,
+		0x00, 0x00, 0x01, 0x7b,
+		0x00, 0x04,
+	}, cc.commands[0])
+
+	_, err = New(WithCommandChannel(cc), WithDeviceName("/dev/tpmrm0"))
+	assert.EqualError(t, err, "invalid TPM options provided: WithCommandChannel and WithDeviceName options are mutually exclusive")
+
+	_, err = New(WithDeviceName("/dev/tpmrm0"), WithCommandChannel(cc))
+	assert.EqualError(t, err, "invalid TPM options provided: WithCommandChannel and WithDeviceName options are mutually exclusive")
+}
+
 func Test_close(t *testing.T) {
 	var emptyErr error
 	anErr := errors.New("anErr")
@@ -74,3 +175,205 @@ func Test_close(t *testing.T) {
 	closeTPM(context.Background(), newCloseErrorTPM(t), &closeErr)
 	require.EqualError(t, closeErr, "failed closing attest.TPM: closeErr") // attest.TPM is backed by the closeSimulator
 }
+
+func TestTPM_Close(t *testing.T) {
+	ctx := context.Background()
+
+	// closing before opening is a no-op
+	tpm, err := New(WithSimulator(&closeSimulator{}))
+	require.NoError(t, err)
+	assert.False(t, tpm.IsOpen())
+	require.NoError(t, tpm.Close(ctx))
+	require.NoError(t, tpm.Close(ctx))
+	assert.False(t, tpm.IsOpen())
+
+	// closing twice doesn't unlock an unlocked lock
+	require.NoError(t, tpm.open(ctx))
+	assert.True(t, tpm.IsOpen())
+	require.NoError(t, tpm.Close(ctx))
+	assert.False(t, tpm.IsOpen())
+	require.NoError(t, tpm.Close(ctx))
+	assert.False(t, tpm.IsOpen())
+
+	// the TPM can be opened again
+	require.NoError(t, tpm.open(ctx))
+	assert.True(t, tpm.IsOpen())
+	require.NoError(t, tpm.close(ctx))
+
+	// Close releases a TPM left open by AttestTPM
+	_, cleanup, err := tpm.AttestTPM(ctx)
+	require.NoError(t, err)
+	assert.True(t, tpm.IsOpen())
+	require.NoError(t, tpm.Close(ctx))
+	assert.False(t, tpm.IsOpen())
+	cleanup()
+	assert.False(t, tpm.IsOpen())
+
+	// a TPM that failed to close is closed
+	tpm = newCloseErrorTPM(t)
+	assert.True(t, tpm.IsOpen())
+	assert.EqualError(t, tpm.Close(ctx), "failed closing attest.TPM: closeErr")
+	assert.False(t, tpm.IsOpen())
+	require.NoError(t, tpm.Close(ctx))
+
+	// closing after a failed open is a no-op
+	t.Cleanup(func() { openAttestTPM = attest.OpenTPM })
+	openAttestTPM = func(config *attest.OpenConfig) (*attest.TPM, error) {
+		return nil, errors.New("open failed")
+	}
+	tpm, err = New(WithSimulator(&closeSimulator{}))
+	require.NoError(t, err)
+	require.Error(t, tpm.open(ctx))
+	assert.False(t, tpm.IsOpen())
+	require.NoError(t, tpm.Close(ctx))
+	require.NoError(t, tpm.Close(ctx))
+	assert.False(t, tpm.IsOpen())
+}
+
+func TestTPM_requireVersion20(t *testing.T) {
+	// simulate a TPM 1.2, the version is detected when the TPM is opened
+	version := Version(attest.TPMVersion12)
+	orig := attestTPMVersion
+	t.Cleanup(func() { attestTPMVersion = orig })
+	attestTPMVersion = func(*attest.TPM) Version { return version }
+
+	tpm, err := New(WithSimulator(&closeSimulator{}))
+	require.NoError(t, err)
+
+	// the version is unknown until the TPM is opened
+	require.NoError(t, tpm.requireVersion20("CreateAK"))
+
+	// the info is still returned
+	tpm.info = &Info{
+		Version:   Version(attest.TPMVersion12),
+		Interface: Interface(attest.TPMInterfaceDirect),
+	}
+	ctx := context.Background()
+	info, err := tpm.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "TPM 1.2", info.Version.String())
+
+	assertNotSupported := func(t *testing.T, err error, operation string) {
+		t.Helper()
+		assert.ErrorIs(t, err, ErrNotSupported)
+		var nse *NotSupportedError
+		if assert.ErrorAs(t, err, &nse) {
+			assert.Equal(t, operation, nse.Operation)
+		}
+		assert.EqualError(t, err, "TPM 1.2 not supported for operation "+operation)
+	}
+
+	_, err = tpm.CreateAK(ctx, "ak")
+	assertNotSupported(t, err, "CreateAK")
+	assert.Equal(t, Version(attest.TPMVersion12), tpm.version)
+
+	_, err = tpm.CreateKey(ctx, "key", CreateKeyConfig{Algorithm: "RSA", Size: 2048})
+	assertNotSupported(t, err, "CreateKey")
+
+	_, err = tpm.AttestKey(ctx, "ak", "key", AttestKeyConfig{Algorithm: "RSA", Size: 2048})
+	assertNotSupported(t, err, "AttestKey")
+
+	_, err = tpm.GetSigner(ctx, "key")
+	assertNotSupported(t, err, "GetSigner")
+
+	// TPM 2.0 is supported
+	version = Version(attest.TPMVersion20)
+	tpm, err = New(WithSimulator(&closeSimulator{}))
+	require.NoError(t, err)
+	require.NoError(t, tpm.open(ctx))
+	require.NoError(t, tpm.requireVersion20("CreateAK"))
+	require.NoError(t, tpm.close(ctx))
+}
+
+type observedCall struct {
+	callback  string
+	operation string
+	name      string
+	err       error
+}
+
+type recordingObserver struct {
+	mu    sync.Mutex
+	calls []observedCall
+}
+
+func (o *recordingObserver) record(c observedCall, d time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if d < 0 {
+		c.callback += " (negative duration)"
+	}
+	o.calls = append(o.calls, c)
+}
+
+func (o *recordingObserver) OnOpen(_ context.Context, d time.Duration) {
+	o.record(observedCall{callback: "OnOpen"}, d)
+}
+
+func (o *recordingObserver) OnClose(_ context.Context, d time.Duration) {
+	o.record(observedCall{callback: "OnClose"}, d)
+}
+
+func (o *recordingObserver) OnSign(_ context.Context, name string, d time.Duration) {
+	o.record(observedCall{callback: "OnSign", name: name}, d)
+}
+
+func (o *recordingObserver) OnError(_ context.Context, operation string, err error, d time.Duration) {
+	o.record(observedCall{callback: "OnError", operation: operation, err: err}, d)
+}
+
+func TestWithObserver(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		o := &recordingObserver{}
+		tpm, err := New(WithSimulator(&closeSimulator{}), WithObserver(o))
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		require.NoError(t, tpm.open(ctx))
+		require.NoError(t, tpm.open(internalCall(ctx))) // internal calls are not observed
+		require.NoError(t, tpm.close(internalCall(ctx)))
+		require.NoError(t, tpm.close(ctx))
+		assert.Equal(t, []observedCall{{callback: "OnOpen"}, {callback: "OnClose"}}, o.calls)
+	})
+
+	t.Run("ok nil", func(t *testing.T) {
+		tpm, err := New(WithSimulator(&closeSimulator{}), WithObserver(nil))
+		require.NoError(t, err)
+		require.NoError(t, tpm.open(context.Background()))
+		require.NoError(t, tpm.close(context.Background()))
+	})
+
+	t.Run("fail close", func(t *testing.T) {
+		o := &recordingObserver{}
+		tpm, err := New(WithSimulator(&closeSimulator{closeErr: errors.New("closeErr")}), WithObserver(o))
+		require.NoError(t, err)
+		require.NoError(t, tpm.open(context.Background()))
+		tpm.simulator = nil // required to skip returning when simulator is configured
+
+		err = tpm.close(context.Background())
+		require.EqualError(t, err, "failed closing attest.TPM: closeErr")
+		assert.Equal(t, []observedCall{{callback: "OnOpen"}, {callback: "OnError", operation: OperationClose, err: err}}, o.calls)
+	})
+}
+
+func TestTPM_CreateSigner_fail(t *testing.T) {
+	tpm, err := New()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		uri    string
+		errMsg string
+	}{
+		{"fail scheme", "kms:name=my-key", `failed parsing "kms:name=my-key": error parsing kms:name=my-key: scheme not expected`},
+		{"fail ak", "tpmkms:name=my-ak;ak=true", `failed creating signer for "tpmkms:name=my-ak;ak=true": signing with an AK is not supported`},
+		{"fail name", "tpmkms:foo=bar", `failed parsing "tpmkms:foo=bar": name is required`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := tpm.CreateSigner(context.Background(), tt.uri)
+			assert.EqualError(t, err, tt.errMsg)
+			assert.Nil(t, signer)
+		})
+	}
+}
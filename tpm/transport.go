@@ -0,0 +1,32 @@
+package tpm
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// WithTransport opens the TPM and calls fn with the raw go-tpm transport,
+// allowing TPM commands that are not covered by this package to be issued.
+// The TPM is locked while fn runs, and it is closed after fn returns. The
+// transport must not be closed by fn, and it must not be used after fn
+// returns.
+func (t *TPM) WithTransport(ctx context.Context, fn func(rwc io.ReadWriteCloser) error) (err error) {
+	if err = t.open(goTPMCall(ctx)); err != nil {
+		return fmt.Errorf("failed opening TPM: %w", err)
+	}
+	defer closeTPM(ctx, t, &err)
+
+	return fn(&transport{t.rwc})
+}
+
+// transport wraps the go-tpm transport, preventing it from being closed
+// by the caller of WithTransport.
+type transport struct {
+	io.ReadWriteCloser
+}
+
+// Close is a no-op; the transport is closed when the TPM is closed.
+func (*transport) Close() error {
+	return nil
+}
@@ -0,0 +1,263 @@
+package x509util
+
+import (
+	"encoding/asn1"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// OIDs of the certificate extensions defined in Common PKI (formerly
+// ISIS-MTT).
+var (
+	oidExtensionAdmission             = ObjectIdentifier{1, 3, 36, 8, 3, 3}
+	oidExtensionRestriction           = ObjectIdentifier{1, 3, 36, 8, 3, 8}
+	oidExtensionAdditionalInformation = ObjectIdentifier{1, 3, 36, 8, 3, 15}
+)
+
+// Maximum lengths of the strings in the Common PKI extensions.
+const (
+	maxAdmissionStringLength       = 128
+	maxRestrictionLength           = 1024
+	maxAdditionalInformationLength = 2048
+)
+
+// AdmissionSyntax is the JSON representation of the AdmissionSyntax extension
+// (OID 1.3.36.8.3.3) defined in Common PKI, and used for example in the
+// German healthcare PKI to encode professional information and registration
+// numbers:
+//
+//	AdmissionSyntax ::= SEQUENCE {
+//	  admissionAuthority GeneralName OPTIONAL,
+//	  contentsOfAdmissions SEQUENCE OF Admissions }
+type AdmissionSyntax struct {
+	AdmissionAuthority *SubjectAlternativeName `json:"admissionAuthority,omitempty"`
+	Admissions         []Admissions            `json:"admissions"`
+}
+
+// Admissions is the JSON representation of the Admissions type in the
+// AdmissionSyntax extension:
+//
+//	Admissions ::= SEQUENCE {
+//	  admissionAuthority [0] EXPLICIT GeneralName OPTIONAL,
+//	  namingAuthority [1] EXPLICIT NamingAuthority OPTIONAL,
+//	  professionInfos SEQUENCE OF ProfessionInfo }
+type Admissions struct {
+	AdmissionAuthority *SubjectAlternativeName `json:"admissionAuthority,omitempty"`
+	NamingAuthority    *NamingAuthority        `json:"namingAuthority,omitempty"`
+	ProfessionInfos    []ProfessionInfo        `json:"professionInfos"`
+}
+
+// ProfessionInfo is the JSON representation of the ProfessionInfo type in the
+// AdmissionSyntax extension. The profession items are encoded as UTF8String
+// and the registration number must be a PrintableString:
+//
+//	ProfessionInfo ::= SEQUENCE {
+//	  namingAuthority [0] EXPLICIT NamingAuthority OPTIONAL,
+//	  professionItems SEQUENCE OF DirectoryString (SIZE(1..128)),
+//	  professionOIDs SEQUENCE OF OBJECT IDENTIFIER OPTIONAL,
+//	  registrationNumber PrintableString (SIZE(1..128)) OPTIONAL,
+//	  addProfessionInfo OCTET STRING OPTIONAL }
+type ProfessionInfo struct {
+	NamingAuthority    *NamingAuthority   `json:"namingAuthority,omitempty"`
+	ProfessionItems    []string           `json:"professionItems"`
+	ProfessionOIDs     []ObjectIdentifier `json:"professionOIDs,omitempty"`
+	RegistrationNumber string             `json:"registrationNumber,omitempty"`
+	AddProfessionInfo  []byte             `json:"addProfessionInfo,omitempty"`
+}
+
+// NamingAuthority is the JSON representation of the NamingAuthority type in
+// the AdmissionSyntax extension:
+//
+//	NamingAuthority ::= SEQUENCE {
+//	  namingAuthorityId OBJECT IDENTIFIER OPTIONAL,
+//	  namingAuthorityUrl IA5String OPTIONAL,
+//	  namingAuthorityText DirectoryString(SIZE(1..128)) OPTIONAL }
+type NamingAuthority struct {
+	ID   ObjectIdentifier `json:"id,omitempty"`
+	URL  string           `json:"url,omitempty"`
+	Text string           `json:"text,omitempty"`
+}
+
+// Extension returns the AdmissionSyntax as a non-critical extension.
+func (a AdmissionSyntax) Extension() (Extension, error) {
+	if len(a.Admissions) == 0 {
+		return Extension{}, errors.New("error creating admission extension: admissions cannot be empty")
+	}
+
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		if a.AdmissionAuthority != nil {
+			addAdmissionGeneralName(b, *a.AdmissionAuthority)
+		}
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			for _, adm := range a.Admissions {
+				adm.marshal(b)
+			}
+		})
+	})
+
+	value, err := b.Bytes()
+	if err != nil {
+		return Extension{}, errors.Wrap(err, "error creating admission extension")
+	}
+	return Extension{
+		ID:    oidExtensionAdmission,
+		Value: value,
+	}, nil
+}
+
+func (a Admissions) marshal(b *cryptobyte.Builder) {
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		if a.AdmissionAuthority != nil {
+			b.AddASN1(cryptobyte_asn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+				addAdmissionGeneralName(b, *a.AdmissionAuthority)
+			})
+		}
+		if a.NamingAuthority != nil {
+			b.AddASN1(cryptobyte_asn1.Tag(1).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+				a.NamingAuthority.marshal(b)
+			})
+		}
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			for _, info := range a.ProfessionInfos {
+				info.marshal(b)
+			}
+		})
+	})
+}
+
+func (p ProfessionInfo) marshal(b *cryptobyte.Builder) {
+	if len(p.ProfessionItems) == 0 {
+		b.SetError(errors.New("professionItems cannot be empty"))
+		return
+	}
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		if p.NamingAuthority != nil {
+			b.AddASN1(cryptobyte_asn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+				p.NamingAuthority.marshal(b)
+			})
+		}
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			for _, item := range p.ProfessionItems {
+				addAdmissionDirectoryString(b, "professionItems", item)
+			}
+		})
+		if len(p.ProfessionOIDs) > 0 {
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				for _, oid := range p.ProfessionOIDs {
+					b.AddASN1ObjectIdentifier(asn1.ObjectIdentifier(oid))
+				}
+			})
+		}
+		if p.RegistrationNumber != "" {
+			if len(p.RegistrationNumber) > maxAdmissionStringLength || !isPrintableString(p.RegistrationNumber, false, false) {
+				b.SetError(errors.Errorf("registrationNumber %q is not a valid PrintableString", p.RegistrationNumber))
+				return
+			}
+			b.AddASN1(cryptobyte_asn1.PrintableString, func(b *cryptobyte.Builder) {
+				b.AddBytes([]byte(p.RegistrationNumber))
+			})
+		}
+		if len(p.AddProfessionInfo) > 0 {
+			b.AddASN1OctetString(p.AddProfessionInfo)
+		}
+	})
+}
+
+func (n NamingAuthority) marshal(b *cryptobyte.Builder) {
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		if len(n.ID) > 0 {
+			b.AddASN1ObjectIdentifier(asn1.ObjectIdentifier(n.ID))
+		}
+		if n.URL != "" {
+			if !isIA5String(n.URL) {
+				b.SetError(errors.Errorf("namingAuthority url %q is not a valid IA5String", n.URL))
+				return
+			}
+			b.AddASN1(cryptobyte_asn1.IA5String, func(b *cryptobyte.Builder) {
+				b.AddBytes([]byte(n.URL))
+			})
+		}
+		if n.Text != "" {
+			addAdmissionDirectoryString(b, "namingAuthority text", n.Text)
+		}
+	})
+}
+
+// addAdmissionGeneralName adds the given SAN encoded as a GeneralName.
+func addAdmissionGeneralName(b *cryptobyte.Builder, san SubjectAlternativeName) {
+	rv, err := san.RawValue()
+	if err != nil {
+		b.SetError(errors.Wrap(err, "error encoding admissionAuthority"))
+		return
+	}
+	b.AddBytes(rv.FullBytes)
+}
+
+// addAdmissionDirectoryString adds the given string as a UTF8String with a
+// maximum length of 128 characters.
+func addAdmissionDirectoryString(b *cryptobyte.Builder, name, s string) {
+	if n := utf8.RuneCountInString(s); n == 0 || n > maxAdmissionStringLength || !utf8.ValidString(s) {
+		b.SetError(errors.Errorf("%s %q must be a UTF-8 string between 1 and %d characters", name, s, maxAdmissionStringLength))
+		return
+	}
+	b.AddASN1(cryptobyte_asn1.UTF8String, func(b *cryptobyte.Builder) {
+		b.AddBytes([]byte(s))
+	})
+}
+
+// Restriction is the text in the restriction extension (OID 1.3.36.8.3.8)
+// defined in Common PKI, used to limit the purposes for which the certificate
+// can be used:
+//
+//	RestrictionSyntax ::= DirectoryString (SIZE(1..1024))
+type Restriction string
+
+// Extension returns the restriction as a non-critical extension. The value is
+// a UTF8String.
+func (r Restriction) Extension() (Extension, error) {
+	value, err := marshalCommonPKIDirectoryString(string(r), maxRestrictionLength)
+	if err != nil {
+		return Extension{}, errors.Wrap(err, "error creating restriction extension")
+	}
+	return Extension{
+		ID:    oidExtensionRestriction,
+		Value: value,
+	}, nil
+}
+
+// AdditionalInformation is the text in the additionalInformation extension
+// (OID 1.3.36.8.3.15) defined in Common PKI, used to add information that
+// does not fit in the other extensions:
+//
+//	AdditionalInformationSyntax ::= DirectoryString (SIZE(1..2048))
+type AdditionalInformation string
+
+// Extension returns the additional information as a non-critical extension.
+// The value is a UTF8String.
+func (a AdditionalInformation) Extension() (Extension, error) {
+	value, err := marshalCommonPKIDirectoryString(string(a), maxAdditionalInformationLength)
+	if err != nil {
+		return Extension{}, errors.Wrap(err, "error creating additionalInformation extension")
+	}
+	return Extension{
+		ID:    oidExtensionAdditionalInformation,
+		Value: value,
+	}, nil
+}
+
+// marshalCommonPKIDirectoryString returns the given string encoded as a
+// UTF8String with a maximum length of maxLength characters.
+func marshalCommonPKIDirectoryString(s string, maxLength int) ([]byte, error) {
+	if n := utf8.RuneCountInString(s); n == 0 || n > maxLength || !utf8.ValidString(s) {
+		return nil, errors.Errorf("%q must be a UTF-8 string between 1 and %d characters", s, maxLength)
+	}
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.UTF8String, func(b *cryptobyte.Builder) {
+		b.AddBytes([]byte(s))
+	})
+	return b.Bytes()
+}
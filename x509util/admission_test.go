@@ -0,0 +1,203 @@
+package x509util
+
+import (
+	"encoding/asn1"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testNamingAuthority does not include the url because encoding/asn1 does not
+// differentiate between string types when decoding optional fields.
+type testNamingAuthority struct {
+	ID   asn1.ObjectIdentifier `asn1:"optional"`
+	Text string                `asn1:"optional,utf8"`
+}
+
+type testProfessionInfo struct {
+	ProfessionItems    []string                `asn1:"utf8"`
+	ProfessionOIDs     []asn1.ObjectIdentifier `asn1:"optional"`
+	RegistrationNumber string                  `asn1:"optional,printable"`
+}
+
+type testAdmissions struct {
+	NamingAuthority testNamingAuthority `asn1:"optional,explicit,tag:1"`
+	ProfessionInfos []testProfessionInfo
+}
+
+type testAdmissionSyntax struct {
+	ContentsOfAdmissions []testAdmissions
+}
+
+func TestNewCertificate_admission(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "Dr. Jane Doe", []string{"jane@example.com"})
+	cert, err := NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"sans": {{ toJson .SANs }},
+		"admission": {
+			"admissions": [{
+				"namingAuthority": {"id": "1.2.276.0.76.3.1.91", "text": "gematik"},
+				"professionInfos": [{
+					"professionItems": ["Ärztin/Arzt"],
+					"professionOIDs": ["1.2.276.0.76.4.30"],
+					"registrationNumber": "1-20123456789"
+				}]
+			}]
+		}
+	}`, CreateTemplateData("Dr. Jane Doe", []string{"jane@example.com"})))
+	require.NoError(t, err)
+	require.NotNil(t, cert.Admission)
+
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+	template := cert.GetCertificate()
+	crt, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+	require.NoError(t, err)
+
+	var value []byte
+	for _, ext := range crt.Extensions {
+		if ext.Id.Equal(asn1.ObjectIdentifier(oidExtensionAdmission)) {
+			assert.False(t, ext.Critical)
+			value = ext.Value
+		}
+	}
+	require.NotNil(t, value, "admission extension not found")
+
+	var admission testAdmissionSyntax
+	rest, err := asn1.Unmarshal(value, &admission)
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+	require.Len(t, admission.ContentsOfAdmissions, 1)
+	adm := admission.ContentsOfAdmissions[0]
+	assert.Equal(t, testNamingAuthority{
+		ID:   asn1.ObjectIdentifier{1, 2, 276, 0, 76, 3, 1, 91},
+		Text: "gematik",
+	}, adm.NamingAuthority)
+	require.Len(t, adm.ProfessionInfos, 1)
+	assert.Equal(t, testProfessionInfo{
+		ProfessionItems:    []string{"Ärztin/Arzt"},
+		ProfessionOIDs:     []asn1.ObjectIdentifier{{1, 2, 276, 0, 76, 4, 30}},
+		RegistrationNumber: "1-20123456789",
+	}, adm.ProfessionInfos[0])
+}
+
+func TestNewCertificate_admissionExtension(t *testing.T) {
+	// An explicit extension takes precedence over the typed field.
+	cr, _ := createCertificateRequest(t, "commonName", []string{"foo.com"})
+	cert, err := NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"admission": {"admissions": [{"professionInfos": [{"professionItems": ["foo"]}]}]},
+		"extensions": [{"id": "1.3.36.8.3.3", "value": "MAA="}]
+	}`, CreateTemplateData("commonName", []string{"foo.com"})))
+	require.NoError(t, err)
+	assert.Equal(t, []Extension{
+		{ID: oidExtensionAdmission, Value: []byte{0x30, 0x00}},
+	}, cert.Extensions)
+}
+
+func TestAdmissionSyntax_Extension(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr bool
+	}{
+		{"ok", `{"admissions": [{"professionInfos": [{"professionItems": ["foo"]}]}]}`, false},
+		{"ok authority", `{
+			"admissionAuthority": {"type": "dns", "value": "example.com"},
+			"admissions": [{
+				"admissionAuthority": {"type": "uri", "value": "https://example.com"},
+				"namingAuthority": {"url": "https://example.com/ra"},
+				"professionInfos": [{
+					"namingAuthority": {"text": "foo"},
+					"professionItems": ["foo", "bar"],
+					"addProfessionInfo": "AQID"
+				}]
+			}]
+		}`, false},
+		{"ok empty profession infos", `{"admissions": [{"professionInfos": []}]}`, false},
+		{"fail no admissions", `{"admissions": []}`, true},
+		{"fail no profession items", `{"admissions": [{"professionInfos": [{"registrationNumber": "123"}]}]}`, true},
+		{"fail empty profession item", `{"admissions": [{"professionInfos": [{"professionItems": [""]}]}]}`, true},
+		{"fail registration number", `{"admissions": [{"professionInfos": [{"professionItems": ["foo"], "registrationNumber": "12_34"}]}]}`, true},
+		{"fail naming authority url", `{"admissions": [{"namingAuthority": {"url": "https://exämple.com"}, "professionInfos": []}]}`, true},
+		{"fail admission authority", `{"admissionAuthority": {"type": "foo", "value": "bar"}, "admissions": [{"professionInfos": []}]}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a AdmissionSyntax
+			require.NoError(t, json.Unmarshal([]byte(tt.json), &a))
+			ext, err := a.Extension()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, oidExtensionAdmission, ext.ID)
+			assert.False(t, ext.Critical)
+
+			var v asn1.RawValue
+			rest, err := asn1.Unmarshal(ext.Value, &v)
+			require.NoError(t, err)
+			assert.Empty(t, rest)
+		})
+	}
+}
+
+func TestNewCertificate_restrictionAdditionalInformation(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "Dr. Jane Doe", []string{"jane@example.com"})
+	cert, err := NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"sans": {{ toJson .SANs }},
+		"restriction": "Nur für die Abrechnung",
+		"additionalInformation": "Zusätzliche Angaben"
+	}`, CreateTemplateData("Dr. Jane Doe", []string{"jane@example.com"})))
+	require.NoError(t, err)
+
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+	template := cert.GetCertificate()
+	crt, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+	require.NoError(t, err)
+
+	got := map[string]string{}
+	for _, ext := range crt.Extensions {
+		id := ObjectIdentifier(ext.Id)
+		if id.Equal(oidExtensionRestriction) || id.Equal(oidExtensionAdditionalInformation) {
+			assert.False(t, ext.Critical)
+			var s string
+			rest, err := asn1.UnmarshalWithParams(ext.Value, &s, "utf8")
+			require.NoError(t, err)
+			assert.Empty(t, rest)
+			got[ext.Id.String()] = s
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"1.3.36.8.3.8":  "Nur für die Abrechnung",
+		"1.3.36.8.3.15": "Zusätzliche Angaben",
+	}, got)
+}
+
+func TestRestriction_Extension(t *testing.T) {
+	ext, err := Restriction("foo").Extension()
+	require.NoError(t, err)
+	assert.Equal(t, Extension{ID: oidExtensionRestriction, Value: []byte{0x0c, 0x03, 0x66, 0x6f, 0x6f}}, ext)
+
+	_, err = Restriction("").Extension()
+	assert.Error(t, err)
+	_, err = Restriction(strings.Repeat("a", 1025)).Extension()
+	assert.Error(t, err)
+}
+
+func TestAdditionalInformation_Extension(t *testing.T) {
+	ext, err := AdditionalInformation("foo").Extension()
+	require.NoError(t, err)
+	assert.Equal(t, Extension{ID: oidExtensionAdditionalInformation, Value: []byte{0x0c, 0x03, 0x66, 0x6f, 0x6f}}, ext)
+
+	_, err = AdditionalInformation("").Extension()
+	assert.Error(t, err)
+	_, err = AdditionalInformation(strings.Repeat("a", 2049)).Extension()
+	assert.Error(t, err)
+	_, err = AdditionalInformation(strings.Repeat("a", 2048)).Extension()
+	assert.NoError(t, err)
+}
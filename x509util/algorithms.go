@@ -2,6 +2,10 @@ package x509util
 
 import (
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
 	"crypto/x509"
 	"encoding/asn1"
 	"strings"
@@ -110,3 +114,33 @@ func (s *SignatureAlgorithm) UnmarshalJSON(data []byte) error {
 
 	return errors.Errorf("unsupported signatureAlgorithm %s", name)
 }
+
+// strongestSignatureAlgorithm returns the strongest signature algorithm that
+// can be used with the given public key: the hash size matches the curve size
+// for ECDSA keys, and it grows with the key size for RSA keys. It returns
+// x509.UnknownSignatureAlgorithm if the key is not supported.
+func strongestSignatureAlgorithm(pub crypto.PublicKey) x509.SignatureAlgorithm {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return x509.ECDSAWithSHA256
+		case elliptic.P384():
+			return x509.ECDSAWithSHA384
+		case elliptic.P521():
+			return x509.ECDSAWithSHA512
+		}
+	case *rsa.PublicKey:
+		switch bits := k.N.BitLen(); {
+		case bits >= 4096:
+			return x509.SHA512WithRSA
+		case bits >= 3072:
+			return x509.SHA384WithRSA
+		default:
+			return x509.SHA256WithRSA
+		}
+	case ed25519.PublicKey:
+		return x509.PureEd25519
+	}
+	return x509.UnknownSignatureAlgorithm
+}
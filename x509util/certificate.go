@@ -4,9 +4,20 @@ package x509util
 
 import (
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"sort"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -14,30 +25,74 @@ import (
 // Certificate is the JSON representation of a X.509 certificate. It is used to
 // build a certificate from a template.
 type Certificate struct {
-	Version               int                      `json:"version"`
-	Subject               Subject                  `json:"subject"`
-	Issuer                Issuer                   `json:"issuer"`
-	SerialNumber          SerialNumber             `json:"serialNumber"`
-	DNSNames              MultiString              `json:"dnsNames"`
-	EmailAddresses        MultiString              `json:"emailAddresses"`
-	IPAddresses           MultiIP                  `json:"ipAddresses"`
-	URIs                  MultiURL                 `json:"uris"`
-	SANs                  []SubjectAlternativeName `json:"sans"`
-	Extensions            []Extension              `json:"extensions"`
-	KeyUsage              KeyUsage                 `json:"keyUsage"`
-	ExtKeyUsage           ExtKeyUsage              `json:"extKeyUsage"`
-	UnknownExtKeyUsage    UnknownExtKeyUsage       `json:"unknownExtKeyUsage"`
-	SubjectKeyID          SubjectKeyID             `json:"subjectKeyId"`
-	AuthorityKeyID        AuthorityKeyID           `json:"authorityKeyId"`
-	OCSPServer            OCSPServer               `json:"ocspServer"`
-	IssuingCertificateURL IssuingCertificateURL    `json:"issuingCertificateURL"`
+	// Version uses the same 1-based values as x509.Certificate; 0 is the
+	// default and means v3. It's only checked by Validate, it's never
+	// encoded: the Go standard library always encodes certificates as v3, so
+	// versions 1 and 2 are rejected.
+	Version        int          `json:"version"`
+	Subject        Subject      `json:"subject"`
+	Issuer         Issuer       `json:"issuer"`
+	SerialNumber   SerialNumber `json:"serialNumber"`
+	DNSNames       MultiString  `json:"dnsNames"`
+	EmailAddresses MultiString  `json:"emailAddresses"`
+	IPAddresses    MultiIP      `json:"ipAddresses"`
+	URIs           MultiURL     `json:"uris"`
+	// SANs are always merged with the dedicated fields (dnsNames,
+	// emailAddresses, ipAddresses, and uris), and duplicated values are kept.
+	// If all the SANs are supported by the Go standard library, the
+	// subjectAltName extension is grouped by type (DNS, email, IP, URI), with
+	// the values in the dedicated field first. Otherwise, it contains the
+	// dedicated fields in the same type order followed by all the SANs in
+	// order.
+	SANs []SubjectAlternativeName `json:"sans"`
+	// Extensions take precedence over the fields that are converted into an
+	// extension, like admission or policyMappings; the field is ignored if
+	// an extension with the same OID is defined.
+	Extensions            []Extension           `json:"extensions"`
+	KeyUsage              KeyUsage              `json:"keyUsage"`
+	ExtKeyUsage           ExtKeyUsage           `json:"extKeyUsage"`
+	UnknownExtKeyUsage    UnknownExtKeyUsage    `json:"unknownExtKeyUsage"`
+	SubjectKeyID          SubjectKeyID          `json:"subjectKeyId"`
+	AuthorityKeyID        AuthorityKeyID        `json:"authorityKeyId"`
+	OCSPServer            OCSPServer            `json:"ocspServer"`
+	IssuingCertificateURL IssuingCertificateURL `json:"issuingCertificateURL"`
+	// SubjectInfoAccess is converted into the subject information access
+	// extension (OID 1.3.6.1.5.5.7.1.11).
+	SubjectInfoAccess     SubjectInformationAccess `json:"subjectInformationAccess"`
 	CRLDistributionPoints CRLDistributionPoints    `json:"crlDistributionPoints"`
 	PolicyIdentifiers     PolicyIdentifiers        `json:"policyIdentifiers"`
-	BasicConstraints      *BasicConstraints        `json:"basicConstraints"`
-	NameConstraints       *NameConstraints         `json:"nameConstraints"`
-	SignatureAlgorithm    SignatureAlgorithm       `json:"signatureAlgorithm"`
-	PublicKeyAlgorithm    x509.PublicKeyAlgorithm  `json:"-"`
-	PublicKey             interface{}              `json:"-"`
+	// PolicyMappings is converted into the critical policy mappings extension
+	// (OID 2.5.29.33), as recommended by RFC 5280.
+	PolicyMappings   PolicyMappings    `json:"policyMappings"`
+	BasicConstraints *BasicConstraints `json:"basicConstraints"`
+	NameConstraints  *NameConstraints  `json:"nameConstraints"`
+	// IssuerUniqueID and SubjectUniqueID are the X.509 v2 unique identifiers.
+	// The Go standard library does not support them, so they are not set by
+	// GetCertificate; pass them to CreateCertificate using
+	// WithUniqueIdentifiers.
+	IssuerUniqueID  *UniqueIdentifier `json:"issuerUniqueID"`
+	SubjectUniqueID *UniqueIdentifier `json:"subjectUniqueID"`
+	// Admission is converted into the AdmissionSyntax extension (OID
+	// 1.3.36.8.3.3).
+	Admission *AdmissionSyntax `json:"admission"`
+	// Restriction and AdditionalInformation are converted into the Common PKI
+	// restriction (OID 1.3.36.8.3.8) and additionalInformation (OID
+	// 1.3.36.8.3.15) non-critical extensions.
+	Restriction           Restriction           `json:"restriction"`
+	AdditionalInformation AdditionalInformation `json:"additionalInformation"`
+	// OCSPNoCheck adds the non-critical id-pkix-ocsp-nocheck extension (OID
+	// 1.3.6.1.5.5.7.48.1.5) used in OCSP responder certificates, with an
+	// ASN.1 NULL value, as defined in RFC 6960.
+	OCSPNoCheck bool `json:"ocspNoCheck"`
+	// NetscapeCertType and NetscapeComment are converted into the legacy
+	// Netscape certificate type (OID 2.16.840.1.113730.1.1) and comment (OID
+	// 2.16.840.1.113730.1.13) extensions, only meant for interoperability
+	// with old systems.
+	NetscapeCertType   NetscapeCertType        `json:"netscapeCertType"`
+	NetscapeComment    NetscapeComment         `json:"netscapeComment"`
+	SignatureAlgorithm SignatureAlgorithm      `json:"signatureAlgorithm"`
+	PublicKeyAlgorithm x509.PublicKeyAlgorithm `json:"-"`
+	PublicKey          interface{}             `json:"-"`
 }
 
 // NewCertificate creates a new Certificate from an x509.CertificateRequest and
@@ -81,11 +136,170 @@ func NewCertificateFromX509(template *x509.Certificate, opts ...Option) (*Certif
 	return newCertificateWithOptions(csr, o)
 }
 
+// ParseTemplate parses a PEM or DER encoded certificate or certificate request
+// and returns a Certificate that can be edited and used as a template.
+//
+// Certificates are converted using NewCertificateFromX509, and their
+// extensions, except the subject and authority key identifiers, are kept as
+// extensions of the template. Certificate requests are converted using
+// NewCertificate, so their signature must be valid.
+func ParseTemplate(pemOrDER []byte, opts ...Option) (*Certificate, error) {
+	var der []byte
+	var isRequest bool
+	if block, _ := pem.Decode(pemOrDER); block != nil {
+		switch block.Type {
+		case "CERTIFICATE":
+		case "CERTIFICATE REQUEST", "NEW CERTIFICATE REQUEST":
+			isRequest = true
+		default:
+			return nil, errors.Errorf("error parsing template: unsupported PEM type %s", block.Type)
+		}
+		der = block.Bytes
+	} else {
+		der = pemOrDER
+		if _, err := x509.ParseCertificate(der); err != nil {
+			isRequest = true
+		}
+	}
+
+	if isRequest {
+		cr, err := x509.ParseCertificateRequest(der)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing template")
+		}
+		return NewCertificate(cr, opts...)
+	}
+
+	crt, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing template")
+	}
+	template := *crt
+	template.ExtraExtensions = nil
+	for _, ext := range crt.Extensions {
+		if id := ObjectIdentifier(ext.Id); id.Equal(oidExtensionSubjectKeyID) || id.Equal(oidExtensionAuthorityKeyID) {
+			continue
+		}
+		template.ExtraExtensions = append(template.ExtraExtensions, ext)
+	}
+	return NewCertificateFromX509(&template, opts...)
+}
+
 // newCertificateWithOptions creates a new Certificate from an x509.CertificateRequest
 // with options applied. If no template was applied, the data from the x509.CertificateRequest
 // will simply be copied over and returned with the default leaf key usages. Otherwise, the
 // data from the template will be filled in.
 func newCertificateWithOptions(csr *x509.CertificateRequest, o *Options) (*Certificate, error) {
+	cert, err := newCertificateFromBuffer(csr, o)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply programmatic options on top of the template or the defaults.
+	for _, fn := range o.modifiers {
+		if err := fn(cert); err != nil {
+			return nil, err
+		}
+	}
+
+	// Generate the subjectAltName extension if the certificate contains SANs
+	// that are not supported in the Go standard library.
+	if cert.hasExtendedSANs() && !cert.hasExtension(oidExtensionSubjectAltName) {
+		ext, err := createCertificateSubjectAltNameExtension(*cert, cert.Subject.IsEmpty())
+		if err != nil {
+			return nil, err
+		}
+		// Prepend extension to achieve a certificate as similar as possible to
+		// the one generated by the Go standard library.
+		cert.Extensions = append([]Extension{ext}, cert.Extensions...)
+	}
+
+	// Generate the nameConstraints extension if it contains directory names,
+	// they are not supported in the Go standard library.
+	if nc := cert.NameConstraints; nc != nil && nc.hasDirectoryNames() && !cert.hasExtension(oidExtensionNameConstraints) {
+		ext, err := nc.Extension()
+		if err != nil {
+			return nil, err
+		}
+		cert.Extensions = append(cert.Extensions, ext)
+	}
+
+	// Generate the admission extension from the typed field.
+	if cert.Admission != nil && !cert.hasExtension(oidExtensionAdmission) {
+		ext, err := cert.Admission.Extension()
+		if err != nil {
+			return nil, err
+		}
+		cert.Extensions = append(cert.Extensions, ext)
+	}
+
+	// Generate the Common PKI text extensions from the typed fields.
+	if cert.Restriction != "" && !cert.hasExtension(oidExtensionRestriction) {
+		ext, err := cert.Restriction.Extension()
+		if err != nil {
+			return nil, err
+		}
+		cert.Extensions = append(cert.Extensions, ext)
+	}
+	if cert.AdditionalInformation != "" && !cert.hasExtension(oidExtensionAdditionalInformation) {
+		ext, err := cert.AdditionalInformation.Extension()
+		if err != nil {
+			return nil, err
+		}
+		cert.Extensions = append(cert.Extensions, ext)
+	}
+
+	// Generate the subject information access extension from the typed field.
+	if len(cert.SubjectInfoAccess) > 0 && !cert.hasExtension(oidExtensionSubjectInfoAccess) {
+		ext, err := cert.SubjectInfoAccess.Extension()
+		if err != nil {
+			return nil, err
+		}
+		cert.Extensions = append(cert.Extensions, ext)
+	}
+
+	// Generate the policy mappings extension from the typed field.
+	if len(cert.PolicyMappings) > 0 && !cert.hasExtension(oidExtensionPolicyMappings) {
+		ext, err := cert.PolicyMappings.Extension()
+		if err != nil {
+			return nil, err
+		}
+		cert.Extensions = append(cert.Extensions, ext)
+	}
+
+	// Generate the legacy Netscape extensions from the typed fields.
+	if cert.NetscapeCertType != 0 && !cert.hasExtension(oidExtensionNetscapeCertType) {
+		ext, err := cert.NetscapeCertType.Extension()
+		if err != nil {
+			return nil, err
+		}
+		cert.Extensions = append(cert.Extensions, ext)
+	}
+	if cert.NetscapeComment != "" && !cert.hasExtension(oidExtensionNetscapeComment) {
+		ext, err := cert.NetscapeComment.Extension()
+		if err != nil {
+			return nil, err
+		}
+		cert.Extensions = append(cert.Extensions, ext)
+	}
+
+	if o.validate {
+		if err := cert.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if o.validateIPNameConstraints {
+		if err := cert.ValidateIPNameConstraints(); err != nil {
+			return nil, err
+		}
+	}
+
+	return cert, nil
+}
+
+// newCertificateFromBuffer creates a new Certificate from the template in the
+// options, or from the certificate request if no template was applied.
+func newCertificateFromBuffer(csr *x509.CertificateRequest, o *Options) (*Certificate, error) {
 	// If no template is set, use only the certificate request with the
 	// default leaf key usages.
 	if o.CertBuffer == nil {
@@ -102,24 +316,59 @@ func newCertificateWithOptions(csr *x509.CertificateRequest, o *Options) (*Certi
 	cert.PublicKey = csr.PublicKey
 	cert.PublicKeyAlgorithm = csr.PublicKeyAlgorithm
 
-	// Generate the subjectAltName extension if the certificate contains SANs
-	// that are not supported in the Go standard library.
-	if cert.hasExtendedSANs() && !cert.hasExtension(oidExtensionSubjectAltName) {
-		ext, err := createCertificateSubjectAltNameExtension(cert, cert.Subject.IsEmpty())
+	return &cert, nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Extended key usages
+// not supported by crypto/x509, like "smartCardLogon", or OIDs can be used in
+// the extKeyUsage field, and they will be added to the unknownExtKeyUsage.
+func (c *Certificate) UnmarshalJSON(data []byte) error {
+	type certificateAlias Certificate
+	var v struct {
+		*certificateAlias
+		ExtKeyUsage json.RawMessage `json:"extKeyUsage"`
+	}
+	v.certificateAlias = (*certificateAlias)(c)
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if len(v.ExtKeyUsage) == 0 {
+		return nil
+	}
+
+	ms, err := unmarshalMultiString(v.ExtKeyUsage)
+	if err != nil {
+		return err
+	}
+	eku := make(ExtKeyUsage, 0, len(ms))
+	for _, s := range ms {
+		if ku, ok := parseExtKeyUsage(s); ok {
+			eku = append(eku, ku)
+			continue
+		}
+		oid, err := parseUnknownExtKeyUsage(s)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		// Prepend extension to achieve a certificate as similar as possible to
-		// the one generated by the Go standard library.
-		cert.Extensions = append([]Extension{ext}, cert.Extensions...)
+		c.UnknownExtKeyUsage = append(c.UnknownExtKeyUsage, oid)
 	}
-
-	return &cert, nil
+	c.ExtKeyUsage = eku
+	return nil
 }
 
 // GetCertificate returns the x509.Certificate representation of the
 // certificate.
-func (c *Certificate) GetCertificate() *x509.Certificate {
+//
+// The extensions marked to be removed in the extensions are not set, even if
+// they are defined by the typed fields. The extensions generated by
+// x509.CreateCertificate, like the subject key identifier of a CA, or the
+// authority key identifier copied from the parent, are not affected.
+func (c *Certificate) GetCertificate(opts ...GetCertificateOption) *x509.Certificate {
+	o := new(getCertificateOptions)
+	for _, fn := range opts {
+		fn(o)
+	}
+
 	cert := new(x509.Certificate)
 
 	// Unparsed data
@@ -128,6 +377,9 @@ func (c *Certificate) GetCertificate() *x509.Certificate {
 
 	// Subject
 	c.Subject.Set(cert)
+	if o.subjectOrder != nil {
+		cert.RawSubject = sortedRawName(cert.Subject, o.subjectOrder)
+	}
 
 	// When we have no extended SANs, use the golang x509 lib to create the
 	// extension instead
@@ -143,35 +395,342 @@ func (c *Certificate) GetCertificate() *x509.Certificate {
 		}
 	}
 
-	// Defined extensions.
-	c.KeyUsage.Set(cert)
-	c.ExtKeyUsage.Set(cert)
-	c.UnknownExtKeyUsage.Set(cert)
-	c.SubjectKeyID.Set(cert)
-	c.AuthorityKeyID.Set(cert)
-	c.OCSPServer.Set(cert)
-	c.IssuingCertificateURL.Set(cert)
-	c.CRLDistributionPoints.Set(cert)
-	c.PolicyIdentifiers.Set(cert)
-	if c.BasicConstraints != nil {
+	// Defined extensions, unless they are marked to be removed.
+	if !c.isRemoved(oidExtensionKeyUsage) {
+		c.KeyUsage.Set(cert)
+	}
+	if !c.isRemoved(oidExtensionExtendedKeyUsage) {
+		c.ExtKeyUsage.Set(cert)
+		c.UnknownExtKeyUsage.Set(cert)
+	}
+	if !c.isRemoved(oidExtensionSubjectKeyID) {
+		c.SubjectKeyID.Set(cert)
+	}
+	if !c.isRemoved(oidExtensionAuthorityKeyID) {
+		c.AuthorityKeyID.Set(cert)
+	}
+	if !c.isRemoved(oidExtensionAuthorityInfoAccess) {
+		c.OCSPServer.Set(cert)
+		c.IssuingCertificateURL.Set(cert)
+	}
+	if !c.isRemoved(oidExtensionCRLDistributionPoints) {
+		c.CRLDistributionPoints.Set(cert)
+	}
+	if !c.isRemoved(oidExtensionCertificatePolicies) {
+		c.PolicyIdentifiers.Set(cert)
+	}
+	if c.BasicConstraints != nil && !c.isRemoved(oidExtensionBasicConstraints) {
 		c.BasicConstraints.Set(cert)
 	}
-	if c.NameConstraints != nil {
+	if c.NameConstraints != nil && !c.isRemoved(oidExtensionNameConstraints) {
 		c.NameConstraints.Set(cert)
 	}
+	if c.OCSPNoCheck && !c.isRemoved(oidExtensionOCSPNoCheck) && !c.hasExtension(oidExtensionOCSPNoCheck) {
+		cert.ExtraExtensions = append(cert.ExtraExtensions, pkix.Extension{
+			Id:    asn1.ObjectIdentifier(oidExtensionOCSPNoCheck),
+			Value: ocspNoCheckValue,
+		})
+	}
 
 	// Custom Extensions.
 	for _, e := range c.Extensions {
-		e.Set(cert)
+		if !c.isRemoved(e.ID) {
+			e.Set(cert)
+		}
 	}
 
 	// Others.
 	c.SerialNumber.Set(cert)
 	c.SignatureAlgorithm.Set(cert)
 
+	if o.extensionOrder != nil {
+		sortExtensions(cert.ExtraExtensions, o.extensionOrder)
+	}
+
 	return cert
 }
 
+// GetCertificateOption is the type used as a variadic argument in
+// Certificate.GetCertificate.
+type GetCertificateOption func(o *getCertificateOptions)
+
+type getCertificateOptions struct {
+	extensionOrder []asn1.ObjectIdentifier
+	subjectOrder   []asn1.ObjectIdentifier
+}
+
+// WithExtensionOrder is an option that sorts the ExtraExtensions of the
+// x509.Certificate returned by GetCertificate using the given order of OIDs,
+// e.g. the order of the extensions in a reference certificate. Extensions with
+// OIDs not in the list are placed at the end, keeping their relative order.
+//
+// x509.CreateCertificate always encodes the extensions generated from the
+// typed fields before the ExtraExtensions, so an exact order can only be
+// achieved if all the extensions are defined in the extensions field.
+func WithExtensionOrder(oids []asn1.ObjectIdentifier) GetCertificateOption {
+	return func(o *getCertificateOptions) {
+		o.extensionOrder = oids
+	}
+}
+
+// WithSubjectOrder is an option that sets the RawSubject of the
+// x509.Certificate returned by GetCertificate to the subject with its relative
+// distinguished names sorted using the given order of attribute OIDs, e.g.
+// {2.5.4.10, 2.5.4.3} to encode the organization first and the common name
+// next. Attributes not in the list are placed at the end, keeping the order
+// used by the Go standard library: country, province, locality, street
+// address, postal code, organization, organizational unit, common name, serial
+// number, and the extra names.
+//
+// If the subject cannot be encoded, the RawSubject is not set and the default
+// order is used.
+func WithSubjectOrder(oids []asn1.ObjectIdentifier) GetCertificateOption {
+	return func(o *getCertificateOptions) {
+		o.subjectOrder = oids
+	}
+}
+
+// sortedRawName returns the DER encoding of the given name with the relative
+// distinguished names sorted using the given order of attribute OIDs. It
+// returns nil if the name cannot be encoded.
+func sortedRawName(name pkix.Name, order []asn1.ObjectIdentifier) []byte {
+	rdns := name.ToRDNSequence()
+	index := func(rdn pkix.RelativeDistinguishedNameSET) int {
+		if len(rdn) > 0 {
+			for i, o := range order {
+				if o.Equal(rdn[0].Type) {
+					return i
+				}
+			}
+		}
+		return len(order)
+	}
+	sort.SliceStable(rdns, func(i, j int) bool {
+		return index(rdns[i]) < index(rdns[j])
+	})
+	b, err := asn1.Marshal(rdns)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// sortExtensions sorts the given extensions in place using the given order of
+// OIDs. Unknown OIDs are placed at the end.
+func sortExtensions(extensions []pkix.Extension, order []asn1.ObjectIdentifier) {
+	index := func(oid asn1.ObjectIdentifier) int {
+		for i, o := range order {
+			if o.Equal(oid) {
+				return i
+			}
+		}
+		return len(order)
+	}
+	sort.SliceStable(extensions, func(i, j int) bool {
+		return index(extensions[i].Id) < index(extensions[j].Id)
+	})
+}
+
+// ApplyLeafProfile sets the default key usages of a leaf certificate, the
+// same ones used by DefaultLeafTemplate. The key usage is set to
+// digitalSignature, and to digitalSignature and keyEncipherment if the public
+// key is an RSA key, and the extended key usage is set to serverAuth and
+// clientAuth. Other fields are not modified.
+func (c *Certificate) ApplyLeafProfile() {
+	keyUsage := x509.KeyUsageDigitalSignature
+	if _, ok := c.PublicKey.(*rsa.PublicKey); ok {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	c.KeyUsage = KeyUsage(keyUsage)
+	c.ExtKeyUsage = ExtKeyUsage([]x509.ExtKeyUsage{
+		x509.ExtKeyUsageServerAuth,
+		x509.ExtKeyUsageClientAuth,
+	})
+}
+
+// ApplyCAProfile sets the default key usages and basic constraints of a CA
+// certificate, like DefaultIntermediateTemplate and DefaultRootTemplate. The
+// key usage is set to certSign and crlSign, and the basic constraints to a CA
+// with the given maximum path length; a negative pathLen means that the path
+// length is not limited. The extended key usage is not modified.
+func (c *Certificate) ApplyCAProfile(pathLen int) {
+	c.KeyUsage = KeyUsage(x509.KeyUsageCertSign | x509.KeyUsageCRLSign)
+	c.BasicConstraints = &BasicConstraints{
+		IsCA:       true,
+		MaxPathLen: pathLen,
+	}
+}
+
+// Validate checks that the version of the certificate is 3, the only version
+// that can be encoded, or not set. It also checks that the
+// timeStamping extended key usage, if present, is the only one and it's in a
+// critical extension, as required by RFC 3161 for time stamping authorities,
+// and that a custom basic constraints extension of a CA is critical, as
+// required by RFC 5280. NewCertificate only runs these checks if the
+// WithValidation option is used.
+func (c *Certificate) Validate() error {
+	switch c.Version {
+	case 0, 3:
+		if err := c.validateBasicConstraints(); err != nil {
+			return err
+		}
+		return c.validateTimeStamping()
+	case 1, 2:
+		if c.hasV3Fields() {
+			return errors.Errorf("invalid certificate version %d: extensions and SANs require version 3", c.Version)
+		}
+		return errors.Errorf("invalid certificate version %d: certificates are always encoded as version 3", c.Version)
+	default:
+		return errors.Errorf("invalid certificate version %d: version must be 3", c.Version)
+	}
+}
+
+// validateBasicConstraints validates that, if the certificate contains a
+// custom basic constraints extension with the cA flag set, the extension is
+// critical. The Go standard library always marks the extension generated from
+// the BasicConstraints field as critical.
+func (c *Certificate) validateBasicConstraints() error {
+	ext, ok := c.getExtension(oidExtensionBasicConstraints)
+	if !ok || ext.Critical {
+		return nil
+	}
+	var bc struct {
+		IsCA       bool `asn1:"optional"`
+		MaxPathLen int  `asn1:"optional,default:-1"`
+	}
+	if _, err := asn1.Unmarshal(ext.Value, &bc); err != nil {
+		return nil //nolint:nilerr // not a valid extension, nothing to validate
+	}
+	if bc.IsCA {
+		return errors.New("invalid certificate: the basic constraints extension must be critical in a CA certificate")
+	}
+	return nil
+}
+
+// oidExtKeyUsageTimeStamping is the OID of the timeStamping extended key
+// usage.
+var oidExtKeyUsageTimeStamping = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 8}
+
+// validateTimeStamping validates that, if the certificate contains the
+// timeStamping extended key usage, it's the only extended key usage, and the
+// extension is critical, as required by RFC 3161. Unless a custom extension is
+// used, the extension is only critical if WithCriticalExtKeyUsage is used.
+func (c *Certificate) validateTimeStamping() error {
+	if c.isRemoved(oidExtensionExtendedKeyUsage) {
+		return nil
+	}
+
+	var critical bool
+	var oids []asn1.ObjectIdentifier
+	if ext, ok := c.getExtension(oidExtensionExtendedKeyUsage); ok {
+		if _, err := asn1.Unmarshal(ext.Value, &oids); err != nil {
+			return nil //nolint:nilerr // not a valid extension, nothing to validate
+		}
+		critical = ext.Critical
+	} else {
+		for _, u := range c.ExtKeyUsage {
+			oids = append(oids, extKeyUsageOIDs[u])
+		}
+		oids = append(oids, c.UnknownExtKeyUsage...)
+	}
+
+	for _, oid := range oids {
+		if !oid.Equal(oidExtKeyUsageTimeStamping) {
+			continue
+		}
+		if len(oids) > 1 {
+			return errors.New("invalid certificate: the timeStamping extended key usage must be the only extended key usage")
+		}
+		if !critical {
+			return errors.New("invalid certificate: the extended key usage extension must be critical if it contains timeStamping")
+		}
+	}
+	return nil
+}
+
+// ValidateIPNameConstraints checks that the IP SANs of the certificate are in
+// the permitted IP ranges, and not in the excluded IP ranges, of its own name
+// constraints. It can be used to detect self-contradictory CA templates.
+//
+// Per RFC 5280 the name constraints of a CA do not apply to the CA certificate
+// itself, so this check is not part of Validate, and it's only performed by
+// NewCertificate if the WithIPNameConstraintsValidation option is used. As in
+// the Go standard library, if permitted IP ranges are defined, an IP must be
+// in one of them, even if they are for a different IP version.
+func (c *Certificate) ValidateIPNameConstraints() error {
+	nc := c.NameConstraints
+	if nc == nil || c.isRemoved(oidExtensionNameConstraints) ||
+		(len(nc.PermittedIPRanges) == 0 && len(nc.ExcludedIPRanges) == 0) {
+		return nil
+	}
+
+	for _, ip := range c.ipSANs() {
+		for _, ipNet := range nc.ExcludedIPRanges {
+			if ipNet.Contains(ip) {
+				return errors.Errorf("invalid certificate: ipAddress %s is in the excluded IP range %s", ip, ipNet)
+			}
+		}
+		if len(nc.PermittedIPRanges) == 0 {
+			continue
+		}
+		var permitted bool
+		for _, ipNet := range nc.PermittedIPRanges {
+			if ipNet.Contains(ip) {
+				permitted = true
+				break
+			}
+		}
+		if !permitted {
+			return errors.Errorf("invalid certificate: ipAddress %s is not in the permitted IP ranges", ip)
+		}
+	}
+	return nil
+}
+
+// ipSANs returns the IP addresses in the ipAddresses and sans fields.
+func (c *Certificate) ipSANs() []net.IP {
+	ips := append([]net.IP{}, c.IPAddresses...)
+	for _, san := range c.SANs {
+		typ := san.Type
+		if typ == "" || typ == AutoType {
+			typ = ClassifySAN(san.Value)
+		}
+		if typ == IPType {
+			if ip := net.ParseIP(san.Value); ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	return ips
+}
+
+// hasV3Fields returns true if the certificate contains any field that will be
+// encoded as an extension.
+func (c *Certificate) hasV3Fields() bool {
+	for _, e := range c.Extensions {
+		if !e.Remove {
+			return true
+		}
+	}
+	return len(c.DNSNames) > 0 || len(c.EmailAddresses) > 0 || len(c.IPAddresses) > 0 ||
+		len(c.URIs) > 0 || len(c.SANs) > 0 || c.KeyUsage != 0 || len(c.ExtKeyUsage) > 0 ||
+		len(c.UnknownExtKeyUsage) > 0 || len(c.SubjectKeyID) > 0 || len(c.AuthorityKeyID) > 0 ||
+		len(c.OCSPServer) > 0 || len(c.IssuingCertificateURL) > 0 || len(c.CRLDistributionPoints) > 0 ||
+		len(c.PolicyIdentifiers) > 0 || c.BasicConstraints != nil || c.NameConstraints != nil ||
+		c.Admission != nil || c.Restriction != "" || c.AdditionalInformation != "" || c.OCSPNoCheck || len(c.SubjectInfoAccess) > 0 ||
+		c.NetscapeCertType != 0 || c.NetscapeComment != "" || len(c.PolicyMappings) > 0
+}
+
+// TBSCertificate returns the DER encoding of the TBSCertificate of the
+// certificate issued by the given parent, so it can be signed by an external
+// service. If parent is nil, the certificate is self-signed.
+//
+// The Certificate type does not define the validity period, use
+// CreateTBSCertificate with the result of GetCertificate to set it.
+func (c *Certificate) TBSCertificate(parent *x509.Certificate) ([]byte, error) {
+	return CreateTBSCertificate(c.GetCertificate(), parent, c.PublicKey, WithUniqueIdentifiers(c.IssuerUniqueID, c.SubjectUniqueID))
+}
+
 // hasExtendedSANs returns true if the certificate contains any SAN types that
 // are not supported by the golang x509 library (i.e. RegisteredID, OtherName,
 // DirectoryName, X400Address, or EDIPartyName)
@@ -186,6 +745,28 @@ func (c *Certificate) hasExtendedSANs() bool {
 	return false
 }
 
+// isRemoved returns true if the extension with the given oid is marked to be
+// removed in the extensions.
+func (c *Certificate) isRemoved(oid ObjectIdentifier) bool {
+	for _, e := range c.Extensions {
+		if e.Remove && e.ID.Equal(oid) {
+			return true
+		}
+	}
+	return false
+}
+
+// getExtension returns the custom extension with the given OID, ignoring the
+// ones marked to be removed.
+func (c *Certificate) getExtension(oid ObjectIdentifier) (Extension, bool) {
+	for _, e := range c.Extensions {
+		if !e.Remove && e.ID.Equal(oid) {
+			return e, true
+		}
+	}
+	return Extension{}, false
+}
+
 // hasExtension returns true if the given extension oid is in the certificate.
 func (c *Certificate) hasExtension(oid ObjectIdentifier) bool {
 	for _, e := range c.Extensions {
@@ -197,34 +778,310 @@ func (c *Certificate) hasExtension(oid ObjectIdentifier) bool {
 }
 
 // CreateCertificate signs the given template using the parent private key and
-// returns it.
-func CreateCertificate(template, parent *x509.Certificate, pub crypto.PublicKey, signer crypto.Signer) (*x509.Certificate, error) {
-	var err error
+// returns it. The options related to the validity period, like WithBackdate,
+// are applied to the template before signing it. The serial number and the
+// subject key identifier are generated if they are not set in the template,
+// use WithNoSerialGeneration and WithNoSKIGeneration to disable it.
+func CreateCertificate(template, parent *x509.Certificate, pub crypto.PublicKey, signer crypto.Signer, opts ...CreateCertificateOption) (*x509.Certificate, error) {
+	template, o, err := prepareTemplate(template, parent, pub, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sign certificate
+	var asn1Data []byte
+	if o.issuerUniqueID == nil && o.subjectUniqueID == nil {
+		asn1Data, err = x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating certificate")
+		}
+	} else {
+		// The Go standard library does not support unique identifiers, so the
+		// TBSCertificate is created with them and signed here.
+		tbs, sigAlg, err := createTBSCertificate(template, parent, pub, o)
+		if err != nil {
+			return nil, err
+		}
+		signature, err := signTBSCertificate(tbs, sigAlg, signer)
+		if err != nil {
+			return nil, err
+		}
+		if asn1Data, err = AssembleCertificate(tbs, sigAlg, signature); err != nil {
+			return nil, err
+		}
+	}
+	cert, err := x509.ParseCertificate(asn1Data)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing certificate")
+	}
+	return cert, nil
+}
+
+// DefaultIssueValidity is the validity period of the certificates created
+// with Issue.
+const DefaultIssueValidity = 24 * time.Hour
+
+// Issue creates a certificate from the given template and certificate
+// request, and signs it with the parent certificate and signer. It combines
+// NewCertificate, GetCertificate and CreateCertificate, and the options are
+// passed to CreateCertificate.
+//
+// The template data contains the subject common name and the SANs in the
+// certificate request. If template is nil, the DefaultLeafTemplate is used.
+// The certificate is valid from now for the DefaultIssueValidity, but never
+// after the parent certificate expires.
+func Issue(template io.Reader, cr *x509.CertificateRequest, parent *x509.Certificate, signer crypto.Signer, opts ...CreateCertificateOption) (*x509.Certificate, error) {
+	text := DefaultLeafTemplate
+	if template != nil {
+		b, err := io.ReadAll(template)
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading template")
+		}
+		text = string(b)
+	}
+
+	sans := append([]string{}, cr.DNSNames...)
+	sans = append(sans, cr.EmailAddresses...)
+	for _, ip := range cr.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, u := range cr.URIs {
+		sans = append(sans, u.String())
+	}
+	data := CreateTemplateData(cr.Subject.CommonName, sans)
+
+	cert, err := NewCertificate(cr, WithTemplate(text, data))
+	if err != nil {
+		return nil, err
+	}
+
+	tpl := cert.GetCertificate()
+	tpl.NotBefore = time.Now().Truncate(time.Second)
+	tpl.NotAfter = tpl.NotBefore.Add(DefaultIssueValidity)
+	if tpl.NotAfter.After(parent.NotAfter) {
+		tpl.NotAfter = parent.NotAfter
+	}
+	opts = append(opts[:len(opts):len(opts)], WithUniqueIdentifiers(cert.IssuerUniqueID, cert.SubjectUniqueID))
+	return CreateCertificate(tpl, parent, tpl.PublicKey, signer, opts...)
+}
+
+// prepareTemplate applies the options to the template and completes it with
+// a serial number and a subject key identifier if they are not set, unless
+// their generation is disabled. It returns the prepared template and the
+// applied options.
+func prepareTemplate(template, parent *x509.Certificate, pub crypto.PublicKey, opts []CreateCertificateOption) (*x509.Certificate, *createCertificateOptions, error) {
+	o, err := new(createCertificateOptions).apply(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if o.backdate > 0 {
+		template = backdateTemplate(template, parent, o.backdate)
+	}
+
 	// Complete certificate.
 	if template.SerialNumber == nil {
-		if template.SerialNumber, err = generateSerialNumber(); err != nil {
-			return nil, err
+		if o.noSerial {
+			return nil, nil, errors.New("error creating certificate: serial number is not set")
+		}
+		if template.SerialNumber, err = generateCheckedSerialNumber(o.checker); err != nil {
+			return nil, nil, err
 		}
 	}
 	if template.SubjectKeyId == nil {
-		if template.SubjectKeyId, err = generateSubjectKeyID(pub); err != nil {
-			return nil, err
+		if o.noSKI {
+			return nil, nil, errors.New("error creating certificate: subject key identifier is not set")
+		}
+		if template.SubjectKeyId, err = GenerateSubjectKeyID(pub, o.skiMethod); err != nil {
+			return nil, nil, err
 		}
 	}
 
-	// Sign certificate
-	asn1Data, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+	if o.autoSigAlg && template.SignatureAlgorithm == x509.UnknownSignatureAlgorithm {
+		signerKey := pub
+		if parent != nil && parent != template && parent.PublicKey != nil {
+			signerKey = parent.PublicKey
+		}
+		template.SignatureAlgorithm = strongestSignatureAlgorithm(signerKey)
+	}
+
+	// Expand the CRL distribution points that depend on the serial number.
+	// The template is copied so it can be used again.
+	if hasCRLDistributionPointPlaceholders(template.CRLDistributionPoints) {
+		cdp, err := expandCRLDistributionPoints(template.CRLDistributionPoints, template.SerialNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+		tpl := *template
+		tpl.CRLDistributionPoints = cdp
+		template = &tpl
+	}
+
+	return template, o, nil
+}
+
+// CreateTBSCertificate returns the DER encoding of the TBSCertificate, the
+// part of the certificate that is signed, of the certificate that
+// CreateCertificate would create. It allows the certificate to be signed by
+// an external service, like an HSM or a KMS. If parent is nil, the certificate
+// is self-signed.
+//
+// The signature algorithm is the one in the template or, if it is not set, the
+// default one for the public key of the parent, and it must be used to sign
+// the returned bytes. The signature can be assembled into the final
+// certificate using AssembleCertificate.
+func CreateTBSCertificate(template, parent *x509.Certificate, pub crypto.PublicKey, opts ...CreateCertificateOption) ([]byte, error) {
+	if parent == nil {
+		parent = template
+	}
+	template, o, err := prepareTemplate(template, parent, pub, opts)
 	if err != nil {
-		return nil, errors.Wrap(err, "error creating certificate")
+		return nil, err
 	}
-	cert, err := x509.ParseCertificate(asn1Data)
+	tbs, _, err := createTBSCertificate(template, parent, pub, o)
+	return tbs, err
+}
+
+// createTBSCertificate returns the DER encoding of the TBSCertificate of the
+// prepared template, including the unique identifiers in the options, and the
+// signature algorithm that must be used to sign it.
+func createTBSCertificate(template, parent *x509.Certificate, pub crypto.PublicKey, o *createCertificateOptions) ([]byte, x509.SignatureAlgorithm, error) {
+	// Sign the certificate with a key of the same type as the issuer key, so
+	// the Go standard library sets the right signature algorithm.
+	issuer := new(x509.Certificate)
+	*issuer = *parent
+	signer, err := newPlaceholderSigner(issuer.PublicKey)
 	if err != nil {
-		return nil, errors.Wrap(err, "error parsing certificate")
+		return nil, 0, err
 	}
-	return cert, nil
+	issuer.PublicKey = signer.Public()
+
+	asn1Data, err := x509.CreateCertificate(rand.Reader, template, issuer, pub, signer)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "error creating certificate")
+	}
+	crt, err := x509.ParseCertificate(asn1Data)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "error parsing certificate")
+	}
+	if o.issuerUniqueID == nil && o.subjectUniqueID == nil {
+		return crt.RawTBSCertificate, crt.SignatureAlgorithm, nil
+	}
+
+	// Add unique identifiers
+	var tbs tbsCertificate
+	if _, err := asn1.Unmarshal(crt.RawTBSCertificate, &tbs); err != nil {
+		return nil, 0, errors.Wrap(err, "error unmarshaling tbsCertificate")
+	}
+	tbs.Raw = nil
+	if o.issuerUniqueID != nil {
+		tbs.IssuerUniqueID = asn1.BitString(*o.issuerUniqueID)
+	}
+	if o.subjectUniqueID != nil {
+		tbs.SubjectUniqueID = asn1.BitString(*o.subjectUniqueID)
+	}
+	b, err := asn1.Marshal(tbs)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "error creating certificate")
+	}
+	return b, crt.SignatureAlgorithm, nil
+}
+
+// AssembleCertificate returns the DER encoding of the certificate with the
+// given TBSCertificate, signature algorithm and signature. It completes the
+// external signing workflow started by CreateTBSCertificate: the tbs bytes
+// are signed by an external service, like an HSM or a KMS, and the signature
+// is assembled into the final certificate.
+//
+// The signature algorithm must match the one in the TBSCertificate, as
+// required by RFC 5280, and the algorithm identifier, including its
+// parameters, is copied from it. The signature is not verified.
+func AssembleCertificate(tbs []byte, sigAlg x509.SignatureAlgorithm, signature []byte) ([]byte, error) {
+	var tbsCert tbsCertificate
+	rest, err := asn1.Unmarshal(tbs, &tbsCert)
+	if err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling tbsCertificate")
+	} else if len(rest) != 0 {
+		return nil, errors.New("error unmarshaling tbsCertificate: trailing data")
+	}
+
+	// Some algorithms have more than one identifier.
+	var supported, matches bool
+	for _, m := range signatureAlgorithmMapping {
+		if m.value == sigAlg && m.oid != nil {
+			supported = true
+			if m.oid.Equal(tbsCert.SignatureAlgorithm.Algorithm) {
+				matches = true
+				break
+			}
+		}
+	}
+	switch {
+	case !supported:
+		return nil, errors.Errorf("error assembling certificate: unsupported signature algorithm %s", sigAlg)
+	case !matches:
+		return nil, errors.Errorf("error assembling certificate: signature algorithm %s does not match the tbsCertificate signature algorithm %s", sigAlg, tbsCert.SignatureAlgorithm.Algorithm)
+	case len(signature) == 0:
+		return nil, errors.New("error assembling certificate: signature cannot be empty")
+	}
+
+	asn1Data, err := asn1.Marshal(certificate{
+		TBSCertificate:     tbsCert,
+		SignatureAlgorithm: tbsCert.SignatureAlgorithm,
+		SignatureValue: asn1.BitString{
+			Bytes:     signature,
+			BitLength: len(signature) * 8,
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error assembling certificate")
+	}
+	return asn1Data, nil
+}
+
+// newPlaceholderSigner returns a new signer with a key of the same type as the
+// given public key. It's used to create certificates that will be signed again
+// with the real key.
+func newPlaceholderSigner(pub crypto.PublicKey) (crypto.Signer, error) {
+	var (
+		signer crypto.Signer
+		err    error
+	)
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		signer, err = ecdsa.GenerateKey(k.Curve, rand.Reader)
+	case *rsa.PublicKey:
+		// The size of the key does not change the signature algorithm.
+		signer, err = rsa.GenerateKey(rand.Reader, 1024)
+	case ed25519.PublicKey:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, errors.Errorf("unsupported public key type %T", pub)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error generating key")
+	}
+	return signer, nil
+}
+
+// backdateTemplate returns a copy of the template with the NotBefore set to
+// the given duration before now. The NotBefore is clamped to the NotBefore of
+// the parent, unless the template is self-signed.
+func backdateTemplate(template, parent *x509.Certificate, d time.Duration) *x509.Certificate {
+	notBefore := time.Now().Add(-d).Truncate(time.Second)
+	if parent != nil && parent != template && notBefore.Before(parent.NotBefore) {
+		notBefore = parent.NotBefore
+	}
+	tpl := *template
+	tpl.NotBefore = notBefore
+	return &tpl
 }
 
 // CreateCertificateTemplate creates a X.509 certificate template from the given certificate request.
+//
+// All the extensions in the certificate request are copied to the template,
+// including extensions like basicConstraints. Use
+// CreateCertificateTemplateWithPolicy to only copy a set of allowed
+// extensions.
 func CreateCertificateTemplate(cr *x509.CertificateRequest) (*x509.Certificate, error) {
 	if err := cr.CheckSignature(); err != nil {
 		return nil, errors.Wrap(err, "error validating certificate request")
@@ -232,15 +1089,109 @@ func CreateCertificateTemplate(cr *x509.CertificateRequest) (*x509.Certificate,
 	// Set SubjectAltName extension as critical if Subject is empty.
 	fixSubjectAltName(cr)
 
+	return newCertificateTemplate(cr, cr.Extensions), nil
+}
+
+// CreateCertificateTemplateWithPolicy creates a X.509 certificate template
+// from the given certificate request, copying only the extensions in the
+// certificate request with an identifier in the allowed list. Any other
+// extension, including basicConstraints, is dropped unless it is explicitly
+// allowed.
+//
+// The subject and the subject alternative names are always copied to the
+// template, but the subjectAltName extension is only copied if it is allowed.
+// Subject alternative names not supported by the x509 package, like otherName,
+// will be dropped if it is not.
+func CreateCertificateTemplateWithPolicy(cr *x509.CertificateRequest, allowed []ObjectIdentifier) (*x509.Certificate, error) {
+	if err := cr.CheckSignature(); err != nil {
+		return nil, errors.Wrap(err, "error validating certificate request")
+	}
+	// Set SubjectAltName extension as critical if Subject is empty.
+	fixSubjectAltName(cr)
+
+	var extensions []pkix.Extension
+	for _, ext := range cr.Extensions {
+		for _, oid := range allowed {
+			if oid.Equal(ObjectIdentifier(ext.Id)) {
+				extensions = append(extensions, ext)
+				break
+			}
+		}
+	}
+
+	return newCertificateTemplate(cr, extensions), nil
+}
+
+// newCertificateTemplate creates a X.509 certificate template from the given
+// certificate request and extensions.
+func newCertificateTemplate(cr *x509.CertificateRequest, extensions []pkix.Extension) *x509.Certificate {
 	return &x509.Certificate{
 		Subject:            cr.Subject,
 		DNSNames:           cr.DNSNames,
 		EmailAddresses:     cr.EmailAddresses,
 		IPAddresses:        cr.IPAddresses,
 		URIs:               cr.URIs,
-		ExtraExtensions:    cr.Extensions,
+		ExtraExtensions:    extensions,
 		PublicKey:          cr.PublicKey,
 		PublicKeyAlgorithm: cr.PublicKeyAlgorithm,
 		SignatureAlgorithm: 0,
-	}, nil
+	}
+}
+
+type tbsCertificate struct {
+	Raw                asn1.RawContent
+	Version            int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber       *big.Int
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Issuer             asn1.RawValue
+	Validity           asn1.RawValue
+	Subject            asn1.RawValue
+	PublicKey          asn1.RawValue
+	IssuerUniqueID     asn1.BitString   `asn1:"optional,tag:1"`
+	SubjectUniqueID    asn1.BitString   `asn1:"optional,tag:2"`
+	Extensions         []pkix.Extension `asn1:"omitempty,optional,explicit,tag:3"`
+}
+
+type certificate struct {
+	TBSCertificate     tbsCertificate
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+// signTBSCertificate signs the DER encoding of a TBSCertificate with the given
+// signer and signature algorithm. Like the Go standard library, it verifies
+// the signature to detect faulty signers.
+func signTBSCertificate(tbs []byte, sigAlg x509.SignatureAlgorithm, signer crypto.Signer) ([]byte, error) {
+	var opts crypto.SignerOpts
+	for _, m := range signatureAlgorithmMapping {
+		if m.value == sigAlg {
+			opts = m.hash
+			break
+		}
+	}
+	if opts == nil {
+		return nil, errors.Errorf("error creating certificate: unsupported signature algorithm %s", sigAlg)
+	}
+	switch sigAlg {
+	case x509.SHA256WithRSAPSS, x509.SHA384WithRSAPSS, x509.SHA512WithRSAPSS:
+		opts = &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthEqualsHash,
+			Hash:       opts.HashFunc(),
+		}
+	}
+
+	signed := tbs
+	if hashFunc := opts.HashFunc(); hashFunc != 0 {
+		h := hashFunc.New()
+		h.Write(signed)
+		signed = h.Sum(nil)
+	}
+	signature, err := signer.Sign(rand.Reader, signed, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating certificate")
+	}
+	if err := (&x509.Certificate{PublicKey: signer.Public()}).CheckSignature(sigAlg, tbs, signature); err != nil {
+		return nil, errors.Wrap(err, "error creating certificate: signature verification failed")
+	}
+	return signature, nil
 }
@@ -3,7 +3,6 @@ package x509util
 import (
 	"crypto"
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
@@ -40,8 +39,9 @@ type certificateRequest struct {
 	SignatureValue     asn1.BitString
 }
 
-// CertificateRequest is the JSON representation of an X.509 certificate. It is
-// used to build a certificate request from a template.
+// CertificateRequest is the JSON representation of an X.509 certificate
+// request. It is used to build a certificate request from a template, see
+// NewCertificateRequest and CreateCertificateRequestFromModel.
 type CertificateRequest struct {
 	Version            int                      `json:"version"`
 	Subject            Subject                  `json:"subject"`
@@ -86,19 +86,27 @@ func NewCertificateRequest(signer crypto.Signer, opts ...Option) (*CertificateRe
 	cr.PublicKey = pub
 	cr.Signer = signer
 
-	// Generate the subjectAltName extension if the certificate contains SANs
-	// that are not supported in the Go standard library.
-	if cr.hasExtendedSANs() && !cr.hasExtension(oidExtensionSubjectAltName) {
-		ext, err := createCertificateRequestSubjectAltNameExtension(cr, cr.Subject.IsEmpty())
+	if err := cr.addSubjectAltNameExtension(); err != nil {
+		return nil, err
+	}
+
+	return &cr, nil
+}
+
+// addSubjectAltNameExtension generates the subjectAltName extension if the
+// certificate request contains SANs that are not supported in the Go standard
+// library.
+func (c *CertificateRequest) addSubjectAltNameExtension() error {
+	if c.hasExtendedSANs() && !c.hasExtension(oidExtensionSubjectAltName) {
+		ext, err := createCertificateRequestSubjectAltNameExtension(*c, c.Subject.IsEmpty())
 		if err != nil {
-			return nil, err
+			return err
 		}
 		// Prepend extension to achieve a certificate as similar as possible to
 		// the one generated by the Go standard library.
-		cr.Extensions = append([]Extension{ext}, cr.Extensions...)
+		c.Extensions = append([]Extension{ext}, c.Extensions...)
 	}
-
-	return &cr, nil
+	return nil
 }
 
 // NewCertificateRequestFromX509 creates a CertificateRequest from an
@@ -295,17 +303,8 @@ func (c *CertificateRequest) GetCertificate() *Certificate {
 // to guarantee that the certificate signer can sign with the CertificateRequest
 // SignatureAlgorithm.
 func (c *CertificateRequest) GetLeafCertificate() *Certificate {
-	keyUsage := x509.KeyUsageDigitalSignature
-	if _, ok := c.PublicKey.(*rsa.PublicKey); ok {
-		keyUsage |= x509.KeyUsageKeyEncipherment
-	}
-
 	cert := c.GetCertificate()
-	cert.KeyUsage = KeyUsage(keyUsage)
-	cert.ExtKeyUsage = ExtKeyUsage([]x509.ExtKeyUsage{
-		x509.ExtKeyUsageServerAuth,
-		x509.ExtKeyUsageClientAuth,
-	})
+	cert.ApplyLeafProfile()
 	return cert
 }
 
@@ -353,6 +352,30 @@ func CreateCertificateRequest(commonName string, sans []string, signer crypto.Si
 	return x509.ParseCertificateRequest(asn1Data)
 }
 
+// CreateCertificateRequestFromModel creates and signs an X.509 certificate
+// request using the given model, e.g. one decoded from JSON, and signer. The
+// public key of the certificate request is the public key of the signer. As in
+// NewCertificateRequest, the subjectAltName extension is generated if the
+// model contains SANs that are not supported in the Go standard library. The
+// model is not modified.
+func CreateCertificateRequestFromModel(model *CertificateRequest, signer crypto.Signer) (*x509.CertificateRequest, error) {
+	switch {
+	case model == nil:
+		return nil, errors.New("error creating certificate request: model cannot be nil")
+	case signer == nil:
+		return nil, errors.New("error creating certificate request: signer cannot be nil")
+	}
+
+	cr := *model
+	cr.PublicKey = signer.Public()
+	cr.Signer = signer
+	if err := cr.addSubjectAltNameExtension(); err != nil {
+		return nil, err
+	}
+
+	return cr.GetCertificateRequest()
+}
+
 // fixSubjectAltName makes sure to mark the SAN extension to critical if the
 // subject is empty.
 func fixSubjectAltName(cr *x509.CertificateRequest) {
@@ -9,6 +9,7 @@ import (
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/base64"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"net"
@@ -754,3 +755,95 @@ func TestCreateCertificateRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateCertificateRequestFromModel(t *testing.T) {
+	_, signer, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	var model CertificateRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"subject": {"commonName": "foo.com", "organization": "Smallstep"},
+		"dnsNames": ["foo.com", "www.foo.com"],
+		"emailAddresses": "jane@foo.com",
+		"ipAddresses": ["10.0.0.1", "::1"],
+		"uris": "spiffe://foo.com/bar",
+		"extensions": [{"id": "1.2.3.4", "critical": true, "value": "Zm9vYmFy"}],
+		"signatureAlgorithm": "Ed25519"
+	}`), &model))
+
+	csr, err := CreateCertificateRequestFromModel(&model, signer)
+	require.NoError(t, err)
+	require.NoError(t, csr.CheckSignature())
+	assert.Equal(t, signer.Public(), csr.PublicKey)
+	assert.Equal(t, x509.PureEd25519, csr.SignatureAlgorithm)
+	assert.Equal(t, "foo.com", csr.Subject.CommonName)
+	assert.Equal(t, []string{"Smallstep"}, csr.Subject.Organization)
+	assert.Equal(t, []string{"foo.com", "www.foo.com"}, csr.DNSNames)
+	assert.Equal(t, []string{"jane@foo.com"}, csr.EmailAddresses)
+	assert.Equal(t, "10.0.0.1", csr.IPAddresses[0].String())
+	assert.Equal(t, "::1", csr.IPAddresses[1].String())
+	assert.Equal(t, "spiffe://foo.com/bar", csr.URIs[0].String())
+	assert.Contains(t, csr.Extensions, pkix.Extension{Id: asn1.ObjectIdentifier{1, 2, 3, 4}, Critical: true, Value: []byte("foobar")})
+
+	// The model is not modified.
+	assert.Nil(t, model.Signer)
+	assert.Nil(t, model.PublicKey)
+
+	// A model created from the certificate request creates an equivalent one.
+	b, err := json.Marshal(NewCertificateRequestFromX509(csr))
+	require.NoError(t, err)
+	var roundTrip CertificateRequest
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	roundTrip.SignatureAlgorithm = model.SignatureAlgorithm
+	csr2, err := CreateCertificateRequestFromModel(&roundTrip, signer)
+	require.NoError(t, err)
+	assert.Equal(t, csr.RawTBSCertificateRequest, csr2.RawTBSCertificateRequest)
+}
+
+func TestCreateCertificateRequestFromModel_extendedSANs(t *testing.T) {
+	_, signer, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	var model CertificateRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"subject": {"commonName": "foo.com"},
+		"sans": [{"type": "dns", "value": "foo.com"}, {"type": "permanentIdentifier", "value": "123456"}]
+	}`), &model))
+
+	csr, err := CreateCertificateRequestFromModel(&model, signer)
+	require.NoError(t, err)
+	assert.Empty(t, model.Extensions)
+	assert.Equal(t, []string{"foo.com"}, csr.DNSNames)
+
+	var sanExt *pkix.Extension
+	for i, ext := range csr.Extensions {
+		if ext.Id.Equal(oidExtensionSubjectAltName) {
+			sanExt = &csr.Extensions[i]
+		}
+	}
+	require.NotNil(t, sanExt)
+	assert.False(t, sanExt.Critical)
+
+	// Same extension as the one created from a template.
+	cr, err := NewCertificateRequest(signer, WithTemplate(`{
+		"subject": {"commonName": "foo.com"},
+		"sans": [{"type": "dns", "value": "foo.com"}, {"type": "permanentIdentifier", "value": "123456"}]
+	}`, nil))
+	require.NoError(t, err)
+	require.NotEmpty(t, cr.Extensions)
+	assert.Equal(t, cr.Extensions[0].Value, sanExt.Value)
+}
+
+func TestCreateCertificateRequestFromModel_fail(t *testing.T) {
+	_, signer, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	_, err = CreateCertificateRequestFromModel(nil, signer)
+	assert.EqualError(t, err, "error creating certificate request: model cannot be nil")
+	_, err = CreateCertificateRequestFromModel(&CertificateRequest{}, nil)
+	assert.EqualError(t, err, "error creating certificate request: signer cannot be nil")
+	_, err = CreateCertificateRequestFromModel(&CertificateRequest{
+		SANs: []SubjectAlternativeName{{Type: "foo", Value: "bar"}},
+	}, signer)
+	assert.Error(t, err)
+}
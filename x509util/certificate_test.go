@@ -7,17 +7,24 @@ import (
 	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"io"
 	"math/big"
 	"net"
 	"net/url"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/stretchr/testify/assert"
@@ -414,6 +421,72 @@ func TestNewCertificateTemplate(t *testing.T) {
 
 }
 
+func TestParseTemplate(t *testing.T) {
+	crtPEM, err := os.ReadFile("testdata/google.crt")
+	require.NoError(t, err)
+	block, _ := pem.Decode(crtPEM)
+	require.NotNil(t, block)
+	crtDER := block.Bytes
+	csrPEM, err := os.ReadFile("testdata/challengePassword.csr")
+	require.NoError(t, err)
+
+	assertCertificate := func(t *testing.T, cert *Certificate) {
+		t.Helper()
+		assert.Equal(t, "www.google.com", cert.Subject.CommonName)
+		assert.Equal(t, MultiString{"Google LLC"}, cert.Subject.Organization)
+		assert.Equal(t, MultiString{"www.google.com"}, cert.DNSNames)
+		assert.NotNil(t, cert.PublicKey)
+		assert.NotEmpty(t, cert.Extensions)
+		for _, ext := range cert.Extensions {
+			assert.False(t, ext.ID.Equal(oidExtensionSubjectKeyID), "unexpected subjectKeyId extension")
+			assert.False(t, ext.ID.Equal(oidExtensionAuthorityKeyID), "unexpected authorityKeyId extension")
+		}
+	}
+
+	t.Run("ok pem certificate", func(t *testing.T) {
+		cert, err := ParseTemplate(crtPEM)
+		require.NoError(t, err)
+		assertCertificate(t, cert)
+	})
+
+	t.Run("ok der certificate", func(t *testing.T) {
+		cert, err := ParseTemplate(crtDER)
+		require.NoError(t, err)
+		assertCertificate(t, cert)
+	})
+
+	t.Run("ok pem certificate request", func(t *testing.T) {
+		cert, err := ParseTemplate(csrPEM)
+		require.NoError(t, err)
+		assert.Equal(t, "commonName", cert.Subject.CommonName)
+		assert.NotNil(t, cert.PublicKey)
+		assert.Equal(t, KeyUsage(x509.KeyUsageDigitalSignature|x509.KeyUsageKeyEncipherment), cert.KeyUsage)
+	})
+
+	t.Run("ok der certificate request with options", func(t *testing.T) {
+		block, _ := pem.Decode(csrPEM)
+		require.NotNil(t, block)
+		cert, err := ParseTemplate(block.Bytes, WithTemplate(`{"subject": {"commonName": "overridden"}}`, TemplateData{}))
+		require.NoError(t, err)
+		assert.Equal(t, "overridden", cert.Subject.CommonName)
+	})
+
+	t.Run("fail pem type", func(t *testing.T) {
+		_, err := ParseTemplate(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{1, 2, 3}}))
+		assert.EqualError(t, err, "error parsing template: unsupported PEM type PRIVATE KEY")
+	})
+
+	t.Run("fail pem certificate", func(t *testing.T) {
+		_, err := ParseTemplate(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1, 2, 3}}))
+		assert.Error(t, err)
+	})
+
+	t.Run("fail der", func(t *testing.T) {
+		_, err := ParseTemplate([]byte("not a certificate"))
+		assert.Error(t, err)
+	})
+}
+
 func TestNewCertificateFromX509(t *testing.T) {
 	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	require.NoError(t, err)
@@ -571,6 +644,55 @@ func TestNewCertificateFromX509(t *testing.T) {
 	}
 }
 
+func TestCertificate_UnmarshalJSON_extKeyUsage(t *testing.T) {
+	smartCardLogon := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2, 2}
+	documentSigning := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 10, 3, 12}
+	tests := []struct {
+		name                   string
+		data                   string
+		wantExtKeyUsage        ExtKeyUsage
+		wantUnknownExtKeyUsage UnknownExtKeyUsage
+		wantErr                bool
+	}{
+		{"ok", `{"extKeyUsage": ["serverAuth", "clientAuth"]}`, ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}, nil, false},
+		{"ok microsoft", `{"extKeyUsage": ["clientAuth", "smartCardLogon", "documentSigning"]}`,
+			ExtKeyUsage{x509.ExtKeyUsageClientAuth}, UnknownExtKeyUsage{smartCardLogon, documentSigning}, false},
+		{"ok oid", `{"extKeyUsage": "1.2.3.4", "unknownExtKeyUsage": ["smart_card_logon"]}`,
+			ExtKeyUsage{}, UnknownExtKeyUsage{smartCardLogon, {1, 2, 3, 4}}, false},
+		{"ok missing", `{"unknownExtKeyUsage": "1.2.3.4"}`, nil, UnknownExtKeyUsage{{1, 2, 3, 4}}, false},
+		{"fail name", `{"extKeyUsage": ["fooBar"]}`, nil, nil, true},
+		{"fail type", `{"extKeyUsage": 1}`, nil, nil, true},
+		{"fail other field", `{"extKeyUsage": "serverAuth", "keyUsage": "fooBar"}`, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cert Certificate
+			err := json.Unmarshal([]byte(tt.data), &cert)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantExtKeyUsage, cert.ExtKeyUsage)
+			assert.Equal(t, tt.wantUnknownExtKeyUsage, cert.UnknownExtKeyUsage)
+		})
+	}
+}
+
+func TestNewCertificate_microsoftExtKeyUsage(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "jane@example.com", nil)
+	cert, err := NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"keyUsage": ["digitalSignature"],
+		"extKeyUsage": ["clientAuth", "smartCardLogon"]
+	}`, CreateTemplateData("jane@example.com", nil)))
+	require.NoError(t, err)
+
+	crt := cert.GetCertificate()
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, crt.ExtKeyUsage)
+	assert.Equal(t, []asn1.ObjectIdentifier{{1, 3, 6, 1, 4, 1, 311, 20, 2, 2}}, crt.UnknownExtKeyUsage)
+}
+
 func TestCertificate_GetCertificate(t *testing.T) {
 	type fields struct {
 		Version               int
@@ -703,6 +825,318 @@ func TestCertificate_GetCertificate(t *testing.T) {
 	}
 }
 
+func TestCertificate_GetCertificate_withExtensionOrder(t *testing.T) {
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+	_, priv := createCertificateRequest(t, "commonName", nil)
+
+	ext := func(oid ObjectIdentifier) Extension {
+		return Extension{ID: oid, Value: []byte("value")}
+	}
+	c := &Certificate{
+		Subject:      Subject{CommonName: "commonName"},
+		SerialNumber: SerialNumber{big.NewInt(1)},
+		PublicKey:    priv.Public(),
+		Extensions: []Extension{
+			ext(ObjectIdentifier{1, 2, 3, 1}),
+			ext(ObjectIdentifier{1, 2, 3, 2}),
+			ext(ObjectIdentifier{1, 2, 3, 3}),
+			ext(ObjectIdentifier{1, 2, 3, 4}),
+		},
+	}
+	order := []asn1.ObjectIdentifier{{1, 2, 3, 3}, {1, 2, 3, 5}, {1, 2, 3, 1}}
+	want := []string{"1.2.3.3", "1.2.3.1", "1.2.3.2", "1.2.3.4"}
+
+	getOIDs := func(extensions []pkix.Extension) []string {
+		var oids []string
+		for _, e := range extensions {
+			if len(e.Id) == 4 && e.Id[0] == 1 && e.Id[1] == 2 && e.Id[2] == 3 {
+				oids = append(oids, e.Id.String())
+			}
+		}
+		return oids
+	}
+
+	// Without options the order of the template is kept.
+	assert.Equal(t, []string{"1.2.3.1", "1.2.3.2", "1.2.3.3", "1.2.3.4"}, getOIDs(c.GetCertificate().ExtraExtensions))
+
+	template := c.GetCertificate(WithExtensionOrder(order))
+	assert.Equal(t, want, getOIDs(template.ExtraExtensions))
+	assert.Equal(t, []string{"1.2.3.1", "1.2.3.2", "1.2.3.3", "1.2.3.4"}, getOIDs(c.GetCertificate().ExtraExtensions))
+
+	// The order is preserved in the signed certificate.
+	template.NotBefore = time.Now()
+	template.NotAfter = template.NotBefore.Add(time.Hour)
+	cert, err := CreateCertificate(template, iss, priv.Public(), issPriv)
+	require.NoError(t, err)
+	assert.Equal(t, want, getOIDs(cert.Extensions))
+}
+
+func TestCertificate_GetCertificate_withSubjectOrder(t *testing.T) {
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+	_, priv := createCertificateRequest(t, "commonName", nil)
+
+	c := &Certificate{
+		Subject: Subject{
+			Country:            []string{"US"},
+			Organization:       []string{"Smallstep"},
+			OrganizationalUnit: []string{"Engineering"},
+			CommonName:         "commonName",
+		},
+		SerialNumber: SerialNumber{big.NewInt(1)},
+		PublicKey:    priv.Public(),
+	}
+
+	getTypes := func(t *testing.T, raw []byte) []string {
+		t.Helper()
+		var rdns pkix.RDNSequence
+		rest, err := asn1.Unmarshal(raw, &rdns)
+		require.NoError(t, err)
+		require.Empty(t, rest)
+		var types []string
+		for _, rdn := range rdns {
+			for _, atv := range rdn {
+				types = append(types, atv.Type.String())
+			}
+		}
+		return types
+	}
+
+	// Without options the RawSubject is not set.
+	assert.Empty(t, c.GetCertificate().RawSubject)
+
+	oidCountry := asn1.ObjectIdentifier{2, 5, 4, 6}
+	oidOrganization := asn1.ObjectIdentifier{2, 5, 4, 10}
+	oidCommonName := asn1.ObjectIdentifier{2, 5, 4, 3}
+
+	tests := []struct {
+		name  string
+		order []asn1.ObjectIdentifier
+		want  []string
+	}{
+		{"organization first", []asn1.ObjectIdentifier{oidOrganization}, []string{"2.5.4.10", "2.5.4.6", "2.5.4.11", "2.5.4.3"}},
+		{"common name first", []asn1.ObjectIdentifier{oidCommonName, oidOrganization, oidCountry}, []string{"2.5.4.3", "2.5.4.10", "2.5.4.6", "2.5.4.11"}},
+		{"unknown attributes", []asn1.ObjectIdentifier{{1, 2, 3, 4}, oidCommonName}, []string{"2.5.4.3", "2.5.4.6", "2.5.4.10", "2.5.4.11"}},
+		{"empty order", []asn1.ObjectIdentifier{}, []string{"2.5.4.6", "2.5.4.10", "2.5.4.11", "2.5.4.3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := c.GetCertificate(WithSubjectOrder(tt.order))
+			assert.Equal(t, tt.want, getTypes(t, template.RawSubject))
+
+			// The order is preserved in the signed certificate.
+			template.NotBefore = time.Now()
+			template.NotAfter = template.NotBefore.Add(time.Hour)
+			cert, err := CreateCertificate(template, iss, priv.Public(), issPriv)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, getTypes(t, cert.RawSubject))
+			assert.Equal(t, "commonName", cert.Subject.CommonName)
+			assert.Equal(t, []string{"Smallstep"}, cert.Subject.Organization)
+		})
+	}
+}
+
+func TestCertificate_ApplyLeafProfile(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		publicKey    crypto.PublicKey
+		wantKeyUsage x509.KeyUsage
+	}{
+		{"ecdsa", ecKey.Public(), x509.KeyUsageDigitalSignature},
+		{"rsa", rsaKey.Public(), x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment},
+		{"ed25519", edPub, x509.KeyUsageDigitalSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Certificate{
+				Subject:   Subject{CommonName: "leaf"},
+				DNSNames:  []string{"leaf.example.com"},
+				KeyUsage:  KeyUsage(x509.KeyUsageCertSign),
+				PublicKey: tt.publicKey,
+			}
+			c.ApplyLeafProfile()
+
+			cert := c.GetCertificate()
+			assert.Equal(t, tt.wantKeyUsage, cert.KeyUsage)
+			assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}, cert.ExtKeyUsage)
+			assert.False(t, cert.BasicConstraintsValid)
+			assert.False(t, cert.IsCA)
+			assert.Equal(t, []string{"leaf.example.com"}, cert.DNSNames)
+		})
+	}
+}
+
+func TestCertificate_ApplyCAProfile(t *testing.T) {
+	tests := []struct {
+		name               string
+		pathLen            int
+		wantMaxPathLen     int
+		wantMaxPathLenZero bool
+	}{
+		{"ok pathLen 0", 0, 0, true},
+		{"ok pathLen 1", 1, 1, false},
+		{"ok no pathLen", -1, -1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Certificate{
+				Subject:     Subject{CommonName: "CA"},
+				ExtKeyUsage: ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+			}
+			c.ApplyCAProfile(tt.pathLen)
+			require.NoError(t, c.Validate())
+
+			cert := c.GetCertificate()
+			assert.Equal(t, x509.KeyUsageCertSign|x509.KeyUsageCRLSign, cert.KeyUsage)
+			assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning}, cert.ExtKeyUsage)
+			assert.True(t, cert.BasicConstraintsValid)
+			assert.True(t, cert.IsCA)
+			assert.Equal(t, tt.wantMaxPathLen, cert.MaxPathLen)
+			assert.Equal(t, tt.wantMaxPathLenZero, cert.MaxPathLenZero)
+		})
+	}
+}
+
+func TestCertificate_Validate(t *testing.T) {
+	uid := &UniqueIdentifier{Bytes: []byte{0x01}, BitLength: 8}
+	tsaEKU := []byte{0x30, 0x0a, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x05, 0x05, 0x07, 0x03, 0x08}
+	tests := []struct {
+		name    string
+		cert    *Certificate
+		wantErr bool
+	}{
+		{"ok default", &Certificate{DNSNames: []string{"foo.com"}}, false},
+		{"ok v3", &Certificate{Version: 3, SubjectUniqueID: uid, DNSNames: []string{"foo.com"}}, false},
+		{"ok v3 extensions", &Certificate{Version: 3, Extensions: []Extension{{ID: []int{1, 2, 3, 4}, Value: []byte("foo")}}}, false},
+		{"fail negative", &Certificate{Version: -1}, true},
+		{"fail v4", &Certificate{Version: 4}, true},
+		{"fail v1", &Certificate{Version: 1, Subject: Subject{CommonName: "foo"}}, true},
+		{"fail v2", &Certificate{Version: 2, SubjectUniqueID: uid}, true},
+		{"fail v1 removed extension", &Certificate{Version: 1, Extensions: []Extension{{ID: []int{2, 5, 29, 15}, Remove: true}}}, true},
+		{"fail v1 sans", &Certificate{Version: 1, SANs: []SubjectAlternativeName{{Type: DNSType, Value: "foo.com"}}}, true},
+		{"fail v1 extensions", &Certificate{Version: 1, Extensions: []Extension{{ID: []int{1, 2, 3, 4}, Value: []byte("foo")}}}, true},
+		{"fail v1 keyUsage", &Certificate{Version: 1, KeyUsage: KeyUsage(x509.KeyUsageDigitalSignature)}, true},
+		{"fail v1 uniqueID", &Certificate{Version: 1, IssuerUniqueID: uid}, true},
+		{"fail v2 dnsNames", &Certificate{Version: 2, DNSNames: []string{"foo.com"}}, true},
+		{"fail v2 basicConstraints", &Certificate{Version: 2, BasicConstraints: &BasicConstraints{IsCA: true}}, true},
+		{"ok timeStamping critical", &Certificate{Extensions: []Extension{{ID: []int{2, 5, 29, 37}, Critical: true, Value: tsaEKU}}}, false},
+		{"ok timeStamping removed", &Certificate{ExtKeyUsage: ExtKeyUsage{x509.ExtKeyUsageTimeStamping}, Extensions: []Extension{{ID: []int{2, 5, 29, 37}, Remove: true}}}, false},
+		{"ok other extKeyUsage", &Certificate{ExtKeyUsage: ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}}, false},
+		{"fail timeStamping not critical", &Certificate{ExtKeyUsage: ExtKeyUsage{x509.ExtKeyUsageTimeStamping}}, true},
+		{"fail timeStamping extension not critical", &Certificate{Extensions: []Extension{{ID: []int{2, 5, 29, 37}, Value: tsaEKU}}}, true},
+		{"fail timeStamping with other", &Certificate{ExtKeyUsage: ExtKeyUsage{x509.ExtKeyUsageTimeStamping, x509.ExtKeyUsageServerAuth}}, true},
+		{"fail timeStamping with unknown", &Certificate{ExtKeyUsage: ExtKeyUsage{x509.ExtKeyUsageTimeStamping}, UnknownExtKeyUsage: UnknownExtKeyUsage{{1, 2, 3, 4}}}, true},
+		{"ok basicConstraints critical", &Certificate{Extensions: []Extension{{ID: []int{2, 5, 29, 19}, Critical: true, Value: []byte{0x30, 0x03, 0x01, 0x01, 0xff}}}}, false},
+		{"ok basicConstraints not CA", &Certificate{Extensions: []Extension{{ID: []int{2, 5, 29, 19}, Value: []byte{0x30, 0x00}}}}, false},
+		{"fail basicConstraints not critical", &Certificate{Extensions: []Extension{{ID: []int{2, 5, 29, 19}, Value: []byte{0x30, 0x03, 0x01, 0x01, 0xff}}}}, true},
+		{"fail timeStamping extension with other", &Certificate{Extensions: []Extension{{ID: []int{2, 5, 29, 37}, Critical: true, Value: []byte{
+			0x30, 0x14, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x05, 0x05, 0x07, 0x03, 0x08, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x05, 0x05, 0x07, 0x03, 0x01,
+		}}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cert.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCertificate_ValidateIPNameConstraints(t *testing.T) {
+	tests := []struct {
+		name    string
+		cert    *Certificate
+		wantErr bool
+	}{
+		{"ok permitted ip", &Certificate{IPAddresses: []net.IP{net.ParseIP("10.1.2.3")}, SANs: []SubjectAlternativeName{{Type: IPType, Value: "10.2.0.1"}, {Value: "192.168.1.1"}}, NameConstraints: &NameConstraints{
+			PermittedIPRanges: []*net.IPNet{mustParseCIDR(t, "10.0.0.0/8"), mustParseCIDR(t, "192.168.0.0/16")},
+			ExcludedIPRanges:  []*net.IPNet{mustParseCIDR(t, "10.3.0.0/16")},
+		}}, false},
+		{"ok ip without constraints", &Certificate{IPAddresses: []net.IP{net.ParseIP("10.1.2.3")}, NameConstraints: &NameConstraints{PermittedDNSDomains: []string{"foo.com"}}}, false},
+		{"ok removed name constraints", &Certificate{IPAddresses: []net.IP{net.ParseIP("10.1.2.3")}, NameConstraints: &NameConstraints{
+			ExcludedIPRanges: []*net.IPNet{mustParseCIDR(t, "10.0.0.0/8")},
+		}, Extensions: []Extension{{ID: ObjectIdentifier(oidExtensionNameConstraints), Remove: true}}}, false},
+		{"fail excluded ip", &Certificate{IPAddresses: []net.IP{net.ParseIP("10.3.2.1")}, NameConstraints: &NameConstraints{
+			PermittedIPRanges: []*net.IPNet{mustParseCIDR(t, "10.0.0.0/8")},
+			ExcludedIPRanges:  []*net.IPNet{mustParseCIDR(t, "10.3.0.0/16")},
+		}}, true},
+		{"fail excluded sans ip", &Certificate{SANs: []SubjectAlternativeName{{Type: AutoType, Value: "10.3.2.1"}}, NameConstraints: &NameConstraints{
+			ExcludedIPRanges: []*net.IPNet{mustParseCIDR(t, "10.3.0.0/16")},
+		}}, true},
+		{"fail not permitted ip", &Certificate{IPAddresses: []net.IP{net.ParseIP("10.1.2.3"), net.ParseIP("::1")}, NameConstraints: &NameConstraints{
+			PermittedIPRanges: []*net.IPNet{mustParseCIDR(t, "10.0.0.0/8")},
+		}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cert.ValidateIPNameConstraints()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			// Validate does not check the name constraints.
+			assert.NoError(t, tt.cert.Validate())
+		})
+	}
+
+	cert := &Certificate{IPAddresses: []net.IP{net.ParseIP("10.3.2.1")}, NameConstraints: &NameConstraints{
+		ExcludedIPRanges: []*net.IPNet{mustParseCIDR(t, "10.3.0.0/16")},
+	}}
+	assert.EqualError(t, cert.ValidateIPNameConstraints(), "invalid certificate: ipAddress 10.3.2.1 is in the excluded IP range 10.3.0.0/16")
+
+	cert = &Certificate{IPAddresses: []net.IP{net.ParseIP("192.168.1.1")}, NameConstraints: &NameConstraints{
+		PermittedIPRanges: []*net.IPNet{mustParseCIDR(t, "10.0.0.0/8")},
+	}}
+	assert.EqualError(t, cert.ValidateIPNameConstraints(), "invalid certificate: ipAddress 192.168.1.1 is not in the permitted IP ranges")
+}
+
+func TestNewCertificate_withIPNameConstraintsValidation(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "commonName", nil)
+	template := `{
+		"subject": {"commonName": "My CA"},
+		"ipAddresses": ["192.168.1.1"],
+		"basicConstraints": {"isCA": true},
+		"nameConstraints": {"critical": true, "permittedIPRanges": ["10.0.0.0/8"]}
+	}`
+
+	// Name constraints do not apply to the CA certificate by default.
+	_, err := NewCertificate(cr, WithTemplate(template, nil))
+	assert.NoError(t, err)
+
+	_, err = NewCertificate(cr, WithTemplate(template, nil), WithIPNameConstraintsValidation())
+	assert.EqualError(t, err, "invalid certificate: ipAddress 192.168.1.1 is not in the permitted IP ranges")
+
+	_, err = NewCertificate(cr, WithTemplate(strings.Replace(template, "192.168.1.1", "10.0.0.1", 1), nil), WithIPNameConstraintsValidation())
+	assert.NoError(t, err)
+}
+
+func TestNewCertificate_version(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "commonName", []string{"foo.com"})
+
+	cert, err := NewCertificate(cr, WithTemplate(`{"version": 3, "subject": {{ toJson .Subject }}}`, CreateTemplateData("commonName", nil)), WithValidation())
+	require.NoError(t, err)
+	assert.Equal(t, 3, cert.Version)
+
+	_, err = NewCertificate(cr, WithTemplate(`{"version": 1, "subject": {{ toJson .Subject }}}`, CreateTemplateData("commonName", nil)), WithValidation())
+	assert.EqualError(t, err, "invalid certificate version 1: certificates are always encoded as version 3")
+
+	_, err = NewCertificate(cr, WithTemplate(`{"version": 1, "subject": {{ toJson .Subject }}, "sans": {{ toJson .SANs }}}`, CreateTemplateData("commonName", []string{"foo.com"})), WithValidation())
+	assert.Error(t, err)
+
+	_, err = NewCertificate(cr, WithTemplate(`{"version": 5, "subject": {{ toJson .Subject }}}`, CreateTemplateData("commonName", nil)), WithValidation())
+	assert.Error(t, err)
+}
+
 func TestCreateCertificate(t *testing.T) {
 	iss, issPriv := createIssuerCertificate(t, "issuer")
 
@@ -775,82 +1209,1055 @@ func TestCreateCertificate(t *testing.T) {
 		})
 	}
 }
+func TestCreateCertificate_backdate(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
 
-func TestCreateCertificate_criticalSANs(t *testing.T) {
-	cr, _ := createCertificateRequest(t, "", []string{"foo.com"})
+	// Self-signed certificates are not clamped.
+	now := time.Now().Truncate(time.Second)
+	rootTemplate := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "root"},
+		NotBefore:             now,
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	root, err := CreateCertificate(rootTemplate, rootTemplate, pub, priv, WithBackdate(time.Hour))
+	require.NoError(t, err)
+	assert.WithinDuration(t, now.Add(-time.Hour), root.NotBefore, 2*time.Second)
+	assert.Equal(t, now, rootTemplate.NotBefore, "template must not be modified")
+
+	cr, _ := createCertificateRequest(t, "commonName", []string{"foo.com"})
+	cert, err := NewCertificate(cr)
+	require.NoError(t, err)
+	template := cert.GetCertificate()
+	template.NotBefore = now
+	template.NotAfter = now.Add(time.Hour)
+
+	t.Run("backdate", func(t *testing.T) {
+		crt, err := CreateCertificate(template, root, template.PublicKey, priv, WithBackdate(time.Minute))
+		require.NoError(t, err)
+		assert.WithinDuration(t, now.Add(-time.Minute), crt.NotBefore, 2*time.Second)
+		assert.Equal(t, now.Add(time.Hour), crt.NotAfter.Local())
+	})
+
+	t.Run("clamped to parent", func(t *testing.T) {
+		crt, err := CreateCertificate(template, root, template.PublicKey, priv, WithBackdate(2*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, root.NotBefore, crt.NotBefore)
+	})
+
+	t.Run("no backdate", func(t *testing.T) {
+		crt, err := CreateCertificate(template, root, template.PublicKey, priv)
+		require.NoError(t, err)
+		assert.Equal(t, now, crt.NotBefore.Local())
+	})
+
+	t.Run("fail negative", func(t *testing.T) {
+		_, err := CreateCertificate(template, root, template.PublicKey, priv, WithBackdate(-time.Minute))
+		assert.EqualError(t, err, "backdate -1m0s cannot be negative")
+	})
+}
+
+func TestCreateCertificate_removeExtension(t *testing.T) {
 	iss, issPriv := createIssuerCertificate(t, "issuer")
+	cr, _ := createCertificateRequest(t, "commonName", []string{"foo.com"})
 
-	type args struct {
-		cr   *x509.CertificateRequest
-		opts []Option
+	hasExtension := func(crt *x509.Certificate, oid ObjectIdentifier) bool {
+		for _, ext := range crt.Extensions {
+			if ext.Id.Equal(asn1.ObjectIdentifier(oid)) {
+				return true
+			}
+		}
+		return false
 	}
+
 	tests := []struct {
-		name string
-		args args
+		name     string
+		template string
+		removed  ObjectIdentifier
 	}{
-		{"okNoOptions", args{cr, nil}},
-		{"okDefaultLeafTemplate", args{cr, []Option{WithTemplate(DefaultLeafTemplate, CreateTemplateData("", []string{"foo.com"}))}}},
-		{"okCertificateRequestTemplate", args{cr, []Option{WithTemplate(CertificateRequestTemplate, CreateTemplateData("", []string{"foo.com"}))}}},
+		{"keyUsage", `{
+			"subject": {{ toJson .Subject }},
+			"sans": {{ toJson .SANs }},
+			"keyUsage": ["digitalSignature"],
+			"extKeyUsage": ["serverAuth", "clientAuth"],
+			"extensions": [{"id": "2.5.29.15", "remove": true}]
+		}`, oidExtensionKeyUsage},
+		{"custom extension", `{
+			"subject": {{ toJson .Subject }},
+			"sans": {{ toJson .SANs }},
+			"extensions": [
+				{"id": "1.2.3.4", "value": "BQA="},
+				{"id": "1.2.3.4", "remove": true}
+			]
+		}`, ObjectIdentifier{1, 2, 3, 4}},
 	}
-
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cert, err := NewCertificate(cr, tt.args.opts...)
-			if err != nil {
-				t.Fatalf("NewCertificate() error = %v", err)
-			}
-
+			cert, err := NewCertificate(cr, WithTemplate(tt.template, CreateTemplateData("commonName", []string{"foo.com"})))
+			require.NoError(t, err)
 			template := cert.GetCertificate()
-			got, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
-			if err != nil {
-				t.Fatalf("Certificate.CheckSignatureFrom() error = %v", err)
-			}
+			assert.Empty(t, template.ExtraExtensions)
 
-			if err := got.CheckSignatureFrom(iss); err != nil {
-				t.Fatalf("Certificate.CheckSignatureFrom() error = %v", err)
-			}
+			crt, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+			require.NoError(t, err)
+			assert.False(t, hasExtension(crt, tt.removed), "extension %s was not removed", tt.removed)
+			assert.True(t, hasExtension(crt, oidExtensionSubjectAltName))
+			assert.Equal(t, []string{"foo.com"}, crt.DNSNames)
+		})
+	}
 
-			asn1Subject, err := asn1.Marshal(got.Subject.ToRDNSequence())
-			if err != nil {
-				t.Fatalf("asn1.Marshal() error = %v", err)
-			}
+	// Other typed fields are not affected.
+	cert, err := NewCertificate(cr, WithTemplate(tests[0].template, CreateTemplateData("commonName", []string{"foo.com"})))
+	require.NoError(t, err)
+	crt, err := CreateCertificate(cert.GetCertificate(), iss, cr.PublicKey, issPriv)
+	require.NoError(t, err)
+	assert.Equal(t, x509.KeyUsage(0), crt.KeyUsage)
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}, crt.ExtKeyUsage)
+}
 
-			if bytes.Equal(asn1Subject, emptyASN1Subject) {
-				for _, ext := range got.Extensions {
-					if ext.Id.Equal(oidExtensionSubjectAltName) && !ext.Critical {
-						t.Errorf("Extension %s is not critical: %v", ext.Id, ext)
-					}
+func TestCreateTBSCertificate(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
 
-				}
-			}
+	// sign signs the tbs like an external service and assembles the
+	// certificate.
+	sign := func(t *testing.T, tbs []byte, signer crypto.Signer, opts crypto.SignerOpts) *x509.Certificate {
+		t.Helper()
+		var tbsCert tbsCertificate
+		rest, err := asn1.Unmarshal(tbs, &tbsCert)
+		require.NoError(t, err)
+		require.Empty(t, rest)
+
+		digest := tbs
+		if h := opts.HashFunc(); h != 0 {
+			hh := h.New()
+			hh.Write(tbs)
+			digest = hh.Sum(nil)
+		}
+		signature, err := signer.Sign(rand.Reader, digest, opts)
+		require.NoError(t, err)
+
+		der, err := asn1.Marshal(struct {
+			TBSCertificate     asn1.RawValue
+			SignatureAlgorithm pkix.AlgorithmIdentifier
+			SignatureValue     asn1.BitString
+		}{
+			TBSCertificate:     asn1.RawValue{FullBytes: tbs},
+			SignatureAlgorithm: tbsCert.SignatureAlgorithm,
+			SignatureValue:     asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
 		})
+		require.NoError(t, err)
+		crt, err := x509.ParseCertificate(der)
+		require.NoError(t, err)
+		return crt
 	}
-}
 
-func TestCreateCertificateTemplate(t *testing.T) {
-	cr1, _ := createCertificateRequest(t, "commonName", []string{"doe.com", "jane@doe.com", "1.2.3.4", "urn:uuid:2bbe86fc-a35e-4c68-a5cb-cb1060f57629"})
-	cr2, _ := createCertificateRequest(t, "", []string{"doe.com"})
-	cr3, _ := createCertificateRequest(t, "commonName", []string{})
+	newIssuer := func(t *testing.T, signer crypto.Signer, sigAlg x509.SignatureAlgorithm) *x509.Certificate {
+		t.Helper()
+		template := &x509.Certificate{
+			Subject:               pkix.Name{CommonName: "issuer"},
+			SerialNumber:          big.NewInt(1),
+			NotBefore:             now.Add(-time.Hour),
+			NotAfter:              now.Add(time.Hour),
+			KeyUsage:              x509.KeyUsageCertSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+			SignatureAlgorithm:    sigAlg,
+		}
+		crt, err := CreateCertificate(template, template, signer.Public(), signer)
+		require.NoError(t, err)
+		return crt
+	}
 
-	fail, _ := createCertificateRequest(t, "commonName", []string{"doe.com"})
-	fail.Signature = []byte{1, 2, 3, 4}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
 
-	type args struct {
-		cr *x509.CertificateRequest
-	}
-	tests := []struct {
-		name    string
-		args    args
-		want    *x509.Certificate
-		wantErr bool
-	}{
-		{"ok", args{cr1}, &x509.Certificate{
-			Subject: pkix.Name{
-				CommonName: "commonName",
-				Names:      []pkix.AttributeTypeAndValue{{Type: asn1.ObjectIdentifier{2, 5, 4, 3}, Value: "commonName"}},
-			},
-			PublicKey:          cr1.PublicKey,
-			PublicKeyAlgorithm: cr1.PublicKeyAlgorithm,
+	cr, _ := createCertificateRequest(t, "commonName", []string{"foo.com"})
+	cert, err := NewCertificate(cr)
+	require.NoError(t, err)
+
+	t.Run("ecdsa", func(t *testing.T) {
+		issuer := newIssuer(t, ecKey, 0)
+		template := cert.GetCertificate()
+		template.NotBefore, template.NotAfter = now, now.Add(time.Minute)
+		tbs, err := CreateTBSCertificate(template, issuer, cr.PublicKey)
+		require.NoError(t, err)
+
+		crt := sign(t, tbs, ecKey, crypto.SHA256)
+		assert.NoError(t, crt.CheckSignatureFrom(issuer))
+		assert.Equal(t, x509.ECDSAWithSHA256, crt.SignatureAlgorithm)
+		assert.Equal(t, "commonName", crt.Subject.CommonName)
+		assert.Equal(t, []string{"foo.com"}, crt.DNSNames)
+		assert.Equal(t, now, crt.NotBefore.Local())
+		assert.Equal(t, issuer.SubjectKeyId, crt.AuthorityKeyId)
+		assert.NotEmpty(t, crt.SubjectKeyId)
+		assert.NotNil(t, crt.SerialNumber)
+	})
+
+	t.Run("rsa-pss", func(t *testing.T) {
+		issuer := newIssuer(t, rsaKey, x509.SHA384WithRSAPSS)
+		template := cert.GetCertificate()
+		template.SignatureAlgorithm = x509.SHA384WithRSAPSS
+		tbs, err := CreateTBSCertificate(template, issuer, cr.PublicKey, WithBackdate(time.Minute))
+		require.NoError(t, err)
+
+		crt := sign(t, tbs, rsaKey, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA384})
+		assert.NoError(t, crt.CheckSignatureFrom(issuer))
+		assert.Equal(t, x509.SHA384WithRSAPSS, crt.SignatureAlgorithm)
+		assert.WithinDuration(t, now.Add(-time.Minute), crt.NotBefore, 2*time.Second)
+	})
+
+	t.Run("self-signed", func(t *testing.T) {
+		c := &Certificate{
+			Subject:          Subject{CommonName: "root"},
+			PublicKey:        edKey.Public(),
+			BasicConstraints: &BasicConstraints{IsCA: true, MaxPathLen: -1},
+			KeyUsage:         KeyUsage(x509.KeyUsageCertSign),
+			SubjectUniqueID:  &UniqueIdentifier{Bytes: []byte{1, 2, 3}, BitLength: 24},
+		}
+		tbs, err := c.TBSCertificate(nil)
+		require.NoError(t, err)
+
+		crt := sign(t, tbs, edKey, crypto.Hash(0))
+		assert.NoError(t, crt.CheckSignature(crt.SignatureAlgorithm, crt.RawTBSCertificate, crt.Signature))
+		assert.Equal(t, x509.PureEd25519, crt.SignatureAlgorithm)
+		assert.Equal(t, crt.Subject.String(), crt.Issuer.String())
+		assert.True(t, crt.IsCA)
+
+		var tbsCert tbsCertificate
+		_, err = asn1.Unmarshal(crt.RawTBSCertificate, &tbsCert)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{1, 2, 3}, tbsCert.SubjectUniqueID.Bytes)
+	})
+
+	t.Run("fail key", func(t *testing.T) {
+		issuer := newIssuer(t, ecKey, 0)
+		issuer.PublicKey = []byte("foo")
+		_, err := CreateTBSCertificate(cert.GetCertificate(), issuer, cr.PublicKey)
+		assert.EqualError(t, err, "unsupported public key type []uint8")
+	})
+}
+
+func TestAssembleCertificate(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	cr, _ := createCertificateRequest(t, "commonName", []string{"foo.com"})
+	cert, err := NewCertificate(cr)
+	require.NoError(t, err)
+
+	// tbsAndSign returns the tbsCertificate of a leaf issued by a CA with the
+	// given key and signature algorithm, and its signature.
+	tbsAndSign := func(t *testing.T, signer crypto.Signer, sigAlg x509.SignatureAlgorithm, opts crypto.SignerOpts) (*x509.Certificate, []byte, []byte) {
+		t.Helper()
+		template := &x509.Certificate{
+			Subject:               pkix.Name{CommonName: "issuer"},
+			SerialNumber:          big.NewInt(1),
+			NotBefore:             now.Add(-time.Hour),
+			NotAfter:              now.Add(time.Hour),
+			KeyUsage:              x509.KeyUsageCertSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+			SignatureAlgorithm:    sigAlg,
+		}
+		issuer, err := CreateCertificate(template, template, signer.Public(), signer)
+		require.NoError(t, err)
+
+		leaf := cert.GetCertificate()
+		leaf.NotBefore, leaf.NotAfter = now, now.Add(time.Minute)
+		leaf.SignatureAlgorithm = sigAlg
+		tbs, err := CreateTBSCertificate(leaf, issuer, cr.PublicKey)
+		require.NoError(t, err)
+
+		digest := tbs
+		if h := opts.HashFunc(); h != 0 {
+			hh := h.New()
+			hh.Write(tbs)
+			digest = hh.Sum(nil)
+		}
+		signature, err := signer.Sign(rand.Reader, digest, opts)
+		require.NoError(t, err)
+		return issuer, tbs, signature
+	}
+
+	tests := []struct {
+		name   string
+		signer crypto.Signer
+		sigAlg x509.SignatureAlgorithm
+		opts   crypto.SignerOpts
+	}{
+		{"ok ecdsa", ecKey, x509.ECDSAWithSHA256, crypto.SHA256},
+		{"ok rsa", rsaKey, x509.SHA256WithRSA, crypto.SHA256},
+		{"ok rsa-pss", rsaKey, x509.SHA384WithRSAPSS, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA384}},
+		{"ok ed25519", edKey, x509.PureEd25519, crypto.Hash(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer, tbs, signature := tbsAndSign(t, tt.signer, tt.sigAlg, tt.opts)
+			der, err := AssembleCertificate(tbs, tt.sigAlg, signature)
+			require.NoError(t, err)
+
+			crt, err := x509.ParseCertificate(der)
+			require.NoError(t, err)
+			assert.NoError(t, crt.CheckSignatureFrom(issuer))
+			assert.Equal(t, tt.sigAlg, crt.SignatureAlgorithm)
+			assert.Equal(t, tbs, crt.RawTBSCertificate)
+			assert.Equal(t, signature, crt.Signature)
+			assert.Equal(t, "commonName", crt.Subject.CommonName)
+			assert.Equal(t, []string{"foo.com"}, crt.DNSNames)
+		})
+	}
+
+	_, tbs, signature := tbsAndSign(t, ecKey, x509.ECDSAWithSHA256, crypto.SHA256)
+	failTests := []struct {
+		name      string
+		tbs       []byte
+		sigAlg    x509.SignatureAlgorithm
+		signature []byte
+	}{
+		{"fail tbs", []byte("foo"), x509.ECDSAWithSHA256, signature},
+		{"fail trailing data", append(append([]byte{}, tbs...), 0x00), x509.ECDSAWithSHA256, signature},
+		{"fail unsupported algorithm", tbs, x509.UnknownSignatureAlgorithm, signature},
+		{"fail algorithm mismatch", tbs, x509.ECDSAWithSHA384, signature},
+		{"fail empty signature", tbs, x509.ECDSAWithSHA256, nil},
+	}
+	for _, tt := range failTests {
+		t.Run(tt.name, func(t *testing.T) {
+			der, err := AssembleCertificate(tt.tbs, tt.sigAlg, tt.signature)
+			assert.Error(t, err)
+			assert.Nil(t, der)
+		})
+	}
+}
+
+func TestCreateCertificate_criticalSANs(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "", []string{"foo.com"})
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+
+	type args struct {
+		cr   *x509.CertificateRequest
+		opts []Option
+	}
+	tests := []struct {
+		name string
+		args args
+	}{
+		{"okNoOptions", args{cr, nil}},
+		{"okDefaultLeafTemplate", args{cr, []Option{WithTemplate(DefaultLeafTemplate, CreateTemplateData("", []string{"foo.com"}))}}},
+		{"okCertificateRequestTemplate", args{cr, []Option{WithTemplate(CertificateRequestTemplate, CreateTemplateData("", []string{"foo.com"}))}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, err := NewCertificate(cr, tt.args.opts...)
+			if err != nil {
+				t.Fatalf("NewCertificate() error = %v", err)
+			}
+
+			template := cert.GetCertificate()
+			got, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+			if err != nil {
+				t.Fatalf("Certificate.CheckSignatureFrom() error = %v", err)
+			}
+
+			if err := got.CheckSignatureFrom(iss); err != nil {
+				t.Fatalf("Certificate.CheckSignatureFrom() error = %v", err)
+			}
+
+			asn1Subject, err := asn1.Marshal(got.Subject.ToRDNSequence())
+			if err != nil {
+				t.Fatalf("asn1.Marshal() error = %v", err)
+			}
+
+			if bytes.Equal(asn1Subject, emptyASN1Subject) {
+				for _, ext := range got.Extensions {
+					if ext.Id.Equal(oidExtensionSubjectAltName) && !ext.Critical {
+						t.Errorf("Extension %s is not critical: %v", ext.Id, ext)
+					}
+
+				}
+			}
+		})
+	}
+}
+
+func TestCreateCertificate_mixedSANs(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "commonName", nil)
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+
+	type want struct {
+		dnsNames []string
+		ips      []net.IP
+		emails   []string
+		sanTags  []int
+	}
+	tests := []struct {
+		name     string
+		template string
+		want     want
+	}{
+		{"ok", `{
+			"subject": "commonName",
+			"dnsNames": ["foo.com", "bar.com"],
+			"ipAddresses": "10.0.0.1",
+			"sans": [
+				{"type": "dns", "value": "zar.com"},
+				{"type": "email", "value": "root@foo.com"},
+				{"type": "dns", "value": "foo.com"},
+				{"type": "ip", "value": "10.0.0.2"}
+			]
+		}`, want{
+			dnsNames: []string{"foo.com", "bar.com", "zar.com", "foo.com"},
+			ips:      []net.IP{net.ParseIP("10.0.0.1").To4(), net.ParseIP("10.0.0.2").To4()},
+			emails:   []string{"root@foo.com"},
+			sanTags:  []int{nameTypeDNS, nameTypeDNS, nameTypeDNS, nameTypeDNS, nameTypeEmail, nameTypeIP, nameTypeIP},
+		}},
+		{"ok extended sans", `{
+			"subject": "commonName",
+			"dnsNames": ["foo.com", "bar.com"],
+			"ipAddresses": "10.0.0.1",
+			"sans": [
+				{"type": "dns", "value": "zar.com"},
+				{"type": "permanentIdentifier", "value": "123456"},
+				{"type": "email", "value": "root@foo.com"},
+				{"type": "dns", "value": "foo.com"},
+				{"type": "ip", "value": "10.0.0.2"}
+			]
+		}`, want{
+			dnsNames: []string{"foo.com", "bar.com", "zar.com", "foo.com"},
+			ips:      []net.IP{net.ParseIP("10.0.0.1").To4(), net.ParseIP("10.0.0.2").To4()},
+			emails:   []string{"root@foo.com"},
+			sanTags:  []int{nameTypeDNS, nameTypeDNS, nameTypeIP, nameTypeDNS, nameTypeOtherName, nameTypeEmail, nameTypeDNS, nameTypeIP},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, err := NewCertificate(cr, WithTemplate(tt.template, NewTemplateData()))
+			require.NoError(t, err)
+
+			template := cert.GetCertificate()
+			got, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want.dnsNames, got.DNSNames)
+			assert.Equal(t, tt.want.ips, got.IPAddresses)
+			assert.Equal(t, tt.want.emails, got.EmailAddresses)
+
+			var sanTags []int
+			for _, ext := range got.Extensions {
+				if ext.Id.Equal(oidExtensionSubjectAltName) {
+					require.NoError(t, forEachSAN(ext.Value, func(v asn1.RawValue) error {
+						sanTags = append(sanTags, v.Tag)
+						return nil
+					}))
+				}
+			}
+			assert.Equal(t, tt.want.sanTags, sanTags)
+		})
+	}
+}
+
+func TestCreateCertificate_multipleExtendedSANs(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "commonName", nil)
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+
+	cert, err := NewCertificate(cr, WithTemplate(`{
+		"subject": "commonName",
+		"sans": [
+			{"type": "1.2.3.4", "value": "utf8:first"},
+			{"type": "dn", "value": "{\"commonName\":\"rocket\"}"},
+			{"type": "1.2.3.5", "value": "utf8:second"},
+			{"type": "dn", "value": "{\"commonName\":\"shuttle\"}"},
+			{"type": "1.2.3.4", "value": "utf8:first"}
+		]
+	}`, NewTemplateData()))
+	require.NoError(t, err)
+
+	template := cert.GetCertificate()
+	got, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+	require.NoError(t, err)
+
+	type otherName struct {
+		TypeID asn1.ObjectIdentifier
+		Value  asn1.RawValue `asn1:"explicit,tag:0"`
+	}
+
+	var names []string
+	for _, ext := range got.Extensions {
+		if !ext.Id.Equal(oidExtensionSubjectAltName) {
+			continue
+		}
+		require.NoError(t, forEachSAN(ext.Value, func(v asn1.RawValue) error {
+			switch v.Tag {
+			case nameTypeOtherName:
+				var on otherName
+				if _, err := asn1.UnmarshalWithParams(v.FullBytes, &on, "tag:0"); err != nil {
+					return err
+				}
+				var value string
+				if _, err := asn1.UnmarshalWithParams(on.Value.Bytes, &value, "utf8"); err != nil {
+					return err
+				}
+				names = append(names, on.TypeID.String()+"="+value)
+			case nameTypeDirectoryName:
+				var rdn pkix.RDNSequence
+				if _, err := asn1.Unmarshal(v.Bytes, &rdn); err != nil {
+					return err
+				}
+				var name pkix.Name
+				name.FillFromRDNSequence(&rdn)
+				names = append(names, "dn="+name.CommonName)
+			default:
+				return fmt.Errorf("unexpected tag %d", v.Tag)
+			}
+			return nil
+		}))
+	}
+	assert.Equal(t, []string{
+		"1.2.3.4=first", "dn=rocket", "1.2.3.5=second", "dn=shuttle", "1.2.3.4=first",
+	}, names)
+}
+
+func TestCreateCertificate_emptySubject(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "", nil)
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+
+	tests := []struct {
+		name         string
+		template     string
+		wantCritical bool
+	}{
+		{"ok empty subject", `{
+			"sans": [{"type": "dns", "value": "foo.com"}]
+		}`, true},
+		{"ok empty subject extended sans", `{
+			"sans": [
+				{"type": "dns", "value": "foo.com"},
+				{"type": "permanentIdentifier", "value": "123456"}
+			]
+		}`, true},
+		{"ok subject", `{
+			"subject": "commonName",
+			"sans": [{"type": "dns", "value": "foo.com"}]
+		}`, false},
+		{"ok subject extended sans", `{
+			"subject": "commonName",
+			"sans": [
+				{"type": "dns", "value": "foo.com"},
+				{"type": "permanentIdentifier", "value": "123456"}
+			]
+		}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, err := NewCertificate(cr, WithTemplate(tt.template, NewTemplateData()))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCritical, cert.Subject.IsEmpty())
+
+			template := cert.GetCertificate()
+			got, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+			require.NoError(t, err)
+
+			var found bool
+			for _, ext := range got.Extensions {
+				if ext.Id.Equal(oidExtensionSubjectAltName) {
+					found = true
+					assert.Equal(t, tt.wantCritical, ext.Critical)
+				}
+			}
+			assert.True(t, found, "subjectAltName extension not found")
+		})
+	}
+}
+
+func TestCreateCertificate_authorityInfoAccess(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "commonName", []string{"foo.com"})
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+
+	cert, err := NewCertificate(cr, WithTemplate(`{
+		"subject": "commonName",
+		"dnsNames": "foo.com",
+		"issuingCertificateURL": ["http://ca1.example.com/ca.crt", "http://ca2.example.com/ca.crt", "ldap://ca3.example.com/ca.crt"],
+		"ocspServer": ["http://ocsp1.example.com", "http://ocsp2.example.com"]
+	}`, NewTemplateData()))
+	require.NoError(t, err)
+
+	template := cert.GetCertificate()
+	got, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"http://ca1.example.com/ca.crt", "http://ca2.example.com/ca.crt", "ldap://ca3.example.com/ca.crt"}, got.IssuingCertificateURL)
+	assert.Equal(t, []string{"http://ocsp1.example.com", "http://ocsp2.example.com"}, got.OCSPServer)
+
+	// Decode the access descriptions from the raw extension.
+	type accessDescription struct {
+		Method   asn1.ObjectIdentifier
+		Location asn1.RawValue
+	}
+	var (
+		oidAuthorityInfoAccess = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 1}
+		oidOCSP                = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1}
+		oidCAIssuers           = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 2}
+	)
+	var aias [][]accessDescription
+	for _, ext := range got.Extensions {
+		if ext.Id.Equal(oidAuthorityInfoAccess) {
+			var ads []accessDescription
+			rest, err := asn1.Unmarshal(ext.Value, &ads)
+			require.NoError(t, err)
+			require.Empty(t, rest)
+			aias = append(aias, ads)
+		}
+	}
+	require.Len(t, aias, 1, "expected a single authorityInfoAccess extension")
+
+	var ocsp, caIssuers []string
+	for _, ad := range aias[0] {
+		assert.Equal(t, nameTypeURI, ad.Location.Tag)
+		switch {
+		case ad.Method.Equal(oidOCSP):
+			ocsp = append(ocsp, string(ad.Location.Bytes))
+		case ad.Method.Equal(oidCAIssuers):
+			caIssuers = append(caIssuers, string(ad.Location.Bytes))
+		default:
+			t.Errorf("unexpected access method %s", ad.Method)
+		}
+	}
+	assert.Len(t, aias[0], 5)
+	assert.Equal(t, []string{"http://ocsp1.example.com", "http://ocsp2.example.com"}, ocsp)
+	assert.Equal(t, []string{"http://ca1.example.com/ca.crt", "http://ca2.example.com/ca.crt", "ldap://ca3.example.com/ca.crt"}, caIssuers)
+}
+
+func TestCreateCertificate_ocspNoCheck(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "OCSP Responder", nil)
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+	oidOCSPNoCheck := asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 5}
+
+	getNoCheck := func(t *testing.T, tmpl string) []pkix.Extension {
+		t.Helper()
+		cert, err := NewCertificate(cr, WithTemplate(tmpl, NewTemplateData()))
+		require.NoError(t, err)
+		template := cert.GetCertificate()
+		got, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+		require.NoError(t, err)
+		var exts []pkix.Extension
+		for _, ext := range got.Extensions {
+			if ext.Id.Equal(oidOCSPNoCheck) {
+				exts = append(exts, ext)
+			}
+		}
+		return exts
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		exts := getNoCheck(t, `{
+			"subject": {{ toJson .Subject }},
+			"extKeyUsage": ["ocspSigning"],
+			"ocspNoCheck": true
+		}`)
+		require.Len(t, exts, 1)
+		assert.False(t, exts[0].Critical)
+		assert.Equal(t, []byte{0x05, 0x00}, exts[0].Value)
+
+		var v asn1.RawValue
+		rest, err := asn1.Unmarshal(exts[0].Value, &v)
+		require.NoError(t, err)
+		assert.Empty(t, rest)
+		assert.Equal(t, asn1.TagNull, v.Tag)
+		assert.Empty(t, v.Bytes)
+	})
+
+	t.Run("ok false", func(t *testing.T) {
+		assert.Empty(t, getNoCheck(t, `{"subject": {{ toJson .Subject }}, "ocspNoCheck": false}`))
+	})
+
+	t.Run("ok removed", func(t *testing.T) {
+		assert.Empty(t, getNoCheck(t, `{
+			"subject": {{ toJson .Subject }},
+			"ocspNoCheck": true,
+			"extensions": [{"id": "1.3.6.1.5.5.7.48.1.5", "remove": true}]
+		}`))
+	})
+
+	t.Run("ok custom extension", func(t *testing.T) {
+		exts := getNoCheck(t, `{
+			"subject": {{ toJson .Subject }},
+			"ocspNoCheck": true,
+			"extensions": [{"id": "1.3.6.1.5.5.7.48.1.5", "critical": true, "value": ""}]
+		}`)
+		require.Len(t, exts, 1)
+		assert.True(t, exts[0].Critical)
+		assert.Empty(t, exts[0].Value)
+	})
+}
+
+func TestCreateCertificate_basicConstraintsCritical(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "Intermediate CA", nil)
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+
+	getBasicConstraints := func(t *testing.T, tmpl string) []pkix.Extension {
+		t.Helper()
+		cert, err := NewCertificate(cr, WithTemplate(tmpl, NewTemplateData()))
+		require.NoError(t, err)
+		template := cert.GetCertificate()
+		got, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+		require.NoError(t, err)
+		var exts []pkix.Extension
+		for _, ext := range got.Extensions {
+			if ext.Id.Equal(asn1.ObjectIdentifier(oidExtensionBasicConstraints)) {
+				exts = append(exts, ext)
+			}
+		}
+		return exts
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		exts := getBasicConstraints(t, `{
+			"subject": {{ toJson .Subject }},
+			"basicConstraints": {"isCA": true, "maxPathLen": 0}
+		}`)
+		require.Len(t, exts, 1)
+		assert.True(t, exts[0].Critical)
+	})
+
+	t.Run("ok custom extension", func(t *testing.T) {
+		exts := getBasicConstraints(t, `{
+			"subject": {{ toJson .Subject }},
+			"extensions": [{"id": "2.5.29.19", "critical": true, "value": "MAMBAf8="}]
+		}`)
+		require.Len(t, exts, 1)
+		assert.True(t, exts[0].Critical)
+	})
+
+	t.Run("fail custom extension not critical", func(t *testing.T) {
+		_, err := NewCertificate(cr, WithTemplate(`{
+			"subject": {{ toJson .Subject }},
+			"extensions": [{"id": "2.5.29.19", "value": "MAMBAf8="}]
+		}`, NewTemplateData()), WithValidation())
+		assert.EqualError(t, err, "invalid certificate: the basic constraints extension must be critical in a CA certificate")
+	})
+}
+
+func TestCreateCertificate_naturalPersonSubject(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "Jane Doe", nil)
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+
+	cert, err := NewCertificate(cr, WithTemplate(`{
+		"subject": {
+			"country": "US",
+			"organization": "Acme",
+			"organizationalUnit": "Engineering",
+			"commonName": "Jane Doe",
+			"givenName": "Jane",
+			"surname": "Doe",
+			"extraNames": [{"type": "2.5.4.12", "value": "Engineer"}]
+		}
+	}`, NewTemplateData()))
+	require.NoError(t, err)
+
+	template := cert.GetCertificate()
+	got, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+	require.NoError(t, err)
+
+	var rdns pkix.RDNSequence
+	rest, err := asn1.Unmarshal(got.RawSubject, &rdns)
+	require.NoError(t, err)
+	require.Empty(t, rest)
+
+	var attrs []string
+	for _, rdn := range rdns {
+		require.Len(t, rdn, 1)
+		attrs = append(attrs, rdn[0].Type.String()+"="+rdn[0].Value.(string))
+	}
+	assert.Equal(t, []string{
+		"2.5.4.6=US",
+		"2.5.4.10=Acme",
+		"2.5.4.11=Engineering",
+		"2.5.4.3=Jane Doe",
+		"2.5.4.42=Jane",
+		"2.5.4.4=Doe",
+		"2.5.4.12=Engineer",
+	}, attrs)
+
+	// The attributes are loaded into the typed fields.
+	subject := newSubject(got.Subject)
+	assert.Equal(t, MultiString{"Jane"}, subject.GivenName)
+	assert.Equal(t, MultiString{"Doe"}, subject.Surname)
+	assert.Empty(t, subject.Pseudonym)
+	assert.Equal(t, []DistinguishedName{{Type: ObjectIdentifier{2, 5, 4, 12}, Value: "Engineer"}}, subject.ExtraNames)
+}
+
+func TestCreateCertificate_policyMappings(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "Bridge CA", nil)
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+
+	cert, err := NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"basicConstraints": {"isCA": true},
+		"policyIdentifiers": ["1.3.6.1.4.1.37476.9000.64.1"],
+		"policyMappings": [
+			{"issuer": "1.3.6.1.4.1.37476.9000.64.1", "subject": "2.16.840.1.101.3.2.1.3.13"},
+			{"issuer": "1.3.6.1.4.1.37476.9000.64.2", "subject": "2.16.840.1.101.3.2.1.3.16"}
+		]
+	}`, NewTemplateData()))
+	require.NoError(t, err)
+
+	template := cert.GetCertificate()
+	got, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+	require.NoError(t, err)
+
+	type policyMapping struct {
+		IssuerDomainPolicy  asn1.ObjectIdentifier
+		SubjectDomainPolicy asn1.ObjectIdentifier
+	}
+	oidPolicyMappings := asn1.ObjectIdentifier{2, 5, 29, 33}
+	var found [][]policyMapping
+	for _, ext := range got.Extensions {
+		if ext.Id.Equal(oidPolicyMappings) {
+			assert.True(t, ext.Critical)
+			var mappings []policyMapping
+			rest, err := asn1.Unmarshal(ext.Value, &mappings)
+			require.NoError(t, err)
+			require.Empty(t, rest)
+			found = append(found, mappings)
+		}
+	}
+	require.Len(t, found, 1, "expected a single policyMappings extension")
+	assert.Equal(t, []policyMapping{
+		{asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 37476, 9000, 64, 1}, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 2, 1, 3, 13}},
+		{asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 37476, 9000, 64, 2}, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 2, 1, 3, 16}},
+	}, found[0])
+
+	// A custom extension has precedence over the typed field.
+	cert, err = NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"policyMappings": [{"issuer": "1.2.3", "subject": "1.2.4"}],
+		"extensions": [{"id": "2.5.29.33", "critical": true, "value": "MAA="}]
+	}`, NewTemplateData()))
+	require.NoError(t, err)
+	require.Len(t, cert.Extensions, 1)
+	assert.Equal(t, []byte{0x30, 0x00}, cert.Extensions[0].Value)
+
+	// Invalid mappings fail.
+	_, err = NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"policyMappings": [{"issuer": "2.5.29.32.0", "subject": "1.2.4"}]
+	}`, NewTemplateData()))
+	assert.Error(t, err)
+}
+
+func TestCreateCertificate_subjectInformationAccess(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "Timestamping CA", nil)
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+
+	cert, err := NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"subjectInformationAccess": [
+			{"method": "1.3.6.1.5.5.7.48.5", "location": "http://repo.example.com/ca"},
+			{"method": "1.3.6.1.5.5.7.48.3", "location": "https://tsa.example.com"}
+		]
+	}`, NewTemplateData()))
+	require.NoError(t, err)
+
+	template := cert.GetCertificate()
+	got, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+	require.NoError(t, err)
+
+	type accessDescription struct {
+		Method   asn1.ObjectIdentifier
+		Location asn1.RawValue
+	}
+	oidSubjectInfoAccess := asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 11}
+	var sias [][]accessDescription
+	for _, ext := range got.Extensions {
+		if ext.Id.Equal(oidSubjectInfoAccess) {
+			assert.False(t, ext.Critical)
+			var ads []accessDescription
+			rest, err := asn1.Unmarshal(ext.Value, &ads)
+			require.NoError(t, err)
+			require.Empty(t, rest)
+			sias = append(sias, ads)
+		}
+	}
+	require.Len(t, sias, 1, "expected a single subjectInformationAccess extension")
+	require.Len(t, sias[0], 2)
+
+	assert.Equal(t, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 5}, sias[0][0].Method)
+	assert.Equal(t, asn1.ClassContextSpecific, sias[0][0].Location.Class)
+	assert.Equal(t, nameTypeURI, sias[0][0].Location.Tag)
+	assert.Equal(t, "http://repo.example.com/ca", string(sias[0][0].Location.Bytes))
+	assert.Equal(t, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 3}, sias[0][1].Method)
+	assert.Equal(t, nameTypeURI, sias[0][1].Location.Tag)
+	assert.Equal(t, "https://tsa.example.com", string(sias[0][1].Location.Bytes))
+
+	// A custom extension has precedence over the typed field.
+	cert, err = NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"subjectInformationAccess": [{"method": "1.3.6.1.5.5.7.48.5", "location": "http://repo.example.com/ca"}],
+		"extensions": [{"id": "1.3.6.1.5.5.7.1.11", "value": "MAA="}]
+	}`, NewTemplateData()))
+	require.NoError(t, err)
+	require.Len(t, cert.Extensions, 1)
+	assert.Equal(t, []byte{0x30, 0x00}, cert.Extensions[0].Value)
+
+	// Invalid access descriptions fail.
+	_, err = NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"subjectInformationAccess": [{"method": "1.3.6.1.5.5.7.48.5", "location": "repo.example.com"}]
+	}`, NewTemplateData()))
+	assert.Error(t, err)
+}
+
+func TestCreateCertificate_uniqueIdentifiers(t *testing.T) {
+	issuerUniqueID := asn1.BitString{Bytes: []byte{0xCA, 0xFE}, BitLength: 15}
+	subjectUniqueID := asn1.BitString{Bytes: []byte{0x01, 0x02, 0x03, 0x04}, BitLength: 32}
+	mustBase64 := func(bs asn1.BitString) string {
+		b, err := asn1.Marshal(bs)
+		require.NoError(t, err)
+		return base64.StdEncoding.EncodeToString(b)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	edIssuer, edKey := createIssuerCertificate(t, "issuer")
+	createIssuer := func(signer crypto.Signer) *x509.Certificate {
+		template := &x509.Certificate{
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+			Subject:               pkix.Name{CommonName: "issuer"},
+			SerialNumber:          big.NewInt(1),
+			NotBefore:             time.Now(),
+			NotAfter:              time.Now().Add(time.Hour),
+		}
+		crt, err := CreateCertificate(template, template, signer.Public(), signer)
+		require.NoError(t, err)
+		return crt
+	}
+
+	tests := []struct {
+		name               string
+		issuer             *x509.Certificate
+		signer             crypto.Signer
+		signatureAlgorithm string
+	}{
+		{"ed25519", edIssuer, edKey, ""},
+		{"ecdsa", createIssuer(ecKey), ecKey, ""},
+		{"rsa", createIssuer(rsaKey), rsaKey, ""},
+		{"rsa-pss", createIssuer(rsaKey), rsaKey, SHA384WithRSAPSS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr, _ := createCertificateRequest(t, "commonName", []string{"foo.com"})
+			cert, err := NewCertificate(cr, WithTemplate(fmt.Sprintf(`{
+				"subject": {{ toJson .Subject }},
+				"sans": {{ toJson .SANs }},
+				"issuerUniqueID": %q,
+				"subjectUniqueID": %q,
+				"signatureAlgorithm": %q
+			}`, mustBase64(issuerUniqueID), mustBase64(subjectUniqueID), tt.signatureAlgorithm), CreateTemplateData("commonName", []string{"foo.com"})))
+			require.NoError(t, err)
+			assert.Equal(t, 0, cert.Version)
+			assert.Equal(t, UniqueIdentifier(issuerUniqueID), *cert.IssuerUniqueID)
+			assert.Equal(t, UniqueIdentifier(subjectUniqueID), *cert.SubjectUniqueID)
+
+			// The unique identifiers are not in the template.
+			template := cert.GetCertificate()
+			assert.Empty(t, template.ExtraExtensions)
+
+			// The certificate is signed once.
+			signer := &countingSigner{Signer: tt.signer}
+			crt, err := CreateCertificate(template, tt.issuer, template.PublicKey, signer,
+				WithUniqueIdentifiers(cert.IssuerUniqueID, cert.SubjectUniqueID))
+			require.NoError(t, err)
+			require.NoError(t, crt.CheckSignatureFrom(tt.issuer))
+			assert.Equal(t, 1, signer.count)
+			assert.Equal(t, 3, crt.Version)
+			assert.Equal(t, []string{"foo.com"}, crt.DNSNames)
+
+			var tbs tbsCertificate
+			_, err = asn1.Unmarshal(crt.RawTBSCertificate, &tbs)
+			require.NoError(t, err)
+			assert.Equal(t, issuerUniqueID, tbs.IssuerUniqueID)
+			assert.Equal(t, subjectUniqueID, tbs.SubjectUniqueID)
+		})
+	}
+}
+
+type countingSigner struct {
+	crypto.Signer
+	count int
+}
+
+func (s *countingSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.count++
+	return s.Signer.Sign(rand, digest, opts)
+}
+
+func TestIssue_uniqueIdentifiers(t *testing.T) {
+	issuer, signer := createIssuerCertificate(t, "issuer")
+	cr, _ := createCertificateRequest(t, "commonName", []string{"foo.com"})
+
+	crt, err := Issue(strings.NewReader(`{
+		"subject": {{ toJson .Subject }},
+		"sans": {{ toJson .SANs }},
+		"subjectUniqueID": "AwIAAQ=="
+	}`), cr, issuer, signer)
+	require.NoError(t, err)
+	require.NoError(t, crt.CheckSignatureFrom(issuer))
+
+	var tbs tbsCertificate
+	_, err = asn1.Unmarshal(crt.RawTBSCertificate, &tbs)
+	require.NoError(t, err)
+	assert.Equal(t, asn1.BitString{Bytes: []byte{0x01}, BitLength: 8}, tbs.SubjectUniqueID)
+	assert.Empty(t, tbs.IssuerUniqueID.Bytes)
+}
+
+func TestNewCertificate_uniqueIdentifiers_fail(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "commonName", []string{"foo.com"})
+	for _, v := range []string{`"not base64"`, `"AQID"`, `"AwIAAQE="`, `1`} {
+		_, err := NewCertificate(cr, WithTemplate(`{"subjectUniqueID": `+v+`}`, NewTemplateData()))
+		assert.Error(t, err, v)
+	}
+}
+
+func TestCreateCertificateTemplate(t *testing.T) {
+	cr1, _ := createCertificateRequest(t, "commonName", []string{"doe.com", "jane@doe.com", "1.2.3.4", "urn:uuid:2bbe86fc-a35e-4c68-a5cb-cb1060f57629"})
+	cr2, _ := createCertificateRequest(t, "", []string{"doe.com"})
+	cr3, _ := createCertificateRequest(t, "commonName", []string{})
+
+	fail, _ := createCertificateRequest(t, "commonName", []string{"doe.com"})
+	fail.Signature = []byte{1, 2, 3, 4}
+
+	type args struct {
+		cr *x509.CertificateRequest
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *x509.Certificate
+		wantErr bool
+	}{
+		{"ok", args{cr1}, &x509.Certificate{
+			Subject: pkix.Name{
+				CommonName: "commonName",
+				Names:      []pkix.AttributeTypeAndValue{{Type: asn1.ObjectIdentifier{2, 5, 4, 3}, Value: "commonName"}},
+			},
+			PublicKey:          cr1.PublicKey,
+			PublicKeyAlgorithm: cr1.PublicKeyAlgorithm,
 			ExtraExtensions: []pkix.Extension{
 				{Id: oidExtensionSubjectAltName, Critical: false, Value: cr1.Extensions[0].Value},
 			},
@@ -892,6 +2299,120 @@ func TestCreateCertificateTemplate(t *testing.T) {
 	}
 }
 
+func TestCreateCertificateTemplateWithPolicy(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	basicConstraints := pkix.Extension{Id: asn1.ObjectIdentifier(oidExtensionBasicConstraints), Critical: true, Value: []byte{0x30, 0x03, 0x01, 0x01, 0xff}}
+	custom := pkix.Extension{Id: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: []byte{0x05, 0x00}}
+	asn1Data, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:            pkix.Name{CommonName: "commonName"},
+		DNSNames:           []string{"doe.com"},
+		ExtraExtensions:    []pkix.Extension{basicConstraints, custom},
+		SignatureAlgorithm: x509.PureEd25519,
+	}, priv)
+	require.NoError(t, err)
+	cr, err := x509.ParseCertificateRequest(asn1Data)
+	require.NoError(t, err)
+	require.Len(t, cr.Extensions, 3)
+	subjectAltName := cr.Extensions[0]
+
+	fail, _ := createCertificateRequest(t, "commonName", []string{"doe.com"})
+	fail.Signature = []byte{1, 2, 3, 4}
+
+	tests := []struct {
+		name           string
+		cr             *x509.CertificateRequest
+		allowed        []ObjectIdentifier
+		wantExtensions []pkix.Extension
+		assertion      assert.ErrorAssertionFunc
+	}{
+		{"ok none", cr, nil, nil, assert.NoError},
+		{"ok subjectAltName", cr, []ObjectIdentifier{oidExtensionSubjectAltName}, []pkix.Extension{subjectAltName}, assert.NoError},
+		{"ok custom", cr, []ObjectIdentifier{{1, 2, 3, 4}, {1, 2, 3, 5}}, []pkix.Extension{custom}, assert.NoError},
+		{"ok basicConstraints", cr, []ObjectIdentifier{oidExtensionSubjectAltName, oidExtensionBasicConstraints}, []pkix.Extension{subjectAltName, basicConstraints}, assert.NoError},
+		{"fail", fail, nil, nil, assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CreateCertificateTemplateWithPolicy(tt.cr, tt.allowed)
+			tt.assertion(t, err)
+			if err != nil {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, tt.wantExtensions, got.ExtraExtensions)
+			assert.Equal(t, cr.Subject, got.Subject)
+			assert.Equal(t, []string{"doe.com"}, got.DNSNames)
+			assert.Equal(t, cr.PublicKey, got.PublicKey)
+
+			// Disallowed extensions must not be in the signed certificate.
+			cert, err := CreateCertificate(got, got, got.PublicKey, priv)
+			require.NoError(t, err)
+			var wantCA, wantCustom bool
+			for _, ext := range tt.wantExtensions {
+				wantCA = wantCA || ext.Id.Equal(basicConstraints.Id)
+				wantCustom = wantCustom || ext.Id.Equal(custom.Id)
+			}
+			assert.Equal(t, wantCA, cert.IsCA)
+			var hasCustom bool
+			for _, ext := range cert.Extensions {
+				hasCustom = hasCustom || ext.Id.Equal(custom.Id)
+			}
+			assert.Equal(t, wantCustom, hasCustom)
+		})
+	}
+}
+
+func TestCreateCertificate_crlDistributionPointTemplate(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "commonName", []string{"doe.com"})
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+
+	cert, err := NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"crlDistributionPoints": [
+			"https://crl.example.com/{{`+"`{{ .SerialHex }}`"+`}}.crl",
+			"https://crl.example.com/by-serial/{{`+"`{{ .SerialNumber }}`"+`}}.crl",
+			"https://crl.example.com/all.crl"
+		]
+	}`, NewTemplateData()))
+	require.NoError(t, err)
+
+	template := cert.GetCertificate()
+	require.Equal(t, "https://crl.example.com/{{ .SerialHex }}.crl", template.CRLDistributionPoints[0])
+
+	got, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+	require.NoError(t, err)
+	serialHex := hex.EncodeToString(got.SerialNumber.Bytes())
+	assert.Equal(t, []string{
+		"https://crl.example.com/" + serialHex + ".crl",
+		"https://crl.example.com/by-serial/" + got.SerialNumber.String() + ".crl",
+		"https://crl.example.com/all.crl",
+	}, got.CRLDistributionPoints)
+
+	// The template is not modified, so it can be used again.
+	assert.Equal(t, "https://crl.example.com/{{ .SerialHex }}.crl", template.CRLDistributionPoints[0])
+
+	// A serial number set in the template is used.
+	got, err = CreateCertificate(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "leaf"},
+		SerialNumber:          big.NewInt(0x1f2e3d),
+		CRLDistributionPoints: []string{"https://crl.example.com/{{ .SerialHex }}.crl"},
+	}, iss, template.PublicKey, issPriv)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://crl.example.com/1f2e3d.crl"}, got.CRLDistributionPoints)
+
+	// Invalid placeholders fail.
+	_, err = CreateCertificate(&x509.Certificate{
+		CRLDistributionPoints: []string{"https://crl.example.com/{{ .Serial }}.crl"},
+	}, iss, template.PublicKey, issPriv)
+	assert.Error(t, err)
+	_, err = CreateCertificate(&x509.Certificate{
+		CRLDistributionPoints: []string{"https://crl.example.com/{{ .SerialHex }.crl"},
+	}, iss, template.PublicKey, issPriv)
+	assert.Error(t, err)
+}
+
 func TestCreateCertificate_debug(t *testing.T) {
 	csr, _ := createCertificateRequest(t, "rocket", nil)
 	iss, issPriv := createIssuerCertificate(t, "issuer")
@@ -937,3 +2458,94 @@ func TestCreateCertificate_debug(t *testing.T) {
 		})
 	}
 }
+
+func TestIssue(t *testing.T) {
+	issPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	now := time.Now()
+	rootTemplate := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Issue Root CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	iss, err := CreateCertificate(rootTemplate, rootTemplate, issPriv.Public(), issPriv)
+	require.NoError(t, err)
+	cr, _ := createCertificateRequest(t, "leaf.example.com", []string{"leaf.example.com", "127.0.0.1", "jane@example.com", "spiffe://example.com/leaf"})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(iss)
+
+	t.Run("ok default template", func(t *testing.T) {
+		before := time.Now()
+		leaf, err := Issue(nil, cr, iss, issPriv, WithBackdate(time.Minute))
+		require.NoError(t, err)
+
+		_, err = leaf.Verify(x509.VerifyOptions{
+			Roots:     roots,
+			DNSName:   "leaf.example.com",
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "leaf.example.com", leaf.Subject.CommonName)
+		assert.Equal(t, []string{"leaf.example.com"}, leaf.DNSNames)
+		assert.Equal(t, []string{"jane@example.com"}, leaf.EmailAddresses)
+		assert.True(t, leaf.IPAddresses[0].Equal(net.ParseIP("127.0.0.1")))
+		assert.Equal(t, "spiffe://example.com/leaf", leaf.URIs[0].String())
+		assert.Equal(t, x509.KeyUsageDigitalSignature, leaf.KeyUsage)
+		assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}, leaf.ExtKeyUsage)
+		assert.Equal(t, cr.PublicKey, leaf.PublicKey)
+		assert.NotNil(t, leaf.SerialNumber)
+		assert.NotEmpty(t, leaf.SubjectKeyId)
+		assert.True(t, leaf.NotBefore.Before(before.Add(-30*time.Second)))
+		assert.Equal(t, DefaultIssueValidity+time.Minute, leaf.NotAfter.Sub(leaf.NotBefore))
+	})
+
+	t.Run("ok short-lived parent", func(t *testing.T) {
+		shortIss, shortIssPriv := createIssuerCertificate(t, "issuer")
+		leaf, err := Issue(nil, cr, shortIss, shortIssPriv)
+		require.NoError(t, err)
+		require.NoError(t, leaf.CheckSignatureFrom(shortIss))
+		assert.Equal(t, shortIss.NotAfter, leaf.NotAfter)
+	})
+
+	t.Run("ok template", func(t *testing.T) {
+		leaf, err := Issue(strings.NewReader(`{
+			"subject": {"commonName": "{{ .Subject.CommonName }}", "organization": "Smallstep"},
+			"dnsNames": {{ toJson .Insecure.CR.DNSNames }},
+			"keyUsage": ["digitalSignature"],
+			"extKeyUsage": ["clientAuth"]
+		}`), cr, iss, issPriv)
+		require.NoError(t, err)
+		require.NoError(t, leaf.CheckSignatureFrom(iss))
+		assert.Equal(t, "leaf.example.com", leaf.Subject.CommonName)
+		assert.Equal(t, []string{"Smallstep"}, leaf.Subject.Organization)
+		assert.Equal(t, []string{"leaf.example.com"}, leaf.DNSNames)
+		assert.Empty(t, leaf.IPAddresses)
+		assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, leaf.ExtKeyUsage)
+	})
+
+	t.Run("fail read", func(t *testing.T) {
+		_, err := Issue(iotest.ErrReader(io.ErrUnexpectedEOF), cr, iss, issPriv)
+		assert.Error(t, err)
+	})
+
+	t.Run("fail template", func(t *testing.T) {
+		_, err := Issue(strings.NewReader(`{{ fail "not allowed" }}`), cr, iss, issPriv)
+		assert.EqualError(t, err, "not allowed")
+	})
+
+	t.Run("fail signature", func(t *testing.T) {
+		badCSR := *cr
+		badCSR.Signature = []byte("foo")
+		_, err := Issue(nil, &badCSR, iss, issPriv)
+		assert.Error(t, err)
+	})
+
+	t.Run("fail options", func(t *testing.T) {
+		_, err := Issue(nil, cr, iss, issPriv, WithNoSerialGeneration())
+		assert.Error(t, err)
+	})
+}
@@ -51,3 +51,42 @@ func ReadCertPool(path string) (*x509.CertPool, error) {
 	}
 	return pool, nil
 }
+
+// VerifyChain verifies the leaf certificate using the given intermediates and
+// roots, and returns the verified chains. If given, the intermediates and
+// roots replace the pools in opts. If no roots are given, the roots in opts
+// are used, or the system roots if they are not set.
+func VerifyChain(leaf *x509.Certificate, intermediates, roots []*x509.Certificate, opts x509.VerifyOptions) ([][]*x509.Certificate, error) {
+	if leaf == nil {
+		return nil, errors.New("error verifying certificate: leaf cannot be nil")
+	}
+
+	var err error
+	if len(intermediates) > 0 {
+		if opts.Intermediates, err = newCertPool(intermediates); err != nil {
+			return nil, errors.Wrap(err, "error verifying certificate")
+		}
+	}
+	if len(roots) > 0 {
+		if opts.Roots, err = newCertPool(roots); err != nil {
+			return nil, errors.Wrap(err, "error verifying certificate")
+		}
+	}
+
+	chains, err := leaf.Verify(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "error verifying certificate")
+	}
+	return chains, nil
+}
+
+func newCertPool(certs []*x509.Certificate) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for i, crt := range certs {
+		if crt == nil {
+			return nil, errors.Errorf("certificate %d cannot be nil", i)
+		}
+		pool.AddCert(crt)
+	}
+	return pool, nil
+}
@@ -1,8 +1,18 @@
 package x509util
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestReadCertPool(t *testing.T) {
@@ -41,3 +51,95 @@ func TestReadCertPool(t *testing.T) {
 		})
 	}
 }
+
+func createTestChain(t *testing.T) (root, intermediate, leaf *x509.Certificate) {
+	t.Helper()
+	now := time.Now()
+	create := func(template, parent *x509.Certificate, signer crypto.Signer) (*x509.Certificate, crypto.Signer) {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		template.NotBefore = now
+		template.NotAfter = now.Add(time.Hour)
+		if parent == nil {
+			parent, signer = template, priv
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+		require.NoError(t, err)
+		crt, err := x509.ParseCertificate(der)
+		require.NoError(t, err)
+		return crt, priv
+	}
+
+	root, rootSigner := create(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Root CA"},
+		SerialNumber:          big.NewInt(1),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	intermediate, intSigner := create(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Intermediate CA"},
+		SerialNumber:          big.NewInt(2),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		MaxPathLenZero:        true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, root, rootSigner)
+	leaf, _ = create(&x509.Certificate{
+		Subject:      pkix.Name{CommonName: "leaf"},
+		SerialNumber: big.NewInt(3),
+		DNSNames:     []string{"leaf.example.com"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, intermediate, intSigner)
+	return
+}
+
+func TestVerifyChain(t *testing.T) {
+	root, intermediate, leaf := createTestChain(t)
+	otherRoot, _, _ := createTestChain(t)
+
+	chains, err := VerifyChain(leaf, []*x509.Certificate{intermediate}, []*x509.Certificate{otherRoot, root}, x509.VerifyOptions{
+		DNSName: "leaf.example.com",
+	})
+	require.NoError(t, err)
+	require.Len(t, chains, 1)
+	assert.Equal(t, []*x509.Certificate{leaf, intermediate, root}, chains[0])
+
+	// Roots in the options are used if no roots are given.
+	rootPool := x509.NewCertPool()
+	rootPool.AddCert(root)
+	chains, err = VerifyChain(leaf, []*x509.Certificate{intermediate}, nil, x509.VerifyOptions{
+		Roots: rootPool,
+	})
+	require.NoError(t, err)
+	require.Len(t, chains, 1)
+	assert.Equal(t, []*x509.Certificate{leaf, intermediate, root}, chains[0])
+}
+
+func TestVerifyChain_fail(t *testing.T) {
+	root, intermediate, leaf := createTestChain(t)
+	otherRoot, _, _ := createTestChain(t)
+
+	tests := []struct {
+		name          string
+		leaf          *x509.Certificate
+		intermediates []*x509.Certificate
+		roots         []*x509.Certificate
+		opts          x509.VerifyOptions
+	}{
+		{"fail missing intermediate", leaf, nil, []*x509.Certificate{root}, x509.VerifyOptions{}},
+		{"fail unknown root", leaf, []*x509.Certificate{intermediate}, []*x509.Certificate{otherRoot}, x509.VerifyOptions{}},
+		{"fail dns name", leaf, []*x509.Certificate{intermediate}, []*x509.Certificate{root}, x509.VerifyOptions{DNSName: "other.example.com"}},
+		{"fail nil leaf", nil, []*x509.Certificate{intermediate}, []*x509.Certificate{root}, x509.VerifyOptions{}},
+		{"fail nil intermediate", leaf, []*x509.Certificate{nil}, []*x509.Certificate{root}, x509.VerifyOptions{}},
+		{"fail nil root", leaf, []*x509.Certificate{intermediate}, []*x509.Certificate{root, nil}, x509.VerifyOptions{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chains, err := VerifyChain(tt.leaf, tt.intermediates, tt.roots, tt.opts)
+			assert.Error(t, err)
+			assert.Nil(t, chains)
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package x509util
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// oidExtensionReasonCode is the OID of the CRL entry reason code extension.
+var oidExtensionReasonCode = asn1.ObjectIdentifier{2, 5, 29, 21}
+
+// Names used for the CRL reason codes defined in RFC 5280, section 5.3.1.
+const (
+	RevocationReasonUnspecified          = "unspecified"
+	RevocationReasonKeyCompromise        = "keyCompromise"
+	RevocationReasonCACompromise         = "cACompromise"
+	RevocationReasonAffiliationChanged   = "affiliationChanged"
+	RevocationReasonSuperseded           = "superseded"
+	RevocationReasonCessationOfOperation = "cessationOfOperation"
+	RevocationReasonCertificateHold      = "certificateHold"
+	RevocationReasonRemoveFromCRL        = "removeFromCRL"
+	RevocationReasonPrivilegeWithdrawn   = "privilegeWithdrawn"
+	RevocationReasonAACompromise         = "aACompromise"
+)
+
+// revocationReasons maps the reason codes to their names. The value 7 is not
+// used.
+var revocationReasons = map[RevocationReason]string{
+	0:  RevocationReasonUnspecified,
+	1:  RevocationReasonKeyCompromise,
+	2:  RevocationReasonCACompromise,
+	3:  RevocationReasonAffiliationChanged,
+	4:  RevocationReasonSuperseded,
+	5:  RevocationReasonCessationOfOperation,
+	6:  RevocationReasonCertificateHold,
+	8:  RevocationReasonRemoveFromCRL,
+	9:  RevocationReasonPrivilegeWithdrawn,
+	10: RevocationReasonAACompromise,
+}
+
+// CRL is the JSON representation of a X.509 certificate revocation list. It is
+// used to build a CRL using CreateCRL.
+//
+// The number field is the value of the CRL number extension and it is
+// required. The authorityKeyId field overrides the subject key identifier of
+// the issuer in the authority key identifier extension; if it is not set, the
+// issuer must have a subject key identifier.
+type CRL struct {
+	Number              SerialNumber         `json:"number"`
+	ThisUpdate          time.Time            `json:"thisUpdate"`
+	NextUpdate          time.Time            `json:"nextUpdate"`
+	RevokedCertificates []RevokedCertificate `json:"revokedCertificates"`
+	Extensions          []Extension          `json:"extensions"`
+	AuthorityKeyID      AuthorityKeyID       `json:"authorityKeyId"`
+	SignatureAlgorithm  SignatureAlgorithm   `json:"signatureAlgorithm"`
+}
+
+// RevokedCertificate is the JSON representation of an entry in a CRL. The
+// reason code extension is only added if the reason is not unspecified, as
+// recommended by RFC 5280.
+type RevokedCertificate struct {
+	SerialNumber   SerialNumber     `json:"serialNumber"`
+	RevocationTime time.Time        `json:"revocationTime"`
+	Reason         RevocationReason `json:"reason"`
+	Extensions     []Extension      `json:"extensions"`
+}
+
+// RevocationReason is the JSON representation of the CRL reason code. In JSON
+// it can be the name of the reason, e.g. "keyCompromise", or its integer
+// value.
+type RevocationReason int
+
+// String returns the name of the reason code.
+func (r RevocationReason) String() string {
+	if s, ok := revocationReasons[r]; ok {
+		return s
+	}
+	return "RevocationReason(" + strconv.Itoa(int(r)) + ")"
+}
+
+// MarshalJSON implements the json.Marshaler interface and encodes the reason
+// code using its name.
+func (r RevocationReason) MarshalJSON() ([]byte, error) {
+	s, ok := revocationReasons[r]
+	if !ok {
+		return nil, errors.Errorf("unsupported reason %d", int(r))
+	}
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface and accepts the name
+// or the integer value of a reason code.
+func (r *RevocationReason) UnmarshalJSON(data []byte) error {
+	if s, ok := maybeString(data); ok {
+		for k, v := range revocationReasons {
+			if convertName(s) == convertName(v) {
+				*r = k
+				return nil
+			}
+		}
+		return errors.Errorf("unsupported reason %s", s)
+	}
+
+	var i int
+	if err := json.Unmarshal(data, &i); err != nil {
+		return errors.Wrap(err, "error unmarshaling json")
+	}
+	if _, ok := revocationReasons[RevocationReason(i)]; !ok {
+		return errors.Errorf("unsupported reason %d", i)
+	}
+	*r = RevocationReason(i)
+	return nil
+}
+
+// GetRevocationList returns the x509.RevocationList that represents the CRL.
+func (c *CRL) GetRevocationList() (*x509.RevocationList, error) {
+	rl := &x509.RevocationList{
+		SignatureAlgorithm: x509.SignatureAlgorithm(c.SignatureAlgorithm),
+		Number:             c.Number.Int,
+		ThisUpdate:         c.ThisUpdate,
+		NextUpdate:         c.NextUpdate,
+		ExtraExtensions:    crlExtensions(c.Extensions),
+	}
+	for i, rc := range c.RevokedCertificates {
+		if rc.SerialNumber.Int == nil {
+			return nil, errors.Errorf("revokedCertificates[%d]: serialNumber is not set", i)
+		}
+		entry := pkix.RevokedCertificate{
+			SerialNumber:   rc.SerialNumber.Int,
+			RevocationTime: rc.RevocationTime,
+			Extensions:     crlExtensions(rc.Extensions),
+		}
+		if rc.Reason != 0 {
+			if _, ok := revocationReasons[rc.Reason]; !ok {
+				return nil, errors.Errorf("revokedCertificates[%d]: unsupported reason %d", i, int(rc.Reason))
+			}
+			value, err := asn1.Marshal(asn1.Enumerated(rc.Reason))
+			if err != nil {
+				return nil, errors.Wrap(err, "error marshaling reason code")
+			}
+			entry.Extensions = append(entry.Extensions, pkix.Extension{
+				Id:    oidExtensionReasonCode,
+				Value: value,
+			})
+		}
+		rl.RevokedCertificates = append(rl.RevokedCertificates, entry) //nolint:staticcheck // compatible with Go 1.20
+	}
+	return rl, nil
+}
+
+// CreateCRL creates a DER encoded certificate revocation list using the given
+// model, and signs it with the issuer certificate and signer.
+func CreateCRL(model *CRL, issuer *x509.Certificate, signer crypto.Signer) ([]byte, error) {
+	switch {
+	case model == nil:
+		return nil, errors.New("error creating CRL: model cannot be nil")
+	case issuer == nil:
+		return nil, errors.New("error creating CRL: issuer cannot be nil")
+	case signer == nil:
+		return nil, errors.New("error creating CRL: signer cannot be nil")
+	case model.Number.Int == nil:
+		return nil, errors.New("error creating CRL: number is not set")
+	}
+
+	rl, err := model.GetRevocationList()
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating CRL")
+	}
+
+	// The standard library always uses the subject key id of the issuer as
+	// the authority key id.
+	if len(model.AuthorityKeyID) > 0 {
+		cp := *issuer
+		cp.SubjectKeyId = model.AuthorityKeyID
+		issuer = &cp
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, rl, issuer, signer)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating CRL")
+	}
+	return der, nil
+}
+
+// crlExtensions converts the given extensions, skipping the ones marked to be
+// removed.
+func crlExtensions(extensions []Extension) []pkix.Extension {
+	var ret []pkix.Extension
+	for _, e := range extensions {
+		if e.Remove {
+			continue
+		}
+		ret = append(ret, pkix.Extension{
+			Id:       asn1.ObjectIdentifier(e.ID),
+			Critical: e.Critical,
+			Value:    e.Value,
+		})
+	}
+	return ret
+}
@@ -0,0 +1,185 @@
+package x509util
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevocationReason_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    RevocationReason
+		wantErr bool
+	}{
+		{"name", `"keyCompromise"`, 1, false},
+		{"name snake case", `"cessation_of_operation"`, 5, false},
+		{"name case insensitive", `"AACOMPROMISE"`, 10, false},
+		{"number", `4`, 4, false},
+		{"fail name", `"foo"`, 0, true},
+		{"fail number", `7`, 0, true},
+		{"fail type", `{}`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got RevocationReason
+			err := got.UnmarshalJSON([]byte(tt.data))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRevocationReason_MarshalJSON(t *testing.T) {
+	b, err := json.Marshal(RevocationReason(6))
+	require.NoError(t, err)
+	assert.Equal(t, `"certificateHold"`, string(b))
+
+	_, err = json.Marshal(RevocationReason(7))
+	assert.Error(t, err)
+	assert.Equal(t, "RevocationReason(7)", RevocationReason(7).String())
+}
+
+func TestCreateCRL(t *testing.T) {
+	issuer, signer := createIssuerCertificate(t, "issuer")
+	now := time.Now().UTC().Truncate(time.Second)
+
+	var model CRL
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"number": 42,
+		"thisUpdate": "`+now.Format(time.RFC3339)+`",
+		"nextUpdate": "`+now.Add(24*time.Hour).Format(time.RFC3339)+`",
+		"revokedCertificates": [
+			{"serialNumber": "0x1234", "revocationTime": "`+now.Add(-time.Hour).Format(time.RFC3339)+`", "reason": "keyCompromise"},
+			{"serialNumber": 5678, "revocationTime": "`+now.Add(-time.Minute).Format(time.RFC3339)+`"}
+		],
+		"extensions": [
+			{"id": "1.2.3.4", "value": "ZGF0YQ=="},
+			{"id": "1.2.3.5", "value": "ZGF0YQ==", "remove": true}
+		]
+	}`), &model))
+
+	der, err := CreateCRL(&model, issuer, signer)
+	require.NoError(t, err)
+
+	crl, err := x509.ParseRevocationList(der)
+	require.NoError(t, err)
+	require.NoError(t, crl.CheckSignatureFrom(issuer))
+	assert.Equal(t, big.NewInt(42), crl.Number)
+	assert.Equal(t, issuer.SubjectKeyId, crl.AuthorityKeyId)
+	assert.True(t, now.Equal(crl.ThisUpdate))
+	assert.True(t, now.Add(24*time.Hour).Equal(crl.NextUpdate))
+
+	// The CRL number extension is added by the standard library.
+	var numberExt *pkix.Extension
+	var hasCustomExt bool
+	for i, ext := range crl.Extensions {
+		switch ext.Id.String() {
+		case "2.5.29.20":
+			numberExt = &crl.Extensions[i]
+		case "1.2.3.4":
+			hasCustomExt = true
+			assert.Equal(t, []byte("data"), ext.Value)
+		case "1.2.3.5":
+			t.Error("removed extension found")
+		}
+	}
+	if assert.NotNil(t, numberExt) {
+		var number *big.Int
+		_, err := asn1.Unmarshal(numberExt.Value, &number)
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(42), number)
+	}
+	assert.True(t, hasCustomExt)
+
+	revoked := crl.RevokedCertificates //nolint:staticcheck // compatible with Go 1.20
+	require.Len(t, revoked, 2)
+	assert.Equal(t, big.NewInt(0x1234), revoked[0].SerialNumber)
+	assert.True(t, now.Add(-time.Hour).Equal(revoked[0].RevocationTime))
+	require.Len(t, revoked[0].Extensions, 1)
+	assert.Equal(t, "2.5.29.21", revoked[0].Extensions[0].Id.String())
+	var reason asn1.Enumerated
+	_, err = asn1.Unmarshal(revoked[0].Extensions[0].Value, &reason)
+	require.NoError(t, err)
+	assert.Equal(t, asn1.Enumerated(1), reason)
+
+	assert.Equal(t, big.NewInt(5678), revoked[1].SerialNumber)
+	assert.Empty(t, revoked[1].Extensions)
+}
+
+func TestCreateCRL_authorityKeyID(t *testing.T) {
+	issuer, signer := createIssuerCertificate(t, "issuer")
+	issuerSKI := issuer.SubjectKeyId
+
+	der, err := CreateCRL(&CRL{
+		Number:         SerialNumber{big.NewInt(1)},
+		ThisUpdate:     time.Now(),
+		NextUpdate:     time.Now().Add(time.Hour),
+		AuthorityKeyID: AuthorityKeyID{1, 2, 3, 4},
+	}, issuer, signer)
+	require.NoError(t, err)
+
+	crl, err := x509.ParseRevocationList(der)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3, 4}, crl.AuthorityKeyId)
+	assert.Equal(t, issuerSKI, issuer.SubjectKeyId, "issuer must not be modified")
+}
+
+func TestCreateCRL_fail(t *testing.T) {
+	issuer, signer := createIssuerCertificate(t, "issuer")
+	model := func() *CRL {
+		return &CRL{
+			Number:     SerialNumber{big.NewInt(1)},
+			ThisUpdate: time.Now(),
+			NextUpdate: time.Now().Add(time.Hour),
+		}
+	}
+	noNumber := model()
+	noNumber.Number = SerialNumber{}
+	noSerial := model()
+	noSerial.RevokedCertificates = []RevokedCertificate{{RevocationTime: time.Now()}}
+	badReason := model()
+	badReason.RevokedCertificates = []RevokedCertificate{{SerialNumber: SerialNumber{big.NewInt(1)}, Reason: 7}}
+	badTimes := model()
+	badTimes.NextUpdate = badTimes.ThisUpdate.Add(-time.Hour)
+
+	tests := []struct {
+		name   string
+		model  *CRL
+		issuer *x509.Certificate
+		errMsg string
+	}{
+		{"nil model", nil, issuer, "error creating CRL: model cannot be nil"},
+		{"nil issuer", model(), nil, "error creating CRL: issuer cannot be nil"},
+		{"no number", noNumber, issuer, "error creating CRL: number is not set"},
+		{"no serial", noSerial, issuer, "error creating CRL: revokedCertificates[0]: serialNumber is not set"},
+		{"bad reason", badReason, issuer, "error creating CRL: revokedCertificates[0]: unsupported reason 7"},
+		{"bad times", badTimes, issuer, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			der, err := CreateCRL(tt.model, tt.issuer, signer)
+			if tt.errMsg == "" {
+				assert.Error(t, err)
+			} else {
+				assert.EqualError(t, err, tt.errMsg)
+			}
+			assert.Nil(t, der)
+		})
+	}
+
+	_, err := CreateCRL(model(), issuer, nil)
+	assert.EqualError(t, err, "error creating CRL: signer cannot be nil")
+}
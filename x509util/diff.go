@@ -0,0 +1,153 @@
+package x509util
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldDiff represents a field with different values in two certificates.
+type FieldDiff struct {
+	Field string
+	A     string
+	B     string
+}
+
+// String returns a human-readable representation of the difference.
+func (d FieldDiff) String() string {
+	return fmt.Sprintf("%s: %s != %s", d.Field, d.A, d.B)
+}
+
+// diffMissing is the value used in a FieldDiff when an extension is not in one
+// of the certificates.
+const diffMissing = "<missing>"
+
+// Diff compares two certificates field by field and returns the fields with
+// different values. It compares the subject, issuer, serial number, validity,
+// subject alternative names, key usages, basic constraints, extensions by OID,
+// and the public key. The differences are always returned in the same order,
+// with the extensions sorted by OID. It returns nil if no differences are
+// found.
+//
+// Extensions are compared by their raw value, so a difference in the subject
+// alternative names or key usages will be reported in the specific field and
+// in the extension.
+func Diff(a, b *x509.Certificate) []FieldDiff {
+	var diffs []FieldDiff
+	add := func(field, va, vb string) {
+		if va != vb {
+			diffs = append(diffs, FieldDiff{Field: field, A: va, B: vb})
+		}
+	}
+
+	add("subject", a.Subject.String(), b.Subject.String())
+	add("issuer", a.Issuer.String(), b.Issuer.String())
+	add("serialNumber", diffSerialNumber(a), diffSerialNumber(b))
+	add("notBefore", a.NotBefore.UTC().Format(time.RFC3339), b.NotBefore.UTC().Format(time.RFC3339))
+	add("notAfter", a.NotAfter.UTC().Format(time.RFC3339), b.NotAfter.UTC().Format(time.RFC3339))
+	add("dnsNames", diffJSON(a.DNSNames), diffJSON(b.DNSNames))
+	add("emailAddresses", diffJSON(a.EmailAddresses), diffJSON(b.EmailAddresses))
+	add("ipAddresses", diffJSON(a.IPAddresses), diffJSON(b.IPAddresses))
+	add("uris", diffJSON(MultiURL(a.URIs)), diffJSON(MultiURL(b.URIs)))
+	add("keyUsage", diffJSON(KeyUsage(a.KeyUsage)), diffJSON(KeyUsage(b.KeyUsage)))
+	add("extKeyUsage", diffJSON(ExtKeyUsage(a.ExtKeyUsage)), diffJSON(ExtKeyUsage(b.ExtKeyUsage)))
+	add("basicConstraints", diffBasicConstraints(a), diffBasicConstraints(b))
+	add("signatureAlgorithm", a.SignatureAlgorithm.String(), b.SignatureAlgorithm.String())
+	add("publicKey", diffPublicKey(a), diffPublicKey(b))
+
+	// Extensions by OID
+	extsA, extsB := diffExtensions(a), diffExtensions(b)
+	oids := make([]string, 0, len(extsA)+len(extsB))
+	for oid := range extsA {
+		oids = append(oids, oid)
+	}
+	for oid := range extsB {
+		if _, ok := extsA[oid]; !ok {
+			oids = append(oids, oid)
+		}
+	}
+	sort.Slice(oids, func(i, j int) bool {
+		return compareOIDs(oids[i], oids[j]) < 0
+	})
+	for _, oid := range oids {
+		va, ok := extsA[oid]
+		if !ok {
+			va = diffMissing
+		}
+		vb, ok := extsB[oid]
+		if !ok {
+			vb = diffMissing
+		}
+		add("extension "+oid, va, vb)
+	}
+
+	return diffs
+}
+
+func diffJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func diffSerialNumber(c *x509.Certificate) string {
+	if c.SerialNumber == nil {
+		return ""
+	}
+	return c.SerialNumber.String()
+}
+
+func diffBasicConstraints(c *x509.Certificate) string {
+	if !c.BasicConstraintsValid {
+		return diffMissing
+	}
+	maxPathLen := c.MaxPathLen
+	if maxPathLen == 0 && !c.MaxPathLenZero {
+		maxPathLen = -1
+	}
+	return diffJSON(BasicConstraints{
+		IsCA:       c.IsCA,
+		MaxPathLen: maxPathLen,
+	})
+}
+
+func diffPublicKey(c *x509.Certificate) string {
+	sum := sha256.Sum256(c.RawSubjectPublicKeyInfo)
+	return c.PublicKeyAlgorithm.String() + " SHA256:" + hex.EncodeToString(sum[:])
+}
+
+// diffExtensions returns the extensions of a certificate indexed by OID. If an
+// extension is present multiple times, all the values are included.
+func diffExtensions(c *x509.Certificate) map[string]string {
+	m := make(map[string]string, len(c.Extensions))
+	for _, ext := range c.Extensions {
+		oid := ext.Id.String()
+		v := "critical=" + strconv.FormatBool(ext.Critical) + " value=" + hex.EncodeToString(ext.Value)
+		if s, ok := m[oid]; ok {
+			v = s + ", " + v
+		}
+		m[oid] = v
+	}
+	return m
+}
+
+// compareOIDs compares two OIDs in dot notation numerically.
+func compareOIDs(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, _ := strconv.Atoi(pa[i])
+		nb, _ := strconv.Atoi(pb[i])
+		if na != nb {
+			return na - nb
+		}
+	}
+	return len(pa) - len(pb)
+}
@@ -0,0 +1,125 @@
+package x509util
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	create := func(fn func(c *x509.Certificate)) *x509.Certificate {
+		template := &x509.Certificate{
+			Subject:      pkix.Name{CommonName: "foo.com"},
+			SerialNumber: big.NewInt(1),
+			NotBefore:    now,
+			NotAfter:     now.Add(24 * time.Hour),
+			DNSNames:     []string{"foo.com"},
+			IPAddresses:  []net.IP{net.ParseIP("1.2.3.4")},
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			SubjectKeyId: []byte{1, 2, 3, 4},
+			PublicKey:    pub,
+		}
+		if fn != nil {
+			fn(template)
+		}
+		crt, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+		require.NoError(t, err)
+		return crt
+	}
+
+	orig := create(nil)
+	reissued := create(func(c *x509.Certificate) {
+		c.SerialNumber = big.NewInt(2)
+		c.NotBefore = now.Add(time.Hour)
+		c.NotAfter = now.Add(25 * time.Hour)
+	})
+	changed := create(func(c *x509.Certificate) {
+		c.Subject = pkix.Name{CommonName: "bar.com"}
+		c.DNSNames = []string{"bar.com", "foo.com"}
+		c.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+		c.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+		c.BasicConstraintsValid = true
+		c.PublicKey = otherPub
+		c.SubjectKeyId = []byte{5, 6, 7, 8}
+	})
+
+	t.Run("identical", func(t *testing.T) {
+		assert.Nil(t, Diff(orig, orig))
+		assert.Nil(t, Diff(orig, create(nil)))
+	})
+
+	t.Run("reissued", func(t *testing.T) {
+		assert.Equal(t, []FieldDiff{
+			{"serialNumber", "1", "2"},
+			{"notBefore", "2024-01-02T03:04:05Z", "2024-01-02T04:04:05Z"},
+			{"notAfter", "2024-01-03T03:04:05Z", "2024-01-03T04:04:05Z"},
+		}, Diff(orig, reissued))
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		diffs := Diff(orig, changed)
+		var fields []string
+		for _, d := range diffs {
+			fields = append(fields, d.Field)
+		}
+		assert.Equal(t, []string{
+			"subject", "dnsNames", "keyUsage", "extKeyUsage", "basicConstraints", "publicKey",
+			"extension 2.5.29.14", "extension 2.5.29.15", "extension 2.5.29.17",
+			"extension 2.5.29.19", "extension 2.5.29.37",
+		}, fields)
+
+		assert.Equal(t, FieldDiff{"subject", "CN=foo.com", "CN=bar.com"}, diffs[0])
+		assert.Equal(t, FieldDiff{"dnsNames", `["foo.com"]`, `["bar.com","foo.com"]`}, diffs[1])
+		assert.Equal(t, FieldDiff{"keyUsage", `["digitalSignature"]`, `["digitalSignature","keyEncipherment"]`}, diffs[2])
+		assert.Equal(t, FieldDiff{"extKeyUsage", `["serverAuth"]`, `["serverAuth","clientAuth"]`}, diffs[3])
+		assert.Equal(t, FieldDiff{"basicConstraints", "<missing>", `{"isCA":false,"maxPathLen":-1}`}, diffs[4])
+		assert.Equal(t, "extension 2.5.29.19: <missing> != critical=true value=3000", diffs[9].String())
+
+		// The order is deterministic, and the reverse diff swaps the values.
+		assert.Equal(t, diffs, Diff(orig, changed))
+		for i, d := range Diff(changed, orig) {
+			assert.Equal(t, FieldDiff{d.Field, diffs[i].B, diffs[i].A}, d)
+		}
+	})
+}
+
+func Test_compareOIDs(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2.5.29.17", "2.5.29.17", 0},
+		{"2.5.29.9", "2.5.29.17", -1},
+		{"2.5.29.17", "2.5.29.9", 1},
+		{"1.3.6.1.5.5.7.1.1", "2.5.29.14", -1},
+		{"2.5.29", "2.5.29.1", -1},
+		{"2.5.29.1", "2.5.29", 1},
+	}
+	for _, tt := range tests {
+		got := compareOIDs(tt.a, tt.b)
+		switch {
+		case tt.want == 0:
+			assert.Zero(t, got, tt.a+" "+tt.b)
+		case tt.want < 0:
+			assert.Negative(t, got, tt.a+" "+tt.b)
+		default:
+			assert.Positive(t, got, tt.a+" "+tt.b)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"math/big"
@@ -13,9 +14,12 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/pkg/errors"
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
 )
 
 func convertName(s string) string {
@@ -53,6 +57,43 @@ const (
 	ExtKeyUsageMicrosoftKernelCodeSigning     = "microsoftKernelCodeSigning"
 )
 
+// Names used for extended key usages not supported by crypto/x509, mostly used
+// in Windows environments. These extended key usages are added to the
+// certificate as unknown extended key usages.
+const (
+	ExtKeyUsageSmartCardLogon                  = "smartCardLogon"
+	ExtKeyUsageDocumentSigning                 = "documentSigning"
+	ExtKeyUsageEncryptingFileSystem            = "encryptingFileSystem"
+	ExtKeyUsageFileRecovery                    = "fileRecovery"
+	ExtKeyUsageKeyRecovery                     = "keyRecovery"
+	ExtKeyUsageLifetimeSigning                 = "lifetimeSigning"
+	ExtKeyUsageCertificateRequestAgent         = "certificateRequestAgent"
+	ExtKeyUsageMicrosoftTrustListSigning       = "microsoftTrustListSigning"
+	ExtKeyUsageMicrosoftTimeStamping           = "microsoftTimeStamping"
+	ExtKeyUsageKDCAuthentication               = "kdcAuthentication"
+	ExtKeyUsagePKINITClientAuth                = "pkinitClientAuth"
+	ExtKeyUsageIPSECIKEIntermediate            = "ipsecIKEIntermediate"
+	ExtKeyUsageMicrosoftQualifiedSubordination = "microsoftQualifiedSubordination"
+)
+
+// unknownExtKeyUsages maps the names of the extended key usages not supported
+// by crypto/x509 to their OIDs.
+var unknownExtKeyUsages = map[string]asn1.ObjectIdentifier{
+	convertName(ExtKeyUsageSmartCardLogon):                  {1, 3, 6, 1, 4, 1, 311, 20, 2, 2},
+	convertName(ExtKeyUsageDocumentSigning):                 {1, 3, 6, 1, 4, 1, 311, 10, 3, 12},
+	convertName(ExtKeyUsageEncryptingFileSystem):            {1, 3, 6, 1, 4, 1, 311, 10, 3, 4},
+	convertName(ExtKeyUsageFileRecovery):                    {1, 3, 6, 1, 4, 1, 311, 10, 3, 4, 1},
+	convertName(ExtKeyUsageKeyRecovery):                     {1, 3, 6, 1, 4, 1, 311, 10, 3, 11},
+	convertName(ExtKeyUsageLifetimeSigning):                 {1, 3, 6, 1, 4, 1, 311, 10, 3, 13},
+	convertName(ExtKeyUsageCertificateRequestAgent):         {1, 3, 6, 1, 4, 1, 311, 20, 2, 1},
+	convertName(ExtKeyUsageMicrosoftTrustListSigning):       {1, 3, 6, 1, 4, 1, 311, 10, 3, 1},
+	convertName(ExtKeyUsageMicrosoftTimeStamping):           {1, 3, 6, 1, 4, 1, 311, 10, 3, 2},
+	convertName(ExtKeyUsageKDCAuthentication):               {1, 3, 6, 1, 5, 2, 3, 5},
+	convertName(ExtKeyUsagePKINITClientAuth):                {1, 3, 6, 1, 5, 2, 3, 4},
+	convertName(ExtKeyUsageIPSECIKEIntermediate):            {1, 3, 6, 1, 5, 5, 8, 2, 2},
+	convertName(ExtKeyUsageMicrosoftQualifiedSubordination): {1, 3, 6, 1, 4, 1, 311, 10, 3, 10},
+}
+
 // Names used and SubjectAlternativeNames types.
 const (
 	AutoType                = "auto"
@@ -173,12 +214,36 @@ func (h *HardwareModuleName) asn1Type() asn1HardwareModuleName {
 }
 
 // Extension is the JSON representation of a raw X.509 extensions.
+//
+// If Remove is true, the extension with the given ID is removed from the
+// certificate, including the extensions generated from the typed fields. This
+// can be used to remove an extension added by a base template, e.g.:
+//
+//	{"id": "2.5.29.15", "remove": true}
 type Extension struct {
 	ID       ObjectIdentifier `json:"id"`
 	Critical bool             `json:"critical"`
 	Value    []byte           `json:"value"`
+	Remove   bool             `json:"remove,omitempty"`
 }
 
+// OIDs of the extensions generated from the typed fields.
+var (
+	oidExtensionSubjectKeyID          = ObjectIdentifier{2, 5, 29, 14}
+	oidExtensionKeyUsage              = ObjectIdentifier{2, 5, 29, 15}
+	oidExtensionBasicConstraints      = ObjectIdentifier{2, 5, 29, 19}
+	oidExtensionCRLDistributionPoints = ObjectIdentifier{2, 5, 29, 31}
+	oidExtensionCertificatePolicies   = ObjectIdentifier{2, 5, 29, 32}
+	oidExtensionAuthorityKeyID        = ObjectIdentifier{2, 5, 29, 35}
+	oidExtensionExtendedKeyUsage      = ObjectIdentifier{2, 5, 29, 37}
+	oidExtensionAuthorityInfoAccess   = ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 1}
+	oidExtensionOCSPNoCheck           = ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 5}
+)
+
+// ocspNoCheckValue is the value of the id-pkix-ocsp-nocheck extension, an
+// ASN.1 NULL as defined in RFC 6960, section 4.2.2.2.1.
+var ocspNoCheckValue = []byte{0x05, 0x00}
+
 // newExtension creates an Extension from a standard pkix.Extension.
 func newExtension(e pkix.Extension) Extension {
 	return Extension{
@@ -200,8 +265,12 @@ func newExtensions(extensions []pkix.Extension) []Extension {
 	return ret
 }
 
-// Set adds the extension to the given X509 certificate.
+// Set adds the extension to the given X509 certificate. An extension marked to
+// be removed is not added.
 func (e Extension) Set(c *x509.Certificate) {
+	if e.Remove {
+		return
+	}
 	c.ExtraExtensions = append(c.ExtraExtensions, pkix.Extension{
 		Id:       asn1.ObjectIdentifier(e.ID),
 		Critical: e.Critical,
@@ -250,7 +319,7 @@ func (o *ObjectIdentifier) UnmarshalJSON(data []byte) error {
 
 // SubjectAlternativeName represents a X.509 subject alternative name. Types
 // supported are "dns", "email", "ip", "uri". A special type "auto" or "" can be
-// used to try to guess the type of the value.
+// used to guess the type of the value using ClassifySAN.
 //
 // ASN1Value can only be used for those types where the string value cannot
 // contain enough information to encode the value.
@@ -278,11 +347,7 @@ func (s SubjectAlternativeName) Set(c *x509.Certificate) {
 			c.URIs = append(c.URIs, u)
 		}
 	case "", AutoType:
-		dnsNames, ips, emails, uris := SplitSANs([]string{s.Value})
-		c.DNSNames = append(c.DNSNames, dnsNames...)
-		c.IPAddresses = append(c.IPAddresses, ips...)
-		c.EmailAddresses = append(c.EmailAddresses, emails...)
-		c.URIs = append(c.URIs, uris...)
+		SubjectAlternativeName{Type: ClassifySAN(s.Value), Value: s.Value}.Set(c)
 	default:
 		panic(fmt.Sprintf("unsupported subject alternative name type %s", s.Type))
 	}
@@ -295,18 +360,7 @@ func (s SubjectAlternativeName) RawValue() (asn1.RawValue, error) {
 	switch s.Type {
 	case "", AutoType:
 		// autotype requires us to find out what the type is.
-		ip := net.ParseIP(s.Value)
-		u, err := url.Parse(s.Value)
-		switch {
-		case ip != nil:
-			return SubjectAlternativeName{Type: IPType, Value: s.Value}.RawValue()
-		case err == nil && u.Scheme != "":
-			return SubjectAlternativeName{Type: URIType, Value: s.Value}.RawValue()
-		case strings.Contains(s.Value, "@"):
-			return SubjectAlternativeName{Type: EmailType, Value: s.Value}.RawValue()
-		default:
-			return SubjectAlternativeName{Type: DNSType, Value: s.Value}.RawValue()
-		}
+		return SubjectAlternativeName{Type: ClassifySAN(s.Value), Value: s.Value}.RawValue()
 	case EmailType:
 		valid := isIA5String(s.Value)
 		if !valid {
@@ -701,37 +755,8 @@ func (k *ExtKeyUsage) UnmarshalJSON(data []byte) error {
 
 	eku := make([]x509.ExtKeyUsage, len(ms))
 	for i, s := range ms {
-		var ku x509.ExtKeyUsage
-		switch convertName(s) {
-		case convertName(ExtKeyUsageAny):
-			ku = x509.ExtKeyUsageAny
-		case convertName(ExtKeyUsageServerAuth):
-			ku = x509.ExtKeyUsageServerAuth
-		case convertName(ExtKeyUsageClientAuth):
-			ku = x509.ExtKeyUsageClientAuth
-		case convertName(ExtKeyUsageCodeSigning):
-			ku = x509.ExtKeyUsageCodeSigning
-		case convertName(ExtKeyUsageEmailProtection):
-			ku = x509.ExtKeyUsageEmailProtection
-		case convertName(ExtKeyUsageIPSECEndSystem):
-			ku = x509.ExtKeyUsageIPSECEndSystem
-		case convertName(ExtKeyUsageIPSECTunnel):
-			ku = x509.ExtKeyUsageIPSECTunnel
-		case convertName(ExtKeyUsageIPSECUser):
-			ku = x509.ExtKeyUsageIPSECUser
-		case convertName(ExtKeyUsageTimeStamping):
-			ku = x509.ExtKeyUsageTimeStamping
-		case convertName(ExtKeyUsageOCSPSigning):
-			ku = x509.ExtKeyUsageOCSPSigning
-		case convertName(ExtKeyUsageMicrosoftServerGatedCrypto):
-			ku = x509.ExtKeyUsageMicrosoftServerGatedCrypto
-		case convertName(ExtKeyUsageNetscapeServerGatedCrypto):
-			ku = x509.ExtKeyUsageNetscapeServerGatedCrypto
-		case convertName(ExtKeyUsageMicrosoftCommercialCodeSigning):
-			ku = x509.ExtKeyUsageMicrosoftCommercialCodeSigning
-		case convertName(ExtKeyUsageMicrosoftKernelCodeSigning):
-			ku = x509.ExtKeyUsageMicrosoftKernelCodeSigning
-		default:
+		ku, ok := parseExtKeyUsage(s)
+		if !ok {
 			return errors.Errorf("unsupported extKeyUsage %s", s)
 		}
 		eku[i] = ku
@@ -741,6 +766,57 @@ func (k *ExtKeyUsage) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// parseExtKeyUsage returns the crypto/x509 extended key usage with the given
+// name.
+func parseExtKeyUsage(s string) (ku x509.ExtKeyUsage, ok bool) {
+	switch convertName(s) {
+	case convertName(ExtKeyUsageAny):
+		ku = x509.ExtKeyUsageAny
+	case convertName(ExtKeyUsageServerAuth):
+		ku = x509.ExtKeyUsageServerAuth
+	case convertName(ExtKeyUsageClientAuth):
+		ku = x509.ExtKeyUsageClientAuth
+	case convertName(ExtKeyUsageCodeSigning):
+		ku = x509.ExtKeyUsageCodeSigning
+	case convertName(ExtKeyUsageEmailProtection):
+		ku = x509.ExtKeyUsageEmailProtection
+	case convertName(ExtKeyUsageIPSECEndSystem):
+		ku = x509.ExtKeyUsageIPSECEndSystem
+	case convertName(ExtKeyUsageIPSECTunnel):
+		ku = x509.ExtKeyUsageIPSECTunnel
+	case convertName(ExtKeyUsageIPSECUser):
+		ku = x509.ExtKeyUsageIPSECUser
+	case convertName(ExtKeyUsageTimeStamping):
+		ku = x509.ExtKeyUsageTimeStamping
+	case convertName(ExtKeyUsageOCSPSigning):
+		ku = x509.ExtKeyUsageOCSPSigning
+	case convertName(ExtKeyUsageMicrosoftServerGatedCrypto):
+		ku = x509.ExtKeyUsageMicrosoftServerGatedCrypto
+	case convertName(ExtKeyUsageNetscapeServerGatedCrypto):
+		ku = x509.ExtKeyUsageNetscapeServerGatedCrypto
+	case convertName(ExtKeyUsageMicrosoftCommercialCodeSigning):
+		ku = x509.ExtKeyUsageMicrosoftCommercialCodeSigning
+	case convertName(ExtKeyUsageMicrosoftKernelCodeSigning):
+		ku = x509.ExtKeyUsageMicrosoftKernelCodeSigning
+	default:
+		return 0, false
+	}
+	return ku, true
+}
+
+// parseUnknownExtKeyUsage returns the OID of an extended key usage not
+// supported by crypto/x509, s can be one of the supported names or an OID.
+func parseUnknownExtKeyUsage(s string) (asn1.ObjectIdentifier, error) {
+	if oid, ok := unknownExtKeyUsages[convertName(s)]; ok {
+		return oid, nil
+	}
+	oid, err := parseObjectIdentifier(s)
+	if err != nil || len(oid) == 0 {
+		return nil, errors.Errorf("unsupported extKeyUsage %s", s)
+	}
+	return oid, nil
+}
+
 // MarshalJSON implements the json.Marshaler interface and converts a list of
 // extended key usages to a list of strings
 func (k ExtKeyUsage) MarshalJSON() ([]byte, error) {
@@ -784,6 +860,54 @@ func (k ExtKeyUsage) MarshalJSON() ([]byte, error) {
 	return json.Marshal(usages)
 }
 
+// extKeyUsageOIDs maps the extended key usages supported by crypto/x509 to
+// their OIDs.
+var extKeyUsageOIDs = map[x509.ExtKeyUsage]asn1.ObjectIdentifier{
+	x509.ExtKeyUsageAny:                            {2, 5, 29, 37, 0},
+	x509.ExtKeyUsageServerAuth:                     {1, 3, 6, 1, 5, 5, 7, 3, 1},
+	x509.ExtKeyUsageClientAuth:                     {1, 3, 6, 1, 5, 5, 7, 3, 2},
+	x509.ExtKeyUsageCodeSigning:                    {1, 3, 6, 1, 5, 5, 7, 3, 3},
+	x509.ExtKeyUsageEmailProtection:                {1, 3, 6, 1, 5, 5, 7, 3, 4},
+	x509.ExtKeyUsageIPSECEndSystem:                 {1, 3, 6, 1, 5, 5, 7, 3, 5},
+	x509.ExtKeyUsageIPSECTunnel:                    {1, 3, 6, 1, 5, 5, 7, 3, 6},
+	x509.ExtKeyUsageIPSECUser:                      {1, 3, 6, 1, 5, 5, 7, 3, 7},
+	x509.ExtKeyUsageTimeStamping:                   {1, 3, 6, 1, 5, 5, 7, 3, 8},
+	x509.ExtKeyUsageOCSPSigning:                    {1, 3, 6, 1, 5, 5, 7, 3, 9},
+	x509.ExtKeyUsageMicrosoftServerGatedCrypto:     {1, 3, 6, 1, 4, 1, 311, 10, 3, 3},
+	x509.ExtKeyUsageNetscapeServerGatedCrypto:      {2, 16, 840, 1, 113730, 4, 1},
+	x509.ExtKeyUsageMicrosoftCommercialCodeSigning: {1, 3, 6, 1, 4, 1, 311, 2, 1, 22},
+	x509.ExtKeyUsageMicrosoftKernelCodeSigning:     {1, 3, 6, 1, 4, 1, 311, 61, 1, 1},
+}
+
+// newExtKeyUsageExtension returns the extended key usage extension with the
+// given known and unknown extended key usages. Go's crypto/x509 always marks
+// this extension as non-critical; this function allows to create a critical
+// one.
+func newExtKeyUsageExtension(eku ExtKeyUsage, unknown UnknownExtKeyUsage, critical bool) (Extension, error) {
+	oids := make([]asn1.ObjectIdentifier, 0, len(eku)+len(unknown))
+	for _, u := range eku {
+		oid, ok := extKeyUsageOIDs[u]
+		if !ok {
+			return Extension{}, errors.Errorf("error creating extended key usage extension: unsupported extKeyUsage %v", u)
+		}
+		oids = append(oids, oid)
+	}
+	oids = append(oids, unknown...)
+	if len(oids) == 0 {
+		return Extension{}, errors.New("error creating extended key usage extension: extended key usages cannot be empty")
+	}
+
+	value, err := asn1.Marshal(oids)
+	if err != nil {
+		return Extension{}, errors.Wrap(err, "error creating extended key usage extension")
+	}
+	return Extension{
+		ID:       oidExtensionExtendedKeyUsage,
+		Critical: critical,
+		Value:    value,
+	}, nil
+}
+
 // UnknownExtKeyUsage represents the list of OIDs of extended key usages unknown
 // to crypto/x509.
 type UnknownExtKeyUsage MultiObjectIdentifier
@@ -794,12 +918,22 @@ func (u UnknownExtKeyUsage) MarshalJSON() ([]byte, error) {
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface in UnknownExtKeyUsage.
+// The values can be OIDs or the names of the extended key usages not supported
+// by crypto/x509, like "smartCardLogon".
 func (u *UnknownExtKeyUsage) UnmarshalJSON(data []byte) error {
-	var v MultiObjectIdentifier
-	if err := json.Unmarshal(data, &v); err != nil {
+	ms, err := unmarshalMultiString(data)
+	if err != nil {
 		return errors.Wrap(err, "error unmarshaling json")
 	}
-	*u = UnknownExtKeyUsage(v)
+	if ms != nil {
+		oids := make([]asn1.ObjectIdentifier, len(ms))
+		for i, s := range ms {
+			if oids[i], err = parseUnknownExtKeyUsage(s); err != nil {
+				return err
+			}
+		}
+		*u = UnknownExtKeyUsage(oids)
+	}
 	return nil
 }
 
@@ -850,7 +984,9 @@ func (o OCSPServer) Set(c *x509.Certificate) {
 }
 
 // IssuingCertificateURL contains the list of the issuing certificate url that
-// will be encoded in the authority information access extension.
+// will be encoded in the authority information access extension. Each url will
+// be encoded as a caIssuers access description, in the same order, after the
+// OCSP servers.
 type IssuingCertificateURL MultiString
 
 // UnmarshalJSON implements the json.Unmarshaler interface in IssuingCertificateURL.
@@ -868,8 +1004,134 @@ func (u IssuingCertificateURL) Set(c *x509.Certificate) {
 	c.IssuingCertificateURL = u
 }
 
+// oidExtensionSubjectInfoAccess is the OID of the subject information access
+// extension defined in RFC 5280, section 4.2.2.2.
+var oidExtensionSubjectInfoAccess = ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 11}
+
+// SubjectInformationAccess contains the list of access descriptions that will
+// be encoded in the subject information access extension. The access method
+// is usually caRepository (1.3.6.1.5.5.7.48.5) in CA certificates, or
+// timeStamping (1.3.6.1.5.5.7.48.3) in time stamping authority certificates.
+type SubjectInformationAccess []AccessDescription
+
+// AccessDescription is the JSON representation of an access description in
+// the subject information access extension. The location must be an absolute
+// URI.
+type AccessDescription struct {
+	Method   ObjectIdentifier `json:"method"`
+	Location string           `json:"location"`
+}
+
+// Extension returns the subject information access as a non-critical
+// extension.
+func (s SubjectInformationAccess) Extension() (Extension, error) {
+	if len(s) == 0 {
+		return Extension{}, errors.New("error creating subject information access extension: access descriptions cannot be empty")
+	}
+
+	type accessDescription struct {
+		Method   asn1.ObjectIdentifier
+		Location asn1.RawValue
+	}
+	ads := make([]accessDescription, len(s))
+	for i, ad := range s {
+		if len(ad.Method) == 0 {
+			return Extension{}, errors.New("error creating subject information access extension: method cannot be empty")
+		}
+		u, err := url.Parse(ad.Location)
+		if err != nil {
+			return Extension{}, errors.Wrap(err, "error creating subject information access extension")
+		}
+		if err := validateURI(u); err != nil {
+			return Extension{}, errors.Wrap(err, "error creating subject information access extension")
+		}
+		if !isIA5String(ad.Location) {
+			return Extension{}, errors.Errorf("error creating subject information access extension: %q is not an IA5String", ad.Location)
+		}
+		ads[i] = accessDescription{
+			Method:   asn1.ObjectIdentifier(ad.Method),
+			Location: asn1.RawValue{Tag: nameTypeURI, Class: asn1.ClassContextSpecific, Bytes: []byte(ad.Location)},
+		}
+	}
+
+	value, err := asn1.Marshal(ads)
+	if err != nil {
+		return Extension{}, errors.Wrap(err, "error creating subject information access extension")
+	}
+	return Extension{
+		ID:    oidExtensionSubjectInfoAccess,
+		Value: value,
+	}, nil
+}
+
+// oidExtensionPolicyMappings is the OID of the policy mappings extension
+// defined in RFC 5280, section 4.2.1.5.
+var oidExtensionPolicyMappings = ObjectIdentifier{2, 5, 29, 33}
+
+// oidAnyPolicy is the OID of the special anyPolicy certificate policy.
+var oidAnyPolicy = ObjectIdentifier{2, 5, 29, 32, 0}
+
+// PolicyMappings contains the list of policy mappings that will be encoded in
+// the policy mappings extension. It is used in CA certificates, e.g. in bridge
+// CAs, to indicate that a policy of the issuer domain is equivalent to a
+// policy of the subject domain.
+type PolicyMappings []PolicyMapping
+
+// PolicyMapping is the JSON representation of a policy mapping. In JSON, the
+// issuer domain policy is represented by the "issuer" key, and the subject
+// domain policy by the "subject" key. Policies cannot be mapped to or from
+// anyPolicy (2.5.29.32.0).
+type PolicyMapping struct {
+	IssuerDomainPolicy  ObjectIdentifier `json:"issuer"`
+	SubjectDomainPolicy ObjectIdentifier `json:"subject"`
+}
+
+// Extension returns the policy mappings as a critical extension, as
+// recommended by RFC 5280.
+func (p PolicyMappings) Extension() (Extension, error) {
+	if len(p) == 0 {
+		return Extension{}, errors.New("error creating policy mappings extension: mappings cannot be empty")
+	}
+
+	type policyMapping struct {
+		IssuerDomainPolicy  asn1.ObjectIdentifier
+		SubjectDomainPolicy asn1.ObjectIdentifier
+	}
+	mappings := make([]policyMapping, len(p))
+	for i, m := range p {
+		if len(m.IssuerDomainPolicy) == 0 || len(m.SubjectDomainPolicy) == 0 {
+			return Extension{}, errors.New("error creating policy mappings extension: issuer and subject policies cannot be empty")
+		}
+		if m.IssuerDomainPolicy.Equal(oidAnyPolicy) || m.SubjectDomainPolicy.Equal(oidAnyPolicy) {
+			return Extension{}, errors.New("error creating policy mappings extension: anyPolicy cannot be mapped")
+		}
+		mappings[i] = policyMapping{
+			IssuerDomainPolicy:  asn1.ObjectIdentifier(m.IssuerDomainPolicy),
+			SubjectDomainPolicy: asn1.ObjectIdentifier(m.SubjectDomainPolicy),
+		}
+	}
+
+	value, err := asn1.Marshal(mappings)
+	if err != nil {
+		return Extension{}, errors.Wrap(err, "error creating policy mappings extension")
+	}
+	return Extension{
+		ID:       oidExtensionPolicyMappings,
+		Critical: true,
+		Value:    value,
+	}, nil
+}
+
 // CRLDistributionPoints contains the list of CRL distribution points that will
 // be encoded in the CRL distribution points extension.
+//
+// The URLs can contain text/template placeholders that are expanded when the
+// certificate is signed with CreateCertificate, once the serial number is
+// known, e.g. "https://crl.example.com/{{ .SerialHex }}.crl". The available
+// fields are SerialHex, the lower case hex encoding of the serial number
+// bytes, and SerialNumber, its decimal representation. In a certificate
+// template, the placeholders must be escaped so they are not evaluated with
+// the template, e.g. "https://crl.example.com/{{`{{ .SerialHex }}`}}.crl".
 type CRLDistributionPoints MultiString
 
 // UnmarshalJSON implements the json.Unmarshaler interface in CRLDistributionPoints.
@@ -887,6 +1149,48 @@ func (u CRLDistributionPoints) Set(c *x509.Certificate) {
 	c.CRLDistributionPoints = u
 }
 
+// hasCRLDistributionPointPlaceholders reports whether any of the CRL
+// distribution points contains a placeholder.
+func hasCRLDistributionPointPlaceholders(urls []string) bool {
+	for _, u := range urls {
+		if strings.Contains(u, "{{") {
+			return true
+		}
+	}
+	return false
+}
+
+// expandCRLDistributionPoints expands the placeholders in the CRL
+// distribution points using the given serial number. URLs without
+// placeholders are returned as they are.
+func expandCRLDistributionPoints(urls []string, serial *big.Int) ([]string, error) {
+	data := struct {
+		SerialHex    string
+		SerialNumber string
+	}{
+		SerialHex:    hex.EncodeToString(serial.Bytes()),
+		SerialNumber: serial.String(),
+	}
+
+	ret := make([]string, len(urls))
+	for i, u := range urls {
+		if !strings.Contains(u, "{{") {
+			ret[i] = u
+			continue
+		}
+		tmpl, err := template.New("crlDistributionPoint").Option("missingkey=error").Parse(u)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing crl distribution point %q", u)
+		}
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, errors.Wrapf(err, "error executing crl distribution point %q", u)
+		}
+		ret[i] = buf.String()
+	}
+	return ret, nil
+}
+
 // PolicyIdentifiers represents the list of OIDs to set in the certificate
 // policies extension.
 type PolicyIdentifiers MultiObjectIdentifier
@@ -921,7 +1225,9 @@ type BasicConstraints struct {
 	MaxPathLen int  `json:"maxPathLen"`
 }
 
-// Set sets the basic constraints to the given certificate.
+// Set sets the basic constraints to the given certificate. The Go standard
+// library always marks the basic constraints extension as critical, as
+// RFC 5280 requires for CA certificates.
 func (b BasicConstraints) Set(c *x509.Certificate) {
 	c.BasicConstraintsValid = true
 	c.IsCA = b.IsCA
@@ -943,10 +1249,17 @@ func (b BasicConstraints) Set(c *x509.Certificate) {
 	}
 }
 
+// oidExtensionNameConstraints is the OID of the name constraints extension.
+var oidExtensionNameConstraints = ObjectIdentifier{2, 5, 29, 30}
+
 // NameConstraints represents the X509 Name constraints extension and defines a
 // names space within which all subject names in subsequent certificates in a
 // certificate path must be located. The name constraints extension must be used
 // only in a CA.
+//
+// The Go standard library does not support directory name constraints, if
+// permittedDirectoryNames or excludedDirectoryNames are set, the extension
+// will be added to the extensions of the certificate.
 type NameConstraints struct {
 	Critical                bool        `json:"critical"`
 	PermittedDNSDomains     MultiString `json:"permittedDNSDomains"`
@@ -957,6 +1270,8 @@ type NameConstraints struct {
 	ExcludedEmailAddresses  MultiString `json:"excludedEmailAddresses"`
 	PermittedURIDomains     MultiString `json:"permittedURIDomains"`
 	ExcludedURIDomains      MultiString `json:"excludedURIDomains"`
+	PermittedDirectoryNames []Name      `json:"permittedDirectoryNames"`
+	ExcludedDirectoryNames  []Name      `json:"excludedDirectoryNames"`
 }
 
 // Set sets the name constraints in the given certificate.
@@ -972,6 +1287,100 @@ func (n NameConstraints) Set(c *x509.Certificate) {
 	c.ExcludedURIDomains = n.ExcludedURIDomains
 }
 
+// hasDirectoryNames returns true if the name constraints contain directory
+// names.
+func (n NameConstraints) hasDirectoryNames() bool {
+	return len(n.PermittedDirectoryNames) > 0 || len(n.ExcludedDirectoryNames) > 0
+}
+
+// Extension returns the name constraints as an extension. The subtrees are
+// encoded in the same order used by the Go standard library, DNS domains, IP
+// ranges, email addresses and URI domains, followed by the directory names.
+func (n NameConstraints) Extension() (Extension, error) {
+	permitted, err := marshalGeneralSubtrees(n.PermittedDNSDomains, n.PermittedIPRanges,
+		n.PermittedEmailAddresses, n.PermittedURIDomains, n.PermittedDirectoryNames)
+	if err != nil {
+		return Extension{}, errors.Wrap(err, "error creating name constraints extension")
+	}
+	excluded, err := marshalGeneralSubtrees(n.ExcludedDNSDomains, n.ExcludedIPRanges,
+		n.ExcludedEmailAddresses, n.ExcludedURIDomains, n.ExcludedDirectoryNames)
+	if err != nil {
+		return Extension{}, errors.Wrap(err, "error creating name constraints extension")
+	}
+
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		if len(permitted) > 0 {
+			b.AddASN1(cryptobyte_asn1.Tag(0).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
+				b.AddBytes(permitted)
+			})
+		}
+		if len(excluded) > 0 {
+			b.AddASN1(cryptobyte_asn1.Tag(1).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
+				b.AddBytes(excluded)
+			})
+		}
+	})
+	value, err := b.Bytes()
+	if err != nil {
+		return Extension{}, errors.Wrap(err, "error creating name constraints extension")
+	}
+	return Extension{
+		ID:       oidExtensionNameConstraints,
+		Critical: n.Critical,
+		Value:    value,
+	}, nil
+}
+
+// marshalGeneralSubtrees returns the DER encoding of the GeneralSubtree
+// elements with the given names as the base.
+func marshalGeneralSubtrees(dns []string, ips []*net.IPNet, emails, uriDomains []string, dirNames []Name) ([]byte, error) {
+	var b cryptobyte.Builder
+	addSubtree := func(tag cryptobyte_asn1.Tag, value []byte) {
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1(tag, func(b *cryptobyte.Builder) {
+				b.AddBytes(value)
+			})
+		})
+	}
+	addStrings := func(tag cryptobyte_asn1.Tag, name string, values []string) error {
+		for _, v := range values {
+			if !isIA5String(v) {
+				return errors.Errorf("%s %q is not a valid IA5String", name, v)
+			}
+			addSubtree(tag, []byte(v))
+		}
+		return nil
+	}
+
+	if err := addStrings(cryptobyte_asn1.Tag(2).ContextSpecific(), "dns domain", dns); err != nil {
+		return nil, err
+	}
+	for _, ipNet := range ips {
+		ip := ipNet.IP.Mask(ipNet.Mask)
+		if len(ip) == net.IPv6len && ip.To4() != nil {
+			return nil, errors.Errorf("ip range %s contains an IPv4-mapped IPv6 address with a IPv6 mask", ipNet)
+		}
+		addSubtree(cryptobyte_asn1.Tag(7).ContextSpecific(), append(ip, ipNet.Mask...))
+	}
+	if err := addStrings(cryptobyte_asn1.Tag(1).ContextSpecific(), "email address", emails); err != nil {
+		return nil, err
+	}
+	if err := addStrings(cryptobyte_asn1.Tag(6).ContextSpecific(), "uri domain", uriDomains); err != nil {
+		return nil, err
+	}
+	for _, name := range dirNames {
+		der, err := asn1.Marshal(name.goValue().ToRDNSequence())
+		if err != nil {
+			return nil, errors.Wrap(err, "error marshaling directory name")
+		}
+		// The directoryName is explicitly tagged, as Name is a CHOICE.
+		addSubtree(cryptobyte_asn1.Tag(4).ContextSpecific().Constructed(), der)
+	}
+
+	return b.Bytes()
+}
+
 // SerialNumber is the JSON representation of the X509 serial number.
 type SerialNumber struct {
 	*big.Int
@@ -983,12 +1392,14 @@ func (s SerialNumber) Set(c *x509.Certificate) {
 }
 
 // MarshalJSON implements the json.Marshaler interface, and encodes a
-// SerialNumber using the big.Int marshaler.
+// SerialNumber as a string with its decimal representation. A string is used
+// instead of a number so serial numbers that do not fit in an int64 can be
+// unmarshaled again.
 func (s *SerialNumber) MarshalJSON() ([]byte, error) {
 	if s == nil || s.Int == nil {
 		return []byte(`null`), nil
 	}
-	return s.Int.MarshalJSON()
+	return json.Marshal(s.Int.String())
 }
 
 // UnmarshalJSON implements the json.Unmarshal interface and unmarshals an
@@ -1020,6 +1431,81 @@ func (s *SerialNumber) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// UniqueIdentifier is the JSON representation of the X.509 v2 issuer and
+// subject unique identifiers. In JSON it is represented as the base64 encoding
+// of the DER encoded BIT STRING.
+type UniqueIdentifier asn1.BitString
+
+// MarshalJSON implements the json.Marshaler interface, and encodes the unique
+// identifier as the base64 of the DER encoded BIT STRING.
+func (u *UniqueIdentifier) MarshalJSON() ([]byte, error) {
+	if u == nil {
+		return []byte(`null`), nil
+	}
+	b, err := asn1.Marshal(asn1.BitString(*u))
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling unique identifier")
+	}
+	return json.Marshal(b)
+}
+
+// UnmarshalJSON implements the json.Unmarshal interface and unmarshals and
+// validates the base64 of a DER encoded BIT STRING.
+func (u *UniqueIdentifier) UnmarshalJSON(data []byte) error {
+	var b []byte
+	if err := json.Unmarshal(data, &b); err != nil {
+		return errors.Wrap(err, "error unmarshaling json")
+	}
+	var bs asn1.BitString
+	if rest, err := asn1.Unmarshal(b, &bs); err != nil {
+		return errors.Wrap(err, "error unmarshaling unique identifier")
+	} else if len(rest) > 0 {
+		return errors.New("error unmarshaling unique identifier: trailing data")
+	}
+	*u = UniqueIdentifier(bs)
+	return nil
+}
+
+// SubjectAlternativeNameError is the error returned by
+// CreateSubjectAltNameExtension if one of the SANs cannot be encoded.
+type SubjectAlternativeNameError struct {
+	Index int
+	SAN   SubjectAlternativeName
+	Err   error
+}
+
+// Error implements the error interface.
+func (e *SubjectAlternativeNameError) Error() string {
+	return fmt.Sprintf("error encoding SAN %d (%s): %v", e.Index, e.SAN.Type, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *SubjectAlternativeNameError) Unwrap() error {
+	return e.Err
+}
+
+// CreateSubjectAltNameExtension creates a subjectAltName extension with the
+// given SANs in the given order. It supports all the SAN types supported in
+// templates, including the ones not supported by the Go standard library. The
+// extension will be marked as critical if subjectIsEmpty is true.
+//
+// If a SAN cannot be encoded, a *SubjectAlternativeNameError is returned.
+func CreateSubjectAltNameExtension(sans []SubjectAlternativeName, subjectIsEmpty bool) (Extension, error) {
+	if len(sans) == 0 {
+		return Extension{}, errors.New("error creating SubjectAlternativeName extension: sans cannot be empty")
+	}
+	for i, san := range sans {
+		if _, err := san.RawValue(); err != nil {
+			return Extension{}, &SubjectAlternativeNameError{
+				Index: i,
+				SAN:   san,
+				Err:   err,
+			}
+		}
+	}
+	return createSubjectAltNameExtension(nil, nil, nil, nil, sans, subjectIsEmpty)
+}
+
 func createCertificateSubjectAltNameExtension(c Certificate, subjectIsEmpty bool) (Extension, error) {
 	return createSubjectAltNameExtension(c.DNSNames, c.EmailAddresses, c.IPAddresses, c.URIs, c.SANs, subjectIsEmpty)
 }
@@ -1030,13 +1516,14 @@ func createCertificateRequestSubjectAltNameExtension(c CertificateRequest, subje
 
 // createSubjectAltNameExtension will construct an Extension containing all
 // SubjectAlternativeNames held in a Certificate. It implements more types than
-// the golang x509 library, so it is used whenever OtherName or RegisteredID
-// type SANs are present in the certificate.
+// the golang x509 library, so it is used whenever OtherName, DirectoryName or
+// RegisteredID type SANs are present in the certificate. The SANs are never
+// deduplicated, so multiple names of the same type are all added, in order.
 //
 // See also https://datatracker.ietf.org/doc/html/rfc5280.html#section-4.2.1.6
 //
-// TODO(mariano,unreality): X400Address, DirectoryName, and EDIPartyName types
-// are defined in RFC5280 but are currently unimplemented
+// TODO(mariano,unreality): X400Address and EDIPartyName types are defined in
+// RFC5280 but are currently unimplemented
 func createSubjectAltNameExtension(dnsNames, emailAddresses MultiString, ipAddresses MultiIP, uris MultiURL, sans []SubjectAlternativeName, subjectIsEmpty bool) (Extension, error) {
 	var zero Extension
 
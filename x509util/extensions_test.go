@@ -2,6 +2,7 @@ package x509util
 
 import (
 	"bytes"
+	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
@@ -478,6 +479,7 @@ func TestKeyUsage_MarshalJSON(t *testing.T) {
 		{"EncipherOnly", KeyUsage(x509.KeyUsageEncipherOnly), `["encipherOnly"]`, false},
 		{"DecipherOnly", KeyUsage(x509.KeyUsageDecipherOnly), `["decipherOnly"]`, false},
 		{"DigitalSignature + KeyEncipherment", KeyUsage(x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment), `["digitalSignature","keyEncipherment"]`, false},
+		{"None", KeyUsage(0), `null`, false},
 		{"Error", KeyUsage(x509.KeyUsageDecipherOnly << 1), "", true},
 	}
 	for _, tt := range tests {
@@ -731,9 +733,14 @@ func TestUnknownExtKeyUsage_UnmarshalJSON(t *testing.T) {
 	}{
 		{"string", args{[]byte(`"1.2.3.4"`)}, []asn1.ObjectIdentifier{[]int{1, 2, 3, 4}}, false},
 		{"array", args{[]byte(`["1.2.3.4", "5.6.7.8.9.0"]`)}, []asn1.ObjectIdentifier{[]int{1, 2, 3, 4}, []int{5, 6, 7, 8, 9, 0}}, false},
+		{"smartCardLogon", args{[]byte(`"smartCardLogon"`)}, []asn1.ObjectIdentifier{[]int{1, 3, 6, 1, 4, 1, 311, 20, 2, 2}}, false},
+		{"names and oids", args{[]byte(`["document_signing", "1.2.3.4", "KDCAuthentication"]`)}, []asn1.ObjectIdentifier{
+			[]int{1, 3, 6, 1, 4, 1, 311, 10, 3, 12}, []int{1, 2, 3, 4}, []int{1, 3, 6, 1, 5, 2, 3, 5},
+		}, false},
 		{"empty", args{[]byte(`[]`)}, []asn1.ObjectIdentifier{}, false},
 		{"null", args{[]byte(`null`)}, nil, false},
 		{"fail", args{[]byte(`":foo:bar"`)}, nil, true},
+		{"fail name", args{[]byte(`"fooBar"`)}, nil, true},
 		{"failJSON", args{[]byte(`["https://iss#sub"`)}, nil, true},
 	}
 	for _, tt := range tests {
@@ -1170,6 +1177,132 @@ func TestNameConstraints_Set(t *testing.T) {
 		})
 	}
 }
+func TestNameConstraints_Extension(t *testing.T) {
+	mustIPNet := func(s string) *net.IPNet {
+		_, ipNet, err := net.ParseCIDR(s)
+		require.NoError(t, err)
+		return ipNet
+	}
+
+	t.Run("ok standard library compatible", func(t *testing.T) {
+		// Without directory names, the encoding matches the Go standard library.
+		nc := NameConstraints{
+			Critical:                true,
+			PermittedDNSDomains:     []string{"example.com"},
+			ExcludedDNSDomains:      []string{"bad.example.com"},
+			PermittedIPRanges:       []*net.IPNet{mustIPNet("10.0.0.0/8"), mustIPNet("2001:db8::/32")},
+			ExcludedIPRanges:        []*net.IPNet{mustIPNet("10.1.0.0/16")},
+			PermittedEmailAddresses: []string{"example.com"},
+			PermittedURIDomains:     []string{".example.com"},
+			ExcludedURIDomains:      []string{"bad.example.com"},
+		}
+		ext, err := nc.Extension()
+		require.NoError(t, err)
+
+		iss, issPriv := createIssuerCertificate(t, "issuer")
+		template := &x509.Certificate{SerialNumber: big.NewInt(1), IsCA: true, BasicConstraintsValid: true}
+		nc.Set(template)
+		crt, err := CreateCertificate(template, iss, iss.PublicKey, issPriv)
+		require.NoError(t, err)
+		for _, e := range crt.Extensions {
+			if e.Id.Equal(asn1.ObjectIdentifier(oidExtensionNameConstraints)) {
+				assert.Equal(t, Extension{ID: oidExtensionNameConstraints, Critical: true, Value: e.Value}, ext)
+			}
+		}
+	})
+
+	t.Run("ok directory names", func(t *testing.T) {
+		nc := NameConstraints{
+			PermittedDNSDomains: []string{"example.com"},
+			PermittedDirectoryNames: []Name{
+				{Country: []string{"US"}, Organization: []string{"Smallstep Labs"}},
+			},
+			ExcludedDirectoryNames: []Name{
+				{CommonName: "Mallory"},
+			},
+		}
+		ext, err := nc.Extension()
+		require.NoError(t, err)
+		assert.Equal(t, oidExtensionNameConstraints, ext.ID)
+		assert.False(t, ext.Critical)
+
+		var constraints struct {
+			Permitted []asn1.RawValue `asn1:"optional,tag:0"`
+			Excluded  []asn1.RawValue `asn1:"optional,tag:1"`
+		}
+		rest, err := asn1.Unmarshal(ext.Value, &constraints)
+		require.NoError(t, err)
+		require.Empty(t, rest)
+		require.Len(t, constraints.Permitted, 2)
+		require.Len(t, constraints.Excluded, 1)
+
+		baseName := func(subtree asn1.RawValue) pkix.RDNSequence {
+			var base asn1.RawValue
+			_, err := asn1.Unmarshal(subtree.Bytes, &base)
+			require.NoError(t, err)
+			require.Equal(t, asn1.ClassContextSpecific, base.Class)
+			require.Equal(t, 4, base.Tag)
+			require.True(t, base.IsCompound)
+			var rdns pkix.RDNSequence
+			rest, err := asn1.Unmarshal(base.Bytes, &rdns)
+			require.NoError(t, err)
+			require.Empty(t, rest)
+			return rdns
+		}
+
+		assert.Equal(t, pkix.RDNSequence{
+			{{Type: asn1.ObjectIdentifier{2, 5, 4, 6}, Value: "US"}},
+			{{Type: asn1.ObjectIdentifier{2, 5, 4, 10}, Value: "Smallstep Labs"}},
+		}, baseName(constraints.Permitted[1]))
+		assert.Equal(t, pkix.RDNSequence{
+			{{Type: asn1.ObjectIdentifier{2, 5, 4, 3}, Value: "Mallory"}},
+		}, baseName(constraints.Excluded[0]))
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		_, err := NameConstraints{PermittedDNSDomains: []string{"exámple.com"}}.Extension()
+		assert.Error(t, err)
+		_, err = NameConstraints{ExcludedEmailAddresses: []string{"jane@exámple.com"}}.Extension()
+		assert.Error(t, err)
+		_, err = NameConstraints{PermittedURIDomains: []string{"exámple.com"}}.Extension()
+		assert.Error(t, err)
+	})
+}
+
+func TestNewCertificate_nameConstraintsDirectoryNames(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "Intermediate CA", nil)
+	cert, err := NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"basicConstraints": {"isCA": true, "maxPathLen": 0},
+		"nameConstraints": {
+			"critical": true,
+			"permittedDNSDomains": ["example.com"],
+			"permittedDirectoryNames": [{"organization": "Smallstep Labs"}]
+		}
+	}`, CreateTemplateData("Intermediate CA", nil)))
+	require.NoError(t, err)
+	require.Len(t, cert.Extensions, 1)
+	assert.Equal(t, oidExtensionNameConstraints, cert.Extensions[0].ID)
+	assert.True(t, cert.Extensions[0].Critical)
+
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+	template := cert.GetCertificate()
+	crt, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+	require.NoError(t, err)
+
+	// The extension is included only once, and the Go standard library does
+	// not handle directory names in critical name constraints.
+	var count int
+	for _, e := range crt.Extensions {
+		if e.Id.Equal(asn1.ObjectIdentifier(oidExtensionNameConstraints)) {
+			count++
+			assert.Equal(t, cert.Extensions[0].Value, e.Value)
+		}
+	}
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []string{"example.com"}, crt.PermittedDNSDomains)
+	assert.Contains(t, crt.UnhandledCriticalExtensions, asn1.ObjectIdentifier(oidExtensionNameConstraints))
+}
 
 func TestSerialNumber_Set(t *testing.T) {
 	type fields struct {
@@ -1207,7 +1340,7 @@ func TestSerialNumber_MarshalJSON(t *testing.T) {
 		want    []byte
 		wantErr bool
 	}{
-		{"ok", &SerialNumber{big.NewInt(1234)}, []byte("1234"), false},
+		{"ok", &SerialNumber{big.NewInt(1234)}, []byte(`"1234"`), false},
 		{"nilStruct", nil, []byte("null"), false},
 		{"nilBigInt", &SerialNumber{}, []byte("null"), false},
 	}
@@ -1225,6 +1358,22 @@ func TestSerialNumber_MarshalJSON(t *testing.T) {
 	}
 }
 
+func TestSerialNumber_roundTrip(t *testing.T) {
+	large, ok := new(big.Int).SetString("340282366920938463463374607431768211455", 10)
+	require.True(t, ok)
+
+	for _, sn := range []*big.Int{big.NewInt(0), big.NewInt(1234), big.NewInt(-1), large} {
+		t.Run(sn.String(), func(t *testing.T) {
+			b, err := json.Marshal(&Certificate{SerialNumber: SerialNumber{sn}})
+			require.NoError(t, err)
+
+			var got Certificate
+			require.NoError(t, json.Unmarshal(b, &got))
+			assert.Equal(t, 0, sn.Cmp(got.SerialNumber.Int), "got %s, want %s", got.SerialNumber, sn)
+		})
+	}
+}
+
 func TestSerialNumber_UnmarshalJSON(t *testing.T) {
 	expected := SerialNumber{big.NewInt(12345)}
 
@@ -1257,6 +1406,114 @@ func TestSerialNumber_UnmarshalJSON(t *testing.T) {
 	}
 }
 
+func TestCreateSubjectAltNameExtension(t *testing.T) {
+	_, signer := createCertificateRequest(t, "", nil)
+	permanentIdentifier, err := SubjectAlternativeName{Type: PermanentIdentifierType, Value: "123456"}.RawValue()
+	require.NoError(t, err)
+
+	type args struct {
+		sans           []SubjectAlternativeName
+		subjectIsEmpty bool
+	}
+	type want struct {
+		dnsNames []string
+		emails   []string
+		ips      []net.IP
+		uris     []*url.URL
+		tags     []int
+		critical bool
+	}
+	tests := []struct {
+		name      string
+		args      args
+		want      want
+		assertion assert.ErrorAssertionFunc
+	}{
+		{"ok", args{[]SubjectAlternativeName{
+			{Type: DNSType, Value: "foo.com"},
+			{Type: EmailType, Value: "root@foo.com"},
+			{Type: IPType, Value: "10.0.0.1"},
+			{Type: URIType, Value: "urn:foo:bar"},
+		}, false}, want{
+			dnsNames: []string{"foo.com"},
+			emails:   []string{"root@foo.com"},
+			ips:      []net.IP{net.ParseIP("10.0.0.1").To4()},
+			uris:     []*url.URL{{Scheme: "urn", Opaque: "foo:bar"}},
+			tags:     []int{nameTypeDNS, nameTypeEmail, nameTypeIP, nameTypeURI},
+		}, assert.NoError},
+		{"ok mixed", args{[]SubjectAlternativeName{
+			{Type: URIType, Value: "urn:foo:bar"},
+			{Type: PermanentIdentifierType, Value: "123456"},
+			{Type: DNSType, Value: "foo.com"},
+			{Type: AutoType, Value: "10.0.0.1"},
+			{Type: "1.2.3.4", Value: "utf8:otherName"},
+			{Type: AutoType, Value: "bar.com"},
+		}, true}, want{
+			dnsNames: []string{"foo.com", "bar.com"},
+			ips:      []net.IP{net.ParseIP("10.0.0.1").To4()},
+			uris:     []*url.URL{{Scheme: "urn", Opaque: "foo:bar"}},
+			tags:     []int{nameTypeURI, nameTypeOtherName, nameTypeDNS, nameTypeIP, nameTypeOtherName, nameTypeDNS},
+			critical: true,
+		}, assert.NoError},
+		{"fail empty", args{nil, false}, want{}, assert.Error},
+		{"fail ip", args{[]SubjectAlternativeName{
+			{Type: DNSType, Value: "foo.com"},
+			{Type: IPType, Value: "not-an-ip"},
+		}, false}, want{}, func(tt assert.TestingT, err error, i ...interface{}) bool {
+			var sanErr *SubjectAlternativeNameError
+			return assert.ErrorAs(tt, err, &sanErr) &&
+				assert.Equal(tt, 1, sanErr.Index) &&
+				assert.Equal(tt, SubjectAlternativeName{Type: IPType, Value: "not-an-ip"}, sanErr.SAN)
+		}},
+		{"fail unsupported", args{[]SubjectAlternativeName{
+			{Type: X400AddressType, Value: "foo"},
+		}, false}, want{}, func(tt assert.TestingT, err error, i ...interface{}) bool {
+			var sanErr *SubjectAlternativeNameError
+			return assert.ErrorAs(tt, err, &sanErr) && assert.Equal(tt, 0, sanErr.Index)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CreateSubjectAltNameExtension(tt.args.sans, tt.args.subjectIsEmpty)
+			tt.assertion(t, err)
+			if err != nil {
+				assert.Equal(t, Extension{}, got)
+				return
+			}
+			assert.Equal(t, ObjectIdentifier(oidExtensionSubjectAltName), got.ID)
+			assert.Equal(t, tt.want.critical, got.Critical)
+
+			// Embed the extension in a CSR.
+			der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+				ExtraExtensions: []pkix.Extension{
+					{Id: asn1.ObjectIdentifier(got.ID), Critical: got.Critical, Value: got.Value},
+				},
+			}, signer)
+			require.NoError(t, err)
+			cr, err := x509.ParseCertificateRequest(der)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.dnsNames, cr.DNSNames)
+			assert.Equal(t, tt.want.emails, cr.EmailAddresses)
+			assert.Equal(t, tt.want.ips, cr.IPAddresses)
+			assert.Equal(t, tt.want.uris, cr.URIs)
+
+			var tags []int
+			var otherNames [][]byte
+			require.NoError(t, forEachSAN(got.Value, func(v asn1.RawValue) error {
+				tags = append(tags, v.Tag)
+				if v.Tag == nameTypeOtherName {
+					otherNames = append(otherNames, v.FullBytes)
+				}
+				return nil
+			}))
+			assert.Equal(t, tt.want.tags, tags)
+			if len(otherNames) > 0 {
+				assert.Equal(t, permanentIdentifier.FullBytes, otherNames[0])
+			}
+		})
+	}
+}
+
 func Test_createSubjectAltNameExtension(t *testing.T) {
 	type args struct {
 		c              Certificate
@@ -1490,3 +1747,96 @@ func TestParseSubjectAlternativeNames(t *testing.T) {
 		})
 	}
 }
+
+func mustParseCIDR(t *testing.T, s string) *net.IPNet {
+	t.Helper()
+	_, ipNet, err := net.ParseCIDR(s)
+	require.NoError(t, err)
+	return ipNet
+}
+
+func TestPolicyMappings_Extension(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       PolicyMappings
+		want    Extension
+		wantErr string
+	}{
+		{"ok", PolicyMappings{
+			{IssuerDomainPolicy: ObjectIdentifier{1, 2, 3}, SubjectDomainPolicy: ObjectIdentifier{1, 2, 4}},
+		}, Extension{
+			ID: oidExtensionPolicyMappings, Critical: true,
+			Value: []byte{0x30, 0x0a, 0x30, 0x08, 0x06, 0x02, 0x2a, 0x03, 0x06, 0x02, 0x2a, 0x04},
+		}, ""},
+		{"ok multiple", PolicyMappings{
+			{IssuerDomainPolicy: ObjectIdentifier{1, 2, 3}, SubjectDomainPolicy: ObjectIdentifier{1, 2, 4}},
+			{IssuerDomainPolicy: ObjectIdentifier{1, 2, 5}, SubjectDomainPolicy: ObjectIdentifier{1, 2, 4}},
+		}, Extension{
+			ID: oidExtensionPolicyMappings, Critical: true,
+			Value: []byte{0x30, 0x14, 0x30, 0x08, 0x06, 0x02, 0x2a, 0x03, 0x06, 0x02, 0x2a, 0x04, 0x30, 0x08, 0x06, 0x02, 0x2a, 0x05, 0x06, 0x02, 0x2a, 0x04},
+		}, ""},
+		{"fail empty", nil, Extension{}, "error creating policy mappings extension: mappings cannot be empty"},
+		{"fail issuer", PolicyMappings{{SubjectDomainPolicy: ObjectIdentifier{1, 2, 4}}}, Extension{}, "error creating policy mappings extension: issuer and subject policies cannot be empty"},
+		{"fail subject", PolicyMappings{{IssuerDomainPolicy: ObjectIdentifier{1, 2, 3}}}, Extension{}, "error creating policy mappings extension: issuer and subject policies cannot be empty"},
+		{"fail anyPolicy", PolicyMappings{{IssuerDomainPolicy: ObjectIdentifier{2, 5, 29, 32, 0}, SubjectDomainPolicy: ObjectIdentifier{1, 2, 4}}}, Extension{}, "error creating policy mappings extension: anyPolicy cannot be mapped"},
+		{"fail marshal", PolicyMappings{{IssuerDomainPolicy: ObjectIdentifier{1}, SubjectDomainPolicy: ObjectIdentifier{1, 2, 4}}}, Extension{}, "error creating policy mappings extension: asn1: structure error: invalid object identifier"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.p.Extension()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSubjectInformationAccess_Extension(t *testing.T) {
+	caRepository := ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 5}
+	timeStamping := ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 3}
+
+	tests := []struct {
+		name    string
+		s       SubjectInformationAccess
+		want    Extension
+		wantErr string
+	}{
+		{"ok", SubjectInformationAccess{
+			{Method: caRepository, Location: "http://repo.example.com"},
+		}, Extension{
+			ID: oidExtensionSubjectInfoAccess,
+			Value: append([]byte{0x30, 0x25, 0x30, 0x23, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x05, 0x05, 0x07, 0x30, 0x05, 0x86, 0x17},
+				[]byte("http://repo.example.com")...),
+		}, ""},
+		{"ok multiple", SubjectInformationAccess{
+			{Method: caRepository, Location: "http://repo.example.com"},
+			{Method: timeStamping, Location: "https://tsa.example.com"},
+		}, Extension{
+			ID: oidExtensionSubjectInfoAccess,
+			Value: append(append(append([]byte{0x30, 0x4a, 0x30, 0x23, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x05, 0x05, 0x07, 0x30, 0x05, 0x86, 0x17},
+				[]byte("http://repo.example.com")...),
+				0x30, 0x23, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x05, 0x05, 0x07, 0x30, 0x03, 0x86, 0x17),
+				[]byte("https://tsa.example.com")...),
+		}, ""},
+		{"fail empty", nil, Extension{}, "error creating subject information access extension: access descriptions cannot be empty"},
+		{"fail method", SubjectInformationAccess{{Location: "http://repo.example.com"}}, Extension{}, "error creating subject information access extension: method cannot be empty"},
+		{"fail location", SubjectInformationAccess{{Method: caRepository}}, Extension{}, "error creating subject information access extension: invalid URI \"\": URI must have a scheme"},
+		{"fail relative", SubjectInformationAccess{{Method: caRepository, Location: "repo.example.com"}}, Extension{}, "error creating subject information access extension: invalid URI \"repo.example.com\": URI must have a scheme"},
+		{"fail parse", SubjectInformationAccess{{Method: caRepository, Location: "http://repo.example.com/%"}}, Extension{}, "error creating subject information access extension: parse \"http://repo.example.com/%\": invalid URL escape \"%\""},
+		{"fail ia5", SubjectInformationAccess{{Method: caRepository, Location: "http://repö.example.com"}}, Extension{}, "error creating subject information access extension: \"http://repö.example.com\" is not an IA5String"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.s.Extension()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
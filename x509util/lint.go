@@ -0,0 +1,171 @@
+package x509util
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// LintSeverity is the severity of a LintResult.
+type LintSeverity string
+
+const (
+	// LintError indicates that the certificate does not comply with a
+	// requirement.
+	LintError LintSeverity = "error"
+	// LintWarning indicates that the certificate follows a practice that is
+	// allowed but discouraged.
+	LintWarning LintSeverity = "warning"
+)
+
+// MaxLintValidity is the maximum validity period allowed by Lint, 398 days as
+// defined in section 6.3.2 of the CA/Browser Forum Baseline Requirements.
+const MaxLintValidity = 398 * 24 * time.Hour
+
+// LintResult is a problem found by Lint.
+type LintResult struct {
+	Severity LintSeverity
+	Message  string
+}
+
+// String implements the fmt.Stringer interface.
+func (r LintResult) String() string {
+	return string(r.Severity) + ": " + r.Message
+}
+
+// reservedIPNets are the IANA reserved ranges that cannot be included in a
+// publicly-trusted certificate.
+var reservedIPNets = mustParseCIDRs(
+	// IPv4
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8",
+	"169.254.0.0/16", "172.16.0.0/12", "192.0.0.0/24", "192.0.2.0/24",
+	"192.88.99.0/24", "192.168.0.0/16", "198.18.0.0/15", "198.51.100.0/24",
+	"203.0.113.0/24", "224.0.0.0/4", "240.0.0.0/4",
+	// IPv6
+	"::/128", "::1/128", "64:ff9b:1::/48", "100::/64",
+	"2001::/23", "2001:db8::/32", "2002::/16", "fc00::/7", "fe80::/10",
+	"ff00::/8",
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, s := range cidrs {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+func isReservedIP(ip net.IP) bool {
+	for _, n := range reservedIPNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lint checks the given TLS server certificate against a subset of the
+// CA/Browser Forum Baseline Requirements and returns the problems found. It
+// checks that:
+//
+//   - the certificate is not a CA.
+//   - the subjectAltName extension is present and not empty.
+//   - the common name, if present, is also included in the SANs.
+//   - the validity period is not longer than 398 days.
+//   - there are no IP addresses in the IANA reserved ranges.
+//   - the extended key usage contains serverAuth and, optionally, clientAuth.
+//
+// This is not a complete linter, it only catches common mistakes. It returns
+// nil if no problems are found.
+func Lint(cert *x509.Certificate) []LintResult {
+	var results []LintResult
+	add := func(severity LintSeverity, format string, args ...interface{}) {
+		results = append(results, LintResult{
+			Severity: severity,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	// Basic constraints
+	if cert.IsCA {
+		add(LintError, "basicConstraints cA must be false in a subscriber certificate")
+	}
+
+	// Subject alternative names
+	if len(cert.DNSNames)+len(cert.IPAddresses) == 0 {
+		add(LintError, "subjectAltName must contain at least one dNSName or iPAddress")
+	}
+	if len(cert.EmailAddresses) > 0 {
+		add(LintError, "subjectAltName must not contain rfc822Name entries")
+	}
+	if len(cert.URIs) > 0 {
+		add(LintError, "subjectAltName must not contain uniformResourceIdentifier entries")
+	}
+	for _, ip := range cert.IPAddresses {
+		if isReservedIP(ip) {
+			add(LintError, "subjectAltName must not contain the reserved IP address %s", ip)
+		}
+	}
+
+	// Common name
+	if cn := cert.Subject.CommonName; cn != "" && !lintHasSAN(cert, cn) {
+		add(LintError, "commonName %q must be included in the subjectAltName", cn)
+	}
+
+	// Validity
+	if !cert.NotAfter.After(cert.NotBefore) {
+		add(LintError, "notAfter must be after notBefore")
+	} else if d := cert.NotAfter.Sub(cert.NotBefore) + time.Second; d > MaxLintValidity {
+		// The validity period includes both notBefore and notAfter.
+		add(LintError, "validity period of %s is longer than the maximum of %d days",
+			d, MaxLintValidity/(24*time.Hour))
+	}
+
+	// Extended key usage
+	var hasServerAuth bool
+	if len(cert.UnknownExtKeyUsage) > 0 {
+		add(LintError, "extKeyUsage must only contain serverAuth and clientAuth")
+	}
+	for _, eku := range cert.ExtKeyUsage {
+		switch eku {
+		case x509.ExtKeyUsageServerAuth:
+			hasServerAuth = true
+		case x509.ExtKeyUsageClientAuth:
+			add(LintWarning, "extKeyUsage contains clientAuth, some root programs only accept serverAuth")
+		case x509.ExtKeyUsageAny:
+			add(LintError, "extKeyUsage must not contain anyExtendedKeyUsage")
+		default:
+			add(LintError, "extKeyUsage must only contain serverAuth and clientAuth")
+		}
+	}
+	if !hasServerAuth {
+		add(LintError, "extKeyUsage must contain serverAuth")
+	}
+
+	return results
+}
+
+// lintHasSAN returns if the given common name is in the dNSName or iPAddress
+// SANs of the certificate.
+func lintHasSAN(cert *x509.Certificate, cn string) bool {
+	if ip := net.ParseIP(cn); ip != nil {
+		for _, v := range cert.IPAddresses {
+			if ip.Equal(v) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, v := range cert.DNSNames {
+		if strings.EqualFold(cn, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package x509util
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	mustURL := func(s string) *url.URL {
+		u, err := url.Parse(s)
+		require.NoError(t, err)
+		return u
+	}
+	leaf := func(fn func(c *x509.Certificate)) *x509.Certificate {
+		c := &x509.Certificate{
+			Subject:     pkix.Name{CommonName: "www.example.com"},
+			NotBefore:   now,
+			NotAfter:    now.Add(90 * 24 * time.Hour),
+			DNSNames:    []string{"www.example.com", "example.com"},
+			IPAddresses: []net.IP{net.ParseIP("8.8.8.8")},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+		if fn != nil {
+			fn(c)
+		}
+		return c
+	}
+	lintError := func(msg string) LintResult {
+		return LintResult{Severity: LintError, Message: msg}
+	}
+
+	tests := []struct {
+		name string
+		cert *x509.Certificate
+		want []LintResult
+	}{
+		{"ok", leaf(nil), nil},
+		{"ok no common name", leaf(func(c *x509.Certificate) {
+			c.Subject = pkix.Name{}
+		}), nil},
+		{"ok common name case", leaf(func(c *x509.Certificate) {
+			c.Subject.CommonName = "WWW.Example.Com"
+		}), nil},
+		{"ok common name ip", leaf(func(c *x509.Certificate) {
+			c.Subject.CommonName = "8.8.8.8"
+		}), nil},
+		{"ok max validity", leaf(func(c *x509.Certificate) {
+			c.NotAfter = now.Add(MaxLintValidity - time.Second)
+		}), nil},
+		{"ok ipv6", leaf(func(c *x509.Certificate) {
+			c.IPAddresses = []net.IP{net.ParseIP("2606:4700:4700::1111")}
+		}), nil},
+		{"warning clientAuth", leaf(func(c *x509.Certificate) {
+			c.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+		}), []LintResult{
+			{LintWarning, "extKeyUsage contains clientAuth, some root programs only accept serverAuth"},
+		}},
+		{"fail ca", leaf(func(c *x509.Certificate) {
+			c.IsCA = true
+		}), []LintResult{
+			lintError("basicConstraints cA must be false in a subscriber certificate"),
+		}},
+		{"fail no sans", leaf(func(c *x509.Certificate) {
+			c.Subject = pkix.Name{}
+			c.DNSNames = nil
+			c.IPAddresses = nil
+		}), []LintResult{
+			lintError("subjectAltName must contain at least one dNSName or iPAddress"),
+		}},
+		{"fail email and uri", leaf(func(c *x509.Certificate) {
+			c.EmailAddresses = []string{"jane@example.com"}
+			c.URIs = []*url.URL{mustURL("https://example.com")}
+		}), []LintResult{
+			lintError("subjectAltName must not contain rfc822Name entries"),
+			lintError("subjectAltName must not contain uniformResourceIdentifier entries"),
+		}},
+		{"fail reserved ips", leaf(func(c *x509.Certificate) {
+			c.IPAddresses = []net.IP{
+				net.ParseIP("8.8.8.8"), net.ParseIP("10.0.0.1"), net.ParseIP("127.0.0.1"),
+				net.ParseIP("192.0.2.10"), net.ParseIP("::1"), net.ParseIP("fd00::1"),
+			}
+		}), []LintResult{
+			lintError("subjectAltName must not contain the reserved IP address 10.0.0.1"),
+			lintError("subjectAltName must not contain the reserved IP address 127.0.0.1"),
+			lintError("subjectAltName must not contain the reserved IP address 192.0.2.10"),
+			lintError("subjectAltName must not contain the reserved IP address ::1"),
+			lintError("subjectAltName must not contain the reserved IP address fd00::1"),
+		}},
+		{"fail common name not in sans", leaf(func(c *x509.Certificate) {
+			c.Subject.CommonName = "foo.example.com"
+		}), []LintResult{
+			lintError(`commonName "foo.example.com" must be included in the subjectAltName`),
+		}},
+		{"fail common name ip not in sans", leaf(func(c *x509.Certificate) {
+			c.Subject.CommonName = "1.1.1.1"
+		}), []LintResult{
+			lintError(`commonName "1.1.1.1" must be included in the subjectAltName`),
+		}},
+		{"fail validity", leaf(func(c *x509.Certificate) {
+			c.NotAfter = now.Add(MaxLintValidity)
+		}), []LintResult{
+			lintError("validity period of 9552h0m1s is longer than the maximum of 398 days"),
+		}},
+		{"fail validity one year", leaf(func(c *x509.Certificate) {
+			c.NotAfter = now.Add(2 * 365 * 24 * time.Hour)
+		}), []LintResult{
+			lintError("validity period of 17520h0m1s is longer than the maximum of 398 days"),
+		}},
+		{"fail validity order", leaf(func(c *x509.Certificate) {
+			c.NotBefore, c.NotAfter = c.NotAfter, c.NotBefore
+		}), []LintResult{
+			lintError("notAfter must be after notBefore"),
+		}},
+		{"fail no eku", leaf(func(c *x509.Certificate) {
+			c.ExtKeyUsage = nil
+		}), []LintResult{
+			lintError("extKeyUsage must contain serverAuth"),
+		}},
+		{"fail eku", leaf(func(c *x509.Certificate) {
+			c.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageAny, x509.ExtKeyUsageCodeSigning}
+		}), []LintResult{
+			lintError("extKeyUsage must not contain anyExtendedKeyUsage"),
+			lintError("extKeyUsage must only contain serverAuth and clientAuth"),
+			lintError("extKeyUsage must contain serverAuth"),
+		}},
+		{"fail unknown eku", leaf(func(c *x509.Certificate) {
+			c.UnknownExtKeyUsage = []asn1.ObjectIdentifier{{1, 2, 3, 4}}
+		}), []LintResult{
+			lintError("extKeyUsage must only contain serverAuth and clientAuth"),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Lint(tt.cert))
+		})
+	}
+}
+
+func TestLint_certificate(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "www.example.com", []string{"www.example.com", "10.1.2.3"})
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+
+	cert, err := NewCertificate(cr)
+	require.NoError(t, err)
+
+	template := cert.GetCertificate()
+	template.NotBefore = time.Now()
+	template.NotAfter = template.NotBefore.Add(24 * time.Hour)
+	crt, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+	require.NoError(t, err)
+
+	assert.Equal(t, []LintResult{
+		{LintError, "subjectAltName must not contain the reserved IP address 10.1.2.3"},
+		{LintWarning, "extKeyUsage contains clientAuth, some root programs only accept serverAuth"},
+	}, Lint(crt))
+}
+
+func TestLintResult_String(t *testing.T) {
+	assert.Equal(t, "error: foo", LintResult{LintError, "foo"}.String())
+	assert.Equal(t, "warning: bar", LintResult{LintWarning, "bar"}.String())
+}
@@ -34,6 +34,18 @@ func (m *MultiString) UnmarshalJSON(data []byte) error {
 // a []net.IP.
 type MultiIP []net.IP
 
+// MarshalJSON implements the json.Marshaler interface for MultiIP.
+func (m MultiIP) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("null"), nil
+	}
+	ips := make([]string, len(m))
+	for i, ip := range m {
+		ips[i] = ip.String()
+	}
+	return json.Marshal(ips)
+}
+
 // UnmarshalJSON implements the json.Unmarshaler interface for MultiIP.
 func (m *MultiIP) UnmarshalJSON(data []byte) error {
 	ms, err := unmarshalMultiString(data)
@@ -129,6 +141,19 @@ func (m *MultiURL) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// Validate checks that all the URLs are absolute URIs with a scheme and a
+// hierarchical part, as required in a uniformResourceIdentifier general name.
+// Relative URIs, like "example.com/path", and opaque URIs, like
+// "mailto:jane@example.com", will return an error.
+func (m MultiURL) Validate() error {
+	for _, u := range m {
+		if err := validateURI(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // MultiObjectIdentifier is a type used to unmarshal a JSON string or an array
 // of strings into a []asn1.ObjectIdentifier.
 type MultiObjectIdentifier []asn1.ObjectIdentifier
@@ -88,6 +88,39 @@ func TestMultiIP_MarshalJSON(t *testing.T) {
 	}
 }
 
+func TestMultiIP_roundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		m    MultiIP
+	}{
+		{"ipv4", []net.IP{net.ParseIP("1.2.3.4")}},
+		{"ipv4 4 bytes", []net.IP{net.IPv4(127, 0, 0, 1).To4()}},
+		{"ipv6", []net.IP{net.ParseIP("2001:db8::1"), net.ParseIP("::1")}},
+		{"empty", []net.IP{}},
+		{"nil", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.m)
+			if err != nil {
+				t.Fatalf("MultiIP.MarshalJSON() error = %v", err)
+			}
+			var got MultiIP
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatalf("MultiIP.UnmarshalJSON() error = %v", err)
+			}
+			if len(got) != len(tt.m) || (got == nil) != (tt.m == nil) {
+				t.Fatalf("MultiIP.UnmarshalJSON(MultiIP.MarshalJSON()) = %v, want %v", got, tt.m)
+			}
+			for i := range tt.m {
+				if !got[i].Equal(tt.m[i]) {
+					t.Errorf("MultiIP.UnmarshalJSON(MultiIP.MarshalJSON()) = %v, want %v", got, tt.m)
+				}
+			}
+		})
+	}
+}
+
 func TestMultiIP_UnmarshalJSON(t *testing.T) {
 	type args struct {
 		data []byte
@@ -244,6 +277,38 @@ func TestMultiURL_UnmarshalJSON(t *testing.T) {
 	}
 }
 
+func TestMultiURL_Validate(t *testing.T) {
+	mustParse := func(s string) *url.URL {
+		u, err := url.Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	tests := []struct {
+		name    string
+		m       MultiURL
+		wantErr bool
+	}{
+		{"ok", MultiURL{mustParse("https://example.com/path"), mustParse("spiffe://example.org/workload")}, false},
+		{"ok file", MultiURL{mustParse("file:///path/to/file")}, false},
+		{"ok empty", MultiURL{}, false},
+		{"ok nil", nil, false},
+		{"fail relative", MultiURL{mustParse("https://example.com"), mustParse("example.com/path")}, true},
+		{"fail relative path", MultiURL{mustParse("/path")}, true},
+		{"fail opaque", MultiURL{mustParse("mailto:jane@example.com")}, true},
+		{"fail urn", MultiURL{mustParse("urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6")}, true},
+		{"fail nil url", MultiURL{nil}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.m.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("MultiURL.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestMultiObjectIdentifier_MarshalJSON(t *testing.T) {
 	tests := []struct {
 		name    string
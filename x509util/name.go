@@ -1,7 +1,6 @@
 package x509util
 
 import (
-	"bytes"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
@@ -23,13 +22,28 @@ var attributeTypeNames = map[string]string{
 	"2.5.4.8":  "ST",
 	"2.5.4.9":  "STREET",
 	"2.5.4.17": "POSTALCODE",
+	"2.5.4.42": "GN",
+	"2.5.4.4":  "SN",
+	"2.5.4.65": "PSEUDONYM",
 }
 
+// Subject attributes not supported by pkix.Name. They are encoded in
+// pkix.Name.ExtraNames.
+var (
+	oidGivenName = asn1.ObjectIdentifier{2, 5, 4, 42}
+	oidSurname   = asn1.ObjectIdentifier{2, 5, 4, 4}
+	oidPseudonym = asn1.ObjectIdentifier{2, 5, 4, 65}
+)
+
 // oidEmailAddress is the oid of the deprecated emailAddress in the subject.
 var oidEmailAddress = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 1}
 
 // Name is the JSON representation of X.501 type Name, used in the X.509 subject
 // and issuer fields.
+//
+// The givenName, surname, and pseudonym attributes are not supported by
+// pkix.Name, they are encoded after the common name, in that order, and before
+// the extraNames.
 type Name struct {
 	Country            MultiString         `json:"country,omitempty"`
 	Organization       MultiString         `json:"organization,omitempty"`
@@ -40,6 +54,9 @@ type Name struct {
 	PostalCode         MultiString         `json:"postalCode,omitempty"`
 	SerialNumber       string              `json:"serialNumber,omitempty"`
 	CommonName         string              `json:"commonName,omitempty"`
+	GivenName          MultiString         `json:"givenName,omitempty"`
+	Surname            MultiString         `json:"surname,omitempty"`
+	Pseudonym          MultiString         `json:"pseudonym,omitempty"`
 	ExtraNames         []DistinguishedName `json:"extraNames,omitempty"`
 }
 
@@ -54,6 +71,9 @@ func newName(n pkix.Name) Name {
 		PostalCode:         n.PostalCode,
 		SerialNumber:       n.SerialNumber,
 		CommonName:         n.CommonName,
+		GivenName:          attributeValues(n.Names, oidGivenName),
+		Surname:            attributeValues(n.Names, oidSurname),
+		Pseudonym:          attributeValues(n.Names, oidPseudonym),
 		ExtraNames:         NewExtraNames(n.Names),
 	}
 }
@@ -70,8 +90,39 @@ func (n Name) goValue() pkix.Name {
 		PostalCode:         n.PostalCode,
 		SerialNumber:       n.SerialNumber,
 		CommonName:         n.CommonName,
-		ExtraNames:         fromDistinguishedNames(n.ExtraNames),
+		ExtraNames:         n.extraNames(),
+	}
+}
+
+// extraNames returns the attributes not supported by pkix.Name followed by
+// the extra names.
+func (n Name) extraNames() []pkix.AttributeTypeAndValue {
+	var atvs []pkix.AttributeTypeAndValue
+	for _, attr := range []struct {
+		oid    asn1.ObjectIdentifier
+		values MultiString
+	}{
+		{oidGivenName, n.GivenName},
+		{oidSurname, n.Surname},
+		{oidPseudonym, n.Pseudonym},
+	} {
+		for _, v := range attr.values {
+			atvs = append(atvs, pkix.AttributeTypeAndValue{Type: attr.oid, Value: v})
+		}
 	}
+	return append(atvs, fromDistinguishedNames(n.ExtraNames)...)
+}
+
+// attributeValues returns the string values of the attributes with the given
+// type.
+func attributeValues(atvs []pkix.AttributeTypeAndValue, oid asn1.ObjectIdentifier) MultiString {
+	var values MultiString
+	for _, atv := range atvs {
+		if v, ok := atv.Value.(string); ok && atv.Type.Equal(oid) {
+			values = append(values, v)
+		}
+	}
+	return values
 }
 
 // UnmarshalJSON implements the json.Unmarshal interface and unmarshals a JSON
@@ -115,13 +166,10 @@ func (s Subject) Set(c *x509.Certificate) {
 }
 
 // IsEmpty returns if the subject is empty. Certificates with an empty subject
-// must have the subjectAltName extension mark as critical.
+// must have the subjectAltName extension mark as critical, see RFC 5280,
+// section 4.2.1.6.
 func (s Subject) IsEmpty() bool {
-	subject := Name(s).goValue()
-	if asn1Subject, err := asn1.Marshal(subject.ToRDNSequence()); err == nil {
-		return bytes.Equal(asn1Subject, emptyASN1Subject)
-	}
-	return false
+	return subjectIsEmpty(Name(s).goValue())
 }
 
 // Issuer is the JSON representation of the X.509 issuer field.
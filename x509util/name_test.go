@@ -55,6 +55,25 @@ func Test_newName(t *testing.T) {
 				{Type: ObjectIdentifier{1, 2, 840, 113549, 1, 9, 1}, Value: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagIA5String, Bytes: []byte("jane@example.com")}},
 			},
 		}},
+		{"ok natural person", args{pkix.Name{
+			CommonName: "Jane Doe",
+			Names: []pkix.AttributeTypeAndValue{
+				{Type: asn1.ObjectIdentifier{2, 5, 4, 3}, Value: "Jane Doe"},
+				{Type: asn1.ObjectIdentifier{2, 5, 4, 42}, Value: "Jane"},
+				{Type: asn1.ObjectIdentifier{2, 5, 4, 42}, Value: "Mary"},
+				{Type: asn1.ObjectIdentifier{2, 5, 4, 4}, Value: "Doe"},
+				{Type: asn1.ObjectIdentifier{2, 5, 4, 65}, Value: "jdoe"},
+				{Type: asn1.ObjectIdentifier{2, 5, 4, 12}, Value: "Engineer"},
+			},
+		}}, Name{
+			CommonName: "Jane Doe",
+			GivenName:  []string{"Jane", "Mary"},
+			Surname:    []string{"Doe"},
+			Pseudonym:  []string{"jdoe"},
+			ExtraNames: []DistinguishedName{
+				{Type: ObjectIdentifier{2, 5, 4, 12}, Value: "Engineer"},
+			},
+		}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
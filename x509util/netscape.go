@@ -0,0 +1,146 @@
+package x509util
+
+import (
+	"encoding/asn1"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// OIDs of the legacy Netscape certificate extensions.
+var (
+	oidExtensionNetscapeCertType = ObjectIdentifier{2, 16, 840, 1, 113730, 1, 1}
+	oidExtensionNetscapeComment  = ObjectIdentifier{2, 16, 840, 1, 113730, 1, 13}
+)
+
+// Names used for the Netscape certificate types.
+const (
+	NetscapeCertTypeSSLClientName       = "sslClient"
+	NetscapeCertTypeSSLServerName       = "sslServer"
+	NetscapeCertTypeSMIMEName           = "smime"
+	NetscapeCertTypeObjectSigningName   = "objectSigning"
+	NetscapeCertTypeSSLCAName           = "sslCA"
+	NetscapeCertTypeSMIMECAName         = "smimeCA"
+	NetscapeCertTypeObjectSigningCAName = "objectSigningCA"
+)
+
+// NetscapeCertType represents the bits in the legacy Netscape certificate
+// type extension (OID 2.16.840.1.113730.1.1). In JSON, it is represented as a
+// string or a list of strings with the names of the bits set, e.g.
+// ["sslClient", "sslServer"].
+type NetscapeCertType int
+
+// Bits in the Netscape certificate type extension. Bit 4 is reserved.
+const (
+	NetscapeCertTypeSSLClient NetscapeCertType = 1 << iota
+	NetscapeCertTypeSSLServer
+	NetscapeCertTypeSMIME
+	NetscapeCertTypeObjectSigning
+	_
+	NetscapeCertTypeSSLCA
+	NetscapeCertTypeSMIMECA
+	NetscapeCertTypeObjectSigningCA
+)
+
+var netscapeCertTypeNames = []struct {
+	name string
+	bit  NetscapeCertType
+}{
+	{NetscapeCertTypeSSLClientName, NetscapeCertTypeSSLClient},
+	{NetscapeCertTypeSSLServerName, NetscapeCertTypeSSLServer},
+	{NetscapeCertTypeSMIMEName, NetscapeCertTypeSMIME},
+	{NetscapeCertTypeObjectSigningName, NetscapeCertTypeObjectSigning},
+	{NetscapeCertTypeSSLCAName, NetscapeCertTypeSSLCA},
+	{NetscapeCertTypeSMIMECAName, NetscapeCertTypeSMIMECA},
+	{NetscapeCertTypeObjectSigningCAName, NetscapeCertTypeObjectSigningCA},
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface and converts a
+// string or a list of strings into a Netscape certificate type.
+func (n *NetscapeCertType) UnmarshalJSON(data []byte) error {
+	ms, err := unmarshalMultiString(data)
+	if err != nil {
+		return err
+	}
+
+	*n = 0
+
+	for _, s := range ms {
+		var found bool
+		for _, v := range netscapeCertTypeNames {
+			if convertName(s) == convertName(v.name) {
+				*n |= v.bit
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf("unsupported netscapeCertType %s", s)
+		}
+	}
+
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface and converts a Netscape
+// certificate type into a list of strings.
+func (n NetscapeCertType) MarshalJSON() ([]byte, error) {
+	var types []string
+	for _, v := range netscapeCertTypeNames {
+		if n&v.bit != 0 {
+			types = append(types, v.name)
+		}
+	}
+	return json.Marshal(types)
+}
+
+// Extension returns the Netscape certificate type as a non-critical
+// extension. The value is a BIT STRING where the bit 0 is the most
+// significant bit of the first byte, and trailing zero bits are removed.
+func (n NetscapeCertType) Extension() (Extension, error) {
+	if n <= 0 || n > 0xff {
+		return Extension{}, errors.Errorf("error creating netscape certificate type extension: invalid value %d", int(n))
+	}
+
+	var b byte
+	var bitLength int
+	for i := 0; i < 8; i++ {
+		if n&(1<<i) != 0 {
+			b |= 0x80 >> i
+			bitLength = i + 1
+		}
+	}
+
+	value, err := asn1.Marshal(asn1.BitString{Bytes: []byte{b}, BitLength: bitLength})
+	if err != nil {
+		return Extension{}, errors.Wrap(err, "error creating netscape certificate type extension")
+	}
+	return Extension{
+		ID:    oidExtensionNetscapeCertType,
+		Value: value,
+	}, nil
+}
+
+// NetscapeComment is the text in the legacy Netscape comment extension (OID
+// 2.16.840.1.113730.1.13). It must only contain ASCII characters.
+type NetscapeComment string
+
+// Extension returns the Netscape comment as a non-critical extension. The
+// value is an IA5String.
+func (n NetscapeComment) Extension() (Extension, error) {
+	if n == "" {
+		return Extension{}, errors.New("error creating netscape comment extension: comment cannot be empty")
+	}
+	if !isIA5String(string(n)) {
+		return Extension{}, errors.Errorf("error creating netscape comment extension: %q is not an IA5String", string(n))
+	}
+
+	value, err := asn1.MarshalWithParams(string(n), "ia5")
+	if err != nil {
+		return Extension{}, errors.Wrap(err, "error creating netscape comment extension")
+	}
+	return Extension{
+		ID:    oidExtensionNetscapeComment,
+		Value: value,
+	}, nil
+}
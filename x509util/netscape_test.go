@@ -0,0 +1,158 @@
+package x509util
+
+import (
+	"encoding/asn1"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetscapeCertType_JSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    NetscapeCertType
+		wantErr bool
+	}{
+		{"string", `"sslServer"`, NetscapeCertTypeSSLServer, false},
+		{"list", `["sslClient", "SSL_Server", "smime", "objectSigning"]`, NetscapeCertTypeSSLClient | NetscapeCertTypeSSLServer | NetscapeCertTypeSMIME | NetscapeCertTypeObjectSigning, false},
+		{"ca", `["sslCA", "smimeCA", "objectSigningCA"]`, NetscapeCertTypeSSLCA | NetscapeCertTypeSMIMECA | NetscapeCertTypeObjectSigningCA, false},
+		{"null", `null`, 0, false},
+		{"fail name", `["sslClient", "reserved"]`, 0, true},
+		{"fail type", `1`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got NetscapeCertType
+			err := json.Unmarshal([]byte(tt.data), &got)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			b, err := json.Marshal(got)
+			require.NoError(t, err)
+			var roundTrip NetscapeCertType
+			require.NoError(t, json.Unmarshal(b, &roundTrip))
+			assert.Equal(t, tt.want, roundTrip)
+		})
+	}
+
+	b, err := json.Marshal(NetscapeCertTypeSSLClient | NetscapeCertTypeSSLCA)
+	require.NoError(t, err)
+	assert.JSONEq(t, `["sslClient", "sslCA"]`, string(b))
+}
+
+func TestNetscapeCertType_Extension(t *testing.T) {
+	tests := []struct {
+		name      string
+		n         NetscapeCertType
+		want      Extension
+		assertion assert.ErrorAssertionFunc
+	}{
+		{"ok client server", NetscapeCertTypeSSLClient | NetscapeCertTypeSSLServer, Extension{
+			ID: oidExtensionNetscapeCertType, Value: []byte{0x03, 0x02, 0x06, 0xc0},
+		}, assert.NoError},
+		{"ok object signing ca", NetscapeCertTypeObjectSigningCA, Extension{
+			ID: oidExtensionNetscapeCertType, Value: []byte{0x03, 0x02, 0x00, 0x01},
+		}, assert.NoError},
+		{"ok ssl ca", NetscapeCertTypeSSLCA, Extension{
+			ID: oidExtensionNetscapeCertType, Value: []byte{0x03, 0x02, 0x02, 0x04},
+		}, assert.NoError},
+		{"fail empty", 0, Extension{}, assert.Error},
+		{"fail overflow", 0x100, Extension{}, assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.n.Extension()
+			tt.assertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNetscapeComment_Extension(t *testing.T) {
+	tests := []struct {
+		name      string
+		n         NetscapeComment
+		want      Extension
+		assertion assert.ErrorAssertionFunc
+	}{
+		{"ok", "hi", Extension{
+			ID: oidExtensionNetscapeComment, Value: []byte{0x16, 0x02, 0x68, 0x69},
+		}, assert.NoError},
+		{"fail empty", "", Extension{}, assert.Error},
+		{"fail ia5", "¡hola!", Extension{}, assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.n.Extension()
+			tt.assertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCreateCertificate_netscape(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "legacy.example.com", []string{"legacy.example.com"})
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+
+	cert, err := NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"dnsNames": ["legacy.example.com"],
+		"netscapeCertType": ["sslClient", "sslServer"],
+		"netscapeComment": "Generated by step"
+	}`, NewTemplateData()))
+	require.NoError(t, err)
+
+	template := cert.GetCertificate()
+	got, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+	require.NoError(t, err)
+
+	var certType asn1.BitString
+	var comment string
+	var found int
+	for _, ext := range got.Extensions {
+		switch {
+		case ext.Id.Equal(asn1.ObjectIdentifier{2, 16, 840, 1, 113730, 1, 1}):
+			assert.False(t, ext.Critical)
+			rest, err := asn1.Unmarshal(ext.Value, &certType)
+			require.NoError(t, err)
+			require.Empty(t, rest)
+			found++
+		case ext.Id.Equal(asn1.ObjectIdentifier{2, 16, 840, 1, 113730, 1, 13}):
+			assert.False(t, ext.Critical)
+			rest, err := asn1.UnmarshalWithParams(ext.Value, &comment, "ia5")
+			require.NoError(t, err)
+			require.Empty(t, rest)
+			found++
+		}
+	}
+	require.Equal(t, 2, found)
+	assert.Equal(t, "Generated by step", comment)
+	assert.Equal(t, 2, certType.BitLength)
+	assert.Equal(t, 1, certType.At(0), "sslClient")
+	assert.Equal(t, 1, certType.At(1), "sslServer")
+
+	// A custom extension has precedence over the typed fields.
+	cert, err = NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"netscapeComment": "ignored",
+		"extensions": [{"id": "2.16.840.1.113730.1.13", "value": "FgNmb28="}]
+	}`, NewTemplateData()))
+	require.NoError(t, err)
+	require.Len(t, cert.Extensions, 1)
+	assert.Equal(t, []byte{0x16, 0x03, 'f', 'o', 'o'}, cert.Extensions[0].Value)
+
+	// Invalid values fail.
+	_, err = NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"netscapeCertType": "sslClient",
+		"netscapeComment": "¡hola!"
+	}`, NewTemplateData()))
+	assert.Error(t, err)
+}
@@ -5,9 +5,14 @@ import (
 	"crypto/x509"
 	encoding_asn1 "encoding/asn1"
 	"encoding/base64"
+	"math/big"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/cryptobyte"
@@ -19,6 +24,11 @@ import (
 // Options are the options that can be passed to NewCertificate.
 type Options struct {
 	CertBuffer *bytes.Buffer
+	modifiers  []func(*Certificate) error
+	baseDir    string
+
+	validate                  bool
+	validateIPNameConstraints bool
 }
 
 func (o *Options) apply(cr *x509.CertificateRequest, opts []Option) (*Options, error) {
@@ -33,6 +43,437 @@ func (o *Options) apply(cr *x509.CertificateRequest, opts []Option) (*Options, e
 // Option is the type used as a variadic argument in NewCertificate.
 type Option func(cr *x509.CertificateRequest, o *Options) error
 
+// modify adds a function that will be used to modify the Certificate after it
+// has been created from the template or from the certificate request.
+func (o *Options) modify(fn func(*Certificate) error) {
+	o.modifiers = append(o.modifiers, fn)
+}
+
+// CreateCertificateOption is the type used as a variadic argument in
+// CreateCertificate, CreateTBSCertificate and Issue.
+type CreateCertificateOption func(o *createCertificateOptions) error
+
+type createCertificateOptions struct {
+	backdate   time.Duration
+	skiMethod  SKIMethod
+	noSerial   bool
+	noSKI      bool
+	checker    func(*big.Int) (bool, error)
+	autoSigAlg bool
+
+	issuerUniqueID  *UniqueIdentifier
+	subjectUniqueID *UniqueIdentifier
+}
+
+func (o *createCertificateOptions) apply(opts []CreateCertificateOption) (*createCertificateOptions, error) {
+	for _, fn := range opts {
+		if err := fn(o); err != nil {
+			return o, err
+		}
+	}
+	return o, nil
+}
+
+// WithBackdate is an option that sets the NotBefore of the certificate to the
+// given duration before the current time, to tolerate clock skew in the
+// verifiers. The NotBefore is never set before the NotBefore of the parent
+// certificate.
+func WithBackdate(d time.Duration) CreateCertificateOption {
+	return func(o *createCertificateOptions) error {
+		if d < 0 {
+			return errors.Errorf("backdate %s cannot be negative", d)
+		}
+		o.backdate = d
+		return nil
+	}
+}
+
+// WithSubjectKeyIDMethod is an option that sets the method used to generate
+// the subject key identifier if the template does not define one. By default
+// SKIMethodSHA1 is used.
+func WithSubjectKeyIDMethod(method SKIMethod) CreateCertificateOption {
+	return func(o *createCertificateOptions) error {
+		switch method {
+		case SKIMethodSHA1, SKIMethodRFC7093Method1, SKIMethodRFC7093Method2, SKIMethodRFC7093Method3:
+			o.skiMethod = method
+			return nil
+		default:
+			return errors.Errorf("unsupported subject key identifier method %s", method)
+		}
+	}
+}
+
+// WithNoSerialGeneration is an option that disables the generation of the
+// serial number when the template does not define one, for flows where it
+// must be set by an external policy. An error is returned if the serial
+// number is not set.
+func WithNoSerialGeneration() CreateCertificateOption {
+	return func(o *createCertificateOptions) error {
+		o.noSerial = true
+		return nil
+	}
+}
+
+// WithSerialChecker is an option that sets a function used to check if a
+// generated serial number is already in use, for example, by looking it up in
+// the database of the CA. The function must return true if the serial number
+// is used, and a new one is generated, up to maxSerialNumberAttempts times.
+// The serial number defined in the template is never checked.
+func WithSerialChecker(fn func(*big.Int) (bool, error)) CreateCertificateOption {
+	return func(o *createCertificateOptions) error {
+		if fn == nil {
+			return errors.New("serial checker cannot be nil")
+		}
+		o.checker = fn
+		return nil
+	}
+}
+
+// WithNoSKIGeneration is an option that disables the generation of the
+// subject key identifier when the template does not define one, for flows
+// where it must be set by an external policy. An error is returned if the
+// subject key identifier is not set.
+func WithNoSKIGeneration() CreateCertificateOption {
+	return func(o *createCertificateOptions) error {
+		o.noSKI = true
+		return nil
+	}
+}
+
+// WithAutoSignatureAlgorithm is an option that sets the signature algorithm of
+// the certificate, if the template does not define one, to the strongest one
+// supported by the key of the parent: ECDSA with SHA-384 for P-384 keys, ECDSA
+// with SHA-512 for P-521 keys, and RSA PKCS #1 v1.5 with SHA-384 or SHA-512
+// for RSA keys of at least 3072 or 4096 bits. Without this option, the Go
+// standard library uses SHA-256 for all RSA keys. If the certificate is
+// self-signed, the key of the certificate is used.
+func WithAutoSignatureAlgorithm() CreateCertificateOption {
+	return func(o *createCertificateOptions) error {
+		o.autoSigAlg = true
+		return nil
+	}
+}
+
+// WithUniqueIdentifiers is an option that sets the X.509 v2 issuer and subject
+// unique identifiers of the certificate, nil values are not set. The Go
+// standard library does not support them, so they cannot be defined in an
+// x509.Certificate; use this option with the IssuerUniqueID and
+// SubjectUniqueID of a Certificate to add them when it's signed.
+func WithUniqueIdentifiers(issuerUniqueID, subjectUniqueID *UniqueIdentifier) CreateCertificateOption {
+	return func(o *createCertificateOptions) error {
+		o.issuerUniqueID = issuerUniqueID
+		o.subjectUniqueID = subjectUniqueID
+		return nil
+	}
+}
+
+// WithCriticalExtKeyUsage is an option that marks the extended key usage
+// extension as critical. The Go standard library always creates a
+// non-critical extension, so if the certificate does not contain a custom
+// extension, it generates one with the extKeyUsage and unknownExtKeyUsage
+// fields. RFC 3161 requires the extension to be critical in the certificates
+// of time stamping authorities.
+func WithCriticalExtKeyUsage() Option {
+	return func(cr *x509.CertificateRequest, o *Options) error {
+		o.modify(func(c *Certificate) error {
+			if c.isRemoved(oidExtensionExtendedKeyUsage) {
+				return nil
+			}
+			for i, e := range c.Extensions {
+				if e.ID.Equal(oidExtensionExtendedKeyUsage) {
+					c.Extensions[i].Critical = true
+					return nil
+				}
+			}
+			if len(c.ExtKeyUsage) == 0 && len(c.UnknownExtKeyUsage) == 0 {
+				return nil
+			}
+			ext, err := newExtKeyUsageExtension(c.ExtKeyUsage, c.UnknownExtKeyUsage, true)
+			if err != nil {
+				return err
+			}
+			c.Extensions = append(c.Extensions, ext)
+			return nil
+		})
+		return nil
+	}
+}
+
+// WithValidation is an option that makes NewCertificate check the
+// certificate with Certificate.Validate before returning it.
+func WithValidation() Option {
+	return func(cr *x509.CertificateRequest, o *Options) error {
+		o.validate = true
+		return nil
+	}
+}
+
+// WithIPNameConstraintsValidation is an option that makes NewCertificate check
+// that the IP SANs of the certificate are allowed by its own IP name
+// constraints, see Certificate.ValidateIPNameConstraints.
+func WithIPNameConstraintsValidation() Option {
+	return func(cr *x509.CertificateRequest, o *Options) error {
+		o.validateIPNameConstraints = true
+		return nil
+	}
+}
+
+// WithKeyUsage is an option that sets the key usage of the certificate. It
+// overrides the key usage defined in a template, or the default key usage if
+// no template is used.
+func WithKeyUsage(ku x509.KeyUsage) Option {
+	return func(cr *x509.CertificateRequest, o *Options) error {
+		o.modify(func(c *Certificate) error {
+			c.KeyUsage = KeyUsage(ku)
+			return nil
+		})
+		return nil
+	}
+}
+
+// WithExtKeyUsage is an option that sets the extended key usages of the
+// certificate. It overrides the extended key usages defined in a template, or
+// the default ones if no template is used.
+func WithExtKeyUsage(eku []x509.ExtKeyUsage) Option {
+	return func(cr *x509.CertificateRequest, o *Options) error {
+		o.modify(func(c *Certificate) error {
+			c.ExtKeyUsage = ExtKeyUsage(eku)
+			return nil
+		})
+		return nil
+	}
+}
+
+// WithBasicConstraints is an option that sets the basic constraints extension
+// of the certificate. A negative pathLen does not impose a limit in the
+// maximum path length of a CA. It overrides the basic constraints defined in a
+// template.
+func WithBasicConstraints(isCA bool, pathLen int) Option {
+	return func(cr *x509.CertificateRequest, o *Options) error {
+		o.modify(func(c *Certificate) error {
+			c.BasicConstraints = &BasicConstraints{
+				IsCA:       isCA,
+				MaxPathLen: pathLen,
+			}
+			return nil
+		})
+		return nil
+	}
+}
+
+// WithDNSNameValidation is an option that validates the DNS names in the
+// dnsNames field and the sans of type dns. Invalid names will return an error,
+// and internationalized names will be converted to A-labels (punycode). A
+// wildcard is only allowed as the complete leftmost label. A subjectAltName
+// extension defined in the extensions is not modified.
+//
+// This validation is not enabled by default for compatibility reasons.
+func WithDNSNameValidation() Option {
+	return func(cr *x509.CertificateRequest, o *Options) error {
+		o.modify(func(c *Certificate) error {
+			// Create new slices, the current ones might be shared with the
+			// certificate request.
+			var dnsNames MultiString
+			for _, name := range c.DNSNames {
+				v, err := validateDNSName(name)
+				if err != nil {
+					return err
+				}
+				dnsNames = append(dnsNames, v)
+			}
+			var sans []SubjectAlternativeName
+			for _, san := range c.SANs {
+				if san.Type == DNSType {
+					v, err := validateDNSName(san.Value)
+					if err != nil {
+						return err
+					}
+					san.Value = v
+				}
+				sans = append(sans, san)
+			}
+			c.DNSNames, c.SANs = dnsNames, sans
+			return nil
+		})
+		return nil
+	}
+}
+
+// WithEmailValidation is an option that validates the emails in the
+// emailAddresses field and the sans of type email. An email must be an address
+// without a display name, as defined by mail.ParseAddress, with an unquoted
+// ASCII local part, otherwise an error is returned. Internationalized domains
+// will be converted to A-labels (punycode). A subjectAltName extension defined
+// in the extensions is not modified.
+//
+// This validation is not enabled by default for compatibility reasons.
+func WithEmailValidation() Option {
+	return func(cr *x509.CertificateRequest, o *Options) error {
+		o.modify(func(c *Certificate) error {
+			// Create new slices, the current ones might be shared with the
+			// certificate request.
+			var emails MultiString
+			for _, email := range c.EmailAddresses {
+				v, err := validateEmail(email)
+				if err != nil {
+					return err
+				}
+				emails = append(emails, v)
+			}
+			var sans []SubjectAlternativeName
+			for _, san := range c.SANs {
+				if san.Type == EmailType {
+					v, err := validateEmail(san.Value)
+					if err != nil {
+						return err
+					}
+					san.Value = v
+				}
+				sans = append(sans, san)
+			}
+			c.EmailAddresses, c.SANs = emails, sans
+			return nil
+		})
+		return nil
+	}
+}
+
+// WithURIValidation is an option that validates the URIs in the uris field and
+// the sans of type uri. A URI must be absolute, with a scheme, and cannot be
+// opaque, otherwise an error is returned. A subjectAltName extension defined in
+// the extensions is not validated.
+//
+// This validation is not enabled by default for compatibility reasons.
+func WithURIValidation() Option {
+	return func(cr *x509.CertificateRequest, o *Options) error {
+		o.modify(func(c *Certificate) error {
+			if err := c.URIs.Validate(); err != nil {
+				return err
+			}
+			for _, san := range c.SANs {
+				if san.Type != URIType {
+					continue
+				}
+				u, err := url.Parse(san.Value)
+				if err != nil {
+					return errors.Wrapf(err, "invalid URI %q", san.Value)
+				}
+				if err := validateURI(u); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		return nil
+	}
+}
+
+// WithCopyCNToSAN is an option that copies the subject common name to the
+// dnsNames or ipAddresses fields, if it is a valid DNS name or an IP address
+// and it is not already present, as TLS clients ignore the common name. An
+// internationalized DNS name is added in its A-label (punycode) form. The
+// common name is not copied if it is not a host, e.g. "Jane Doe".
+//
+// If the extensions contain a subjectAltName extension with the same names,
+// e.g. the one copied from the certificate request when no template is used,
+// the extension is regenerated. Other subjectAltName extensions are not
+// modified, and the common name is not copied.
+func WithCopyCNToSAN() Option {
+	return func(cr *x509.CertificateRequest, o *Options) error {
+		o.modify(func(c *Certificate) error {
+			cn := c.Subject.CommonName
+			if cn == "" {
+				return nil
+			}
+
+			ip := net.ParseIP(cn)
+			var name string
+			if ip == nil {
+				var err error
+				if name, err = validateDNSName(cn); err != nil {
+					return nil //nolint:nilerr // the common name is not a host
+				}
+			}
+			if (ip != nil && hasIPSAN(c, ip)) || (ip == nil && hasDNSSAN(c, name)) {
+				return nil
+			}
+
+			// Look for a subjectAltName extension generated from the same
+			// names.
+			index := -1
+			for i, e := range c.Extensions {
+				if e.ID.Equal(oidExtensionSubjectAltName) {
+					ext, err := createCertificateSubjectAltNameExtension(*c, c.Subject.IsEmpty())
+					if err != nil || !bytes.Equal(ext.Value, e.Value) {
+						return nil //nolint:nilerr // custom extension
+					}
+					index = i
+					break
+				}
+			}
+
+			// Create new slices, the current ones might be shared with the
+			// certificate request.
+			if ip != nil {
+				c.IPAddresses = append(append(MultiIP{}, c.IPAddresses...), ip)
+			} else {
+				c.DNSNames = append(append(MultiString{}, c.DNSNames...), name)
+			}
+
+			if index >= 0 {
+				ext, err := createCertificateSubjectAltNameExtension(*c, c.Subject.IsEmpty())
+				if err != nil {
+					return err
+				}
+				ext.Critical = c.Extensions[index].Critical
+				c.Extensions = append([]Extension{}, c.Extensions...)
+				c.Extensions[index] = ext
+			}
+			return nil
+		})
+		return nil
+	}
+}
+
+// hasIPSAN returns true if the certificate contains the given IP address in
+// the ipAddresses or the sans fields.
+func hasIPSAN(c *Certificate, ip net.IP) bool {
+	for _, v := range c.IPAddresses {
+		if v.Equal(ip) {
+			return true
+		}
+	}
+	for _, san := range c.SANs {
+		if san.Type == IPType && ip.Equal(net.ParseIP(san.Value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDNSSAN returns true if the certificate contains the given DNS name in the
+// dnsNames or the sans fields. DNS names are compared in their ASCII form and
+// case-insensitively.
+func hasDNSSAN(c *Certificate, name string) bool {
+	equal := func(v string) bool {
+		if ascii, err := validateDNSName(v); err == nil {
+			v = ascii
+		}
+		return strings.EqualFold(v, name)
+	}
+	for _, v := range c.DNSNames {
+		if equal(v) {
+			return true
+		}
+	}
+	for _, san := range c.SANs {
+		if san.Type == DNSType && equal(san.Value) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetFuncMap returns the list of functions used by the templates. It will
 // return all the functions supported by "sprig.TxtFuncMap()" but exclude "env"
 // and "expandenv", removed to avoid the leak of information. It will also add
@@ -46,26 +487,120 @@ type Option func(cr *x509.CertificateRequest, o *Options) error
 //     asn1.MarshalWithParams.
 //   - asn1Seq: encodes a sequence of the given ASN.1 data.
 //   - asn1Set: encodes a set of the given ASN.1 data.
+//
+// And the following functions to read files:
+//
+//   - readFile: returns the contents of the given file as a string.
+//   - readLines: returns the non-empty lines of the given file, trimmed, and
+//     excluding the ones starting with '#'.
+//
+// The files are read from the directory set with WithTemplateBaseDir, so these
+// functions always fail in the map returned by GetFuncMap, and in templates
+// unless WithTemplateBaseDir is passed before WithTemplate.
 func GetFuncMap() template.FuncMap {
-	return getFuncMap(new(TemplateError))
+	return getFuncMap(new(TemplateError), "")
 }
 
-func getFuncMap(err *TemplateError) template.FuncMap {
+func getFuncMap(err *TemplateError, baseDir string) template.FuncMap {
 	funcMap := templates.GetFuncMap(&err.Message)
 	// asn1 methods
 	funcMap["asn1Enc"] = asn1Encode
 	funcMap["asn1Marshal"] = asn1Marshal
 	funcMap["asn1Seq"] = asn1Sequence
 	funcMap["asn1Set"] = asn1Set
+	// file methods
+	funcMap["readFile"] = func(name string) (string, error) {
+		b, err := readTemplateFile(baseDir, name)
+		return string(b), err
+	}
+	funcMap["readLines"] = func(name string) ([]string, error) {
+		b, err := readTemplateFile(baseDir, name)
+		if err != nil {
+			return nil, err
+		}
+		return readLines(b), nil
+	}
 	return funcMap
 }
 
+// WithTemplateBaseDir is an option that sets the base directory of the files
+// that can be read in a template using the functions readFile and readLines,
+// e.g. to keep a long list of SANs in a separate file:
+//
+//	"dnsNames": {{ readLines "dns-names.txt" | toJson }}
+//
+// readFile returns the contents of the file as a string, and readLines returns
+// the list of non-empty lines in the file, trimmed, and excluding the ones
+// starting with '#'. The file names must be relative to the base directory and
+// they cannot point outside of it, e.g. using ".." or symbolic links. If this
+// option is not used, both functions fail.
+//
+// This option must be passed before the template option, as the template is
+// executed when that option is applied.
+func WithTemplateBaseDir(dir string) Option {
+	return func(cr *x509.CertificateRequest, o *Options) error {
+		if dir == "" {
+			return errors.New("template base directory cannot be empty")
+		}
+		o.baseDir = dir
+		return nil
+	}
+}
+
+// readTemplateFile reads the file with the given name in the base directory.
+// It fails if the base directory is not set or if the file is outside of it.
+func readTemplateFile(baseDir, name string) ([]byte, error) {
+	if baseDir == "" {
+		return nil, errors.Errorf("error reading %s: template base directory is not set", name)
+	}
+	if !filepath.IsLocal(name) {
+		return nil, errors.Errorf("error reading %s: file is outside of the template base directory", name)
+	}
+
+	// Resolve symbolic links and check that the file is still in the base
+	// directory.
+	base, err := filepath.EvalSymlinks(baseDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", name)
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(base, name))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", name)
+	}
+	if rel, err := filepath.Rel(base, path); err != nil || !filepath.IsLocal(rel) {
+		return nil, errors.Errorf("error reading %s: file is outside of the template base directory", name)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", name)
+	}
+	return b, nil
+}
+
+// readLines returns the non-empty lines in b that do not start with '#'.
+func readLines(b []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
 // WithTemplate is an options that executes the given template text with the
-// given data.
+// given data. The certificate request is added to the data, and its fields can
+// be used in the template, e.g. {{ .Insecure.CR.DNSNames }}, see
+// TemplateData.SetCertificateRequest.
 func WithTemplate(text string, data TemplateData) Option {
 	return func(cr *x509.CertificateRequest, o *Options) error {
+		if data == nil {
+			data = NewTemplateData()
+		}
 		terr := new(TemplateError)
-		funcMap := getFuncMap(terr)
+		funcMap := getFuncMap(terr, o.baseDir)
 		// Parse template
 		tmpl, err := template.New("template").Funcs(funcMap).Parse(text)
 		if err != nil {
@@ -3,12 +3,20 @@ package x509util
 import (
 	"bytes"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/base64"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -45,6 +53,7 @@ func TestGetFuncMap(t *testing.T) {
 	ok := []string{
 		"fail", "contains", "split", // generic sprig functions
 		"asn1Enc", "asn1Marshal", "asn1Seq", "asn1Set", // custom functions
+		"readFile", "readLines", // file functions
 	}
 	fail := []string{"env", "expandenv"}
 
@@ -204,6 +213,27 @@ func TestWithTemplate(t *testing.T) {
 	}
 }
 
+func TestWithTemplate_certificateRequest(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "commonName", []string{"foo.com", "bar.com", "1.2.3.4", "jane@example.com", "https://example.com"})
+	text := `{
+	"subject": {"commonName": {{ toJson .Insecure.CR.Subject.CommonName }}},
+	"dnsNames": [{{ range $i, $v := .Insecure.CR.DNSNames }}{{ if $i }},{{ end }}{{ printf "%q" (printf "www.%s" $v) }}{{ end }}],
+	"ipAddresses": {{ toJson .Insecure.CR.IPAddresses }},
+	"emailAddresses": {{ toJson .Insecure.CR.EmailAddresses }},
+	"uris": {{ toJson .Insecure.CR.URIs }}
+}`
+
+	for _, data := range []TemplateData{nil, NewTemplateData(), CreateTemplateData("other", []string{"other.com"})} {
+		cert, err := NewCertificate(cr, WithTemplate(text, data))
+		require.NoError(t, err)
+		require.Equal(t, "commonName", cert.Subject.CommonName)
+		require.Equal(t, MultiString{"www.foo.com", "www.bar.com"}, cert.DNSNames)
+		require.Equal(t, MultiIP{net.ParseIP("1.2.3.4")}, cert.IPAddresses)
+		require.Equal(t, MultiString{"jane@example.com"}, cert.EmailAddresses)
+		require.Equal(t, MultiURL{{Scheme: "https", Host: "example.com"}}, cert.URIs)
+	}
+}
+
 func TestWithTemplateBase64(t *testing.T) {
 	cr, _ := createCertificateRequest(t, "foo", []string{"foo.com", "foo@foo.com", "::1", "https://foo.com"})
 	type args struct {
@@ -309,6 +339,337 @@ func TestWithTemplateFile(t *testing.T) {
 	}
 }
 
+func TestNewCertificate_withOptions(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "commonName", []string{"foo.com"})
+	issuer, signer := createIssuerCertificate(t, "issuer")
+
+	tests := []struct {
+		name                 string
+		opts                 []Option
+		wantKeyUsage         x509.KeyUsage
+		wantExtKeyUsage      []x509.ExtKeyUsage
+		wantIsCA             bool
+		wantMaxPathLen       int
+		wantMaxPathLenZero   bool
+		wantBasicConstraints bool
+	}{
+		{"ok leaf", []Option{
+			WithKeyUsage(x509.KeyUsageDigitalSignature),
+			WithExtKeyUsage([]x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}),
+		}, x509.KeyUsageDigitalSignature, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, false, 0, false, false},
+		{"ok intermediate", []Option{
+			WithKeyUsage(x509.KeyUsageCertSign | x509.KeyUsageCRLSign),
+			WithExtKeyUsage(nil),
+			WithBasicConstraints(true, 0),
+		}, x509.KeyUsageCertSign | x509.KeyUsageCRLSign, nil, true, 0, true, true},
+		{"ok root", []Option{
+			WithKeyUsage(x509.KeyUsageCertSign | x509.KeyUsageCRLSign),
+			WithExtKeyUsage(nil),
+			WithBasicConstraints(true, -1),
+		}, x509.KeyUsageCertSign | x509.KeyUsageCRLSign, nil, true, -1, false, true},
+		{"ok override template", []Option{
+			WithTemplate(DefaultLeafTemplate, CreateTemplateData("commonName", []string{"foo.com"})),
+			WithKeyUsage(x509.KeyUsageDigitalSignature),
+			WithExtKeyUsage([]x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}),
+			WithBasicConstraints(false, 0),
+		}, x509.KeyUsageDigitalSignature, []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}, false, -1, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCertificate(cr, tt.opts...)
+			require.NoError(t, err)
+
+			template := c.GetCertificate()
+			template.NotBefore = time.Now()
+			template.NotAfter = template.NotBefore.Add(time.Hour)
+			cert, err := CreateCertificate(template, issuer, cr.PublicKey, signer)
+			require.NoError(t, err)
+
+			require.Equal(t, "commonName", cert.Subject.CommonName)
+			require.Equal(t, []string{"foo.com"}, cert.DNSNames)
+			require.Equal(t, tt.wantKeyUsage, cert.KeyUsage)
+			require.Equal(t, tt.wantExtKeyUsage, cert.ExtKeyUsage)
+			require.Equal(t, tt.wantBasicConstraints, cert.BasicConstraintsValid)
+			require.Equal(t, tt.wantIsCA, cert.IsCA)
+			require.Equal(t, tt.wantMaxPathLen, cert.MaxPathLen)
+			require.Equal(t, tt.wantMaxPathLenZero, cert.MaxPathLenZero)
+		})
+	}
+}
+
+func TestWithDNSNameValidation(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "commonName", []string{"Foo.com"})
+
+	tests := []struct {
+		name         string
+		opts         []Option
+		wantDNSNames MultiString
+		wantSANs     []SubjectAlternativeName
+		wantErr      bool
+	}{
+		{"ok no template", []Option{WithDNSNameValidation()}, MultiString{"foo.com"}, nil, false},
+		{"ok template", []Option{
+			WithTemplate(`{
+				"subject": {{ toJson .Subject }},
+				"dnsNames": ["*.example.com", "münchen.example"],
+				"sans": [
+					{"type": "dns", "value": "*.münchen.example"},
+					{"type": "email", "value": "jane@example.com"}
+				]
+			}`, CreateTemplateData("commonName", nil)),
+			WithDNSNameValidation(),
+		}, MultiString{"*.example.com", "xn--mnchen-3ya.example"}, []SubjectAlternativeName{
+			{Type: "dns", Value: "*.xn--mnchen-3ya.example"},
+			{Type: "email", Value: "jane@example.com"},
+		}, false},
+		{"ok extended sans", []Option{
+			WithTemplate(`{
+				"subject": {{ toJson .Subject }},
+				"sans": [
+					{"type": "dns", "value": "münchen.example"},
+					{"type": "permanentIdentifier", "value": "123456"}
+				]
+			}`, CreateTemplateData("commonName", nil)),
+			WithDNSNameValidation(),
+		}, nil, []SubjectAlternativeName{
+			{Type: "dns", Value: "xn--mnchen-3ya.example"},
+			{Type: "permanentIdentifier", Value: "123456"},
+		}, false},
+		{"ok without validation", []Option{
+			WithTemplate(`{"dnsNames": ["foo_bar..com"]}`, NewTemplateData()),
+		}, MultiString{"foo_bar..com"}, nil, false},
+		{"fail dnsNames", []Option{
+			WithTemplate(`{"dnsNames": ["foo_bar..com"]}`, NewTemplateData()),
+			WithDNSNameValidation(),
+		}, nil, nil, true},
+		{"fail sans", []Option{
+			WithTemplate(`{"sans": [{"type": "dns", "value": "foo.*.com"}]}`, NewTemplateData()),
+			WithDNSNameValidation(),
+		}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewCertificate(cr, tt.opts...)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDNSNames, got.DNSNames)
+			require.Equal(t, tt.wantSANs, got.SANs)
+			require.Equal(t, []string{"Foo.com"}, cr.DNSNames)
+
+			// The extended subjectAltName extension uses the normalized names.
+			if got.hasExtendedSANs() {
+				require.True(t, got.hasExtension(oidExtensionSubjectAltName))
+				var names []string
+				require.NoError(t, forEachSAN(got.Extensions[0].Value, func(v asn1.RawValue) error {
+					if v.Tag == nameTypeDNS {
+						names = append(names, string(v.Bytes))
+					}
+					return nil
+				}))
+				require.Equal(t, []string{"xn--mnchen-3ya.example"}, names)
+			}
+		})
+	}
+}
+
+func TestWithEmailValidation(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "commonName", []string{"jane@Example.com"})
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantEmails MultiString
+		wantSANs   []SubjectAlternativeName
+		wantErr    bool
+	}{
+		{"ok no template", []Option{WithEmailValidation()}, MultiString{"jane@example.com"}, nil, false},
+		{"ok template", []Option{
+			WithTemplate(`{
+				"subject": {{ toJson .Subject }},
+				"emailAddresses": ["jane@example.com", "jane@münchen.example"],
+				"sans": [
+					{"type": "email", "value": "joe@münchen.example"},
+					{"type": "dns", "value": "example.com"}
+				]
+			}`, CreateTemplateData("commonName", nil)),
+			WithEmailValidation(),
+		}, MultiString{"jane@example.com", "jane@xn--mnchen-3ya.example"}, []SubjectAlternativeName{
+			{Type: "email", Value: "joe@xn--mnchen-3ya.example"},
+			{Type: "dns", Value: "example.com"},
+		}, false},
+		{"ok without validation", []Option{
+			WithTemplate(`{"emailAddresses": ["Jane Doe <jane@example.com>"]}`, NewTemplateData()),
+		}, MultiString{"Jane Doe <jane@example.com>"}, nil, false},
+		{"fail display name", []Option{
+			WithTemplate(`{"emailAddresses": ["Jane Doe <jane@example.com>"]}`, NewTemplateData()),
+			WithEmailValidation(),
+		}, nil, nil, true},
+		{"fail sans", []Option{
+			WithTemplate(`{"sans": [{"type": "email", "value": "jane.example.com"}]}`, NewTemplateData()),
+			WithEmailValidation(),
+		}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewCertificate(cr, tt.opts...)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantEmails, got.EmailAddresses)
+			require.Equal(t, tt.wantSANs, got.SANs)
+			require.Equal(t, []string{"jane@Example.com"}, cr.EmailAddresses)
+		})
+	}
+}
+
+func TestWithURIValidation(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "commonName", []string{"foo.com"})
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		want    []string
+		wantErr bool
+	}{
+		{"ok no template", []Option{WithURIValidation()}, nil, false},
+		{"ok template", []Option{
+			WithTemplate(`{
+				"uris": ["https://example.com/path"],
+				"sans": [
+					{"type": "uri", "value": "spiffe://example.org/workload"},
+					{"type": "dns", "value": "example.com"}
+				]
+			}`, NewTemplateData()),
+			WithURIValidation(),
+		}, []string{"https://example.com/path", "spiffe://example.org/workload"}, false},
+		{"ok without validation", []Option{
+			WithTemplate(`{"uris": ["example.com/path"]}`, NewTemplateData()),
+		}, []string{"example.com/path"}, false},
+		{"fail relative uris", []Option{
+			WithTemplate(`{"uris": ["example.com/path"]}`, NewTemplateData()),
+			WithURIValidation(),
+		}, nil, true},
+		{"fail opaque uris", []Option{
+			WithTemplate(`{"uris": "mailto:jane@example.com"}`, NewTemplateData()),
+			WithURIValidation(),
+		}, nil, true},
+		{"fail relative sans", []Option{
+			WithTemplate(`{"sans": [{"type": "uri", "value": "example.com/path"}]}`, NewTemplateData()),
+			WithURIValidation(),
+		}, nil, true},
+		{"fail parse sans", []Option{
+			WithTemplate(`{"sans": [{"type": "uri", "value": ":foo"}]}`, NewTemplateData()),
+			WithURIValidation(),
+		}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewCertificate(cr, tt.opts...)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			var uris []string
+			for _, u := range got.GetCertificate().URIs {
+				uris = append(uris, u.String())
+			}
+			require.Equal(t, tt.want, uris)
+		})
+	}
+}
+func TestWithCopyCNToSAN(t *testing.T) {
+	template := func(commonName, dnsNames, ips string) Option {
+		return WithTemplate(`{
+			"subject": {"commonName": "`+commonName+`"},
+			"dnsNames": `+dnsNames+`,
+			"ipAddresses": `+ips+`,
+			"sans": [{"type": "dns", "value": "bar.example.com"}, {"type": "ip", "value": "10.0.0.2"}]
+		}`, NewTemplateData())
+	}
+
+	tests := []struct {
+		name       string
+		commonName string
+		sans       []string
+		opts       []Option
+		wantDNS    MultiString
+		wantIPs    MultiIP
+	}{
+		{"ok hostname", "www.example.com", []string{"example.com"}, nil,
+			MultiString{"example.com", "www.example.com"}, nil},
+		{"ok ip", "10.0.0.1", []string{"example.com"}, nil,
+			MultiString{"example.com"}, MultiIP{net.ParseIP("10.0.0.1")}},
+		{"ok ipv6", "2001:db8::1", nil, nil,
+			nil, MultiIP{net.ParseIP("2001:db8::1")}},
+		{"ok idn", "münchen.example", nil, nil,
+			MultiString{"xn--mnchen-3ya.example"}, nil},
+		{"ok not a host", "Jane Doe", []string{"jane.example.com"}, nil,
+			MultiString{"jane.example.com"}, nil},
+		{"ok already present", "WWW.Example.com", []string{"www.example.com", "10.0.0.1"}, nil,
+			MultiString{"www.example.com"}, MultiIP{net.ParseIP("10.0.0.1")}},
+		{"ok ip already present", "10.0.0.1", []string{"www.example.com", "10.0.0.1"}, nil,
+			MultiString{"www.example.com"}, MultiIP{net.ParseIP("10.0.0.1")}},
+		{"ok template", "commonName", nil, []Option{template("www.example.com", `["foo.example.com"]`, `["10.0.0.1"]`)},
+			MultiString{"foo.example.com", "www.example.com"}, MultiIP{net.ParseIP("10.0.0.1")}},
+		{"ok template wildcard", "commonName", nil, []Option{template("*.example.com", `null`, `null`)},
+			MultiString{"*.example.com"}, nil},
+		{"ok template present in sans", "commonName", nil, []Option{template("bar.example.com", `["foo.example.com"]`, `null`)},
+			MultiString{"foo.example.com"}, nil},
+		{"ok template ip present in sans", "commonName", nil, []Option{template("10.0.0.2", `null`, `null`)},
+			nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr, _ := createCertificateRequest(t, tt.commonName, tt.sans)
+			crDNSNames := cr.DNSNames[:len(cr.DNSNames):len(cr.DNSNames)]
+
+			cert, err := NewCertificate(cr, append(tt.opts, WithCopyCNToSAN())...)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDNS, cert.DNSNames)
+			require.Len(t, cert.IPAddresses, len(tt.wantIPs))
+			for i, ip := range tt.wantIPs {
+				require.True(t, ip.Equal(cert.IPAddresses[i]), "%s != %s", ip, cert.IPAddresses[i])
+			}
+			require.Equal(t, crDNSNames, cr.DNSNames)
+
+			// The names are in the signed certificate.
+			iss, issPriv := createIssuerCertificate(t, "issuer")
+			template := cert.GetCertificate()
+			crt, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+			require.NoError(t, err)
+			for _, name := range tt.wantDNS {
+				require.Contains(t, crt.DNSNames, name)
+			}
+			for _, ip := range tt.wantIPs {
+				var found bool
+				for _, v := range crt.IPAddresses {
+					found = found || ip.Equal(v)
+				}
+				require.True(t, found, "%s not found", ip)
+			}
+		})
+	}
+
+	t.Run("ok custom extension", func(t *testing.T) {
+		cr, _ := createCertificateRequest(t, "www.example.com", nil)
+		cert, err := NewCertificate(cr, WithTemplate(`{
+			"subject": {{ toJson .Subject }},
+			"extensions": [{"id": "2.5.29.17", "value": "MBGCD2Zvby5leGFtcGxlLmNvbQ=="}]
+		}`, CreateTemplateData("www.example.com", nil)), WithCopyCNToSAN())
+		require.NoError(t, err)
+		require.Nil(t, cert.DNSNames)
+		require.Equal(t, []Extension{
+			{ID: oidExtensionSubjectAltName, Value: []byte("\x30\x11\x82\x0ffoo.example.com")},
+		}, cert.Extensions)
+	})
+}
+
 func mustMarshal(t *testing.T, value interface{}, params string) string {
 	t.Helper()
 	b, err := asn1.MarshalWithParams(value, params)
@@ -487,3 +848,348 @@ func Test_asn1Set(t *testing.T) {
 		})
 	}
 }
+
+func TestWithSubjectKeyIDMethod(t *testing.T) {
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	pub := priv.Public()
+
+	for _, method := range []SKIMethod{SKIMethodSHA1, SKIMethodRFC7093Method1, SKIMethodRFC7093Method2, SKIMethodRFC7093Method3} {
+		t.Run(method.String(), func(t *testing.T) {
+			want, err := GenerateSubjectKeyID(pub, method)
+			require.NoError(t, err)
+			template := &x509.Certificate{
+				Subject:   pkix.Name{CommonName: "leaf"},
+				NotBefore: time.Now(),
+				NotAfter:  time.Now().Add(time.Hour),
+			}
+			crt, err := CreateCertificate(template, iss, pub, issPriv, WithSubjectKeyIDMethod(method))
+			require.NoError(t, err)
+			require.Equal(t, want, crt.SubjectKeyId)
+		})
+	}
+
+	_, err = CreateCertificate(&x509.Certificate{}, iss, pub, issPriv, WithSubjectKeyIDMethod(SKIMethod(100)))
+	require.Error(t, err)
+}
+
+func TestWithNoSerialGeneration_WithNoSKIGeneration(t *testing.T) {
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	pub := priv.Public()
+
+	newTemplate := func(sn *big.Int, ski []byte) *x509.Certificate {
+		return &x509.Certificate{
+			Subject:      pkix.Name{CommonName: "leaf"},
+			SerialNumber: sn,
+			SubjectKeyId: ski,
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+	}
+
+	tests := []struct {
+		name     string
+		template *x509.Certificate
+		opts     []CreateCertificateOption
+		wantSN   *big.Int
+		wantSKI  []byte
+		wantErr  bool
+	}{
+		{"ok set", newTemplate(big.NewInt(1234), []byte("the-ski")), []CreateCertificateOption{WithNoSerialGeneration(), WithNoSKIGeneration()}, big.NewInt(1234), []byte("the-ski"), false},
+		{"ok no serial generation", newTemplate(big.NewInt(1234), nil), []CreateCertificateOption{WithNoSerialGeneration()}, big.NewInt(1234), nil, false},
+		{"ok no ski generation", newTemplate(nil, []byte("the-ski")), []CreateCertificateOption{WithNoSKIGeneration()}, nil, []byte("the-ski"), false},
+		{"fail serial", newTemplate(nil, []byte("the-ski")), []CreateCertificateOption{WithNoSerialGeneration()}, nil, nil, true},
+		{"fail ski", newTemplate(big.NewInt(1234), nil), []CreateCertificateOption{WithNoSKIGeneration()}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crt, err := CreateCertificate(tt.template, iss, pub, issPriv, tt.opts...)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantSN != nil {
+				require.Equal(t, tt.wantSN, crt.SerialNumber)
+			} else {
+				require.NotNil(t, crt.SerialNumber)
+			}
+			if tt.wantSKI != nil {
+				require.Equal(t, tt.wantSKI, crt.SubjectKeyId)
+			} else {
+				require.NotEmpty(t, crt.SubjectKeyId)
+			}
+		})
+	}
+}
+
+func TestWithSerialChecker(t *testing.T) {
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	pub := priv.Public()
+
+	newTemplate := func() *x509.Certificate {
+		return &x509.Certificate{
+			Subject:   pkix.Name{CommonName: "leaf"},
+			NotBefore: time.Now(),
+			NotAfter:  time.Now().Add(time.Hour),
+		}
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		var seen []*big.Int
+		checker := func(sn *big.Int) (bool, error) {
+			seen = append(seen, sn)
+			return len(seen) == 1, nil
+		}
+		crt, err := CreateCertificate(newTemplate(), iss, pub, issPriv, WithSerialChecker(checker))
+		require.NoError(t, err)
+		require.Len(t, seen, 2)
+		require.NotEqual(t, seen[0], crt.SerialNumber)
+		require.Equal(t, seen[1], crt.SerialNumber)
+	})
+
+	t.Run("ok template serial", func(t *testing.T) {
+		tpl := newTemplate()
+		tpl.SerialNumber = big.NewInt(1234)
+		crt, err := CreateCertificate(tpl, iss, pub, issPriv, WithSerialChecker(func(*big.Int) (bool, error) {
+			return true, nil
+		}))
+		require.NoError(t, err)
+		require.Equal(t, big.NewInt(1234), crt.SerialNumber)
+	})
+
+	t.Run("fail too many attempts", func(t *testing.T) {
+		var calls int
+		_, err := CreateCertificate(newTemplate(), iss, pub, issPriv, WithSerialChecker(func(*big.Int) (bool, error) {
+			calls++
+			return true, nil
+		}))
+		require.Error(t, err)
+		require.Equal(t, maxSerialNumberAttempts, calls)
+	})
+
+	t.Run("fail checker", func(t *testing.T) {
+		_, err := CreateCertificate(newTemplate(), iss, pub, issPriv, WithSerialChecker(func(*big.Int) (bool, error) {
+			return false, errors.New("database is down")
+		}))
+		require.ErrorContains(t, err, "database is down")
+	})
+
+	t.Run("fail nil", func(t *testing.T) {
+		_, err := CreateCertificate(newTemplate(), iss, pub, issPriv, WithSerialChecker(nil))
+		require.Error(t, err)
+	})
+}
+
+func TestWithCriticalExtKeyUsage(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "Time Stamping Authority", nil)
+	iss, issPriv := createIssuerCertificate(t, "issuer")
+
+	tsaTemplate := `{
+		"subject": {{ toJson .Subject }},
+		"keyUsage": ["digitalSignature"],
+		"extKeyUsage": ["timeStamping"]
+	}`
+	getEKU := func(t *testing.T, cert *x509.Certificate) pkix.Extension {
+		t.Helper()
+		for _, ext := range cert.Extensions {
+			if ext.Id.Equal(asn1.ObjectIdentifier{2, 5, 29, 37}) {
+				return ext
+			}
+		}
+		t.Fatal("extended key usage extension not found")
+		return pkix.Extension{}
+	}
+
+	// A compliant TSA certificate.
+	cert, err := NewCertificate(cr, WithTemplate(tsaTemplate, nil), WithCriticalExtKeyUsage())
+	require.NoError(t, err)
+	template := cert.GetCertificate()
+	got, err := CreateCertificate(template, iss, template.PublicKey, issPriv)
+	require.NoError(t, err)
+	require.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping}, got.ExtKeyUsage)
+	require.Empty(t, got.UnhandledCriticalExtensions)
+	ext := getEKU(t, got)
+	require.True(t, ext.Critical)
+	require.Equal(t, []byte{0x30, 0x0a, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x05, 0x05, 0x07, 0x03, 0x08}, ext.Value)
+
+	// A non-compliant TSA certificate, with extra extended key usages.
+	_, err = NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"keyUsage": ["digitalSignature"],
+		"extKeyUsage": ["timeStamping", "serverAuth"]
+	}`, nil), WithCriticalExtKeyUsage(), WithValidation())
+	require.EqualError(t, err, "invalid certificate: the timeStamping extended key usage must be the only extended key usage")
+
+	// A non-compliant TSA certificate, with a non-critical extension.
+	_, err = NewCertificate(cr, WithTemplate(tsaTemplate, nil), WithValidation())
+	require.EqualError(t, err, "invalid certificate: the extended key usage extension must be critical if it contains timeStamping")
+
+	// Without WithValidation, the non-critical extension is not rejected.
+	cert, err = NewCertificate(cr, WithTemplate(tsaTemplate, nil))
+	require.NoError(t, err)
+	require.Equal(t, ExtKeyUsage{x509.ExtKeyUsageTimeStamping}, cert.ExtKeyUsage)
+
+	// The option marks a custom extension as critical.
+	cert, err = NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"extensions": [{"id": "2.5.29.37", "value": "MAoGCCsGAQUFBwMI"}]
+	}`, nil), WithCriticalExtKeyUsage())
+	require.NoError(t, err)
+	require.Len(t, cert.Extensions, 1)
+	require.True(t, cert.Extensions[0].Critical)
+
+	// Other extended key usages are also marked as critical.
+	cert, err = NewCertificate(cr, WithTemplate(`{
+		"subject": {{ toJson .Subject }},
+		"extKeyUsage": ["serverAuth", "clientAuth"],
+		"unknownExtKeyUsage": ["1.2.3.4"]
+	}`, nil), WithCriticalExtKeyUsage())
+	require.NoError(t, err)
+	template = cert.GetCertificate()
+	got, err = CreateCertificate(template, iss, template.PublicKey, issPriv)
+	require.NoError(t, err)
+	require.True(t, getEKU(t, got).Critical)
+	require.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}, got.ExtKeyUsage)
+	require.Equal(t, []asn1.ObjectIdentifier{{1, 2, 3, 4}}, got.UnknownExtKeyUsage)
+
+	// Without extended key usages, the extension is not added.
+	cert, err = NewCertificate(cr, WithTemplate(`{"subject": {{ toJson .Subject }}}`, nil), WithCriticalExtKeyUsage())
+	require.NoError(t, err)
+	require.Empty(t, cert.Extensions)
+}
+
+func TestWithAutoSignatureAlgorithm(t *testing.T) {
+	mustSigner := func(t *testing.T, fn func() (crypto.Signer, error)) crypto.Signer {
+		t.Helper()
+		signer, err := fn()
+		require.NoError(t, err)
+		return signer
+	}
+	ecKey := func(c elliptic.Curve) func() (crypto.Signer, error) {
+		return func() (crypto.Signer, error) { return ecdsa.GenerateKey(c, rand.Reader) }
+	}
+	rsaKey := func(bits int) func() (crypto.Signer, error) {
+		return func() (crypto.Signer, error) { return rsa.GenerateKey(rand.Reader, bits) }
+	}
+	edKey := func() (crypto.Signer, error) {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		return priv, err
+	}
+
+	p256 := mustSigner(t, ecKey(elliptic.P256()))
+	p384 := mustSigner(t, ecKey(elliptic.P384()))
+	p521 := mustSigner(t, ecKey(elliptic.P521()))
+	rsa2048 := mustSigner(t, rsaKey(2048))
+	rsa3072 := mustSigner(t, rsaKey(3072))
+	rsa4096 := mustSigner(t, rsaKey(4096))
+	ed := mustSigner(t, edKey)
+
+	tests := []struct {
+		name   string
+		signer crypto.Signer
+		sigAlg x509.SignatureAlgorithm
+		opts   []CreateCertificateOption
+		want   x509.SignatureAlgorithm
+	}{
+		{"P-256", p256, 0, []CreateCertificateOption{WithAutoSignatureAlgorithm()}, x509.ECDSAWithSHA256},
+		{"P-384", p384, 0, []CreateCertificateOption{WithAutoSignatureAlgorithm()}, x509.ECDSAWithSHA384},
+		{"P-521", p521, 0, []CreateCertificateOption{WithAutoSignatureAlgorithm()}, x509.ECDSAWithSHA512},
+		{"RSA 2048", rsa2048, 0, []CreateCertificateOption{WithAutoSignatureAlgorithm()}, x509.SHA256WithRSA},
+		{"RSA 3072", rsa3072, 0, []CreateCertificateOption{WithAutoSignatureAlgorithm()}, x509.SHA384WithRSA},
+		{"RSA 4096", rsa4096, 0, []CreateCertificateOption{WithAutoSignatureAlgorithm()}, x509.SHA512WithRSA},
+		{"Ed25519", ed, 0, []CreateCertificateOption{WithAutoSignatureAlgorithm()}, x509.PureEd25519},
+		{"template", rsa4096, x509.SHA256WithRSAPSS, []CreateCertificateOption{WithAutoSignatureAlgorithm()}, x509.SHA256WithRSAPSS},
+		{"default P-384", p384, 0, nil, x509.ECDSAWithSHA384},
+		{"default RSA 4096", rsa4096, 0, nil, x509.SHA256WithRSA},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := &x509.Certificate{
+				Subject:            pkix.Name{CommonName: "root"},
+				NotBefore:          time.Now(),
+				NotAfter:           time.Now().Add(time.Hour),
+				SignatureAlgorithm: tt.sigAlg,
+			}
+			crt, err := CreateCertificate(template, template, tt.signer.Public(), tt.signer, tt.opts...)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, crt.SignatureAlgorithm)
+		})
+	}
+
+	t.Run("parent", func(t *testing.T) {
+		parent, err := CreateCertificate(&x509.Certificate{
+			Subject:               pkix.Name{CommonName: "issuer"},
+			NotBefore:             time.Now(),
+			NotAfter:              time.Now().Add(time.Hour),
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}, &x509.Certificate{Subject: pkix.Name{CommonName: "issuer"}}, p521.Public(), p521, WithAutoSignatureAlgorithm())
+		require.NoError(t, err)
+		require.Equal(t, x509.ECDSAWithSHA512, parent.SignatureAlgorithm)
+
+		// The algorithm depends on the key of the parent, not the leaf.
+		crt, err := CreateCertificate(&x509.Certificate{
+			Subject:   pkix.Name{CommonName: "leaf"},
+			NotBefore: time.Now(),
+			NotAfter:  time.Now().Add(time.Hour),
+		}, parent, ed.Public(), p521, WithAutoSignatureAlgorithm())
+		require.NoError(t, err)
+		require.Equal(t, x509.ECDSAWithSHA512, crt.SignatureAlgorithm)
+		require.NoError(t, crt.CheckSignatureFrom(parent))
+	})
+}
+
+func TestWithTemplateBaseDir(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "foo", nil)
+
+	root := t.TempDir()
+	baseDir := filepath.Join(root, "templates")
+	require.NoError(t, os.Mkdir(baseDir, 0o700))
+	require.NoError(t, os.Mkdir(filepath.Join(baseDir, "sans"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(baseDir, "sans", "dns.txt"), []byte("# DNS names\nfoo.example.com\n\n  bar.example.com  \n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(baseDir, "ou.txt"), []byte("Engineering"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret.example.com"), 0o600))
+	require.NoError(t, os.Symlink(filepath.Join(root, "secret.txt"), filepath.Join(baseDir, "link.txt")))
+
+	tests := []struct {
+		name     string
+		text     string
+		opts     []Option
+		wantDNS  []string
+		wantOU   []string
+		errMatch string
+	}{
+		{"ok", `{
+			"subject": {"commonName": "foo", "organizationalUnit": {{ readFile "ou.txt" | toJson }}},
+			"dnsNames": {{ readLines "sans/dns.txt" | toJson }}
+		}`, []Option{WithTemplateBaseDir(baseDir)}, []string{"foo.example.com", "bar.example.com"}, []string{"Engineering"}, ""},
+		{"fail traversal", `{"dnsNames": {{ readLines "../secret.txt" | toJson }}}`, []Option{WithTemplateBaseDir(baseDir)}, nil, nil, "file is outside of the template base directory"},
+		{"fail nested traversal", `{"dnsNames": {{ readLines "sans/../../secret.txt" | toJson }}}`, []Option{WithTemplateBaseDir(baseDir)}, nil, nil, "file is outside of the template base directory"},
+		{"fail absolute", `{"dnsNames": {{ readLines "` + filepath.ToSlash(filepath.Join(root, "secret.txt")) + `" | toJson }}}`, []Option{WithTemplateBaseDir(baseDir)}, nil, nil, "file is outside of the template base directory"},
+		{"fail symlink", `{"dnsNames": {{ readLines "link.txt" | toJson }}}`, []Option{WithTemplateBaseDir(baseDir)}, nil, nil, "file is outside of the template base directory"},
+		{"fail missing", `{"dnsNames": {{ readLines "missing.txt" | toJson }}}`, []Option{WithTemplateBaseDir(baseDir)}, nil, nil, "error reading missing.txt"},
+		{"fail no base dir", `{"dnsNames": {{ readLines "sans/dns.txt" | toJson }}}`, nil, nil, nil, "template base directory is not set"},
+		{"fail empty base dir", `{}`, []Option{WithTemplateBaseDir("")}, nil, nil, "template base directory cannot be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append(tt.opts, WithTemplate(tt.text, NewTemplateData()))
+			cert, err := NewCertificate(cr, opts...)
+			if tt.errMatch != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.errMatch)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, MultiString(tt.wantDNS), cert.DNSNames)
+			require.Equal(t, MultiString(tt.wantOU), cert.Subject.OrganizationalUnit)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package x509util
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	oidPKCS7Data       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidPKCS7SignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+)
+
+// pkcs7ContentInfo is the ContentInfo type defined in RFC 2315, section 7.
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"optional"`
+}
+
+// pkcs7SignedData is the SignedData type defined in RFC 2315, section 9.1.
+// The optional crls field is never used.
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      pkcs7ContentInfo
+	Certificates     asn1.RawValue   `asn1:"optional,tag:0"`
+	SignerInfos      []asn1.RawValue `asn1:"set"`
+}
+
+// MarshalPKCS7 returns the DER encoding of a degenerate PKCS#7 SignedData
+// containing only the given certificates, without signers or content. This
+// is the format usually found in .p7b or .p7c files. The certificates are
+// encoded in the given order.
+func MarshalPKCS7(certs []*x509.Certificate) ([]byte, error) {
+	if len(certs) == 0 {
+		return nil, errors.New("error marshaling PKCS#7: certificates cannot be empty")
+	}
+
+	var raw []byte
+	for i, crt := range certs {
+		if crt == nil || len(crt.Raw) == 0 {
+			return nil, errors.Errorf("error marshaling PKCS#7: certificate %d is not valid", i)
+		}
+		raw = append(raw, crt.Raw...)
+	}
+
+	signedData, err := asn1.Marshal(pkcs7SignedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{},
+		ContentInfo: pkcs7ContentInfo{
+			ContentType: oidPKCS7Data,
+		},
+		Certificates: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      raw,
+		},
+		SignerInfos: []asn1.RawValue{},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling PKCS#7")
+	}
+
+	der, err := asn1.Marshal(pkcs7ContentInfo{
+		ContentType: oidPKCS7SignedData,
+		Content: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      signedData,
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling PKCS#7")
+	}
+	return der, nil
+}
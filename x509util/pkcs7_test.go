@@ -0,0 +1,64 @@
+package x509util
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalPKCS7(t *testing.T) {
+	root, _ := createIssuerCertificate(t, "root")
+	intermediate, _ := createIssuerCertificate(t, "intermediate")
+
+	tests := []struct {
+		name  string
+		certs []*x509.Certificate
+	}{
+		{"one", []*x509.Certificate{root}},
+		{"chain", []*x509.Certificate{intermediate, root}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			der, err := MarshalPKCS7(tt.certs)
+			require.NoError(t, err)
+
+			var ci pkcs7ContentInfo
+			rest, err := asn1.Unmarshal(der, &ci)
+			require.NoError(t, err)
+			assert.Empty(t, rest)
+			assert.Equal(t, oidPKCS7SignedData, ci.ContentType)
+			assert.Equal(t, asn1.ClassContextSpecific, ci.Content.Class)
+			assert.Equal(t, 0, ci.Content.Tag)
+
+			var sd pkcs7SignedData
+			rest, err = asn1.Unmarshal(ci.Content.Bytes, &sd)
+			require.NoError(t, err)
+			assert.Empty(t, rest)
+			assert.Equal(t, 1, sd.Version)
+			assert.Empty(t, sd.DigestAlgorithms)
+			assert.Empty(t, sd.SignerInfos)
+			assert.Equal(t, oidPKCS7Data, sd.ContentInfo.ContentType)
+
+			certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+			require.NoError(t, err)
+			require.Len(t, certs, len(tt.certs))
+			for i, crt := range tt.certs {
+				assert.Equal(t, crt.Raw, certs[i].Raw)
+			}
+		})
+	}
+}
+
+func TestMarshalPKCS7_fail(t *testing.T) {
+	root, _ := createIssuerCertificate(t, "root")
+
+	_, err := MarshalPKCS7(nil)
+	assert.EqualError(t, err, "error marshaling PKCS#7: certificates cannot be empty")
+	_, err = MarshalPKCS7([]*x509.Certificate{root, nil})
+	assert.EqualError(t, err, "error marshaling PKCS#7: certificate 1 is not valid")
+	_, err = MarshalPKCS7([]*x509.Certificate{{}})
+	assert.EqualError(t, err, "error marshaling PKCS#7: certificate 0 is not valid")
+}
@@ -121,7 +121,20 @@ func (t TemplateData) SetAuthorizationCertificateChain(chain interface{}) {
 }
 
 // SetCertificateRequest sets the given certificate request in the insecure
-// template data.
+// template data. The certificate request is available in templates as
+// `.Insecure.CR`, with the following fields:
+//
+//   - .Insecure.CR.Subject: the subject, e.g. .Insecure.CR.Subject.CommonName.
+//   - .Insecure.CR.DNSNames: the DNS names.
+//   - .Insecure.CR.EmailAddresses: the email addresses.
+//   - .Insecure.CR.IPAddresses: the IP addresses.
+//   - .Insecure.CR.URIs: the URIs.
+//   - .Insecure.CR.Extensions: the extensions in the certificate request.
+//   - .Insecure.CR.PublicKey: the public key, e.g. *rsa.PublicKey.
+//   - .Insecure.CR.PublicKeyAlgorithm: the public key algorithm.
+//
+// These values are not validated, and templates should only use them if the
+// certificate request has been authorized.
 func (t TemplateData) SetCertificateRequest(cr *x509.CertificateRequest) {
 	t.SetInsecure(CertificateRequestKey, NewCertificateRequestFromX509(cr))
 }
@@ -5,11 +5,15 @@ import (
 	"crypto"
 	"crypto/rand"
 	"crypto/sha1" //nolint:gosec // SubjectKeyIdentifier by RFC 5280
+	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
+	"fmt"
 	"math/big"
 	"net"
+	"net/mail"
 	"net/url"
 	"strings"
 	"unicode"
@@ -43,9 +47,98 @@ func SanitizeName(domain string) (string, error) {
 	return name, nil
 }
 
-// SplitSANs splits a slice of Subject Alternative Names into slices of
-// IP Addresses and DNS Names. If an element is not an IP address, then it
-// is bucketed as a DNS Name.
+// dnsNameProfile is the IDNA profile used to validate and normalize DNS names.
+var dnsNameProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.ValidateLabels(true),
+	idna.VerifyDNSLength(true),
+	idna.StrictDomainName(true),
+)
+
+// validateDNSName validates the given DNS name and returns its ASCII form,
+// converting internationalized labels to A-labels (punycode). A wildcard is
+// only allowed as the complete leftmost label.
+func validateDNSName(name string) (string, error) {
+	var prefix string
+	if strings.HasPrefix(name, "*.") {
+		prefix, name = "*.", name[2:]
+	}
+	if name == "" || strings.HasSuffix(name, ".") {
+		return "", errors.Errorf("invalid DNS name %q", prefix+name)
+	}
+	ascii, err := dnsNameProfile.ToASCII(name)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid DNS name %q", prefix+name)
+	}
+	return prefix + ascii, nil
+}
+
+// validateEmail validates that the given email is an addr-spec, as parsed by
+// mail.ParseAddress, without a display name, angle brackets or comments, and
+// returns it with the domain converted to its ASCII form. The local part must
+// only contain ASCII characters, and it cannot be quoted.
+func validateEmail(email string) (string, error) {
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid email %q", email)
+	}
+	if addr.Name != "" || addr.Address != email {
+		return "", errors.Errorf("invalid email %q: email must be an address without a display name", email)
+	}
+	i := strings.LastIndex(email, "@")
+	local, domain := email[:i], email[i+1:]
+	if !isIA5String(local) {
+		return "", errors.Errorf("invalid email %q: local part must only contain ASCII characters", email)
+	}
+	if strings.HasSuffix(domain, ".") {
+		return "", errors.Errorf("invalid email %q", email)
+	}
+	ascii, err := dnsNameProfile.ToASCII(domain)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid email %q", email)
+	}
+	return local + "@" + ascii, nil
+}
+
+// validateURI validates that the given URI can be used in a
+// uniformResourceIdentifier general name, it must be an absolute URI with a
+// scheme and a hierarchical part, relative and opaque URIs are not allowed.
+func validateURI(u *url.URL) error {
+	switch {
+	case u == nil:
+		return errors.New("invalid URI: URI cannot be empty")
+	case u.Scheme == "":
+		return errors.Errorf("invalid URI %q: URI must have a scheme", u.String())
+	case u.Opaque != "":
+		return errors.Errorf("invalid URI %q: URI cannot be opaque", u.String())
+	}
+	return nil
+}
+
+// ClassifySAN returns the type of subject alternative name used for the given
+// value when the type is "auto" or empty. The rules are applied in order:
+//   - IPType if the value is an IP address.
+//   - EmailType if the value contains an "@", e.g. "mailto:jane@doe.com".
+//   - URIType if the value is a URI with a scheme.
+//   - DNSType otherwise.
+func ClassifySAN(value string) string {
+	if ip := net.ParseIP(value); ip != nil {
+		return IPType
+	}
+	if strings.Contains(value, "@") {
+		return EmailType
+	}
+	if u, err := url.Parse(value); err == nil && u.Scheme != "" {
+		return URIType
+	}
+	return DNSType
+}
+
+// SplitSANs splits a slice of Subject Alternative Names into slices of DNS
+// names, IP addresses, email addresses and URIs. Unlike ClassifySAN, values
+// with a scheme are always URIs, even if they contain an "@", e.g.
+// "mailto:jane@doe.com".
 func SplitSANs(sans []string) (dnsNames []string, ips []net.IP, emails []string, uris []*url.URL) {
 	dnsNames = []string{}
 	ips = []net.IP{}
@@ -98,19 +191,84 @@ func generateSerialNumber() (*big.Int, error) {
 	return sn, nil
 }
 
+// maxSerialNumberAttempts is the maximum number of serial numbers generated
+// when a serial checker reports that they are already in use.
+const maxSerialNumberAttempts = 10
+
+// generateCheckedSerialNumber returns a random serial number that the given
+// checker does not report as used. If checker is nil, the first serial number
+// generated is returned.
+func generateCheckedSerialNumber(checker func(*big.Int) (bool, error)) (*big.Int, error) {
+	for i := 0; i < maxSerialNumberAttempts; i++ {
+		sn, err := generateSerialNumber()
+		if err != nil {
+			return nil, err
+		}
+		if checker == nil {
+			return sn, nil
+		}
+		used, err := checker(sn)
+		if err != nil {
+			return nil, errors.Wrap(err, "error checking serial number")
+		}
+		if !used {
+			return sn, nil
+		}
+	}
+	return nil, errors.Errorf("error generating serial number: too many attempts (%d)", maxSerialNumberAttempts)
+}
+
 // subjectPublicKeyInfo is a PKIX public key structure defined in RFC 5280.
 type subjectPublicKeyInfo struct {
 	Algorithm        pkix.AlgorithmIdentifier
 	SubjectPublicKey asn1.BitString
 }
 
-// generateSubjectKeyID generates the key identifier according the the RFC 5280
-// section 4.2.1.2.
-//
-// The keyIdentifier is composed of the 160-bit SHA-1 hash of the value of the
-// BIT STRING subjectPublicKey (excluding the tag, length, and number of unused
-// bits).
-func generateSubjectKeyID(pub crypto.PublicKey) ([]byte, error) {
+// SKIMethod is the method used to generate the subject key identifier of a
+// certificate.
+type SKIMethod int
+
+const (
+	// SKIMethodSHA1 generates the key identifier using the 160-bit SHA-1 hash
+	// of the value of the BIT STRING subjectPublicKey (excluding the tag,
+	// length, and number of unused bits), as described in RFC 5280 section
+	// 4.2.1.2. This is the default method.
+	SKIMethodSHA1 SKIMethod = iota
+	// SKIMethodRFC7093Method1 generates the key identifier using the leftmost
+	// 160 bits of the SHA-256 hash of the value of the BIT STRING
+	// subjectPublicKey.
+	SKIMethodRFC7093Method1
+	// SKIMethodRFC7093Method2 generates the key identifier using the leftmost
+	// 160 bits of the SHA-384 hash of the value of the BIT STRING
+	// subjectPublicKey.
+	SKIMethodRFC7093Method2
+	// SKIMethodRFC7093Method3 generates the key identifier using the leftmost
+	// 160 bits of the SHA-512 hash of the value of the BIT STRING
+	// subjectPublicKey.
+	SKIMethodRFC7093Method3
+)
+
+// String returns a text representation of the SKIMethod.
+func (m SKIMethod) String() string {
+	switch m {
+	case SKIMethodSHA1:
+		return "SHA-1"
+	case SKIMethodRFC7093Method1:
+		return "RFC 7093 method 1"
+	case SKIMethodRFC7093Method2:
+		return "RFC 7093 method 2"
+	case SKIMethodRFC7093Method3:
+		return "RFC 7093 method 3"
+	default:
+		return fmt.Sprintf("SKIMethod(%d)", int(m))
+	}
+}
+
+// GenerateSubjectKeyID generates the subject key identifier of the given public
+// key using the given method. The SKIMethodSHA1 method is the one used by
+// default in CreateCertificate, and it's compatible with the one used in the
+// Go standard library.
+func GenerateSubjectKeyID(pub crypto.PublicKey, method SKIMethod) ([]byte, error) {
 	b, err := x509.MarshalPKIXPublicKey(pub)
 	if err != nil {
 		return nil, errors.Wrap(err, "error marshaling public key")
@@ -119,9 +277,32 @@ func generateSubjectKeyID(pub crypto.PublicKey) ([]byte, error) {
 	if _, err = asn1.Unmarshal(b, &info); err != nil {
 		return nil, errors.Wrap(err, "error unmarshaling public key")
 	}
-	//nolint:gosec // SubjectKeyIdentifier by RFC 5280
-	hash := sha1.Sum(info.SubjectPublicKey.Bytes)
-	return hash[:], nil
+
+	var hash []byte
+	switch method {
+	case SKIMethodSHA1:
+		//nolint:gosec // SubjectKeyIdentifier by RFC 5280
+		sum := sha1.Sum(info.SubjectPublicKey.Bytes)
+		hash = sum[:]
+	case SKIMethodRFC7093Method1:
+		sum := sha256.Sum256(info.SubjectPublicKey.Bytes)
+		hash = sum[:]
+	case SKIMethodRFC7093Method2:
+		sum := sha512.Sum384(info.SubjectPublicKey.Bytes)
+		hash = sum[:]
+	case SKIMethodRFC7093Method3:
+		sum := sha512.Sum512(info.SubjectPublicKey.Bytes)
+		hash = sum[:]
+	default:
+		return nil, errors.Errorf("unsupported subject key identifier method %s", method)
+	}
+	return hash[:20], nil
+}
+
+// generateSubjectKeyID generates the key identifier according the the RFC 5280
+// section 4.2.1.2.
+func generateSubjectKeyID(pub crypto.PublicKey) ([]byte, error) {
+	return GenerateSubjectKeyID(pub, SKIMethodSHA1)
 }
 
 // subjectIsEmpty returns whether the given pkix.Name (aka Subject) is an empty sequence
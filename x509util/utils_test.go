@@ -2,12 +2,15 @@ package x509util
 
 import (
 	"crypto"
+	"crypto/ed25519"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/pem"
 	"net"
 	"net/url"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -28,6 +31,45 @@ func decodeCertificateFile(t *testing.T, filename string) *x509.Certificate {
 	return crt
 }
 
+func TestClassifySAN(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"1.2.3.4", IPType},
+		{"::1", IPType},
+		{"2102:446:c001:d65e:ab1a:bf20:4b26:31f7", IPType},
+		{"a@b", EmailType},
+		{"jane@doe.com", EmailType},
+		{"https://x", URIType},
+		{"mailto:jane@doe.com", EmailType},
+		{"https://user@host.example", EmailType},
+		{"urn:uuid:ddfe62ba-7e99-4bc1-83b3-8f57fe3e9959", URIType},
+		{"host.example", DNSType},
+		{"*.example.com", DNSType},
+		{"1.2.3.4.example", DNSType},
+		{"localhost", DNSType},
+		{"", DNSType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := ClassifySAN(tt.value); got != tt.want {
+				t.Errorf("ClassifySAN() = %v, want %v", got, tt.want)
+			}
+			// Set must use the same rules.
+			want := &x509.Certificate{}
+			SubjectAlternativeName{Type: tt.want, Value: tt.value}.Set(want)
+			for _, typ := range []string{"", AutoType} {
+				got := &x509.Certificate{}
+				SubjectAlternativeName{Type: typ, Value: tt.value}.Set(got)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("SubjectAlternativeName.Set() = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
 func TestSplitSANs(t *testing.T) {
 	type args struct {
 		sans []string
@@ -176,6 +218,53 @@ func Test_generateSubjectKeyID(t *testing.T) {
 	}
 }
 
+func TestGenerateSubjectKeyID(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	pub := ed25519.NewKeyFromSeed(seed).Public()
+	rsaCrt := decodeCertificateFile(t, "testdata/smallstep.crt")
+	mustHex := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	type args struct {
+		pub    crypto.PublicKey
+		method SKIMethod
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []byte
+		wantErr bool
+	}{
+		{"sha1", args{pub, SKIMethodSHA1}, mustHex("fd81a6db64d6faf7f702c07971a82c25c1dc3c90"), false},
+		{"rfc7093 method 1", args{pub, SKIMethodRFC7093Method1}, mustHex("56475aa75463474c0285df5dbf2bcab73da65135"), false},
+		{"rfc7093 method 2", args{pub, SKIMethodRFC7093Method2}, mustHex("7866f6eafd2da405968eafb547117488936209b4"), false},
+		{"rfc7093 method 3", args{pub, SKIMethodRFC7093Method3}, mustHex("ed4242ead4ac69486ebba1694968b592f3cd476b"), false},
+		{"rsa sha1", args{rsaCrt.PublicKey, SKIMethodSHA1}, rsaCrt.SubjectKeyId, false},
+		{"fail method", args{pub, SKIMethod(100)}, nil, true},
+		{"fail key", args{[]byte("fail"), SKIMethodSHA1}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateSubjectKeyID(tt.args.pub, tt.args.method)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GenerateSubjectKeyID() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GenerateSubjectKeyID() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestSanitizeName(t *testing.T) {
 	type args struct {
 		domain string
@@ -205,3 +294,83 @@ func TestSanitizeName(t *testing.T) {
 		})
 	}
 }
+
+func Test_validateDNSName(t *testing.T) {
+	tests := []struct {
+		name    string
+		dnsName string
+		want    string
+		wantErr bool
+	}{
+		{"ok", "example.com", "example.com", false},
+		{"ok lowercase", "Foo.Example.COM", "foo.example.com", false},
+		{"ok wildcard", "*.example.com", "*.example.com", false},
+		{"ok idna", "münchen.example", "xn--mnchen-3ya.example", false},
+		{"ok idna wildcard", "*.münchen.example", "*.xn--mnchen-3ya.example", false},
+		{"ok punycode", "xn--mnchen-3ya.example", "xn--mnchen-3ya.example", false},
+		{"ok single label", "localhost", "localhost", false},
+		{"fail empty", "", "", true},
+		{"fail empty label", "foo_bar..com", "", true},
+		{"fail double dot", "foo..com", "", true},
+		{"fail underscore", "foo_bar.com", "", true},
+		{"fail trailing dot", "example.com.", "", true},
+		{"fail hyphen", "-foo.example.com", "", true},
+		{"fail space", "foo bar.com", "", true},
+		{"fail wildcard only", "*.", "", true},
+		{"fail wildcard", "*", "", true},
+		{"fail wildcard not leftmost", "foo.*.example.com", "", true},
+		{"fail partial wildcard", "f*.example.com", "", true},
+		{"fail label too long", strings.Repeat("a", 64) + ".com", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateDNSName(tt.dnsName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateDNSName() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateDNSName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateEmail(t *testing.T) {
+	tests := []struct {
+		name    string
+		email   string
+		want    string
+		wantErr bool
+	}{
+		{"ok", "jane@example.com", "jane@example.com", false},
+		{"ok plus", "jane+certs@example.com", "jane+certs@example.com", false},
+		{"ok lowercase domain", "Jane@Example.COM", "Jane@example.com", false},
+		{"ok idna", "jane@münchen.example", "jane@xn--mnchen-3ya.example", false},
+		{"ok punycode", "jane@xn--mnchen-3ya.example", "jane@xn--mnchen-3ya.example", false},
+		{"fail empty", "", "", true},
+		{"fail no at", "jane.example.com", "", true},
+		{"fail no local part", "@example.com", "", true},
+		{"fail no domain", "jane@", "", true},
+		{"fail display name", "Jane Doe <jane@example.com>", "", true},
+		{"fail angle brackets", "<jane@example.com>", "", true},
+		{"fail comment", "jane@example.com (Jane)", "", true},
+		{"fail quoted local part", `"jane doe"@example.com`, "", true},
+		{"fail two at", "jane@doe@example.com", "", true},
+		{"fail non ascii local part", "jäne@example.com", "", true},
+		{"fail trailing dot", "jane@example.com.", "", true},
+		{"fail invalid domain", "jane@foo_bar.com", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateEmail(tt.email)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateEmail() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateEmail() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package x509util
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// NewCertificateFromYAML creates a new Certificate from the given certificate
+// request and the certificate template in YAML format read from r. The YAML
+// document is converted to JSON, so it uses the same keys as a JSON template,
+// and it can contain comments and multiline strings. The template is not
+// evaluated as a text/template.
+//
+// The options are applied as in NewCertificate, but the template options, like
+// WithTemplate, are ignored.
+func NewCertificateFromYAML(cr *x509.CertificateRequest, r io.Reader, opts ...Option) (*Certificate, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading template")
+	}
+	data, err := yamlToJSON(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling certificate")
+	}
+
+	opts = append(opts[:len(opts):len(opts)], func(_ *x509.CertificateRequest, o *Options) error {
+		o.CertBuffer = bytes.NewBuffer(data)
+		return nil
+	})
+	return NewCertificate(cr, opts...)
+}
+
+// yamlToJSON converts the given YAML document into JSON.
+func yamlToJSON(b []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	v, err := convertYAMLValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// convertYAMLValue converts the maps with non-string keys that YAML supports
+// into maps with string keys that can be encoded as JSON.
+func convertYAMLValue(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, vv := range t {
+			cv, err := convertYAMLValue(vv)
+			if err != nil {
+				return nil, err
+			}
+			t[k] = cv
+		}
+		return t, nil
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, vv := range t {
+			cv, err := convertYAMLValue(vv)
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case string, bool, int, int64, uint64, float64:
+				m[fmt.Sprint(k)] = cv
+			default:
+				return nil, errors.Errorf("unsupported key %v of type %T", k, k)
+			}
+		}
+		return m, nil
+	case []interface{}:
+		for i, vv := range t {
+			cv, err := convertYAMLValue(vv)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = cv
+		}
+		return t, nil
+	default:
+		return v, nil
+	}
+}
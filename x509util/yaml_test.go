@@ -0,0 +1,101 @@
+package x509util
+
+import (
+	"crypto/x509"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCertificateFromYAML(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "leaf.example.com", []string{"leaf.example.com"})
+
+	jsonTemplate := `{
+		"version": 3,
+		"subject": {"commonName": "leaf.example.com", "organization": ["Smallstep"]},
+		"serialNumber": 1234,
+		"dnsNames": ["leaf.example.com", "www.example.com"],
+		"ipAddresses": ["127.0.0.1"],
+		"keyUsage": ["digitalSignature", "keyEncipherment"],
+		"extKeyUsage": ["serverAuth", "clientAuth"],
+		"basicConstraints": {"isCA": false},
+		"policyIdentifiers": ["1.2.3.4"],
+		"netscapeComment": "Issued by the example CA.\nDo not use in production.\n",
+		"extensions": [{"id": "1.2.3.4", "critical": false, "value": "AQID"}]
+	}`
+
+	yamlTemplate := `# Leaf certificate for the web servers.
+version: 3
+subject:
+  commonName: leaf.example.com
+  organization: Smallstep  # a single value is also valid
+serialNumber: 1234
+dnsNames:
+  - leaf.example.com
+  - www.example.com
+ipAddresses: [127.0.0.1]
+keyUsage: [digitalSignature, keyEncipherment]
+extKeyUsage:
+  - serverAuth
+  - clientAuth
+basicConstraints:
+  isCA: false
+policyIdentifiers: ["1.2.3.4"]
+netscapeComment: |
+  Issued by the example CA.
+  Do not use in production.
+extensions:
+  - id: 1.2.3.4
+    critical: false
+    value: AQID
+`
+
+	want, err := NewCertificate(cr, WithTemplate(jsonTemplate, nil))
+	require.NoError(t, err)
+
+	got, err := NewCertificateFromYAML(cr, strings.NewReader(yamlTemplate))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, "Issued by the example CA.\nDo not use in production.\n", string(got.NetscapeComment))
+
+	// Options are applied.
+	got, err = NewCertificateFromYAML(cr, strings.NewReader(yamlTemplate), WithKeyUsage(0), WithCopyCNToSAN())
+	require.NoError(t, err)
+	assert.Equal(t, KeyUsage(0), got.KeyUsage)
+	assert.Equal(t, MultiString{"leaf.example.com", "www.example.com"}, got.DNSNames)
+
+	// An empty document uses the defaults.
+	got, err = NewCertificateFromYAML(cr, strings.NewReader("# nothing here\n"))
+	require.NoError(t, err)
+	assert.Equal(t, &Certificate{PublicKey: cr.PublicKey, PublicKeyAlgorithm: cr.PublicKeyAlgorithm}, got)
+}
+
+func TestNewCertificateFromYAML_fail(t *testing.T) {
+	cr, _ := createCertificateRequest(t, "leaf.example.com", []string{"leaf.example.com"})
+
+	badCSR := *cr
+	badCSR.Signature = []byte("foo")
+
+	tests := []struct {
+		name string
+		cr   *x509.CertificateRequest
+		r    io.Reader
+	}{
+		{"fail read", cr, iotest.ErrReader(io.ErrUnexpectedEOF)},
+		{"fail yaml", cr, strings.NewReader("subject: [commonName: foo")},
+		{"fail key", cr, strings.NewReader("? [a, b]\n: value\n")},
+		{"fail type", cr, strings.NewReader("dnsNames: {foo: bar}")},
+		{"fail validate", cr, strings.NewReader("ipAddresses: [not-an-ip]")},
+		{"fail signature", &badCSR, strings.NewReader("subject: {commonName: foo}")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCertificateFromYAML(tt.cr, tt.r)
+			assert.Error(t, err)
+		})
+	}
+}